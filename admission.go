@@ -0,0 +1,138 @@
+// Copyright 2025 TimeWtr
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package vortexrotate
+
+import "bytes"
+
+// LogLevel 写入内容的日志级别，用于准入控制
+type LogLevel int
+
+const (
+	LogLevelTrace LogLevel = iota + 1
+	LogLevelDebug
+	LogLevelInfo
+	LogLevelWarn
+	LogLevelError
+)
+
+func (l LogLevel) String() string {
+	switch l {
+	case LogLevelTrace:
+		return "trace"
+	case LogLevelDebug:
+		return "debug"
+	case LogLevelInfo:
+		return "info"
+	case LogLevelWarn:
+		return "warn"
+	case LogLevelError:
+		return "error"
+	default:
+		return "unknown"
+	}
+}
+
+// 背压等级达到阈值之后丢弃对应级别的写入，warn和error级别的写入不会被丢弃
+const (
+	AdmissionDropTrace = 0.5
+	AdmissionDropDebug = 0.75
+	AdmissionDropInfo  = 1.0
+)
+
+// LevelParser 从写入内容中解析日志级别
+type LevelParser func(p []byte) LogLevel
+
+// levelPrefixLen 解析日志级别时检查的内容长度
+const levelPrefixLen = 64
+
+// levelTokens 日志级别在内容中的约定写法
+var levelTokens = []struct {
+	token []byte
+	level LogLevel
+}{
+	{[]byte("TRACE"), LogLevelTrace},
+	{[]byte("DEBUG"), LogLevelDebug},
+	{[]byte("INFO"), LogLevelInfo},
+	{[]byte("WARN"), LogLevelWarn},
+	{[]byte("ERROR"), LogLevelError},
+}
+
+// PrefixLevel 按照前缀约定解析日志级别：在内容的前64个字节中查找大写的TRACE、DEBUG、INFO、
+// WARN、ERROR，取最先出现的一个，比如"2025-01-01 10:00:00 [DEBUG] ..."，找不到时按照info处理
+func PrefixLevel(p []byte) LogLevel {
+	if len(p) > levelPrefixLen {
+		p = p[:levelPrefixLen]
+	}
+
+	level, pos := LogLevelInfo, -1
+	for _, t := range levelTokens {
+		idx := bytes.Index(p, t.token)
+		if idx >= 0 && (pos < 0 || idx < pos) {
+			level, pos = t.level, idx
+		}
+	}
+
+	return level
+}
+
+// WithAdmissionControl 开启准入控制，磁盘或者写入队列出现压力时优先丢弃低级别的日志，保证error
+// 日志可以正常写入：背压等级达到AdmissionDropTrace时丢弃trace日志，达到AdmissionDropDebug时
+// 丢弃debug日志，达到AdmissionDropInfo时丢弃info日志。通过Write写入的内容使用parser解析日志
+// 级别，parser为nil时使用PrefixLevel，通过WriteLevel写入时直接使用指定的级别。被丢弃的写入
+// 返回len(p)和nil，丢弃的数量可以通过Stats获取。
+func WithAdmissionControl(parser LevelParser) Option {
+	return func(r *Rotator) error {
+		if parser == nil {
+			parser = PrefixLevel
+		}
+
+		r.admission = parser
+		return nil
+	}
+}
+
+// WriteLevel 写入指定日志级别的内容，开启了准入控制时按照级别和当前的背压等级决定是否丢弃
+func (r *Rotator) WriteLevel(level LogLevel, p []byte) (int, error) {
+	if r.admission != nil && !r.admit(level) {
+		return len(p), nil
+	}
+	if r.writeQueue != nil {
+		return r.enqueueWrite(p)
+	}
+
+	return r.writeEntry(p)
+}
+
+// admit 判断当前的背压等级下是否允许写入该级别的内容，不允许时计入丢弃的数量
+func (r *Rotator) admit(level LogLevel) bool {
+	var threshold float64
+	switch level {
+	case LogLevelTrace:
+		threshold = AdmissionDropTrace
+	case LogLevelDebug:
+		threshold = AdmissionDropDebug
+	case LogLevelInfo:
+		threshold = AdmissionDropInfo
+	default:
+		return true
+	}
+
+	if r.backpressureLevel() < threshold {
+		return true
+	}
+
+	r.droppedWrites.Add(1)
+	return false
+}
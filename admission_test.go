@@ -0,0 +1,75 @@
+// Copyright 2025 TimeWtr
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package vortexrotate
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestPrefixLevel(t *testing.T) {
+	testCases := []struct {
+		name  string
+		input string
+		want  LogLevel
+	}{
+		{name: "debug", input: "2025-01-01 10:00:00 [DEBUG] hello\n", want: LogLevelDebug},
+		{name: "trace", input: "TRACE hello\n", want: LogLevelTrace},
+		{name: "error", input: "2025-01-01 10:00:00 ERROR hello DEBUG\n", want: LogLevelError},
+		{name: "no level", input: "hello world\n", want: LogLevelInfo},
+		{
+			name:  "beyond prefix",
+			input: "0123456789012345678901234567890123456789012345678901234567890123456789 DEBUG\n",
+			want:  LogLevelInfo,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			assert.Equal(t, tc.want, PrefixLevel([]byte(tc.input)))
+		})
+	}
+}
+
+func TestRotator_AdmissionControl(t *testing.T) {
+	const budget = 1000
+	rotator, err := NewRotator(t.TempDir(), "testdata.log",
+		WithMemoryBudget(budget), WithAdmissionControl(nil))
+	assert.NoError(t, err)
+	defer rotator.Close()
+
+	// 没有压力时全部写入
+	_, err = rotator.Write([]byte("[DEBUG] hello\n"))
+	assert.NoError(t, err)
+	assert.Equal(t, uint64(0), rotator.Stats().DroppedWrites)
+
+	rotator.budget.acquire(budget * 0.8)
+	defer rotator.budget.release(budget * 0.8)
+
+	n, err := rotator.Write([]byte("[DEBUG] hello\n"))
+	assert.NoError(t, err)
+	assert.Equal(t, len("[DEBUG] hello\n"), n)
+	_, err = rotator.WriteLevel(LogLevelTrace, []byte("hello\n"))
+	assert.NoError(t, err)
+	assert.Equal(t, uint64(2), rotator.Stats().DroppedWrites)
+
+	_, err = rotator.Write([]byte("[INFO] hello\n"))
+	assert.NoError(t, err)
+	_, err = rotator.WriteLevel(LogLevelError, []byte("hello\n"))
+	assert.NoError(t, err)
+	assert.Equal(t, uint64(2), rotator.Stats().DroppedWrites)
+	assert.Equal(t, int64(len("[DEBUG] hello\n[INFO] hello\nhello\n")), rotator.offset)
+}
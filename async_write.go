@@ -0,0 +1,280 @@
+// Copyright 2025 TimeWtr
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package vortexrotate
+
+import (
+	"sync"
+	"time"
+
+	"github.com/TimeWtr/vortexrotate/errorx"
+)
+
+// DefaultWriteQueueSize 异步写入队列的默认长度
+const DefaultWriteQueueSize = 1024
+
+// WithAsyncWrite 开启异步写入，Write只将内容的副本放入长度为queueSize的环形队列后立即返回，
+// 由后台goroutine按照入队的顺序依次写入文件。顺序保证：每条内容入队时在队列锁内分配递增的
+// 序列号，后台goroutine严格按照序列号写入，因此同一个调用方(同一个goroutine或者有先后顺序的
+// 多次调用)的写入与调用顺序一致，不同调用方并发的写入可以交错，但是各自的顺序不变，按照来源
+// 解析日志时不会乱序。failFast为true时队列已满返回errorx.ErrQueueFull，调用方可以重试而不会
+// 破坏顺序，否则阻塞等待(背压)。后台写入失败时只能记录日志并计入统计，调用方需要确认数据已经
+// 写入时调用Sync，Sync等待调用之前入队的内容全部写入之后再刷盘。关闭轮转器时先写完队列中的
+// 内容。queueSize<=0时使用DefaultWriteQueueSize，不能与同步模式以及WAL模式一起使用。
+func WithAsyncWrite(queueSize int, failFast bool) Option {
+	return func(r *Rotator) error {
+		if queueSize <= 0 {
+			queueSize = DefaultWriteQueueSize
+		}
+
+		r.writeQueueSize = queueSize
+		r.writeFailFast = failFast
+		return nil
+	}
+}
+
+// queuedWrite 异步写入队列中的一条内容
+type queuedWrite struct {
+	// 入队时分配的序列号
+	seq uint64
+	// 入队的时间
+	at time.Time
+	// 写入内容的副本
+	data []byte
+}
+
+// writeRing 异步写入使用的有界环形队列，入队时分配序列号，出队的顺序与序列号的顺序一致
+type writeRing struct {
+	lock     sync.Mutex
+	notEmpty *sync.Cond
+	notFull  *sync.Cond
+	// 已经写入完成的序列号变化时通知等待方
+	written *sync.Cond
+	buf     []queuedWrite
+	head    int
+	size    int
+	// 下一个分配的序列号
+	next uint64
+	// 已经写入完成的内容数量，即下一个等待写入的序列号
+	done   uint64
+	closed bool
+}
+
+func newWriteRing(size int) *writeRing {
+	q := &writeRing{buf: make([]queuedWrite, size)}
+	q.notEmpty = sync.NewCond(&q.lock)
+	q.notFull = sync.NewCond(&q.lock)
+	q.written = sync.NewCond(&q.lock)
+	return q
+}
+
+// push 复制内容并分配序列号放入队列，block为false时队列已满返回ErrQueueFull
+func (q *writeRing) push(p []byte, block bool) error {
+	q.lock.Lock()
+	defer q.lock.Unlock()
+
+	for q.size == len(q.buf) && !q.closed {
+		if !block {
+			return errorx.ErrQueueFull
+		}
+		q.notFull.Wait()
+	}
+	if q.closed {
+		return errorx.ErrRotateClosed
+	}
+
+	data := make([]byte, len(p))
+	copy(data, p)
+	q.buf[(q.head+q.size)%len(q.buf)] = queuedWrite{seq: q.next, at: time.Now(), data: data}
+	q.next++
+	q.size++
+	q.notEmpty.Signal()
+
+	return nil
+}
+
+// pop 取出序列号最小的内容，队列为空时阻塞，队列关闭并且为空时返回false
+func (q *writeRing) pop() (queuedWrite, bool) {
+	q.lock.Lock()
+	defer q.lock.Unlock()
+
+	for q.size == 0 && !q.closed {
+		q.notEmpty.Wait()
+	}
+	if q.size == 0 {
+		return queuedWrite{}, false
+	}
+
+	w := q.buf[q.head]
+	q.buf[q.head] = queuedWrite{}
+	q.head = (q.head + 1) % len(q.buf)
+	q.size--
+	q.notFull.Signal()
+
+	return w, true
+}
+
+// ack 序列号为seq的内容已经写入
+func (q *writeRing) ack(seq uint64) {
+	q.lock.Lock()
+	defer q.lock.Unlock()
+
+	q.done = seq + 1
+	q.written.Broadcast()
+}
+
+// wait 等待调用之前入队的内容全部写入，队列关闭之后不再等待
+func (q *writeRing) wait() {
+	q.lock.Lock()
+	defer q.lock.Unlock()
+
+	target := q.next
+	for q.done < target && !q.closed {
+		q.written.Wait()
+	}
+}
+
+// waitUntil 等待调用之前入队的内容全部写入，最多等待到deadline，超时返回false
+func (q *writeRing) waitUntil(deadline time.Time) bool {
+	q.lock.Lock()
+	target := q.next
+	q.lock.Unlock()
+
+	for {
+		q.lock.Lock()
+		done := q.done >= target
+		q.lock.Unlock()
+		if done {
+			return true
+		}
+		if time.Now().After(deadline) {
+			return false
+		}
+		time.Sleep(time.Millisecond)
+	}
+}
+
+// close 关闭队列，不再接收新的内容，已经入队的内容仍然可以取出
+func (q *writeRing) close() {
+	q.lock.Lock()
+	defer q.lock.Unlock()
+
+	q.closed = true
+	q.notEmpty.Broadcast()
+	q.notFull.Broadcast()
+	q.written.Broadcast()
+}
+
+// len 队列中等待写入的内容数量
+func (q *writeRing) len() int {
+	q.lock.Lock()
+	defer q.lock.Unlock()
+
+	return q.size
+}
+
+// age 队列中最早的内容已经等待的时间，队列为空时返回0
+func (q *writeRing) age() time.Duration {
+	q.lock.Lock()
+	defer q.lock.Unlock()
+
+	if q.size == 0 {
+		return 0
+	}
+
+	return time.Since(q.buf[q.head].at)
+}
+
+// startWriter 启动后台写入的goroutine
+func (r *Rotator) startWriter() {
+	r.writeQueue = newWriteRing(r.writeQueueSize)
+	r.writerWG.Add(1)
+	go r.writeLoop()
+}
+
+// enqueueWrite 将内容放入异步写入队列
+func (r *Rotator) enqueueWrite(p []byte) (int, error) {
+	if r.sig.Load() == 1 {
+		return 0, errorx.ErrRotateClosed
+	}
+	if r.wal {
+		return 0, errorx.ErrWALMode
+	}
+	// 队列中的副本计入内存预算，写入文件之后释放
+	n := int64(len(p))
+	if !r.budget.acquireBelow(n, r.queueReserve(), !r.writeFailFast) {
+		return 0, errorx.ErrQueueFull
+	}
+	if err := r.writeQueue.push(p, !r.writeFailFast); err != nil {
+		r.budget.release(n)
+		return 0, err
+	}
+
+	return len(p), nil
+}
+
+// writeLoop 按照序列号的顺序依次写入队列中的内容，队列关闭之后写完剩余的内容再退出
+func (r *Rotator) writeLoop() {
+	defer r.writerWG.Done()
+
+	for {
+		w, ok := r.writeQueue.pop()
+		if !ok {
+			return
+		}
+
+		// 入队时已经计入内存预算，写入文件之后释放
+		if _, err := r.writeEntryBudget(w.data, false); err != nil {
+			r.asyncWriteErrors.Add(1)
+			r.l.Printf("async write #%d error: %v", w.seq, err)
+		}
+		r.budget.release(int64(len(w.data)))
+		r.writeQueue.ack(w.seq)
+	}
+}
+
+// awaitWrites 等待异步写入队列中已经入队的内容全部写入，没有开启异步写入时直接返回
+func (r *Rotator) awaitWrites() {
+	if r.writeQueue != nil {
+		r.writeQueue.wait()
+	}
+}
+
+// drainWriter 关闭异步写入队列并等待队列中的内容全部写入
+func (r *Rotator) drainWriter() {
+	if r.writeQueue == nil {
+		return
+	}
+
+	r.writeQueue.close()
+	r.writerWG.Wait()
+}
+
+// writeQueueAge 异步写入队列中最早的内容已经等待的时间
+func (r *Rotator) writeQueueAge() time.Duration {
+	if r.writeQueue == nil {
+		return 0
+	}
+
+	return r.writeQueue.age()
+}
+
+// writeQueueDepth 异步写入队列中等待写入的内容数量
+func (r *Rotator) writeQueueDepth() int {
+	if r.writeQueue == nil {
+		return 0
+	}
+
+	return r.writeQueue.len()
+}
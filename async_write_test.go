@@ -0,0 +1,123 @@
+// Copyright 2025 TimeWtr
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package vortexrotate
+
+import (
+	"bufio"
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/TimeWtr/vortexrotate/errorx"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestWriteRing(t *testing.T) {
+	q := newWriteRing(2)
+	assert.NoError(t, q.push([]byte("a"), false))
+	assert.NoError(t, q.push([]byte("b"), false))
+	assert.True(t, errorx.IsQueueFull(q.push([]byte("c"), false)))
+	assert.Equal(t, 2, q.len())
+
+	w, ok := q.pop()
+	assert.True(t, ok)
+	assert.Equal(t, uint64(0), w.seq)
+	assert.Equal(t, "a", string(w.data))
+	q.ack(w.seq)
+	// 出队之后有空位，环形队列从头部继续写入
+	assert.NoError(t, q.push([]byte("c"), false))
+	for _, expected := range []string{"b", "c"} {
+		w, ok = q.pop()
+		assert.True(t, ok)
+		assert.Equal(t, expected, string(w.data))
+		q.ack(w.seq)
+	}
+	assert.Equal(t, uint64(3), w.seq+1)
+	q.wait()
+
+	q.close()
+	_, ok = q.pop()
+	assert.False(t, ok)
+	assert.ErrorIs(t, q.push([]byte("d"), true), errorx.ErrRotateClosed)
+}
+
+func TestRotator_WithAsyncWrite(t *testing.T) {
+	_, err := newRotator(t.TempDir(), "testdata.log", WithAsyncWrite(0, false), WithSynchronousMode())
+	assert.Error(t, err)
+
+	rotator, err := newRotator(t.TempDir(), "testdata.log", WithAsyncWrite(8, false))
+	assert.NoError(t, err)
+
+	const (
+		writers = 8
+		count   = 200
+	)
+	var wg sync.WaitGroup
+	for i := 0; i < writers; i++ {
+		wg.Add(1)
+		go func(id int) {
+			defer wg.Done()
+			for j := 0; j < count; j++ {
+				_, werr := rotator.Write([]byte(fmt.Sprintf("%d %d\n", id, j)))
+				assert.NoError(t, werr)
+			}
+		}(i)
+	}
+	wg.Wait()
+	assert.NoError(t, rotator.Sync())
+	assert.Equal(t, 0, rotator.Stats().WriteQueueDepth)
+	path := rotator.f.Name()
+	assert.NoError(t, rotator.Close())
+	_, err = rotator.Write([]byte("closed\n"))
+	assert.ErrorIs(t, err, errorx.ErrRotateClosed)
+
+	// 并发写入交错，但是每个调用方的写入保持调用顺序
+	f, err := os.Open(path)
+	assert.NoError(t, err)
+	defer f.Close()
+	next := make([]int, writers)
+	lines := 0
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		fields := strings.Fields(scanner.Text())
+		assert.Len(t, fields, 2)
+		id, _ := strconv.Atoi(fields[0])
+		seq, _ := strconv.Atoi(fields[1])
+		assert.Equal(t, next[id], seq)
+		next[id] = seq + 1
+		lines++
+	}
+	assert.Equal(t, writers*count, lines)
+}
+
+func TestRotator_WithAsyncWriteCloseDrain(t *testing.T) {
+	rotator, err := newRotator(t.TempDir(), "testdata.log", WithAsyncWrite(1024, true))
+	assert.NoError(t, err)
+
+	for i := 0; i < 100; i++ {
+		_, err = rotator.Write([]byte("async write test\n"))
+		assert.NoError(t, err)
+	}
+	path := rotator.f.Name()
+	// 关闭时写完队列中的内容
+	assert.NoError(t, rotator.Close())
+	info, err := os.Stat(path)
+	assert.NoError(t, err)
+	assert.Equal(t, int64(100*len("async write test\n")), info.Size())
+	assert.Equal(t, uint64(0), rotator.Stats().AsyncWriteErrors)
+}
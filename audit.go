@@ -0,0 +1,331 @@
+// Copyright 2025 TimeWtr
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package vortexrotate
+
+import (
+	"bytes"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// AuditIssueType 启动审计发现的问题类型
+type AuditIssueType string
+
+const (
+	// AuditDuplicateSequence 多个不同的轮转文件使用了同一个序列号，开启单调排序时序列号在所有日期中
+	// 唯一，否则只检查同一个日期中的序列号
+	AuditDuplicateSequence AuditIssueType = "duplicate_sequence"
+	// AuditDuplicateName 多个目录中存在名称相同但是内容不同的文件
+	AuditDuplicateName AuditIssueType = "duplicate_name"
+	// AuditChecksumMissing 归档文件缺少校验和文件
+	AuditChecksumMissing AuditIssueType = "checksum_missing"
+	// AuditChecksumMismatch 文件内容与校验和文件中记录的校验和不一致
+	AuditChecksumMismatch AuditIssueType = "checksum_mismatch"
+)
+
+// AuditIssue 启动审计发现的一个问题
+type AuditIssue struct {
+	// 问题类型
+	Type AuditIssueType
+	// 相关的文件，按照路径排序，存在冲突时第一个文件为保留的文件
+	Paths []string
+	// 被移动到隔离目录的文件，只在开启隔离时有效
+	Quarantined []string
+	// 冲突的文件，开启隔离时移动到隔离目录
+	conflicts []string
+}
+
+func (i AuditIssue) String() string {
+	return fmt.Sprintf("%s: %s", i.Type, strings.Join(i.Paths, ", "))
+}
+
+// AuditReport 启动审计的报告
+type AuditReport struct {
+	// 扫描的轮转文件数量，不包括校验和等关联文件
+	Files int
+	// 发现的问题
+	Issues []AuditIssue
+}
+
+// WithStartupAudit 初始化时审计存储目录中与文件名称模板匹配的文件，检查序列号重复、同名文件内容
+// 不一致、校验和文件缺失或者不匹配等会导致保存策略和读取方产生歧义的状态，审计报告通过fn返回，
+// 发现问题时发送EventAudit事件。quarantine为true时将冲突的文件移动到隔离目录：序列号重复时保留
+// 名称排序最靠前的文件(开启单调排序时不同日期的文件也不能使用相同的序列号，保留日期最早的文件)，同名文件保留路径排序最靠前的文件，校验和不匹配的文件直接隔离，缺少校验和的文件
+// 只报告。审计需要读取有校验和文件的所有文件，目录中文件较多时会增加启动耗时。
+func WithStartupAudit(fn func(*AuditReport), quarantine bool) Option {
+	return func(r *Rotator) error {
+		r.audit = true
+		r.onAudit = fn
+		r.auditQuarantine = quarantine
+		return nil
+	}
+}
+
+// Audit 只读地审计存储目录，filename为基础文件名称，格式与NewRotator一致，不会修改目录中的任何
+// 文件。目录中存在校验和文件时，认为目录开启了校验和，报告缺少校验和文件的归档文件；存在排序标记
+// 文件时，认为目录开启了单调排序，检查不同日期之间的序列号重复。
+func Audit(dir, filename string) (*AuditReport, error) {
+	name, _, err := splitFilename(filename)
+	if err != nil {
+		return nil, err
+	}
+
+	dir, err = normalizeDir(dir)
+	if err != nil {
+		return nil, err
+	}
+
+	return audit(dir, name, false, monotonicOrder(dir, name))
+}
+
+// auditFile 审计的一个轮转文件
+type auditFile struct {
+	path string
+	// 不包括压缩等后缀的轮转文件名称
+	base string
+	// 文件名称中的日期
+	date string
+	seq  uint64
+}
+
+// auditSeqKey 检查序列号重复的分组键，没有开启单调排序时序列号只在同一个日期中唯一
+type auditSeqKey struct {
+	date string
+	seq  uint64
+}
+
+// audit 扫描存储目录生成审计报告，checksum为true时总是检查归档文件是否缺少校验和文件，
+// monotonic为true时序列号在所有日期中唯一
+func audit(dir, name string, checksum, monotonic bool) (*AuditReport, error) {
+	re := segmentRegexp(name)
+	var files []auditFile
+	err := filepath.WalkDir(dir, func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if isQuarantineDir(dir, path, d) {
+			return filepath.SkipDir
+		}
+		if d.IsDir() {
+			return nil
+		}
+
+		const matchesLen = 3
+		fn := d.Name()
+		matches := re.FindStringSubmatch(fn)
+		if len(matches) < matchesLen {
+			return nil
+		}
+		if strings.HasSuffix(fn, ChecksumFileExt) {
+			checksum = true
+			return nil
+		}
+		if fn != matches[0] && !isArchive(fn) {
+			// 临时文件、完成标记等关联文件
+			return nil
+		}
+
+		seq, err := strconv.ParseUint(matches[2], 10, 64)
+		if err != nil {
+			return nil
+		}
+		files = append(files, auditFile{path: path, base: matches[0], date: matches[1], seq: seq})
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	sort.Slice(files, func(i, j int) bool {
+		return files[i].path < files[j].path
+	})
+	report := &AuditReport{Files: len(files)}
+	report.Issues = append(report.Issues, auditSequences(files, monotonic)...)
+	issues, err := auditNames(files)
+	if err != nil {
+		return nil, err
+	}
+	report.Issues = append(report.Issues, issues...)
+	issues, err = auditChecksums(files, checksum)
+	if err != nil {
+		return nil, err
+	}
+	report.Issues = append(report.Issues, issues...)
+
+	return report, nil
+}
+
+// isArchive 判断文件是否为压缩或者加密的归档文件
+func isArchive(fn string) bool {
+	return compressTypeOf(fn) != CompressTypeUnknown || strings.HasSuffix(fn, EncryptFileExt)
+}
+
+// auditSequences 检查多个不同的轮转文件使用同一个序列号，monotonic为false时只比较同一个日期中的
+// 文件，保留名称排序最靠前(日期最早)的文件
+func auditSequences(files []auditFile, monotonic bool) []AuditIssue {
+	bySeq := make(map[auditSeqKey][]auditFile)
+	var keys []auditSeqKey
+	for _, f := range files {
+		key := auditSeqKey{date: f.date, seq: f.seq}
+		if monotonic {
+			key.date = ""
+		}
+		if _, ok := bySeq[key]; !ok {
+			keys = append(keys, key)
+		}
+		bySeq[key] = append(bySeq[key], f)
+	}
+	sort.Slice(keys, func(i, j int) bool {
+		if keys[i].date != keys[j].date {
+			return keys[i].date < keys[j].date
+		}
+		return keys[i].seq < keys[j].seq
+	})
+
+	var issues []AuditIssue
+	for _, key := range keys {
+		group := bySeq[key]
+		keep := group[0].base
+		for _, f := range group {
+			if f.base < keep {
+				keep = f.base
+			}
+		}
+
+		issue := AuditIssue{Type: AuditDuplicateSequence}
+		for _, f := range group {
+			issue.Paths = append(issue.Paths, f.path)
+			if f.base != keep {
+				issue.conflicts = append(issue.conflicts, f.path)
+			}
+		}
+		if len(issue.conflicts) > 0 {
+			issues = append(issues, issue)
+		}
+	}
+
+	return issues
+}
+
+// auditNames 检查多个目录中名称相同但是内容不同的文件，保留路径排序最靠前的文件
+func auditNames(files []auditFile) ([]AuditIssue, error) {
+	byName := make(map[string][]string)
+	for _, f := range files {
+		fn := filepath.Base(f.path)
+		byName[fn] = append(byName[fn], f.path)
+	}
+
+	var issues []AuditIssue
+	for _, fn := range sortedKeys(byName) {
+		paths := byName[fn]
+		if len(paths) < 2 {
+			continue
+		}
+
+		want, err := fileChecksum(paths[0])
+		if err != nil {
+			return nil, err
+		}
+		issue := AuditIssue{Type: AuditDuplicateName, Paths: paths}
+		for _, path := range paths[1:] {
+			sum, err := fileChecksum(path)
+			if err != nil {
+				return nil, err
+			}
+			if !bytes.Equal(sum, want) {
+				issue.conflicts = append(issue.conflicts, path)
+			}
+		}
+		if len(issue.conflicts) > 0 {
+			issues = append(issues, issue)
+		}
+	}
+
+	return issues, nil
+}
+
+// auditChecksums 检查文件内容与校验和文件是否一致，required为true时报告缺少校验和文件的归档文件
+func auditChecksums(files []auditFile, required bool) ([]AuditIssue, error) {
+	var issues []AuditIssue
+	for _, f := range files {
+		want, err := readChecksum(f.path)
+		if os.IsNotExist(err) {
+			if required && isArchive(f.path) {
+				issues = append(issues, AuditIssue{Type: AuditChecksumMissing, Paths: []string{f.path}})
+			}
+			continue
+		}
+
+		var sum []byte
+		if err == nil {
+			if sum, err = fileChecksum(f.path); err != nil {
+				return nil, err
+			}
+		}
+		if err != nil || !bytes.Equal(sum, want) {
+			// 校验和文件损坏也视为不一致
+			issues = append(issues, AuditIssue{
+				Type:      AuditChecksumMismatch,
+				Paths:     []string{f.path},
+				conflicts: []string{f.path},
+			})
+		}
+	}
+
+	return issues, nil
+}
+
+// startupAudit 初始化时审计存储目录，按照配置隔离冲突的文件
+func (r *Rotator) startupAudit() error {
+	report, err := audit(r.dir, r.filename, r.checksum, r.monotonic || monotonicOrder(r.dir, r.filename))
+	if err != nil {
+		return err
+	}
+
+	if r.auditQuarantine {
+		quarantined := make(map[string]struct{})
+		for i := range report.Issues {
+			issue := &report.Issues[i]
+			for _, path := range issue.conflicts {
+				if _, ok := quarantined[path]; ok {
+					continue
+				}
+				if err = r.quarantine(path, fmt.Errorf("audit: %s", issue.Type)); err != nil {
+					return err
+				}
+				quarantined[path] = struct{}{}
+				issue.Quarantined = append(issue.Quarantined, path)
+			}
+		}
+	}
+
+	if len(report.Issues) > 0 {
+		r.emit(Event{
+			Type:    EventAudit,
+			Path:    r.dir,
+			Message: fmt.Sprintf("audit directory %s, issues: %v", r.dir, report.Issues),
+		})
+	}
+	if r.onAudit != nil {
+		r.onAudit(report)
+	}
+
+	return nil
+}
@@ -0,0 +1,134 @@
+// Copyright 2025 TimeWtr
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package vortexrotate
+
+import (
+	"crypto/sha256"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// prepareAuditDir 准备包含各种冲突状态的存储目录，monotonic为true时写入排序标记文件，
+// 不同日期的文件使用相同的序列号视为冲突
+func prepareAuditDir(t *testing.T, monotonic bool) (dir string, want map[AuditIssueType][]string) {
+	dir = t.TempDir()
+	write := func(rel, content string, checksum bool) string {
+		path := filepath.Join(dir, rel)
+		assert.NoError(t, os.MkdirAll(filepath.Dir(path), os.ModePerm))
+		assert.NoError(t, os.WriteFile(path, []byte(content), ReadWriteFile))
+		if checksum {
+			sum := sha256.Sum256([]byte(content))
+			assert.NoError(t, writeChecksum(path, sum[:], renameLocal))
+		}
+		return path
+	}
+
+	write("testdata_20250101_0001.log.gz", "a", true)
+	dupSeq := write("testdata_20250102_0001.log.gz", "b", true)
+	// 同名文件保留路径排序最靠前的文件
+	write("20250103/done/testdata_20250103_0002.log.gz", "c", true)
+	dupName := write("20250103/testdata_20250103_0002.log.gz", "d", true)
+	mismatch := write("testdata_20250104_0003.log.gz", "e", false)
+	assert.NoError(t, os.WriteFile(mismatch+ChecksumFileExt, []byte("00  x\n"), ReadWriteFile))
+	missing := write("testdata_20250105_0004.log.gz", "f", false)
+	write("testdata_20250106_0005.log.gz", "g", true)
+
+	want = map[AuditIssueType][]string{
+		AuditDuplicateName:    {dupName},
+		AuditChecksumMismatch: {mismatch},
+		AuditChecksumMissing:  {missing},
+	}
+	if monotonic {
+		assert.NoError(t, writeOrderFile(dir, "testdata", renameLocal))
+		want[AuditDuplicateSequence] = []string{dupSeq}
+	}
+
+	return dir, want
+}
+
+func TestAudit(t *testing.T) {
+	// 没有开启单调排序时序列号按照日期重新分配，不同日期的相同序列号不是冲突
+	for _, monotonic := range []bool{false, true} {
+		dir, want := prepareAuditDir(t, monotonic)
+		report, err := Audit(dir, "testdata.log")
+		assert.NoError(t, err)
+		assert.Equal(t, 7, report.Files)
+		assert.Len(t, report.Issues, len(want))
+		for _, issue := range report.Issues {
+			assert.Contains(t, want, issue.Type)
+			if issue.Type == AuditChecksumMissing {
+				assert.Equal(t, want[issue.Type], issue.Paths)
+				continue
+			}
+			assert.Equal(t, want[issue.Type], issue.conflicts, issue.Type)
+			// 只读审计不会移动文件
+			assert.FileExists(t, want[issue.Type][0])
+		}
+	}
+
+	// 同一个日期中的相同序列号总是冲突
+	files := []auditFile{
+		{path: "a", base: "testdata_20250101_00001.log", date: "20250101", seq: 1},
+		{path: "b", base: "testdata_20250101_0001.log", date: "20250101", seq: 1},
+		{path: "c", base: "testdata_20250102_0001.log", date: "20250102", seq: 1},
+	}
+	issues := auditSequences(files, false)
+	assert.Len(t, issues, 1)
+	assert.Equal(t, []string{"b"}, issues[0].conflicts)
+}
+
+func TestRotator_StartupAudit(t *testing.T) {
+	dir, want := prepareAuditDir(t, true)
+	var (
+		report *AuditReport
+		events []Event
+	)
+	rotator, err := newRotator(dir, "testdata.log",
+		WithAutoRepair(false),
+		WithStartupAudit(func(r *AuditReport) {
+			report = r
+		}, true),
+		WithEventHandler(func(e Event) {
+			events = append(events, e)
+		}))
+	assert.NoError(t, err)
+	defer rotator.Close()
+
+	assert.NotNil(t, report)
+	assert.Len(t, report.Issues, len(want))
+	for _, issue := range report.Issues {
+		if issue.Type == AuditChecksumMissing {
+			// 缺少校验和的文件只报告，不隔离
+			assert.Empty(t, issue.Quarantined)
+			assert.FileExists(t, want[issue.Type][0])
+			continue
+		}
+		assert.Equal(t, want[issue.Type], issue.Quarantined)
+		assert.NoFileExists(t, want[issue.Type][0])
+	}
+	quarantined, err := os.ReadDir(filepath.Join(dir, QuarantineDirName))
+	assert.NoError(t, err)
+	// 3个冲突文件以及它们的校验和文件
+	assert.Len(t, quarantined, 6)
+	assert.Equal(t, EventAudit, events[len(events)-1].Type)
+
+	report, err = Audit(dir, "testdata.log")
+	assert.NoError(t, err)
+	assert.Len(t, report.Issues, 1)
+	assert.Equal(t, AuditChecksumMissing, report.Issues[0].Type)
+}
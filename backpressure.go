@@ -0,0 +1,79 @@
+// Copyright 2025 TimeWtr
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package vortexrotate
+
+import (
+	"errors"
+	"time"
+)
+
+const (
+	// BackpressureMaxWriters 阻塞等待写锁的写入方数量达到该值时背压等级为1
+	BackpressureMaxWriters = 64
+	// BackpressureCheckInterval 检查背压等级的时间间隔
+	BackpressureCheckInterval = time.Millisecond * 100
+)
+
+// BackpressureHandler 背压等级变化时的回调函数，level的取值范围为[0, 1]，0表示没有压力，
+// 1表示日志子系统已经饱和
+type BackpressureHandler func(level float64)
+
+// WithBackpressureCallback 设置背压回调，后台定时计算背压等级，等级发生变化时调用fn，应用可以
+// 根据背压等级主动降级自身的日志负载(比如丢弃debug日志)。背压等级取阻塞等待写入的写入方数量、
+// 内存预算使用率和异步压缩队列深度中的较大值，回调在后台goroutine中调用，不会阻塞写入。
+func WithBackpressureCallback(fn BackpressureHandler) Option {
+	return func(r *Rotator) error {
+		if fn == nil {
+			return errors.New("backpressure callback must not be nil")
+		}
+
+		r.onBackpressure = fn
+		return nil
+	}
+}
+
+// lockWrite 获取写锁，等待期间计入阻塞的写入方数量
+func (r *Rotator) lockWrite() {
+	r.blockedWriters.Add(1)
+	r.writeLock.Lock()
+	r.blockedWriters.Add(-1)
+}
+
+// backpressureLevel 计算当前的背压等级
+func (r *Rotator) backpressureLevel() float64 {
+	level := float64(r.blockedWriters.Load()) / BackpressureMaxWriters
+	if r.budget != nil {
+		level = max(level, float64(r.budget.InUse())/float64(r.budget.total))
+	}
+	if r.sealQueue != nil {
+		level = max(level, float64(r.compressQueueDepth())/float64(cap(r.sealQueue)))
+	}
+	if r.writeQueue != nil {
+		level = max(level, float64(r.writeQueueDepth())/float64(r.writeQueueSize))
+	}
+
+	return min(level, 1)
+}
+
+// checkBackpressure 定时检查背压等级，等级发生变化时调用回调函数
+func (r *Rotator) checkBackpressure() {
+	level := r.backpressureLevel()
+	if level == r.lastBackpressure {
+		return
+	}
+
+	r.lastBackpressure = level
+	r.onBackpressure(level)
+}
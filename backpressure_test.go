@@ -0,0 +1,66 @@
+// Copyright 2025 TimeWtr
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package vortexrotate
+
+import (
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestRotator_Backpressure(t *testing.T) {
+	var lock sync.Mutex
+	var levels []float64
+	rotator, err := NewRotator(t.TempDir(), "testdata.log",
+		WithBackpressureCallback(func(level float64) {
+			lock.Lock()
+			defer lock.Unlock()
+			levels = append(levels, level)
+		}))
+	assert.NoError(t, err)
+	defer rotator.Close()
+
+	const writers = 16
+	var wg sync.WaitGroup
+	rotator.writeLock.Lock()
+	for i := 0; i < writers; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			_, _ = rotator.Write([]byte("hello\n"))
+		}()
+	}
+
+	assert.Eventually(t, func() bool {
+		return rotator.Stats().BlockedWriters == writers
+	}, time.Second, time.Millisecond*10)
+	expected := float64(writers) / BackpressureMaxWriters
+	assert.Equal(t, expected, rotator.Stats().BackpressureLevel)
+	assert.Eventually(t, func() bool {
+		lock.Lock()
+		defer lock.Unlock()
+		return len(levels) > 0 && levels[len(levels)-1] == expected
+	}, time.Second, time.Millisecond*10)
+
+	rotator.writeLock.Unlock()
+	wg.Wait()
+	assert.Eventually(t, func() bool {
+		lock.Lock()
+		defer lock.Unlock()
+		return levels[len(levels)-1] == 0
+	}, time.Second, time.Millisecond*10)
+}
@@ -0,0 +1,199 @@
+// Copyright 2025 TimeWtr
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package bench 在目标机器上测量不同配置下轮转器的写入吞吐量和延迟，用于根据实际的硬件和
+// 文件系统选择压缩算法、同步/异步压缩等配置。
+package bench
+
+import (
+	"context"
+	"os"
+	"sort"
+	"sync"
+	"time"
+
+	vr "github.com/TimeWtr/vortexrotate"
+)
+
+const (
+	// DefaultWriters 默认的并发写入goroutine数量
+	DefaultWriters = 4
+	// DefaultWrites 每个goroutine默认的写入次数
+	DefaultWrites = 10000
+	// DefaultSize 默认的单次写入大小(字节)
+	DefaultSize = 256
+	// DefaultMaxSize 默认配置中单个文件的最大大小，保证测量过程中会发生多次轮转
+	DefaultMaxSize = 4 << 20
+)
+
+// Config 参与比较的一组配置
+type Config struct {
+	// 配置名称
+	Name string
+	// 创建轮转器的配置
+	Options []vr.Option
+}
+
+// Params 测量的参数
+type Params struct {
+	// 测量使用的目录，每个配置在该目录下的临时目录中执行，执行完成之后删除，为空时使用系统临时目录
+	Dir string
+	// 并发写入的goroutine数量，默认为DefaultWriters
+	Writers int
+	// 每个goroutine的写入次数，默认为DefaultWrites
+	Writes int
+	// 单次写入的大小，默认为DefaultSize
+	Size int
+}
+
+func (p Params) withDefaults() Params {
+	if p.Writers <= 0 {
+		p.Writers = DefaultWriters
+	}
+	if p.Writes <= 0 {
+		p.Writes = DefaultWrites
+	}
+	if p.Size <= 0 {
+		p.Size = DefaultSize
+	}
+
+	return p
+}
+
+// Result 单个配置的测量结果
+type Result struct {
+	// 配置名称
+	Name string
+	// 成功写入的次数
+	Writes int
+	// 写入的总字节数
+	Bytes int64
+	// 从开始写入到关闭轮转器(等待压缩完成)的总耗时
+	Duration time.Duration
+	// 吞吐量(字节/秒)
+	Throughput float64
+	// 写入延迟的分位数
+	P50, P99, Max time.Duration
+	// 测量过程中的轮转次数
+	Rotations uint64
+}
+
+// Run 使用cfg的配置测量写入的吞吐量和延迟
+func Run(ctx context.Context, params Params, cfg Config) (Result, error) {
+	params = params.withDefaults()
+	res := Result{Name: cfg.Name}
+	dir, err := os.MkdirTemp(params.Dir, "vortex-bench-")
+	if err != nil {
+		return res, err
+	}
+	defer func() {
+		_ = os.RemoveAll(dir)
+	}()
+
+	rotator, err := vr.NewRotator(dir, "bench.log", cfg.Options...)
+	if err != nil {
+		return res, err
+	}
+
+	line := make([]byte, params.Size)
+	for i := range line {
+		line[i] = 'a' + byte(i%26)
+	}
+	line[len(line)-1] = '\n'
+
+	var (
+		wg        sync.WaitGroup
+		lock      sync.Mutex
+		firstErr  error
+		latencies = make([]time.Duration, 0, params.Writers*params.Writes)
+	)
+	start := time.Now()
+	for i := 0; i < params.Writers; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+
+			local := make([]time.Duration, 0, params.Writes)
+			var err error
+			for j := 0; j < params.Writes && ctx.Err() == nil; j++ {
+				begin := time.Now()
+				if _, err = rotator.Write(line); err != nil {
+					break
+				}
+				local = append(local, time.Since(begin))
+			}
+
+			lock.Lock()
+			defer lock.Unlock()
+			latencies = append(latencies, local...)
+			if firstErr == nil {
+				firstErr = err
+			}
+		}()
+	}
+	wg.Wait()
+
+	res.Rotations = rotator.Stats().TotalRotations
+	if err = rotator.Close(); firstErr == nil {
+		firstErr = err
+	}
+	if firstErr == nil {
+		firstErr = ctx.Err()
+	}
+	res.Duration = time.Since(start)
+
+	res.Writes = len(latencies)
+	res.Bytes = int64(res.Writes) * int64(params.Size)
+	if res.Duration > 0 {
+		res.Throughput = float64(res.Bytes) / res.Duration.Seconds()
+	}
+	if len(latencies) > 0 {
+		sort.Slice(latencies, func(i, j int) bool { return latencies[i] < latencies[j] })
+		res.P50 = latencies[len(latencies)/2]
+		res.P99 = latencies[len(latencies)*99/100]
+		res.Max = latencies[len(latencies)-1]
+	}
+
+	return res, firstErr
+}
+
+// Compare 依次测量每一组配置，返回每组配置的测量结果，某一组配置失败时返回已经完成的结果和错误
+func Compare(ctx context.Context, params Params, cfgs ...Config) ([]Result, error) {
+	results := make([]Result, 0, len(cfgs))
+	for _, cfg := range cfgs {
+		res, err := Run(ctx, params, cfg)
+		if err != nil {
+			return results, err
+		}
+		results = append(results, res)
+	}
+
+	return results, nil
+}
+
+// DefaultConfigs 常用的配置组合：不压缩、gzip/zstd/snappy同步压缩以及gzip/zstd异步压缩，
+// 单个文件的最大大小为DefaultMaxSize
+func DefaultConfigs() []Config {
+	rotate := vr.WithRotate(DefaultMaxSize, vr.Hour)
+	return []Config{
+		{Name: "none", Options: []vr.Option{rotate}},
+		{Name: "gzip", Options: []vr.Option{rotate, vr.WithCompress(vr.CompressTypeGzip)}},
+		{Name: "zstd", Options: []vr.Option{rotate, vr.WithCompress(vr.CompressTypeZstd)}},
+		{Name: "snappy", Options: []vr.Option{rotate, vr.WithCompress(vr.CompressTypeSnappy)}},
+		{Name: "gzip-async", Options: []vr.Option{rotate, vr.WithCompress(vr.CompressTypeGzip),
+			vr.WithAsyncCompress(vr.DefaultCompressQueueSize)}},
+		{Name: "zstd-async", Options: []vr.Option{rotate, vr.WithCompress(vr.CompressTypeZstd),
+			vr.WithAsyncCompress(vr.DefaultCompressQueueSize)}},
+	}
+}
@@ -0,0 +1,56 @@
+// Copyright 2025 TimeWtr
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package bench
+
+import (
+	"context"
+	"os"
+	"testing"
+
+	vr "github.com/TimeWtr/vortexrotate"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestCompare(t *testing.T) {
+	dir := t.TempDir()
+	params := Params{Dir: dir, Writers: 2, Writes: 200, Size: 100}
+	results, err := Compare(context.Background(), params, DefaultConfigs()...)
+	assert.NoError(t, err)
+	assert.Len(t, results, len(DefaultConfigs()))
+	for _, res := range results {
+		assert.Equal(t, 400, res.Writes)
+		assert.Equal(t, int64(40000), res.Bytes)
+		assert.Greater(t, res.Throughput, float64(0))
+		assert.LessOrEqual(t, res.P50, res.P99)
+		assert.LessOrEqual(t, res.P99, res.Max)
+	}
+
+	// 临时目录已经删除
+	entries, err := os.ReadDir(dir)
+	assert.NoError(t, err)
+	assert.Empty(t, entries)
+}
+
+func TestRun_Rotations(t *testing.T) {
+	res, err := Run(context.Background(), Params{Dir: t.TempDir(), Writers: 1, Writes: 100, Size: 64},
+		Config{Name: "small", Options: []vr.Option{vr.WithRotate(1024, vr.Hour)}})
+	assert.NoError(t, err)
+	assert.Equal(t, "small", res.Name)
+	assert.Greater(t, res.Rotations, uint64(0))
+
+	_, err = Run(context.Background(), Params{Dir: t.TempDir()},
+		Config{Options: []vr.Option{vr.WithCompress(-1)}})
+	assert.Error(t, err)
+}
@@ -0,0 +1,152 @@
+// Copyright 2025 TimeWtr
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package vortexrotate
+
+import (
+	"errors"
+	"sync"
+)
+
+// 各个压缩算法单个压缩任务占用内存的估算值(不包括读缓冲区)
+const (
+	gzipMemoryEstimate   = 256 * 1024
+	zstdMemoryEstimate   = 2 * 1024 * 1024
+	snappyMemoryEstimate = 128 * 1024
+	xzMemoryEstimate     = 16 * 1024 * 1024
+	s2MemoryEstimate     = 4 * 1024 * 1024
+)
+
+// WithMemoryBudget 设置轮转器的内存预算，限制异步写入队列中内容的副本、转换函数的缓冲区以及
+// 压缩缓冲区等占用内存的总和，超过预算时申请内存的一方阻塞等待(背压)，直到其他任务释放内存，
+// 保证轮转器在内存受限的容器中占用的内存是可预期的。异步写入队列最多占用预算减去一个压缩任务
+// 的部分，保证写入goroutine内联封存时不会因为队列占满预算而永久阻塞，队列占满时开启了failFast
+// 的写入返回errorx.ErrQueueFull。
+func WithMemoryBudget(bytes int64) Option {
+	return func(r *Rotator) error {
+		if bytes <= 0 {
+			return errors.New("memory budget must be greater than 0")
+		}
+
+		r.budget = newMemoryBudget(bytes)
+		return nil
+	}
+}
+
+// memoryBudget 内存预算，nil表示不限制
+type memoryBudget struct {
+	lock sync.Mutex
+	cond *sync.Cond
+	// 内存预算总量
+	total int64
+	// 已经使用的内存
+	used int64
+}
+
+func newMemoryBudget(total int64) *memoryBudget {
+	b := &memoryBudget{total: total}
+	b.cond = sync.NewCond(&b.lock)
+	return b
+}
+
+// acquire 申请n字节的内存，超过预算时阻塞等待，单次申请超过预算总量时，
+// 只有在没有其他内存占用的情况下才允许申请，防止永久阻塞
+func (b *memoryBudget) acquire(n int64) {
+	b.acquireBelow(n, 0, true)
+}
+
+// acquireBelow 申请n字节的内存，并为其他任务保留reserve字节，即申请之后占用的内存不超过
+// 预算总量减去reserve，block为false时超过预算直接返回false
+func (b *memoryBudget) acquireBelow(n, reserve int64, block bool) bool {
+	if b == nil {
+		return true
+	}
+
+	b.lock.Lock()
+	defer b.lock.Unlock()
+
+	for b.used > 0 && b.used+n > b.total-reserve {
+		if !block {
+			return false
+		}
+		b.cond.Wait()
+	}
+	b.used += n
+	return true
+}
+
+// release 释放n字节的内存
+func (b *memoryBudget) release(n int64) {
+	if b == nil {
+		return
+	}
+
+	b.lock.Lock()
+	defer b.lock.Unlock()
+
+	b.used -= n
+	b.cond.Broadcast()
+}
+
+// InUse 当前已经使用的内存
+func (b *memoryBudget) InUse() int64 {
+	if b == nil {
+		return 0
+	}
+
+	b.lock.Lock()
+	defer b.lock.Unlock()
+
+	return b.used
+}
+
+// queueReserve 异步写入队列需要为封存保留的内存，没有开启压缩时不保留
+func (r *Rotator) queueReserve() int64 {
+	if !r.cpr.compress {
+		return 0
+	}
+
+	return r.cpr.memory()
+}
+
+// memory 估算单个压缩任务占用的内存，并行gzip压缩时每个goroutine额外占用输入和输出两个数据块
+func (c *Compress) memory() int64 {
+	mem := compressMemory(c.compressType)
+	if c.compressType == CompressTypeGzip && c.workers > 0 {
+		mem += int64(c.workers) * 2 * PgzipBlockSize
+	}
+	if c.compressType == CompressTypeZstd && c.windowLog > 0 {
+		mem += 1 << c.windowLog
+	}
+
+	return mem
+}
+
+// compressMemory 估算单个压缩任务占用的内存
+func compressMemory(tp int) int64 {
+	switch tp {
+	case CompressTypeGzip:
+		return bufferSize + gzipMemoryEstimate
+	case CompressTypeZstd:
+		return bufferSize + zstdMemoryEstimate
+	case CompressTypeSnappy:
+		return bufferSize + snappyMemoryEstimate
+	case CompressTypeXz:
+		return bufferSize + xzMemoryEstimate
+	case CompressTypeS2:
+		return bufferSize + s2MemoryEstimate
+	default:
+		return bufferSize
+	}
+}
@@ -0,0 +1,84 @@
+// Copyright 2025 TimeWtr
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package vortexrotate
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/TimeWtr/vortexrotate/errorx"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestMemoryBudget(t *testing.T) {
+	b := newMemoryBudget(100)
+	b.acquire(60)
+	assert.Equal(t, int64(60), b.InUse())
+
+	acquired := make(chan struct{})
+	go func() {
+		b.acquire(60)
+		close(acquired)
+	}()
+
+	select {
+	case <-acquired:
+		t.Fatal("acquire should block when budget exceeded")
+	case <-time.After(time.Millisecond * 20):
+	}
+
+	b.release(60)
+	<-acquired
+	assert.Equal(t, int64(60), b.InUse())
+	b.release(60)
+
+	// 单次申请超过预算总量时，没有其他占用的情况下允许申请
+	b.acquire(200)
+	assert.Equal(t, int64(200), b.InUse())
+	b.release(200)
+
+	// 为其他任务保留内存，超过时不阻塞直接返回
+	b.acquire(50)
+	assert.False(t, b.acquireBelow(30, 40, false))
+	assert.True(t, b.acquireBelow(10, 40, false))
+	assert.Equal(t, int64(60), b.InUse())
+	b.release(60)
+
+	var nilBudget *memoryBudget
+	nilBudget.acquire(100)
+	nilBudget.release(100)
+	assert.Equal(t, int64(0), nilBudget.InUse())
+}
+
+func TestRotator_MemoryBudgetAsyncWrite(t *testing.T) {
+	rotator, err := newRotator(t.TempDir(), "testdata.log",
+		WithMemoryBudget(100), WithAsyncWrite(16, true), WithTransformers(StripANSI))
+	assert.NoError(t, err)
+	defer rotator.Close()
+
+	// 后台写入阻塞时，队列中的副本占满预算之后写入失败
+	line := []byte(strings.Repeat("x", 59) + "\n")
+	rotator.writeLock.Lock()
+	_, err = rotator.Write(line)
+	assert.NoError(t, err)
+	assert.Equal(t, int64(len(line)), rotator.budget.InUse())
+	_, err = rotator.Write(line)
+	assert.ErrorIs(t, err, errorx.ErrQueueFull)
+	rotator.writeLock.Unlock()
+
+	assert.NoError(t, rotator.Sync())
+	assert.Equal(t, int64(0), rotator.budget.InUse())
+}
@@ -0,0 +1,321 @@
+// Copyright 2025 TimeWtr
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package vortexrotate
+
+import (
+	"archive/tar"
+	"archive/zip"
+	"bytes"
+	"cmp"
+	"compress/gzip"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"regexp"
+	"slices"
+
+	"github.com/TimeWtr/vortexrotate/errorx"
+)
+
+// DefaultBundleCron 每日打包任务的执行时间，每10分钟检查一次是否有已经结束的日期需要打包
+const DefaultBundleCron = "0 */10 * * * *"
+
+// bundleRegexp 匹配每日打包生成的归档文件，文件名称格式为：YYYYMMDD.tar.zst
+var bundleRegexp = regexp.MustCompile(`^(\d{8})(\.tar\.zst|\.tar\.gz|\.zip)$`)
+
+// WithDailyBundle 开启每日打包，后台任务将已经结束的日期的所有轮转文件打包为存储目录下的一个
+// YYYYMMDD.tar.zst(或者.tar.gz、.zip)归档，归档中是解压之后的原始日志，路径与ExportDay一致。
+// 归档先写入临时文件，重新读取并与每一个轮转文件的内容逐一比对之后才rename为正式文件，再删除
+// 轮转文件及其校验和、完成标记等关联文件，避免大量的小文件拖慢目录遍历和日志传输。仍在写入或者
+// 等待封存的日期不会打包。打包之后的归档作为一个整体参与数量、大小和保存时间等保存策略的计算，
+// 只读方式(OpenReadOnly)不再列出已经打包的轮转文件。
+func WithDailyBundle(format ExportFormat) Option {
+	return func(r *Rotator) error {
+		switch format {
+		case ExportTarZst, ExportTarGz, ExportZip:
+		default:
+			return fmt.Errorf("bundle format %d not support", format)
+		}
+
+		r.bundle = format
+		return nil
+	}
+}
+
+// bundleDays 打包所有已经结束的日期
+func (r *Rotator) bundleDays() {
+	r.archiveLock.Lock()
+	defer r.archiveLock.Unlock()
+
+	lc := NewFileCountCleanUp(r.dir, r.filename, 0, 0)
+	lc.dirLock = r.dirLock
+	lc.SetErrorHandler(func(err error) {
+		r.l.Printf("bundle: %v", err)
+	})
+
+	days, err := r.bundleCandidates(lc)
+	if err != nil {
+		r.l.Printf("bundle: list files in %s error: %v", r.dir, err)
+		return
+	}
+
+	for _, day := range sortedKeys(days) {
+		if err = r.bundleDay(lc, day, days[day]); err != nil {
+			r.l.Printf("bundle: bundle %s error: %v", day, err)
+			continue
+		}
+		r.l.Printf("bundle: %d files of %s bundled", len(days[day]), day)
+	}
+}
+
+// bundleCandidates 按照日期分组返回可以打包的轮转文件，当天、当前写入的文件所在的日期以及有文件
+// 正在封存的日期不打包。在写锁的保护下遍历目录，同步封存的文件在轮转时已经完成封存
+func (r *Rotator) bundleCandidates(lc *CleanUp) (map[string][]FileInfo, error) {
+	r.writeLock.RLock()
+	defer r.writeLock.RUnlock()
+
+	today := r.currentDate()
+	if r.bucketDate != "" && r.bucketDate < today {
+		today = r.bucketDate
+	}
+	var active string
+	if r.f != nil {
+		active = trimCompressExt(r.f.Name())
+	}
+
+	files, err := lc.listFileInfo()
+	if err != nil {
+		return nil, err
+	}
+
+	days := make(map[string][]FileInfo)
+	busy := make(map[string]bool)
+	for _, fi := range files {
+		day := fi.Date.Format(Layout)
+		if day >= today {
+			continue
+		}
+
+		days[day] = append(days[day], fi)
+		for _, path := range fi.Files {
+			raw := trimCompressExt(path)
+			if raw == active || r.tracker.pending(raw) || filepath.Ext(path) == TmpFileExt {
+				busy[day] = true
+			}
+		}
+	}
+	for day := range busy {
+		delete(days, day)
+	}
+
+	return days, nil
+}
+
+// bundleDay 打包一天的轮转文件，校验通过之后删除轮转文件。归档已经存在时(比如上一次打包之后
+// 删除轮转文件之前进程退出)，校验已有的归档与轮转文件一致之后直接删除轮转文件，不一致时将已有的
+// 归档移动到隔离目录之后重新打包
+func (r *Rotator) bundleDay(lc *CleanUp, day string, files []FileInfo) error {
+	ro := &ReadOnly{
+		dir:       r.dir,
+		filename:  r.filename,
+		re:        segmentRegexp(r.filename),
+		monotonic: r.monotonic,
+	}
+	segments, err := ro.daySegments(day)
+	if err != nil {
+		return err
+	}
+	if len(segments) == 0 {
+		return nil
+	}
+
+	path := filepath.Join(r.dir, day+r.bundle.Ext())
+	exists := false
+	if _, err = os.Lstat(path); err == nil {
+		// 已有的归档与轮转文件不一致时隔离之后重新打包
+		if err = verifyBundle(ro, path, day, segments, r.bundle); err == nil {
+			exists = true
+		} else if qerr := r.quarantine(path, err); qerr != nil {
+			return fmt.Errorf("quarantine bundle %s error: %w", path, qerr)
+		}
+	}
+	if !exists {
+		if err = r.writeBundle(ro, path, day, segments); err != nil {
+			return err
+		}
+	}
+
+	// 只删除归档中包含的轮转文件
+	bundled := make(map[string]struct{}, len(segments))
+	for _, seg := range segments {
+		bundled[trimCompressExt(seg.Path)] = struct{}{}
+	}
+	removed := make([]FileInfo, 0, len(files))
+	for _, fi := range files {
+		if _, ok := bundled[filepath.Join(fi.UpDir, fi.Name)]; ok {
+			removed = append(removed, fi)
+		}
+	}
+	lc.remove(removed, nil)
+
+	return nil
+}
+
+// writeBundle 将轮转文件写入临时文件，校验通过之后rename为正式的归档文件
+func (r *Rotator) writeBundle(ro *ReadOnly, path, day string, segments []SegmentInfo) error {
+	tmp := path + TmpFileExt
+	_ = os.Remove(tmp)
+	f, err := os.OpenFile(tmp, os.O_CREATE|os.O_EXCL|os.O_WRONLY|openNoFollow, ReadWriteFile)
+	if err != nil {
+		return err
+	}
+
+	err = ro.export(context.Background(), day, segments, f, r.bundle)
+	if err == nil {
+		err = f.Sync()
+	}
+	if err1 := f.Close(); err == nil {
+		err = err1
+	}
+	if err == nil {
+		err = verifyBundle(ro, tmp, day, segments, r.bundle)
+	}
+	if err == nil {
+		err = r.rename(tmp, path)
+	}
+	if err != nil {
+		_ = os.Remove(tmp)
+	}
+
+	return err
+}
+
+// bundleEntry 归档中的一个文件
+type bundleEntry struct {
+	name string
+	sum  []byte
+}
+
+// verifyBundle 重新读取归档，逐一比对归档中的文件与轮转文件的名称和内容
+func verifyBundle(ro *ReadOnly, path, day string, segments []SegmentInfo, format ExportFormat) error {
+	entries, err := readBundle(path, format)
+	if err != nil {
+		return err
+	}
+	if len(entries) != len(segments) {
+		return fmt.Errorf("%w: bundle has %d files, want %d", errorx.ErrArchiveMismatch, len(entries), len(segments))
+	}
+
+	for i, seg := range segments {
+		rc, err := ro.Open(seg)
+		if err != nil {
+			return err
+		}
+		sum, err := readerChecksum(rc)
+		_ = rc.Close()
+		if err != nil {
+			return err
+		}
+
+		if entries[i].name != exportName(day, seg) || !bytes.Equal(entries[i].sum, sum) {
+			return fmt.Errorf("%w: %s", errorx.ErrArchiveMismatch, seg.Path)
+		}
+	}
+
+	return nil
+}
+
+// readBundle 按照顺序读取归档中所有文件的名称和校验和
+func readBundle(path string, format ExportFormat) ([]bundleEntry, error) {
+	if format == ExportZip {
+		zr, err := zip.OpenReader(path)
+		if err != nil {
+			return nil, err
+		}
+		defer func() {
+			_ = zr.Close()
+		}()
+
+		entries := make([]bundleEntry, 0, len(zr.File))
+		for _, zf := range zr.File {
+			rc, err := zf.Open()
+			if err != nil {
+				return nil, err
+			}
+			sum, err := readerChecksum(rc)
+			_ = rc.Close()
+			if err != nil {
+				return nil, err
+			}
+			entries = append(entries, bundleEntry{name: zf.Name, sum: sum})
+		}
+		return entries, nil
+	}
+
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer func() {
+		_ = f.Close()
+	}()
+
+	var cr io.ReadCloser
+	switch format {
+	case ExportTarGz:
+		cr, err = gzip.NewReader(f)
+	case ExportTarZst:
+		cr, err = newDecompressReader(CompressTypeZstd, f)
+	default:
+		err = errors.New("unknown bundle format")
+	}
+	if err != nil {
+		return nil, err
+	}
+	defer func() {
+		_ = cr.Close()
+	}()
+
+	var entries []bundleEntry
+	tr := tar.NewReader(cr)
+	for {
+		hdr, err := tr.Next()
+		if err == io.EOF {
+			return entries, nil
+		}
+		if err != nil {
+			return nil, err
+		}
+		sum, err := readerChecksum(tr)
+		if err != nil {
+			return nil, err
+		}
+		entries = append(entries, bundleEntry{name: hdr.Name, sum: sum})
+	}
+}
+
+// sortedKeys 按照升序返回map的所有键
+func sortedKeys[K cmp.Ordered, V any](m map[K]V) []K {
+	keys := make([]K, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	slices.Sort(keys)
+
+	return keys
+}
@@ -0,0 +1,143 @@
+// Copyright 2025 TimeWtr
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package vortexrotate
+
+import (
+	"archive/tar"
+	"compress/gzip"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestRotator_DailyBundle(t *testing.T) {
+	_, err := NewRotator(t.TempDir(), "testdata.log", WithDailyBundle(ExportFormat(0)))
+	assert.Error(t, err)
+
+	dir := t.TempDir()
+	day := time.Now().AddDate(0, 0, -1).Format(Layout)
+	assert.NoError(t, os.MkdirAll(filepath.Join(dir, day), os.ModePerm))
+	want := make(map[string]string)
+	for i := 1; i <= 3; i++ {
+		name := fmt.Sprintf("testdata_%s_%04d.log", day, i)
+		line := fmt.Sprintf("bundle line %d\n", i)
+		want[day+"/"+name] = line
+		path := filepath.Join(dir, day, name)
+		if i == 1 {
+			// 原始文件和完成标记
+			assert.NoError(t, os.WriteFile(path, []byte(line), ReadWriteFile))
+			assert.NoError(t, os.WriteFile(path+DoneFileExt, nil, ReadWriteFile))
+			continue
+		}
+
+		// 压缩文件和校验和文件
+		f, err := os.Create(compressFn(path, CompressTypeGzip))
+		assert.NoError(t, err)
+		gw := gzip.NewWriter(f)
+		_, err = gw.Write([]byte(line))
+		assert.NoError(t, err)
+		assert.NoError(t, gw.Close())
+		assert.NoError(t, f.Close())
+		sum, err := fileChecksum(f.Name())
+		assert.NoError(t, err)
+		assert.NoError(t, writeChecksum(f.Name(), sum, renameLocal))
+	}
+
+	rotator, err := newRotator(dir, "testdata.log", WithCompress(CompressTypeGzip),
+		WithDailyBundle(ExportTarZst), WithPeriod(30))
+	assert.NoError(t, err)
+	defer rotator.Close()
+	_, err = rotator.Write([]byte("today\n"))
+	assert.NoError(t, err)
+
+	rotator.bundleDays()
+	bundle := filepath.Join(dir, day+".tar.zst")
+	assert.FileExists(t, bundle)
+	assert.NoDirExists(t, filepath.Join(dir, day))
+	assert.FileExists(t, rotator.f.Name())
+
+	f, err := os.Open(bundle)
+	assert.NoError(t, err)
+	defer f.Close()
+	zr, err := newDecompressReader(CompressTypeZstd, f)
+	assert.NoError(t, err)
+	defer zr.Close()
+	got := make(map[string]string)
+	tr := tar.NewReader(zr)
+	for {
+		hdr, err := tr.Next()
+		if err == io.EOF {
+			break
+		}
+		assert.NoError(t, err)
+		bs, err := io.ReadAll(tr)
+		assert.NoError(t, err)
+		got[hdr.Name] = string(bs)
+	}
+	assert.Equal(t, want, got)
+
+	// 归档作为一个整体参与保存策略的计算
+	files, err := rotator.cleanup.listFileInfo()
+	assert.NoError(t, err)
+	assert.Len(t, files, 2)
+	rotator.cleanup.sortFiles(files)
+	assert.Equal(t, filepath.Base(bundle), files[0].Name)
+	assert.Equal(t, []string{bundle}, files[0].Files)
+
+	// 再次打包时没有需要打包的日期
+	rotator.bundleDays()
+	assert.FileExists(t, bundle)
+}
+
+func TestRotator_DailyBundleExisting(t *testing.T) {
+	dir := t.TempDir()
+	day := time.Now().AddDate(0, 0, -1).Format(Layout)
+	assert.NoError(t, os.MkdirAll(filepath.Join(dir, day), os.ModePerm))
+	path := filepath.Join(dir, day, fmt.Sprintf("testdata_%s_0001.log", day))
+	assert.NoError(t, os.WriteFile(path, []byte("line\n"), ReadWriteFile))
+
+	rotator, err := newRotator(dir, "testdata.log", WithDailyBundle(ExportZip))
+	assert.NoError(t, err)
+	defer rotator.Close()
+
+	// 与轮转文件不一致的归档移动到隔离目录之后重新打包
+	bundle := filepath.Join(dir, day+".zip")
+	assert.NoError(t, os.WriteFile(bundle, []byte("broken"), ReadWriteFile))
+	rotator.bundleDays()
+	assert.NoFileExists(t, path)
+	bs, err := os.ReadFile(filepath.Join(dir, QuarantineDirName, day+".zip"))
+	assert.NoError(t, err)
+	assert.Equal(t, "broken", string(bs))
+	entries, err := readBundle(bundle, ExportZip)
+	assert.NoError(t, err)
+	assert.Len(t, entries, 1)
+
+	// 上一次打包之后没有删除轮转文件，校验通过之后删除
+	assert.NoError(t, os.MkdirAll(filepath.Dir(path), os.ModePerm))
+	assert.NoError(t, os.WriteFile(path, []byte("line\n"), ReadWriteFile))
+	assert.NoError(t, os.Remove(bundle))
+	ro := &ReadOnly{dir: dir, filename: "testdata", re: segmentRegexp("testdata")}
+	segments, err := ro.daySegments(day)
+	assert.NoError(t, err)
+	assert.NoError(t, rotator.writeBundle(ro, bundle, day, segments))
+	rotator.bundleDays()
+	assert.NoFileExists(t, path)
+	assert.FileExists(t, bundle)
+}
@@ -0,0 +1,88 @@
+// Copyright 2025 TimeWtr
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package vortexrotate
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// ChecksumFileExt 校验和文件的后缀名
+const ChecksumFileExt = ".sha256"
+
+// WithChecksum 为每一个封存的文件生成SHA-256校验和文件<文件名>.sha256，格式与sha256sum的输出
+// 一致，可以直接使用sha256sum -c校验。开启压缩时在压缩的同时计算压缩文件的校验和，不需要重新
+// 读取文件，不开启压缩时读取一次原始文件计算校验和。校验和文件与轮转文件一起被清理。
+func WithChecksum() Option {
+	return func(r *Rotator) error {
+		r.checksum = true
+		return nil
+	}
+}
+
+// writeChecksum 通过写临时文件+rename的方式生成path的校验和文件
+func writeChecksum(path string, sum []byte, rename renamer) error {
+	content := fmt.Sprintf("%x  %s\n", sum, filepath.Base(path))
+	return writeFileAtomic(path+ChecksumFileExt, []byte(content), rename)
+}
+
+// fileChecksum 读取文件计算SHA-256校验和
+func fileChecksum(path string) ([]byte, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer func() {
+		_ = f.Close()
+	}()
+
+	return readerChecksum(f)
+}
+
+// readerChecksum 读取r中的所有数据计算SHA-256校验和
+func readerChecksum(r io.Reader) ([]byte, error) {
+	h := sha256.New()
+	buf := resources.getBuffer()
+	defer resources.putBuffer(buf)
+	if _, err := io.CopyBuffer(h, r, *buf); err != nil {
+		return nil, err
+	}
+
+	return h.Sum(nil), nil
+}
+
+// readChecksum 读取path的校验和文件中记录的SHA-256校验和
+func readChecksum(path string) ([]byte, error) {
+	bs, err := os.ReadFile(path + ChecksumFileExt)
+	if err != nil {
+		return nil, err
+	}
+
+	fields := strings.Fields(string(bs))
+	if len(fields) == 0 {
+		return nil, fmt.Errorf("empty checksum file %s", path+ChecksumFileExt)
+	}
+	sum, err := hex.DecodeString(fields[0])
+	if err != nil || len(sum) != sha256.Size {
+		return nil, fmt.Errorf("invalid checksum file %s", path+ChecksumFileExt)
+	}
+
+	return sum, nil
+}
@@ -0,0 +1,84 @@
+// Copyright 2025 TimeWtr
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package vortexrotate
+
+import (
+	"crypto/sha256"
+	"fmt"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// assertChecksum 校验path的校验和文件内容正确
+func assertChecksum(t *testing.T, path string) {
+	bs, err := os.ReadFile(path)
+	assert.NoError(t, err)
+	sidecar, err := os.ReadFile(path + ChecksumFileExt)
+	assert.NoError(t, err)
+	assert.Equal(t, fmt.Sprintf("%x  %s\n", sha256.Sum256(bs), filepath.Base(path)), string(sidecar))
+}
+
+func TestRotator_Checksum(t *testing.T) {
+	testCases := []struct {
+		name string
+		opts []Option
+		// 封存之后的文件路径
+		sealed func(path string) string
+	}{
+		{
+			name:   "compress",
+			opts:   []Option{WithCompress(CompressTypeGzip)},
+			sealed: func(path string) string { return compressFn(path, CompressTypeGzip) },
+		},
+		{
+			name:   "raw",
+			sealed: func(path string) string { return path },
+		},
+		{
+			name: "done dir",
+			opts: []Option{WithCompress(CompressTypeZstd), WithDoneMarker(DoneMarkerDir)},
+			sealed: func(path string) string {
+				return filepath.Join(filepath.Dir(path), DoneDirName, compressFn(filepath.Base(path), CompressTypeZstd))
+			},
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			dir := t.TempDir()
+			rotator, err := newRotator(dir, "testdata.log", append(tc.opts, WithChecksum())...)
+			assert.NoError(t, err)
+			defer rotator.Close()
+
+			_, err = rotator.Write([]byte("checksum test\n"))
+			assert.NoError(t, err)
+			path := rotator.f.Name()
+			assert.NoError(t, rotator.Rotate())
+			assertChecksum(t, tc.sealed(path))
+
+			// 校验和文件与轮转文件一起清理
+			c := NewFileCountCleanUp(dir, "testdata", 1, 0)
+			files, err := c.listFileInfo()
+			assert.NoError(t, err)
+			c.sortFiles(files)
+			assert.Contains(t, files[0].Files, tc.sealed(path)+ChecksumFileExt)
+			c.remove(files[:1], nil)
+			assert.NoFileExists(t, tc.sealed(path)+ChecksumFileExt)
+		})
+	}
+}
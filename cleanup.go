@@ -22,116 +22,475 @@ import (
 	"regexp"
 	"sort"
 	"strconv"
+	"strings"
 	"sync"
 	"time"
+
+	"github.com/TimeWtr/vortexrotate/errorx"
+)
+
+// DefaultCleanInterval 默认的过期文件检查间隔
+const DefaultCleanInterval = time.Hour
+
+const (
+	// cleanJobName 定时清理任务的名称
+	cleanJobName = "cleanup"
+	// diskJobName 磁盘空间检查任务的名称
+	diskJobName = "disk-check"
 )
 
-// CleanUp 根据文件最大数量来确定是否执行清理
+// CleanUp 根据文件最大数量、保存周期、保存时长和总大小清理过期的轮转文件，最新的文件(正在写入的文件)不会被清理
 type CleanUp struct {
 	// 文件所在目录
 	dir string
-	// 最大数量
+	// 基础的文件名称
+	filename string
+	// 最大数量，0表示不限制
 	maxCount uint64
-	// 保存的周期
+	// 保存的周期(天)，0表示不限制
 	period uint16
-	// timer
-	timer *time.Timer
-	// ticker
-	ticker *time.Ticker
+	// 所有轮转文件(包括压缩文件)的最大总大小，0表示不限制
+	maxTotalSize int64
+	// 文件的最大保存时长，0表示不限制
+	maxAge time.Duration
+	// 分层保存策略
+	tiers []RetentionTier
+	// 磁盘可用空间的最低比例，低于该比例时立即执行紧急清理，0表示不检查
+	minFreeRatio float64
+	// 磁盘可用空间的检查间隔
+	diskInterval time.Duration
+	// 获取磁盘空间的方法
+	diskUsage func(path string) (free, total uint64, err error)
+	// 紧急清理完成之后的回调，参数为删除的文件
+	onEmergency func([]FileInfo)
+	// 是否只计算需要清理的文件，不执行删除
+	dryRun bool
+	// 演练模式下每次清理计算出候选文件之后的回调
+	onDryRun func([]FileInfo)
+	// 清理过程中的错误处理函数
+	errHandler func(error)
+	// 是否由外部的cron任务调度清理，为true时不再按照检查间隔定时清理
+	scheduled bool
+	// 定时任务调度器
+	sched *scheduler
+	// 调度器是否由CleanUp自己创建，为false时与轮转器共享调度器
+	ownSched bool
 	// 关闭信号
 	sig chan struct{}
 	// 检查的时间间隔
 	interval time.Duration
 	// 正则匹配
 	re *regexp.Regexp
+	// 目录级别的建议锁，删除文件时持有排他锁
+	dirLock *dirLock
+	// 是否只按照序列号排序
+	monotonic bool
+	// 是否将每日打包的归档文件作为一个整体参与保存策略的计算
+	bundles bool
+	// 隔离文件的保存时间，0表示不清理隔离文件
+	quarantine time.Duration
+	// 删除文件时是否在清单中记录墓碑
+	tombstones bool
+	// 墓碑的保存时间，0表示永久保存
+	tombstoneRetention time.Duration
+	// 是否已经启动
+	started bool
 	// 加锁保护
 	lock sync.RWMutex
+	// 保证同一时间只有一个清理任务在执行
+	running sync.Mutex
+	// 重命名文件的函数
+	rename renamer
+	// 为清理任务添加pprof标签的函数，nil表示不添加
+	profile func(task string, fn func())
+	// 保证只关闭一次
+	stopOnce sync.Once
 }
 
 func NewFileCountCleanUp(dir, filename string, maxCount uint64, period uint16) *CleanUp {
-	// 正则匹配文件名中的日期和序号
-	escapedPrefix := regexp.QuoteMeta(filename)
-	fileNameRegexPattern := fmt.Sprintf(`%s_(\d{8})_(\d{4})\.log`, escapedPrefix)
 	fc := CleanUp{
-		dir:      dir,
-		maxCount: maxCount,
-		period:   period,
-		sig:      make(chan struct{}),
-		lock:     sync.RWMutex{},
-		re:       regexp.MustCompile(fileNameRegexPattern),
+		dir:          dir,
+		filename:     filename,
+		maxCount:     maxCount,
+		period:       period,
+		sig:          make(chan struct{}),
+		interval:     DefaultCleanInterval,
+		diskUsage:    diskUsage,
+		diskInterval: DefaultDiskCheckInterval,
+		lock:         sync.RWMutex{},
+		re:           segmentRegexp(filename),
+		monotonic:    monotonicOrder(dir, filename),
+		rename:       renameLocal,
 	}
 
 	return &fc
 }
 
+// WithMaxTotalSize 设置所有轮转文件(包括压缩文件)的最大总大小，超过限制时后台清理任务从最旧的
+// 文件开始删除，直到总大小低于限制，比如：WithMaxTotalSize(10 << 30)限制日志目录最多占用10GB
+func WithMaxTotalSize(size int64) Option {
+	return func(r *Rotator) error {
+		if size < 0 {
+			return fmt.Errorf("max total size %d must not be negative", size)
+		}
+
+		r.maxTotalSize = size
+		return nil
+	}
+}
+
+// WithMaxAge 设置轮转文件的最大保存时长，最后写入时间(文件的修改时间，没有修改时间时使用
+// 文件名称中的日期)早于当前时间减去保存时长的文件会被后台清理任务删除，比如：
+// WithMaxAge(72 * time.Hour)只保留最近三天的文件
+func WithMaxAge(d time.Duration) Option {
+	return func(r *Rotator) error {
+		if d < 0 {
+			return fmt.Errorf("max age %s must not be negative", d)
+		}
+
+		r.maxAge = d
+		return nil
+	}
+}
+
+// WithCleanupDryRun 开启清理的演练模式，后台清理任务按照配置的保存策略计算需要清理的文件，
+// 但不执行删除，紧急清理也不会删除文件。每次计算完成之后发送EventCleanupDryRun事件，并调用
+// fn(可以为nil)报告候选的文件，用于在生产环境开启激进的保存策略之前确认清理的范围。
+func WithCleanupDryRun(fn func(candidates []FileInfo)) Option {
+	return func(r *Rotator) error {
+		r.cleanupDryRun = true
+		r.onCleanupDryRun = func(candidates []FileInfo) {
+			r.emit(Event{
+				Type:    EventCleanupDryRun,
+				Path:    r.dir,
+				Message: fmt.Sprintf("cleanup dry run, %d files would be removed", len(candidates)),
+			})
+			if fn != nil {
+				fn(candidates)
+			}
+		}
+		return nil
+	}
+}
+
+// WithCleanupCron 通过cron表达式(支持秒级，比如"0 30 3 * * *"表示每天03:30)调度过期文件的清理，
+// 使清理在业务低峰期执行，设置之后不再按照固定的检查间隔清理，启动时也不会立即清理，磁盘空间不足时
+// 的紧急清理不受影响。需要同时配置保存策略。
+func WithCleanupCron(spec string) Option {
+	return func(r *Rotator) error {
+		if spec == "" {
+			return fmt.Errorf("cleanup cron spec must not be empty")
+		}
+
+		r.cleanupCron = spec
+		return nil
+	}
+}
+
+// scheduleCleanup 将清理任务注册到轮转器的定时任务中
+func (r *Rotator) scheduleCleanup() error {
+	if r.cleanupCron == "" {
+		return nil
+	}
+	if r.cleanup == nil {
+		return fmt.Errorf("cleanup cron %q requires a retention policy", r.cleanupCron)
+	}
+
+	if err := r.addJob(cleanJobName, r.cleanupCron, r.cleanup.labeled(r.cleanup.cleanExpiredFiles)); err != nil {
+		return fmt.Errorf("invalid cleanup cron %q: %w", r.cleanupCron, err)
+	}
+	r.cleanup.scheduled = true
+	return nil
+}
+
+// CleanNow 立即按照保存策略执行一次清理(包括磁盘空间不足时的紧急清理)，清理完成之后返回，
+// 同步模式下只有调用CleanNow时才会清理。没有配置保存策略时直接返回
+func (r *Rotator) CleanNow() error {
+	if r.sig.Load() == 1 {
+		return errorx.ErrRotateClosed
+	}
+	if r.cleanup == nil {
+		return nil
+	}
+
+	if err := r.cleanup.cleanNow(); err != nil {
+		return err
+	}
+	r.cleanup.emergencyCleanup()
+	return nil
+}
+
+// triggerCleanup 立即执行一次调度器中的清理任务，清理任务正在执行时跳过，调度器停止之后不再执行，
+// 同步模式下在当前goroutine中执行
+func (r *Rotator) triggerCleanup() {
+	if r.synchronous {
+		r.cleanup.cleanExpiredFiles()
+		return
+	}
+
+	r.sched.trigger(cleanJobName)
+}
+
+// CleanupPlan 返回按照当前的保存策略需要清理的文件，不会删除任何文件，没有配置保存策略时返回nil
+func (r *Rotator) CleanupPlan() ([]FileInfo, error) {
+	if r.cleanup == nil {
+		return nil, nil
+	}
+
+	return r.cleanup.Plan()
+}
+
+// newCleanUp 根据轮转器的保存配置创建清理任务，没有配置任何保存策略时返回nil
+func (r *Rotator) newCleanUp() *CleanUp {
+	if r.maxCount == 0 && r.period == 0 && r.maxTotalSize == 0 && r.maxAge == 0 &&
+		len(r.tiers) == 0 && r.minFreeRatio == 0 {
+		return nil
+	}
+
+	c := NewFileCountCleanUp(r.dir, r.filename, r.maxCount, r.period)
+	c.maxTotalSize = r.maxTotalSize
+	c.maxAge = r.maxAge
+	c.tiers = r.tiers
+	c.minFreeRatio = r.minFreeRatio
+	c.onEmergency = r.onEmergencyCleanup
+	if r.networkFS {
+		c.diskInterval = NetworkFSDiskCheckInterval
+	}
+	c.SetErrorHandler(func(err error) {
+		r.emit(Event{
+			Type:    EventCleanupError,
+			Path:    r.dir,
+			Message: fmt.Sprintf("cleanup error: %v", err),
+			Err:     err,
+		})
+	})
+	c.dryRun = r.cleanupDryRun
+	c.onDryRun = r.onCleanupDryRun
+	c.dirLock = r.dirLock
+	c.rename = r.rename
+	if r.profiling {
+		c.profile = r.profile
+	}
+	c.monotonic = c.monotonic || r.monotonic
+	c.bundles = r.bundle != 0
+	c.quarantine = r.quarantineRetention
+	c.tombstones = r.tombstones
+	c.tombstoneRetention = r.tombstoneRetention
+	return c
+}
+
+// Start 启动后台清理，启动时立即执行一次清理，之后按照检查间隔定时执行
 func (c *CleanUp) Start() {
+	sched := newScheduler()
+	if err := c.start(sched, true, 0); err != nil {
+		c.reportError(err)
+		return
+	}
+	sched.start()
+}
+
+// startOn 将清理任务注册到轮转器的调度器上，由轮转器统一启动和停止调度器
+func (c *CleanUp) startOn(sched *scheduler, jitter time.Duration) error {
+	return c.start(sched, false, jitter)
+}
+
+// start 注册定时清理和磁盘空间检查任务，注册之后立即执行一次
+func (c *CleanUp) start(sched *scheduler, own bool, jitter time.Duration) error {
 	c.lock.Lock()
 	defer c.lock.Unlock()
-	const TimeDuration = 24 * time.Hour
-	c.timer = time.AfterFunc(TimeDuration, func() {
-		c.cleanExpiredFiles()
-		go c.startTicker()
-	})
+	if c.started {
+		return nil
+	}
+
+	// 通过cron表达式调度时，只在指定的时间执行清理
+	if !c.scheduled {
+		if err := sched.every(cleanJobName, c.interval, jitter, c.labeled(c.clean)); err != nil {
+			return err
+		}
+		sched.trigger(cleanJobName)
+	}
+	if c.minFreeRatio > 0 {
+		if err := sched.every(diskJobName, c.diskInterval, 0, c.labeled(c.emergencyCleanup)); err != nil {
+			return err
+		}
+		sched.trigger(diskJobName)
+	}
+
+	c.sched, c.ownSched, c.started = sched, own, true
+	return nil
 }
 
-func (c *CleanUp) startTicker() {
-	ticker := time.NewTicker(c.interval)
-	defer ticker.Stop()
+// labeled 开启pprof标签时为清理任务添加标签
+func (c *CleanUp) labeled(fn func()) func() {
+	if c.profile == nil {
+		return fn
+	}
 
-	for {
-		select {
-		case <-c.sig:
-			return
-		case <-ticker.C:
-			c.cleanExpiredFiles()
-		}
+	return func() {
+		c.profile(ProfileCleanup, fn)
 	}
 }
 
 func (c *CleanUp) ResetInterval(newInterval time.Duration) {
+	if newInterval <= 0 {
+		return
+	}
+
 	c.lock.Lock()
 	defer c.lock.Unlock()
 	c.interval = newInterval
-	if c.ticker != nil {
-		c.ticker.Reset(newInterval)
+	if c.sched != nil && !c.scheduled {
+		c.sched.reschedule(cleanJobName, newInterval)
 	}
 }
 
+// cleanExpiredFiles 由外部(cron任务、目录文件数量超限等)触发的清理，已经停止时不再执行
 func (c *CleanUp) cleanExpiredFiles() {
-	list, err := c.listFileInfo()
-	if err != nil {
-		// TODO 处理错误
+	select {
+	case <-c.sig:
 		return
+	default:
 	}
-	if len(list) == 0 {
-		return
-	}
-	fileInfos := make([]string, 0, len(list))
-	for _, info := range list {
-		fileInfos = append(fileInfos, info.Name())
+
+	c.clean()
+}
+
+// clean 清理过期的文件
+func (c *CleanUp) clean() {
+	if err := c.cleanNow(); err != nil {
+		c.reportError(err)
 	}
-	_, err = c.sortFiles(fileInfos)
+}
+
+// cleanNow 清理过期的文件，返回遍历目录的错误，删除文件的错误通过错误处理函数报告
+func (c *CleanUp) cleanNow() error {
+	c.running.Lock()
+	defer c.running.Unlock()
+
+	hook(hookBeforeCleanup, c.dir)
+	defer hook(hookAfterCleanup, c.dir)
+
+	candidates, reasons, err := c.plan()
 	if err != nil {
-		// TODO 处理错误
-		return
+		return fmt.Errorf("list files in %s error: %w", c.dir, err)
+	}
+
+	if c.dryRun {
+		if c.onDryRun != nil {
+			c.onDryRun(candidates)
+		}
+		return nil
 	}
 
 	// 执行删除
+	c.remove(candidates, reasons)
+	c.cleanQuarantine(time.Now())
+	c.expireTombstones()
+	return nil
+}
+
+// SetErrorHandler 设置清理过程中的错误处理函数，遍历目录失败、删除文件失败(比如权限不足)、文件名称
+// 中的日期或者序列号无法解析等错误都会通过fn报告，用于告警。fn在清理任务的goroutine中同步调用，
+// 不能阻塞。没有设置时错误被忽略。
+func (c *CleanUp) SetErrorHandler(fn func(error)) {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+	c.errHandler = fn
 }
 
-func (c *CleanUp) listFileInfo() ([]os.FileInfo, error) {
-	var logFiles []os.FileInfo
-	err := filepath.WalkDir(c.dir, func(_ string, d fs.DirEntry, err error) error {
+// reportError 报告清理过程中的错误
+func (c *CleanUp) reportError(err error) {
+	c.lock.RLock()
+	fn := c.errHandler
+	c.lock.RUnlock()
+	if fn != nil {
+		fn(err)
+	}
+}
+
+// Plan 根据所有配置的保存策略计算需要清理的文件，只返回候选的文件，不会删除任何文件，用于在开启
+// 保存策略之前确认清理的范围
+func (c *CleanUp) Plan() ([]FileInfo, error) {
+	c.running.Lock()
+	defer c.running.Unlock()
+
+	candidates, _, err := c.plan()
+	return candidates, err
+}
+
+// plan 计算需要清理的文件以及每个文件被清理的原因
+func (c *CleanUp) plan() ([]FileInfo, []DeleteReason, error) {
+	fileInfos, err := c.listFileInfo()
+	if err != nil {
+		return nil, nil, err
+	}
+	if len(fileInfos) == 0 {
+		return nil, nil, nil
+	}
+	c.sortFiles(fileInfos)
+
+	candidates, reasons := c.expire(fileInfos, time.Now())
+	return candidates, reasons, nil
+}
+
+// listFileInfo 遍历目录，返回所有的轮转文件，同一个轮转文件的原始文件、压缩文件和完成标记等
+// 关联文件(可能位于不同的子目录中)合并为一个FileInfo，作为一个整体计算数量、大小和删除
+func (c *CleanUp) listFileInfo() ([]FileInfo, error) {
+	groups := make(map[string]*FileInfo)
+	const matchesLen = 3
+	err := filepath.WalkDir(c.dir, func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if isQuarantineDir(c.dir, path, d) {
+			return filepath.SkipDir
+		}
 		if d.IsDir() {
 			return nil
 		}
 
-		fileInfo, err := d.Info()
+		matches := c.re.FindStringSubmatch(d.Name())
+		if len(matches) < matchesLen {
+			// 序列号文件、锁文件等非轮转文件
+			return c.listBundle(groups, path, d)
+		}
+
+		info, err := d.Info()
 		if err != nil {
+			if os.IsNotExist(err) {
+				// 遍历过程中文件被压缩或者删除
+				return nil
+			}
 			return err
 		}
-		if fileInfo != nil {
-			logFiles = append(logFiles, fileInfo)
+
+		fi, ok := groups[matches[0]]
+		if !ok {
+			t, err := time.Parse(Layout, matches[1])
+			if err != nil {
+				c.reportError(fmt.Errorf("parse date of file %s error: %w", path, err))
+				return nil
+			}
+
+			sequence, err := strconv.ParseInt(matches[2], 10, 64)
+			if err != nil {
+				c.reportError(fmt.Errorf("parse sequence of file %s error: %w", path, err))
+				return nil
+			}
+
+			fi = &FileInfo{
+				UpDir:    filepath.Dir(path),
+				Name:     matches[0],
+				Date:     t,
+				Sequence: sequence,
+			}
+			groups[matches[0]] = fi
+		}
+
+		fi.Files = append(fi.Files, path)
+		fi.Size += info.Size()
+		if info.ModTime().After(fi.ModTime) {
+			fi.ModTime = info.ModTime()
 		}
 
 		return nil
@@ -140,55 +499,209 @@ func (c *CleanUp) listFileInfo() ([]os.FileInfo, error) {
 		return nil, err
 	}
 
-	return logFiles, nil
+	fileInfos := make([]FileInfo, 0, len(groups))
+	for _, fi := range groups {
+		fileInfos = append(fileInfos, *fi)
+	}
+
+	return fileInfos, nil
 }
 
-func (c *CleanUp) sortFiles(files []string) ([]FileInfo, error) {
-	fileInfos := make([]FileInfo, 0, len(files))
-	const matchesLen = 3
-	for _, f := range files {
-		matches := c.re.FindStringSubmatch(f)
-		if len(matches) < matchesLen {
-			return fileInfos, fmt.Errorf("regrexp find sub match error, filename: %s", f)
+// listBundle 存储目录下的每日打包归档作为一个文件，日期为打包的日期，序列号为0
+func (c *CleanUp) listBundle(groups map[string]*FileInfo, path string, d fs.DirEntry) error {
+	if !c.bundles || filepath.Dir(path) != c.dir {
+		return nil
+	}
+	matches := bundleRegexp.FindStringSubmatch(d.Name())
+	if matches == nil {
+		return nil
+	}
+
+	t, err := time.Parse(Layout, matches[1])
+	if err != nil {
+		return nil
+	}
+	info, err := d.Info()
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil
 		}
+		return err
+	}
 
-		date := matches[1]
-		t, err := time.Parse(Layout, date)
-		if err != nil {
-			return fileInfos, fmt.Errorf("regrexp parse date error, filename: %s, date: %s", f, matches[1])
+	groups[path] = &FileInfo{
+		UpDir:   c.dir,
+		Name:    d.Name(),
+		Date:    t,
+		Files:   []string{path},
+		Size:    info.Size(),
+		ModTime: info.ModTime(),
+	}
+	return nil
+}
+
+// sortFiles 按照日期和序列号从旧到新排序，开启了单调排序时只按照序列号排序
+func (c *CleanUp) sortFiles(fileInfos []FileInfo) {
+	sort.Slice(fileInfos, func(i, j int) bool {
+		return segmentBefore(fileInfos[i].Date, fileInfos[i].Sequence,
+			fileInfos[j].Date, fileInfos[j].Sequence, c.monotonic)
+	})
+}
+
+// expired 从已经排序的文件中选出需要清理的文件：超过最大数量的最旧的文件、日期早于保存周期的
+// 文件、保存时长超过限制的文件、分层保存策略中不需要保留的文件，以及总大小超过限制时从最旧的
+// 文件开始直到总大小低于限制的文件，最新的文件不会被选中
+func (c *CleanUp) expired(fileInfos []FileInfo, now time.Time) []FileInfo {
+	res, _ := c.expire(fileInfos, now)
+	return res
+}
+
+// expire 与expired相同，同时返回每个文件被清理的原因
+func (c *CleanUp) expire(fileInfos []FileInfo, now time.Time) ([]FileInfo, []DeleteReason) {
+	if len(fileInfos) <= 1 {
+		return nil, nil
+	}
+
+	candidates := fileInfos[:len(fileInfos)-1]
+	reasons := make([]DeleteReason, len(candidates))
+	var n int
+	if c.maxCount > 0 && uint64(len(fileInfos)) > c.maxCount {
+		n = min(len(fileInfos)-int(c.maxCount), len(candidates))
+		for i := 0; i < n; i++ {
+			reasons[i] = DeleteMaxCount
 		}
+	}
 
-		sequence, err := strconv.ParseInt(matches[2], 10, 64)
-		if err != nil {
-			return fileInfos, fmt.Errorf("parse sequence error, filename: %s, sequence: %s", f, matches[2])
+	if c.period > 0 {
+		today, _ := time.Parse(Layout, now.Format(Layout))
+		deadline := today.AddDate(0, 0, -int(c.period))
+		for n < len(candidates) && candidates[n].Date.Before(deadline) {
+			reasons[n] = DeletePeriod
+			n++
 		}
-		fileInfos = append(fileInfos, FileInfo{
-			UpDir:    fmt.Sprintf("%s/%s", c.dir, date),
-			Name:     f,
-			Date:     t,
-			Sequence: sequence,
-		})
 	}
 
-	sort.Slice(fileInfos, func(i, j int) bool {
-		if !fileInfos[i].Date.Equal(fileInfos[j].Date) {
-			// 不同日期的文件
-			return fileInfos[i].Date.Before(fileInfos[j].Date)
+	if c.maxAge > 0 {
+		deadline := now.Add(-c.maxAge)
+		for n < len(candidates) && candidates[n].age().Before(deadline) {
+			reasons[n] = DeleteMaxAge
+			n++
 		}
+	}
 
-		// 相同日志的文件比对序列号
-		return fileInfos[i].Sequence < fileInfos[j].Sequence
-	})
+	removed := make([]bool, len(candidates))
+	for i := 0; i < n; i++ {
+		removed[i] = true
+	}
 
-	return fileInfos, nil
+	if len(c.tiers) > 0 {
+		c.markTiered(fileInfos[n:], removed[n:], now)
+		for i := n; i < len(candidates); i++ {
+			if removed[i] {
+				reasons[i] = DeleteTier
+			}
+		}
+	}
+
+	if c.maxTotalSize > 0 {
+		var total int64
+		for i, fi := range fileInfos {
+			if i >= len(removed) || !removed[i] {
+				total += fi.Size
+			}
+		}
+		for i := 0; i < len(candidates) && total > c.maxTotalSize; i++ {
+			if !removed[i] {
+				removed[i] = true
+				reasons[i] = DeleteMaxTotalSize
+				total -= candidates[i].Size
+			}
+		}
+	}
+
+	res := make([]FileInfo, 0, n)
+	resReasons := make([]DeleteReason, 0, n)
+	for i, fi := range candidates {
+		if removed[i] {
+			res = append(res, fi)
+			resReasons = append(resReasons, reasons[i])
+		}
+	}
+
+	return res, resReasons
+}
+
+// remove 删除过期的文件，包括所有的关联文件，删除之后清理空的日期目录，reasons为每个文件被清理的原因
+func (c *CleanUp) remove(fileInfos []FileInfo, reasons []DeleteReason) {
+	if len(fileInfos) == 0 {
+		return
+	}
+
+	if err := c.dirLock.Lock(); err != nil {
+		c.reportError(fmt.Errorf("lock dir %s error: %w", c.dir, err))
+		return
+	}
+	defer func() {
+		_ = c.dirLock.Unlock()
+	}()
+
+	if c.tombstones {
+		// 删除之前计算校验和并持久化墓碑，墓碑无法写入时不删除文件，下一次清理时重试
+		tombstones := make([]Tombstone, 0, len(fileInfos))
+		for i, fi := range fileInfos {
+			tombstones = append(tombstones, c.tombstone(fi, reasons[i]))
+		}
+		if err := c.appendTombstones(tombstones...); err != nil {
+			c.reportError(err)
+			return
+		}
+	}
+
+	dirs := make(map[string]struct{})
+	for _, fi := range fileInfos {
+		for _, path := range fi.Files {
+			hook(hookBeforeRemove, path)
+			err := os.Remove(path)
+			if err != nil && !os.IsNotExist(err) {
+				c.reportError(err)
+				continue
+			}
+			dirs[filepath.Dir(path)] = struct{}{}
+		}
+	}
+
+	for dir := range dirs {
+		c.removeEmptyDirs(dir)
+	}
 }
 
+// removeEmptyDirs 从dir开始向上删除空目录，直到存储目录为止
+func (c *CleanUp) removeEmptyDirs(dir string) {
+	for dir != c.dir && strings.HasPrefix(dir, c.dir+string(filepath.Separator)) {
+		// 目录不为空时删除失败
+		if err := os.Remove(dir); err != nil {
+			return
+		}
+		dir = filepath.Dir(dir)
+	}
+}
+
+// Stop 停止后台清理，等待正在执行的清理任务完成
 func (c *CleanUp) Stop() {
-	c.lock.Lock()
-	defer c.lock.Unlock()
-	close(c.sig)
-	if c.timer != nil {
-		c.timer.Stop()
+	c.stopOnce.Do(func() {
+		close(c.sig)
+	})
+
+	c.lock.RLock()
+	sched, own := c.sched, c.ownSched
+	c.lock.RUnlock()
+	switch {
+	case sched == nil:
+	case own:
+		<-sched.stop()
+	default:
+		sched.remove(cleanJobName)
+		sched.remove(diskJobName)
 	}
 }
 
@@ -198,4 +711,16 @@ type FileInfo struct {
 	Name     string    // 文件名称
 	Date     time.Time // 文件时间(年月日)
 	Sequence int64     // 文件序列号
+	Size     int64     // 文件大小，包括所有关联文件
+	ModTime  time.Time // 文件的修改时间，所有关联文件中最新的修改时间
+	Files    []string  // 原始文件、压缩文件、完成标记等所有关联文件的路径
+}
+
+// age 文件的最后写入时间，优先使用文件的修改时间，没有修改时间时使用文件名称中日期的结束时间
+func (f FileInfo) age() time.Time {
+	if !f.ModTime.IsZero() {
+		return f.ModTime
+	}
+
+	return time.Date(f.Date.Year(), f.Date.Month(), f.Date.Day()+1, 0, 0, 0, 0, time.Local)
 }
@@ -0,0 +1,280 @@
+// Copyright 2025 TimeWtr
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package vortexrotate
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestCleanUp_Expired(t *testing.T) {
+	now := time.Date(2025, 3, 10, 12, 0, 0, 0, time.Local)
+	files := make([]FileInfo, 0, 10)
+	for i := 1; i <= 10; i++ {
+		date := time.Date(2025, 3, i, 0, 0, 0, 0, time.UTC)
+		files = append(files, FileInfo{
+			Name:     fmt.Sprintf("app_%s_%04d.log", date.Format(Layout), i),
+			Date:     date,
+			Sequence: int64(i),
+			Size:     100,
+			ModTime:  date.Add(23 * time.Hour),
+		})
+	}
+
+	testCases := []struct {
+		name         string
+		maxCount     uint64
+		period       uint16
+		maxTotalSize int64
+		maxAge       time.Duration
+		want         int
+	}{
+		{name: "no limit", want: 0},
+		{name: "max count", maxCount: 4, want: 6},
+		{name: "period", period: 3, want: 6},
+		{name: "max count and period", maxCount: 8, period: 5, want: 4},
+		{name: "keep newest", maxCount: 1, period: 1, want: 9},
+		{name: "total size", maxTotalSize: 450, want: 6},
+		{name: "total size under limit", maxTotalSize: 1000, want: 0},
+		{name: "total size and max count", maxCount: 8, maxTotalSize: 750, want: 3},
+		{name: "total size keep newest", maxTotalSize: 1, want: 9},
+		{name: "max age", maxAge: 72 * time.Hour, want: 6},
+		{name: "max age and max count", maxCount: 3, maxAge: 72 * time.Hour, want: 7},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			c := NewFileCountCleanUp(t.TempDir(), "app", tc.maxCount, tc.period)
+			c.maxTotalSize = tc.maxTotalSize
+			c.maxAge = tc.maxAge
+			got := c.expired(files, now)
+			assert.Len(t, got, tc.want)
+			assert.Equal(t, files[:tc.want], got)
+		})
+	}
+}
+
+func TestRotator_MaxCount(t *testing.T) {
+	dir := t.TempDir()
+	rotator, err := newRotator(dir, "testdata.log",
+		WithRotate(64, _Second),
+		WithMaxCount(3))
+	assert.NoError(t, err)
+
+	for i := 0; i < 20; i++ {
+		_, err = rotator.Write([]byte(fmt.Sprintf("line %02d, rotate by size\n", i)))
+		assert.NoError(t, err)
+	}
+	rotator.cleanup.cleanExpiredFiles()
+	assert.NoError(t, rotator.Close())
+
+	ro, err := OpenReadOnly(dir, "testdata.log")
+	assert.NoError(t, err)
+	segments, err := ro.List()
+	assert.NoError(t, err)
+	assert.Len(t, segments, 3)
+	// 保留的是最新的文件
+	assert.Equal(t, int64(rotator.seq.Load()-1), segments[2].Sequence)
+}
+
+func TestRotator_Period(t *testing.T) {
+	dir := t.TempDir()
+	old := time.Now().AddDate(0, 0, -10).Format(Layout)
+	assert.NoError(t, os.MkdirAll(filepath.Join(dir, old), os.ModePerm))
+	oldFile := filepath.Join(dir, old, fmt.Sprintf("testdata_%s_0001.log", old))
+	assert.NoError(t, os.WriteFile(oldFile, []byte("old\n"), ReadWriteFile))
+
+	rotator, err := newRotator(dir, "testdata.log", WithPeriod(7))
+	assert.NoError(t, err)
+	_, err = rotator.Write([]byte("new\n"))
+	assert.NoError(t, err)
+	// 启动时会立即执行一次清理，关闭时等待清理完成
+	assert.NoError(t, rotator.Close())
+
+	_, err = os.Stat(oldFile)
+	assert.True(t, os.IsNotExist(err))
+}
+
+func TestRotator_MaxTotalSize(t *testing.T) {
+	dir := t.TempDir()
+	rotator, err := newRotator(dir, "testdata.log",
+		WithRotate(100, _Second),
+		WithCompress(CompressTypeSnappy),
+		WithMaxTotalSize(1024))
+	assert.NoError(t, err)
+
+	line := strings.Repeat("x", 99) + "\n"
+	for i := 0; i < 100; i++ {
+		_, err = rotator.Write([]byte(line))
+		assert.NoError(t, err)
+	}
+	rotator.cleanup.cleanExpiredFiles()
+	assert.NoError(t, rotator.Close())
+
+	fileInfos, err := rotator.cleanup.listFileInfo()
+	assert.NoError(t, err)
+	var total int64
+	for _, fi := range fileInfos {
+		total += fi.Size
+	}
+	assert.LessOrEqual(t, total, int64(1024))
+	assert.Greater(t, len(fileInfos), 1)
+}
+
+func TestRotator_MaxAge(t *testing.T) {
+	dir := t.TempDir()
+	today := time.Now().Format(Layout)
+	assert.NoError(t, os.MkdirAll(filepath.Join(dir, today), os.ModePerm))
+	oldFile := filepath.Join(dir, today, fmt.Sprintf("testdata_%s_0001.log", today))
+	assert.NoError(t, os.WriteFile(oldFile, []byte("old\n"), ReadWriteFile))
+	mtime := time.Now().Add(-2 * time.Hour)
+	assert.NoError(t, os.Chtimes(oldFile, mtime, mtime))
+
+	rotator, err := newRotator(dir, "testdata.log", WithMaxAge(time.Hour))
+	assert.NoError(t, err)
+	assert.NoError(t, rotator.Close())
+
+	_, err = os.Stat(oldFile)
+	assert.True(t, os.IsNotExist(err))
+}
+
+func TestCleanUp_RemoveVariants(t *testing.T) {
+	dir := t.TempDir()
+	old := time.Now().AddDate(0, 0, -10).Format(Layout)
+	base := fmt.Sprintf("testdata_%s_0001.log", old)
+	files := []string{
+		filepath.Join(dir, old, base),
+		filepath.Join(dir, old, base+".gz"),
+		filepath.Join(dir, old, base+".snappy"+DoneFileExt),
+		filepath.Join(dir, old, "002", DoneDirName, base+".zst"),
+	}
+	for _, fn := range files {
+		assert.NoError(t, os.MkdirAll(filepath.Dir(fn), os.ModePerm))
+		assert.NoError(t, os.WriteFile(fn, []byte("old\n"), ReadWriteFile))
+	}
+	newest := filepath.Join(dir, "20991231", "testdata_20991231_0002.log")
+	assert.NoError(t, os.MkdirAll(filepath.Dir(newest), os.ModePerm))
+	assert.NoError(t, os.WriteFile(newest, []byte("new\n"), ReadWriteFile))
+
+	c := NewFileCountCleanUp(dir, "testdata", 1, 0)
+	fileInfos, err := c.listFileInfo()
+	assert.NoError(t, err)
+	assert.Len(t, fileInfos, 2)
+
+	c.cleanExpiredFiles()
+	for _, fn := range files {
+		_, err = os.Stat(fn)
+		assert.True(t, os.IsNotExist(err))
+	}
+	// 空的日期目录一起删除
+	_, err = os.Stat(filepath.Join(dir, old))
+	assert.True(t, os.IsNotExist(err))
+	_, err = os.Stat(newest)
+	assert.NoError(t, err)
+}
+
+func TestRotator_CleanupDryRun(t *testing.T) {
+	dir := t.TempDir()
+	var candidates []FileInfo
+	rotator, err := newRotator(dir, "testdata.log",
+		WithRotate(64, _Second),
+		WithMaxCount(2),
+		WithCleanupDryRun(func(files []FileInfo) {
+			candidates = files
+		}))
+	assert.NoError(t, err)
+
+	for i := 0; i < 10; i++ {
+		_, err = rotator.Write([]byte(fmt.Sprintf("line %02d, rotate by size\n", i)))
+		assert.NoError(t, err)
+	}
+
+	plan, err := rotator.CleanupPlan()
+	assert.NoError(t, err)
+	rotator.cleanup.cleanExpiredFiles()
+	assert.NoError(t, rotator.Close())
+
+	ro, err := OpenReadOnly(dir, "testdata.log")
+	assert.NoError(t, err)
+	segments, err := ro.List()
+	assert.NoError(t, err)
+	// 演练模式下不删除文件
+	assert.Len(t, plan, len(segments)-2)
+	assert.Equal(t, plan, candidates)
+	for i, fi := range plan {
+		assert.Equal(t, segments[i].Sequence, fi.Sequence)
+	}
+}
+
+func TestCleanUp_ErrorHandler(t *testing.T) {
+	dir := t.TempDir()
+	// 日期无法解析的文件
+	bad := filepath.Join(dir, "testdata_20251399_0001.log")
+	assert.NoError(t, os.WriteFile(bad, []byte("bad\n"), ReadWriteFile))
+
+	var events []Event
+	r := &Rotator{dir: dir, filename: "testdata", maxCount: 1, eventHandler: func(e Event) {
+		events = append(events, e)
+	}}
+	c := r.newCleanUp()
+	c.cleanExpiredFiles()
+	assert.Len(t, events, 1)
+	assert.Equal(t, EventCleanupError, events[0].Type)
+	assert.Error(t, events[0].Err)
+
+	var errs []error
+	c.SetErrorHandler(func(err error) {
+		errs = append(errs, err)
+	})
+	c.dir = filepath.Join(dir, "not-exist")
+	c.cleanExpiredFiles()
+	assert.Len(t, errs, 1)
+	assert.ErrorIs(t, errs[0], os.ErrNotExist)
+}
+
+func TestRotator_CleanupCron(t *testing.T) {
+	_, err := newRotator(t.TempDir(), "testdata.log", WithCleanupCron("0 30 3 * * *"))
+	assert.Error(t, err)
+	_, err = newRotator(t.TempDir(), "testdata.log", WithMaxCount(1), WithCleanupCron("invalid"))
+	assert.Error(t, err)
+
+	dir := t.TempDir()
+	rotator, err := newRotator(dir, "testdata.log",
+		WithRotate(32, _Second),
+		WithMaxCount(1),
+		WithCleanupCron("* * * * * *"))
+	assert.NoError(t, err)
+	assert.True(t, rotator.cleanup.scheduled)
+
+	for i := 0; i < 5; i++ {
+		_, err = rotator.Write([]byte(fmt.Sprintf("line %02d, rotate by size\n", i)))
+		assert.NoError(t, err)
+	}
+
+	// 每秒执行一次的cron任务清理多余的文件
+	ro, err := OpenReadOnly(dir, "testdata.log")
+	assert.NoError(t, err)
+	assert.Eventually(t, func() bool {
+		segments, err := ro.List()
+		return err == nil && len(segments) == 1
+	}, 3*time.Second, 50*time.Millisecond)
+	assert.NoError(t, rotator.Close())
+}
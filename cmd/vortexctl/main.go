@@ -0,0 +1,325 @@
+// Copyright 2025 TimeWtr
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// vortexctl 是vortexrotate的命令行工具，目前提供以下子命令：
+//
+//	selftest  在指定目录中执行一次完整的写入、轮转、压缩、校验和清理流程，用于新部署环境的预检
+//	export    将指定日期的所有轮转文件导出为一个tar.gz、tar.zst或者zip归档
+//	plan      不修改任何文件，模拟下一次清理、下一次轮转以及未来若干天内目录的变化
+//	recompress 将已有的归档文件转换为另一种压缩格式，比如将历史的.gz文件转换为.zst
+//	audit     只读地检查目录中序列号重复、同名文件冲突、校验和缺失或者不一致的文件
+//	features  列出当前构建包含的可选功能
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"math"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/TimeWtr/vortexrotate"
+)
+
+func usage() {
+	fmt.Fprintf(os.Stderr, "usage: vortexctl <command> [flags]\n\ncommands:\n")
+	fmt.Fprintf(os.Stderr, "  selftest  run a full write/rotate/compress/verify/clean cycle in a directory\n")
+	fmt.Fprintf(os.Stderr, "  export    export all segments of a day into a tar.gz, tar.zst or zip archive\n")
+	fmt.Fprintf(os.Stderr, "  plan      simulate the next cleanup, the next rotation and how the directory evolves\n")
+	fmt.Fprintf(os.Stderr, "  recompress convert existing archives to another compress type\n")
+	fmt.Fprintf(os.Stderr, "  audit     report duplicate sequences, name conflicts and checksum problems\n")
+	fmt.Fprintf(os.Stderr, "  features  list optional features compiled into this build\n")
+}
+
+func main() {
+	if len(os.Args) < 2 {
+		usage()
+		os.Exit(2)
+	}
+
+	switch os.Args[1] {
+	case "selftest":
+		os.Exit(selftest(os.Args[2:]))
+	case "export":
+		os.Exit(export(os.Args[2:]))
+	case "plan":
+		os.Exit(plan(os.Args[2:]))
+	case "recompress":
+		os.Exit(recompress(os.Args[2:]))
+	case "audit":
+		os.Exit(audit(os.Args[2:]))
+	case "features":
+		for _, f := range vortexrotate.Features() {
+			fmt.Println(f)
+		}
+	case "-h", "-help", "--help", "help":
+		usage()
+	default:
+		fmt.Fprintf(os.Stderr, "unknown command %q\n", os.Args[1])
+		usage()
+		os.Exit(2)
+	}
+}
+
+func selftest(args []string) int {
+	fs := flag.NewFlagSet("selftest", flag.ExitOnError)
+	dir := fs.String("dir", ".", "log directory to test")
+	compress := fs.String("compress", "gzip", "compress type: none, gzip, zstd, snappy or xz")
+	timeout := fs.Duration("timeout", time.Minute, "timeout of the whole self test")
+	_ = fs.Parse(args)
+
+	var opts []vortexrotate.Option
+	if *compress != "none" {
+		tp, ok := vortexrotate.CompressTypeByName(*compress)
+		if !ok {
+			fmt.Fprintf(os.Stderr, "unknown compress type %q\n", *compress)
+			return 2
+		}
+		opts = append(opts, vortexrotate.WithCompress(tp))
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), *timeout)
+	defer cancel()
+
+	res, err := vortexrotate.SelfTest(ctx, *dir, opts...)
+	if res != nil {
+		for _, step := range res.Steps {
+			status := "ok"
+			switch {
+			case step.Err != nil:
+				status = "FAIL: " + step.Err.Error()
+			case step.Skipped:
+				status = "skipped: " + step.Message
+			}
+			fmt.Printf("%-8s %10s  %s\n", step.Name, step.Duration.Round(time.Microsecond), status)
+		}
+	}
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		return 1
+	}
+
+	return 0
+}
+
+func export(args []string) int {
+	fs := flag.NewFlagSet("export", flag.ExitOnError)
+	dir := fs.String("dir", ".", "log directory")
+	filename := fs.String("filename", "", "base filename of the rotator, for example: app.log")
+	date := fs.String("date", time.Now().Format(vortexrotate.Layout), "day to export, format: YYYYMMDD")
+	format := fs.String("format", "zip", "archive format: zip, tar.gz or tar.zst")
+	out := fs.String("o", "", "output file, default: <name>_<date>.<format>")
+	_ = fs.Parse(args)
+
+	if *filename == "" {
+		fmt.Fprintln(os.Stderr, "-filename is required")
+		return 2
+	}
+	day, err := time.ParseInLocation(vortexrotate.Layout, *date, time.Local)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "invalid date %q\n", *date)
+		return 2
+	}
+	ef, err := vortexrotate.ParseExportFormat(*format)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		return 2
+	}
+
+	ro, err := vortexrotate.OpenReadOnly(*dir, *filename)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		return 1
+	}
+
+	path := *out
+	if path == "" {
+		path = strings.TrimSuffix(*filename, filepath.Ext(*filename)) + "_" + *date + ef.Ext()
+	}
+	f, err := os.Create(path)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		return 1
+	}
+
+	err = ro.ExportDay(context.Background(), day, f, ef)
+	if err1 := f.Close(); err == nil {
+		err = err1
+	}
+	if err != nil {
+		_ = os.Remove(path)
+		fmt.Fprintln(os.Stderr, err)
+		return 1
+	}
+
+	fmt.Println(path)
+	return 0
+}
+
+func plan(args []string) int {
+	fs := flag.NewFlagSet("plan", flag.ExitOnError)
+	dir := fs.String("dir", ".", "log directory")
+	filename := fs.String("filename", "", "base filename of the rotator, for example: app.log")
+	rate := fs.Float64("rate", 0, "estimated write rate in bytes per second")
+	maxSize := fs.Uint64("max-size", vortexrotate.DefaultMaxSize, "max size of a single file in bytes")
+	timing := fs.String("timing", "hour", "timed rotation: hour, day, week or month")
+	maxCount := fs.Uint("max-count", 0, "max number of files to keep, 0 means no limit")
+	period := fs.Uint("period", 0, "days to keep, 0 means no limit")
+	maxAge := fs.Duration("max-age", 0, "max age of files, 0 means no limit")
+	maxTotal := fs.Int64("max-total-size", 0, "max total size of all files in bytes, 0 means no limit")
+	compress := fs.String("compress", "none", "compress type: none, gzip, zstd, snappy or xz")
+	ratio := fs.Float64("ratio", vortexrotate.DefaultPlanCompressRatio, "estimated compressed size / raw size")
+	days := fs.Int("days", vortexrotate.DefaultPlanDays, "days to simulate")
+	_ = fs.Parse(args)
+
+	if *filename == "" {
+		fmt.Fprintln(os.Stderr, "-filename is required")
+		return 2
+	}
+	if *maxCount > math.MaxUint16 || *period > math.MaxUint16 {
+		fmt.Fprintln(os.Stderr, "-max-count and -period must not exceed 65535")
+		return 2
+	}
+
+	opts := []vortexrotate.Option{
+		vortexrotate.WithRotate(*maxSize, vortexrotate.TimingType(*timing)),
+		vortexrotate.WithMaxCount(uint16(*maxCount)),
+		vortexrotate.WithPeriod(uint16(*period)),
+		vortexrotate.WithMaxAge(*maxAge),
+		vortexrotate.WithMaxTotalSize(*maxTotal),
+	}
+	if *compress != "none" {
+		tp, ok := vortexrotate.CompressTypeByName(*compress)
+		if !ok {
+			fmt.Fprintf(os.Stderr, "unknown compress type %q\n", *compress)
+			return 2
+		}
+		opts = append(opts, vortexrotate.WithCompress(tp))
+	}
+
+	report, err := vortexrotate.Plan(vortexrotate.PlanConfig{
+		Filename:      *filename,
+		Options:       opts,
+		ByteRate:      *rate,
+		CompressRatio: *ratio,
+		Days:          *days,
+	}, *dir)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		return 1
+	}
+
+	fmt.Printf("next cleanup removes %d files\n", len(report.NextCleanup))
+	for _, fi := range report.NextCleanup {
+		fmt.Printf("  %s  %d bytes\n", filepath.Join(fi.UpDir, fi.Name), fi.Size)
+	}
+	if report.NextRotation.IsZero() {
+		fmt.Println("next rotation: none")
+	} else {
+		fmt.Printf("next rotation: %s (%s)\n", report.NextRotation.Format(time.RFC3339), report.NextRotationReason)
+	}
+	fmt.Printf("\n%-10s %8s %8s %8s %14s\n", "date", "created", "removed", "files", "bytes")
+	for _, day := range report.Days {
+		fmt.Printf("%-10s %8d %8d %8d %14d\n", day.Date.Format("2006-01-02"),
+			day.Created, day.Removed, day.Segments, day.TotalBytes)
+	}
+
+	return 0
+}
+
+func recompress(args []string) int {
+	fs := flag.NewFlagSet("recompress", flag.ExitOnError)
+	dir := fs.String("dir", ".", "log directory")
+	filename := fs.String("filename", "", "base filename of the rotator, empty means all rotated files")
+	from := fs.String("from", "gzip", "compress type of existing archives: gzip, zstd, snappy, xz or s2")
+	to := fs.String("to", "zstd", "target compress type: gzip, zstd, snappy, xz or s2")
+	level := fs.Int("level", 0, "target compress level, 0 means the default level")
+	olderThan := fs.Duration("older-than", 0, "only convert archives older than this, 0 means all")
+	dryRun := fs.Bool("dry-run", false, "only list the archives to convert")
+	_ = fs.Parse(args)
+
+	fromType, ok := vortexrotate.CompressTypeByName(*from)
+	if !ok {
+		fmt.Fprintf(os.Stderr, "unknown compress type %q\n", *from)
+		return 2
+	}
+	toType, ok := vortexrotate.CompressTypeByName(*to)
+	if !ok {
+		fmt.Fprintf(os.Stderr, "unknown compress type %q\n", *to)
+		return 2
+	}
+
+	opts := vortexrotate.RecompressOptions{
+		Filename: *filename,
+		Level:    *level,
+		DryRun:   *dryRun,
+	}
+	if *olderThan > 0 {
+		opts.Before = time.Now().Add(-*olderThan)
+	}
+
+	report, err := vortexrotate.Recompress(*dir, fromType, toType, opts)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		return 1
+	}
+
+	for _, res := range report.Files {
+		switch {
+		case res.Err != nil:
+			fmt.Printf("%s  FAIL: %v\n", res.Source, res.Err)
+		case *dryRun:
+			fmt.Printf("%s -> %s\n", res.Source, res.Target)
+		default:
+			fmt.Printf("%s -> %s  %d -> %d bytes\n", res.Source, res.Target, res.SourceSize, res.TargetSize)
+		}
+	}
+	fmt.Printf("converted: %d, failed: %d\n", report.Converted, report.Failed)
+	if report.Failed > 0 {
+		return 1
+	}
+
+	return 0
+}
+
+func audit(args []string) int {
+	fs := flag.NewFlagSet("audit", flag.ExitOnError)
+	dir := fs.String("dir", ".", "log directory")
+	filename := fs.String("filename", "", "base filename of the rotator, for example: app.log")
+	_ = fs.Parse(args)
+
+	if *filename == "" {
+		fmt.Fprintln(os.Stderr, "-filename is required")
+		return 2
+	}
+
+	report, err := vortexrotate.Audit(*dir, *filename)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		return 1
+	}
+
+	fmt.Printf("files: %d, issues: %d\n", report.Files, len(report.Issues))
+	for _, issue := range report.Issues {
+		fmt.Println(issue)
+	}
+	if len(report.Issues) > 0 {
+		return 1
+	}
+
+	return 0
+}
@@ -0,0 +1,125 @@
+// Copyright 2025 TimeWtr
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package vortexrotate
+
+import (
+	"compress/gzip"
+	"io"
+	"strings"
+
+	"github.com/TimeWtr/vortexrotate/errorx"
+	"github.com/golang/snappy"
+	"github.com/klauspost/compress/s2"
+	"github.com/ulikunitz/xz"
+)
+
+// compressTypeOf 根据文件后缀名判断压缩类型，未压缩的文件返回CompressTypeUnknown
+func compressTypeOf(path string) int {
+	types := append([]int{CompressTypeGzip, CompressTypeZstd, CompressTypeSnappy, CompressTypeXz, CompressTypeS2},
+		registeredCompressTypes()...)
+	for _, tp := range types {
+		if strings.HasSuffix(path, compressFn("", tp)) {
+			return tp
+		}
+	}
+
+	return CompressTypeUnknown
+}
+
+// trimCompressExt 去掉文件名中的压缩后缀
+func trimCompressExt(path string) string {
+	tp := compressTypeOf(path)
+	if tp == CompressTypeUnknown {
+		return path
+	}
+
+	return strings.TrimSuffix(path, compressFn("", tp))
+}
+
+// newCompressWriter 创建流式的压缩写入器，写入的数据压缩之后写入w，Close时刷新所有数据，
+// 但不会关闭w
+func newCompressWriter(tp, level int, w io.Writer) (io.WriteCloser, error) {
+	switch tp {
+	case CompressTypeGzip:
+		return gzip.NewWriterLevel(w, level)
+	case CompressTypeZstd:
+		backend := resolveZstdBackend(ZstdBackendDefault)
+		p := ZstdParams{Level: level}
+		zw, err := resources.getZstdWriter(backend, w, p)
+		if err != nil {
+			return nil, err
+		}
+		return &zstdWriteCloser{w: zw, backend: backend, params: p}, nil
+	case CompressTypeSnappy:
+		return snappy.NewBufferedWriter(w), nil
+	case CompressTypeXz:
+		return newXzWriter(w, level, 0)
+	case CompressTypeS2:
+		return newS2Writer(w, level, 0), nil
+	default:
+		if c, ok := compressorOf(tp); ok {
+			return c.NewWriter(w, level)
+		}
+		return nil, errorx.ErrCompressType
+	}
+}
+
+// zstdWriteCloser Close时将压缩上下文归还到共享的资源池
+type zstdWriteCloser struct {
+	w       ZstdWriter
+	backend ZstdBackend
+	params  ZstdParams
+}
+
+func (z *zstdWriteCloser) Write(p []byte) (int, error) {
+	return z.w.Write(p)
+}
+
+func (z *zstdWriteCloser) Flush() error {
+	return z.w.Flush()
+}
+
+func (z *zstdWriteCloser) Close() error {
+	err := z.w.Close()
+	resources.putZstdWriter(z.backend, z.w, z.params)
+	return err
+}
+
+// newDecompressReader 创建流式的解压读取器，CompressTypeUnknown表示未压缩，直接读取r
+func newDecompressReader(tp int, r io.Reader) (io.ReadCloser, error) {
+	switch tp {
+	case CompressTypeUnknown:
+		return io.NopCloser(r), nil
+	case CompressTypeGzip:
+		return gzip.NewReader(r)
+	case CompressTypeZstd:
+		return newZstdReader(ZstdBackendDefault, r)
+	case CompressTypeSnappy:
+		return io.NopCloser(snappy.NewReader(r)), nil
+	case CompressTypeXz:
+		xr, err := xz.NewReader(r)
+		if err != nil {
+			return nil, err
+		}
+		return io.NopCloser(xr), nil
+	case CompressTypeS2:
+		return io.NopCloser(s2.NewReader(r)), nil
+	default:
+		if c, ok := compressorOf(tp); ok {
+			return c.NewReader(r)
+		}
+		return nil, errorx.ErrCompressType
+	}
+}
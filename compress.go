@@ -20,9 +20,14 @@ import (
 	"fmt"
 	"io"
 	"os"
+	"runtime"
+	"time"
 
+	"github.com/TimeWtr/vortexrotate/errorx"
 	"github.com/golang/snappy"
-	"github.com/valyala/gozstd"
+	"github.com/klauspost/compress/s2"
+	"github.com/klauspost/pgzip"
+	"github.com/ulikunitz/xz"
 )
 
 const (
@@ -30,9 +35,11 @@ const (
 	CompressTypeGzip
 	CompressTypeZstd
 	CompressTypeSnappy
+	CompressTypeXz
+	CompressTypeS2
 
 	_minCompressType = CompressTypeGzip
-	_maxCompressType = CompressTypeSnappy
+	_maxCompressType = CompressTypeS2
 )
 
 const bufferSize = 128 * 1024
@@ -45,6 +52,26 @@ const (
 	GzipHuffmanOnly        = gzip.HuffmanOnly
 )
 
+// Xz压缩的等级，与xz命令行的-0~-9对应，等级越高字典越大，压缩比越高，占用的内存也越多
+const (
+	XzBestSpeed          = 0
+	XzDefaultCompression = 6
+	XzBestCompression    = 9
+)
+
+// S2压缩的等级，等级越高压缩比越高，压缩速度越慢，解压速度基本不受影响
+const (
+	S2DefaultCompression = 0
+	S2BetterCompression  = 1
+	S2BestCompression    = 2
+)
+
+// xzDictCaps 各个压缩等级对应的字典大小
+var xzDictCaps = [...]int{
+	256 << 10, 1 << 20, 2 << 20, 4 << 20, 4 << 20,
+	8 << 20, 8 << 20, 16 << 20, 32 << 20, 64 << 20,
+}
+
 // compressFn 根据文件名和压缩类型生成压缩文件名
 func compressFn(fn string, tp int) string {
 	switch tp {
@@ -54,7 +81,14 @@ func compressFn(fn string, tp int) string {
 		return fmt.Sprintf("%s.zst", fn)
 	case CompressTypeSnappy:
 		return fmt.Sprintf("%s.snappy", fn)
+	case CompressTypeXz:
+		return fmt.Sprintf("%s.xz", fn)
+	case CompressTypeS2:
+		return fmt.Sprintf("%s.s2", fn)
 	default:
+		if c, ok := compressorOf(tp); ok {
+			return fn + c.Ext()
+		}
 		return ""
 	}
 }
@@ -63,12 +97,81 @@ func compressFn(fn string, tp int) string {
 type Compress struct {
 	// 是否执行压缩操作
 	compress bool
-	// 压缩类型(Gzip/Zstd/Snappy)
+	// 压缩类型(Gzip/Zstd/Snappy/Xz)
 	compressType int
+	// 压缩等级
+	level int
+	// 并行gzip压缩的goroutine数量，0表示不使用并行压缩
+	workers int
+	// 压缩成功之后是否保留源文件
+	keepSource bool
+	// zstd窗口大小以2为底的对数，0表示使用压缩等级对应的默认值
+	windowLog int
+	// xz的字典大小，0表示使用压缩等级对应的默认值
+	dictCap int
+	// s2并行压缩的goroutine数量，0表示使用GOMAXPROCS
+	concurrency int
+	// 小于该大小的文件不压缩，0表示全部压缩
+	minSize int64
+	// 轮转之后延迟压缩的时间，0表示轮转时立即压缩
+	delay time.Duration
+	// 允许压缩的时间窗口，nil表示不限制
+	window *compressWindow
+	// gzip文件头中的注释
+	gzipComment string
+	// 压缩前预留的磁盘空间与源文件大小的比例，0表示不检查
+	reserveRatio float64
+	// 获取磁盘空间的函数
+	diskUsage func(path string) (free, total uint64, err error)
 	// 压缩的策略
 	cs CompressStrategy
 }
 
+// newCompressStrategy 根据压缩配置创建新的压缩策略，用于多个goroutine并行压缩时每个goroutine
+// 使用独立的压缩上下文
+func (r *Rotator) newCompressStrategy() (CompressStrategy, error) {
+	c, ok := compressCodecOf(r.cpr.compressType)
+	if !ok {
+		return nil, errorx.ErrCompressType
+	}
+
+	return c.strategy(r, r.cpr.level)
+}
+
+// newGzipStrategy 创建gzip压缩策略，设置了并发压缩的协程数量时使用pgzip
+func newGzipStrategy(r *Rotator, level int) (CompressStrategy, error) {
+	if r.cpr.workers > 0 {
+		return NewPgzip(nil, nil, level, r.cpr.workers)
+	}
+	return NewGzip(nil, nil, level)
+}
+
+// newZstdStrategy 创建zstd压缩策略
+func newZstdStrategy(r *Rotator, level int) (CompressStrategy, error) {
+	if level < ZstdMinLevel || level > ZstdMaxLevel {
+		return nil, fmt.Errorf("zstd compress level %d not support", level)
+	}
+	return &Zstd{l: level, windowLog: r.cpr.windowLog, backend: r.zstdBackend, dict: r.zstdDict}, nil
+}
+
+// newSnappyStrategy 创建snappy压缩策略，snappy没有压缩级别
+func newSnappyStrategy(_ *Rotator, _ int) (CompressStrategy, error) {
+	return NewSnappy(nil, nil), nil
+}
+
+// newXzStrategy 创建xz压缩策略
+func newXzStrategy(r *Rotator, level int) (CompressStrategy, error) {
+	if _, err := NewXz(nil, nil, level); err != nil {
+		return nil, err
+	}
+	return &Xz{l: level, dictCap: r.cpr.dictCap}, nil
+}
+
+// newS2Strategy 创建s2压缩策略
+func newS2Strategy(r *Rotator, level int) (CompressStrategy, error) {
+	return NewS2(nil, nil, level, r.cpr.concurrency)
+}
+
 // CompressStrategy 压缩策略，对文件执行压缩操作
 type CompressStrategy interface {
 	// Compress 执行压缩逻辑
@@ -106,7 +209,9 @@ func (g *Gzip) Compress() error {
 		_ = g.f.Close()
 	}()
 
-	bs := make([]byte, bufferSize)
+	buf := resources.getBuffer()
+	defer resources.putBuffer(buf)
+	bs := *buf
 	for {
 		n, err := g.f.Read(bs)
 		if err != nil && !errors.Is(err, io.EOF) {
@@ -130,23 +235,227 @@ func (g *Gzip) Reset(w io.Writer, f *os.File) {
 	g.f = f
 }
 
-type Zstd struct {
-	w *gozstd.Writer
+// CompressOptions 各个压缩算法的配置，只有与压缩类型对应的配置生效，值为0的字段使用默认值，
+// snappy的分帧格式没有可以调整的参数
+type CompressOptions struct {
+	Gzip GzipOptions
+	Zstd ZstdOptions
+	Xz   XzOptions
+	S2   S2Options
+}
+
+// GzipOptions gzip压缩的配置
+type GzipOptions struct {
+	// 压缩等级，GzipHuffmanOnly~GzipBestCompression，0表示GzipDefaultCompression
+	Level int
+	// 并行压缩的goroutine数量，0表示不使用并行压缩，详见WithParallelGzip
+	Workers int
+}
+
+// ZstdOptions zstd压缩的配置
+type ZstdOptions struct {
+	// 压缩等级，ZstdMinLevel~ZstdMaxLevel，0表示ZstdDefaultLevel
+	Level int
+	// 窗口大小以2为底的对数，ZstdMinWindowLog~ZstdMaxWindowLog，0表示使用压缩等级对应的
+	// 默认值，窗口越大压缩比越高，压缩和解压占用的内存也越多
+	WindowLog int
+}
+
+// XzOptions xz压缩的配置
+type XzOptions struct {
+	// 压缩等级，XzBestSpeed~XzBestCompression，0表示XzDefaultCompression，需要XzBestSpeed
+	// 时使用WithCompress设置
+	Level int
+	// 字典大小(字节)，0表示使用压缩等级对应的默认值
+	DictCap int
+}
+
+// S2Options s2压缩的配置
+type S2Options struct {
+	// 压缩等级，S2DefaultCompression~S2BestCompression
+	Level int
+	// 并行压缩的goroutine数量，0表示使用GOMAXPROCS
+	Concurrency int
+}
+
+// WithCompressOptions 开启压缩，使用opts中与压缩类型对应的配置，每一种压缩算法的所有参数都
+// 可以单独设置，比如：
+//
+//	WithCompressOptions(CompressTypeZstd, CompressOptions{Zstd: ZstdOptions{Level: 9, WindowLog: 24}})
+func WithCompressOptions(tp int, opts CompressOptions) Option {
+	return func(r *Rotator) error {
+		var level int
+		switch tp {
+		case CompressTypeGzip:
+			level = opts.Gzip.Level
+			if level == 0 {
+				level = GzipDefaultCompression
+			}
+			if opts.Gzip.Workers < 0 {
+				return errors.New("gzip workers must not be negative")
+			}
+			if opts.Gzip.Workers > 0 {
+				r.cpr.workers = opts.Gzip.Workers
+			}
+		case CompressTypeZstd:
+			level = opts.Zstd.Level
+			if level == 0 {
+				level = ZstdDefaultLevel
+			}
+			if opts.Zstd.WindowLog != 0 &&
+				(opts.Zstd.WindowLog < ZstdMinWindowLog || opts.Zstd.WindowLog > ZstdMaxWindowLog) {
+				return fmt.Errorf("zstd window log %d not support", opts.Zstd.WindowLog)
+			}
+			r.cpr.windowLog = opts.Zstd.WindowLog
+		case CompressTypeXz:
+			level = opts.Xz.Level
+			if level == 0 {
+				level = XzDefaultCompression
+			}
+			if opts.Xz.DictCap < 0 {
+				return errors.New("xz dict cap must not be negative")
+			}
+			r.cpr.dictCap = opts.Xz.DictCap
+		case CompressTypeS2:
+			level = opts.S2.Level
+			if opts.S2.Concurrency < 0 {
+				return errors.New("s2 concurrency must not be negative")
+			}
+			r.cpr.concurrency = opts.S2.Concurrency
+		}
+
+		return WithCompress(tp, level)(r)
+	}
+}
+
+// WithCompressMinSize 设置执行压缩的最小文件大小(字节)，小于该大小的轮转文件不压缩，直接以
+// 原始文件封存，避免低峰期的小文件压缩浪费CPU并且压缩之后反而更大，不开启压缩时不生效
+func WithCompressMinSize(size int64) Option {
+	return func(r *Rotator) error {
+		if size < 0 {
+			return errors.New("compress min size must not be negative")
+		}
+
+		r.cpr.minSize = size
+		return nil
+	}
+}
+
+// WithRemoveSource 设置压缩成功之后是否删除源文件，默认开启，压缩文件完整写入并关闭、解压校验
+// 与源文件一致之后才删除源文件，压缩或者校验失败时保留源文件，校验失败时发送EventVerifyFailed
+// 事件，不开启压缩时不生效
+func WithRemoveSource(enable bool) Option {
+	return func(r *Rotator) error {
+		r.cpr.keepSource = !enable
+		return nil
+	}
+}
+
+// PgzipBlockSize 并行gzip压缩时每个数据块的大小
+const PgzipBlockSize = 1 << 20
+
+// WithParallelGzip gzip压缩时使用workers个goroutine并行压缩，workers<=0时使用GOMAXPROCS，
+// 与WithCompress的顺序无关，只对CompressTypeGzip生效。每个goroutine额外占用两个PgzipBlockSize
+// 大小的数据块，输出仍然是标准的gzip文件。
+func WithParallelGzip(workers int) Option {
+	return func(r *Rotator) error {
+		if workers <= 0 {
+			workers = runtime.GOMAXPROCS(0)
+		}
+
+		r.cpr.workers = workers
+		if _, ok := r.cpr.cs.(*Gzip); ok {
+			cs, err := NewPgzip(nil, r.f, r.cpr.level, workers)
+			if err != nil {
+				return err
+			}
+			r.cpr.cs = cs
+		}
+		return nil
+	}
+}
+
+// Pgzip 并行gzip压缩，将输入切分为固定大小的数据块，由多个goroutine并行压缩，输出仍然是
+// 标准的gzip流，大文件的压缩耗时随着goroutine数量近似线性下降
+type Pgzip struct {
+	w *pgzip.Writer
 	f *os.File
-	l int
+}
+
+func NewPgzip(outFile io.Writer, f *os.File, compressLevel, workers int) (CompressStrategy, error) {
+	if workers <= 0 {
+		return nil, fmt.Errorf("pgzip workers %d must be greater than 0", workers)
+	}
+
+	w, err := pgzip.NewWriterLevel(outFile, compressLevel)
+	if err != nil {
+		return nil, err
+	}
+	if err = w.SetConcurrency(PgzipBlockSize, workers); err != nil {
+		return nil, err
+	}
+
+	return &Pgzip{
+		w: w,
+		f: f,
+	}, nil
+}
+
+func (p *Pgzip) Compress() error {
+	if p.f == nil {
+		_ = p.w.Close()
+		return os.ErrClosed
+	}
+	defer func() {
+		_ = p.f.Close()
+	}()
+
+	buf := resources.getBuffer()
+	defer resources.putBuffer(buf)
+	if _, err := io.CopyBuffer(p.w, p.f, *buf); err != nil {
+		_ = p.w.Close()
+		return err
+	}
+
+	return p.w.Close()
+}
+
+func (p *Pgzip) Reset(w io.Writer, f *os.File) {
+	p.w.Reset(w)
+	p.f = f
+}
+
+// Zstd zstd压缩，压缩上下文从进程内共享的资源池中获取，压缩完成之后归还
+type Zstd struct {
+	out io.Writer
+	f   *os.File
+	l   int
+	// 窗口大小以2为底的对数，0表示使用压缩等级对应的默认值
+	windowLog int
+	// zstd压缩的实现
+	backend ZstdBackend
+	// 压缩使用的字典ID，0表示不使用字典
+	dict uint32
 }
 
 func NewZstd(outFile io.Writer, f *os.File, compressLevel int) CompressStrategy {
 	return &Zstd{
-		w: gozstd.NewWriterLevel(outFile, compressLevel),
-		f: f,
-		l: compressLevel,
+		out: outFile,
+		f:   f,
+		l:   compressLevel,
 	}
 }
 
 func (z *Zstd) Compress() error {
+	backend := resolveZstdBackend(z.backend)
+	p := ZstdParams{Level: z.l, WindowLog: z.windowLog, Dict: z.dict}
+	w, err := resources.getZstdWriter(backend, z.out, p)
+	if err != nil {
+		return err
+	}
 	defer func() {
-		_ = z.w.Close()
+		_ = w.Close()
+		resources.putZstdWriter(backend, w, p)
 	}()
 
 	if z.f == nil {
@@ -156,7 +465,9 @@ func (z *Zstd) Compress() error {
 		_ = z.f.Close()
 	}()
 
-	bs := make([]byte, bufferSize)
+	buf := resources.getBuffer()
+	defer resources.putBuffer(buf)
+	bs := *buf
 	for {
 		n, err := z.f.Read(bs)
 		if err != nil && !errors.Is(err, io.EOF) {
@@ -167,16 +478,16 @@ func (z *Zstd) Compress() error {
 			break
 		}
 
-		if _, err = z.w.Write(bs[:n]); err != nil {
+		if _, err = w.Write(bs[:n]); err != nil {
 			return err
 		}
 	}
 
-	return z.w.Flush()
+	return w.Flush()
 }
 
 func (z *Zstd) Reset(w io.Writer, f *os.File) {
-	z.w = gozstd.NewWriterLevel(w, z.l)
+	z.out = w
 	z.f = f
 }
 
@@ -204,7 +515,9 @@ func (s *Snappy) Compress() error {
 		_ = s.f.Close()
 	}()
 
-	bs := make([]byte, bufferSize)
+	buf := resources.getBuffer()
+	defer resources.putBuffer(buf)
+	bs := *buf
 	for {
 		n, err := s.f.Read(bs)
 		if err != nil && !errors.Is(err, io.EOF) {
@@ -227,3 +540,134 @@ func (s *Snappy) Reset(w io.Writer, f *os.File) {
 	s.w.Reset(w)
 	s.f = f
 }
+
+// Xz xz(LZMA2)压缩，压缩比高于其他算法，但是压缩速度慢、占用内存多，适用于压缩比比CPU更重要
+// 的长期冷归档
+type Xz struct {
+	out io.Writer
+	f   *os.File
+	l   int
+	// 字典大小，0表示使用压缩等级对应的默认值
+	dictCap int
+}
+
+func NewXz(outFile io.Writer, f *os.File, compressLevel int) (CompressStrategy, error) {
+	if compressLevel < XzBestSpeed || compressLevel > XzBestCompression {
+		return nil, fmt.Errorf("xz compress level %d not support", compressLevel)
+	}
+
+	return &Xz{
+		out: outFile,
+		f:   f,
+		l:   compressLevel,
+	}, nil
+}
+
+func (x *Xz) Compress() error {
+	if x.f == nil {
+		return os.ErrClosed
+	}
+	defer func() {
+		_ = x.f.Close()
+	}()
+
+	w, err := newXzWriter(x.out, x.l, x.dictCap)
+	if err != nil {
+		return err
+	}
+
+	buf := resources.getBuffer()
+	defer resources.putBuffer(buf)
+	if _, err = io.CopyBuffer(w, x.f, *buf); err != nil {
+		_ = w.Close()
+		return err
+	}
+
+	return w.Close()
+}
+
+func (x *Xz) Reset(w io.Writer, f *os.File) {
+	x.out = w
+	x.f = f
+}
+
+// newXzWriter 创建xz压缩写入器，dictCap为0时使用压缩等级对应的字典大小，Close时写入流的结尾，
+// 但不会关闭w
+func newXzWriter(w io.Writer, level, dictCap int) (*xz.Writer, error) {
+	if level < XzBestSpeed || level > XzBestCompression {
+		level = XzDefaultCompression
+	}
+	if dictCap == 0 {
+		dictCap = xzDictCaps[level]
+	}
+
+	cfg := xz.WriterConfig{DictCap: dictCap}
+	return cfg.NewWriter(w)
+}
+
+// S2 s2压缩，snappy的扩展格式，压缩比和压缩速度都优于snappy，大文件按块由多个goroutine并行压缩，
+// 读取方可以同时解压s2和snappy的分帧格式
+type S2 struct {
+	out io.Writer
+	f   *os.File
+	l   int
+	// 并行压缩的goroutine数量，0表示使用GOMAXPROCS
+	concurrency int
+}
+
+func NewS2(outFile io.Writer, f *os.File, compressLevel, concurrency int) (CompressStrategy, error) {
+	if compressLevel < S2DefaultCompression || compressLevel > S2BestCompression {
+		return nil, fmt.Errorf("s2 compress level %d not support", compressLevel)
+	}
+	if concurrency < 0 {
+		return nil, fmt.Errorf("s2 concurrency %d must not be negative", concurrency)
+	}
+
+	return &S2{
+		out:         outFile,
+		f:           f,
+		l:           compressLevel,
+		concurrency: concurrency,
+	}, nil
+}
+
+func (s *S2) Compress() error {
+	if s.f == nil {
+		return os.ErrClosed
+	}
+	defer func() {
+		_ = s.f.Close()
+	}()
+
+	w := newS2Writer(s.out, s.l, s.concurrency)
+	buf := resources.getBuffer()
+	defer resources.putBuffer(buf)
+	if _, err := io.CopyBuffer(w, s.f, *buf); err != nil {
+		_ = w.Close()
+		return err
+	}
+
+	return w.Close()
+}
+
+func (s *S2) Reset(w io.Writer, f *os.File) {
+	s.out = w
+	s.f = f
+}
+
+// newS2Writer 创建s2压缩写入器，concurrency为0时使用GOMAXPROCS，Close时写入所有缓冲的数据，
+// 但不会关闭w
+func newS2Writer(w io.Writer, level, concurrency int) *s2.Writer {
+	var opts []s2.WriterOption
+	switch level {
+	case S2BetterCompression:
+		opts = append(opts, s2.WriterBetterCompression())
+	case S2BestCompression:
+		opts = append(opts, s2.WriterBestCompression())
+	}
+	if concurrency > 0 {
+		opts = append(opts, s2.WriterConcurrency(concurrency))
+	}
+
+	return s2.NewWriter(w, opts...)
+}
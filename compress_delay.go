@@ -0,0 +1,102 @@
+// Copyright 2025 TimeWtr
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package vortexrotate
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"time"
+)
+
+// DefaultCompressDelayCron 延迟压缩和压缩时间窗口的扫描任务的执行时间，每分钟扫描一次
+const DefaultCompressDelayCron = "0 * * * * *"
+
+// WithCompressDelay 开启延迟压缩，轮转之后的文件先以原始文件保留，方便直接grep最近的日志，
+// 后台定时任务每分钟扫描存储目录，将修改时间超过delay的原始轮转文件压缩。延迟压缩的文件在
+// 压缩完成之后才生成校验和文件和完成标记，进程重启之后未压缩的文件由扫描任务继续处理，
+// 不开启压缩时不生效
+func WithCompressDelay(delay time.Duration) Option {
+	return func(r *Rotator) error {
+		if delay < 0 {
+			return errors.New("compress delay must not be negative")
+		}
+
+		r.cpr.delay = delay
+		return nil
+	}
+}
+
+// deferCompress 判断轮转文件是否延迟压缩，开启了延迟压缩或者不在压缩时间窗口内时由后台任务压缩，
+// 同步模式下在调用Rotate时压缩
+func (r *Rotator) deferCompress(path string) bool {
+	return r.shouldCompress(path) && (r.cpr.delay > 0 || !r.compressAllowed() || r.synchronous)
+}
+
+// compressDelayed 扫描存储目录，封存修改时间超过延迟时间的原始轮转文件，设置了压缩时间窗口
+// 时只在窗口内执行
+func (r *Rotator) compressDelayed() {
+	if !r.compressAllowed() {
+		return
+	}
+	if !r.delayRunning.CompareAndSwap(false, true) {
+		// 上一次扫描还没有结束
+		return
+	}
+	defer r.delayRunning.Store(false)
+	r.archiveLock.Lock()
+	defer r.archiveLock.Unlock()
+
+	candidates, err := r.leftoverFiles(time.Now().Add(-r.cpr.delay))
+	if err != nil {
+		r.l.Printf("compress delay: walk dir %s error: %v", r.dir, err)
+		return
+	}
+	if len(candidates) == 0 {
+		return
+	}
+
+	cs, err := r.newCompressStrategy()
+	if err != nil {
+		r.l.Printf("compress delay: create compress strategy error: %v", err)
+		return
+	}
+
+	for _, path := range candidates {
+		if !r.reserveSpace(path) {
+			// 磁盘空间不足，等待清理之后下一次扫描再压缩
+			return
+		}
+		var pause RotatePause
+		if err = r.sealFile(path, cs, &pause); err != nil {
+			r.l.Printf("compress delay: seal %s error: %v", path, err)
+		}
+	}
+}
+
+// archived 判断保留了源文件的轮转文件是否已经压缩过，压缩文件可能已经移动到done/子目录中
+func archived(path string, tp int) bool {
+	base := filepath.Base(compressFn(path, tp))
+	for _, p := range []string{
+		filepath.Join(filepath.Dir(path), base),
+		filepath.Join(filepath.Dir(path), DoneDirName, base),
+	} {
+		if _, err := os.Stat(p); err == nil {
+			return true
+		}
+	}
+
+	return false
+}
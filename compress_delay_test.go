@@ -0,0 +1,94 @@
+// Copyright 2025 TimeWtr
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package vortexrotate
+
+import (
+	"os"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestRotator_CompressDelay(t *testing.T) {
+	testCases := []struct {
+		name string
+		opts []Option
+	}{
+		{
+			name: "remove source",
+		},
+		{
+			name: "keep source",
+			opts: []Option{WithRemoveSource(false)},
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			opts := append([]Option{
+				WithCompress(CompressTypeGzip),
+				WithCompressDelay(time.Hour),
+				WithDoneMarker(DoneMarkerFile),
+			}, tc.opts...)
+			rotator, err := newRotator(t.TempDir(), "testdata.log", opts...)
+			assert.NoError(t, err)
+			defer rotator.Close()
+
+			_, err = rotator.Write([]byte("compress delay test\n"))
+			assert.NoError(t, err)
+			path := rotator.f.Name()
+			assert.NoError(t, rotator.Rotate())
+
+			// 轮转之后保留原始文件，不写入完成标记
+			gz := compressFn(path, CompressTypeGzip)
+			assert.FileExists(t, path)
+			assert.NoFileExists(t, gz)
+			assert.NoFileExists(t, path+DoneFileExt)
+
+			// 没有超过延迟时间的文件不压缩
+			rotator.compressDelayed()
+			assert.NoFileExists(t, gz)
+
+			// 超过延迟时间之后压缩，正在写入的文件不压缩
+			old := time.Now().Add(-2 * time.Hour)
+			assert.NoError(t, os.Chtimes(path, old, old))
+			active := rotator.f.Name()
+			assert.NoError(t, os.Chtimes(active, old, old))
+			rotator.compressDelayed()
+			assert.FileExists(t, gz)
+			assert.FileExists(t, gz+DoneFileExt)
+			assert.NoFileExists(t, compressFn(active, CompressTypeGzip))
+			if rotator.cpr.keepSource {
+				assert.FileExists(t, path)
+			} else {
+				assert.NoFileExists(t, path)
+			}
+
+			// 已经压缩的文件不重复压缩
+			info, err := os.Stat(gz)
+			assert.NoError(t, err)
+			rotator.compressDelayed()
+			info2, err := os.Stat(gz)
+			assert.NoError(t, err)
+			assert.Equal(t, info.ModTime(), info2.ModTime())
+		})
+	}
+}
+
+func TestWithCompressDelay(t *testing.T) {
+	_, err := newRotator(t.TempDir(), "testdata.log", WithCompress(CompressTypeGzip), WithCompressDelay(-time.Second))
+	assert.Error(t, err)
+}
@@ -0,0 +1,132 @@
+// Copyright 2025 TimeWtr
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package vortexrotate
+
+import "fmt"
+
+// DefaultCompressQueueSize 异步压缩队列的默认长度
+const DefaultCompressQueueSize = 16
+
+// WithAsyncCompress 开启异步压缩，轮转时只关闭旧文件并立即打开新文件，旧文件放入长度为queueSize
+// 的有界队列，由后台goroutine依次执行压缩、完成标记等封存流程，大文件的压缩不会阻塞写入。队列
+// 已满时轮转阻塞等待(背压)，队列深度计入背压等级。关闭轮转器时等待队列中的文件全部封存完成。
+// queueSize<=0时使用DefaultCompressQueueSize。
+func WithAsyncCompress(queueSize int) Option {
+	return func(r *Rotator) error {
+		if queueSize <= 0 {
+			queueSize = DefaultCompressQueueSize
+		}
+
+		r.sealQueueSize = queueSize
+		return nil
+	}
+}
+
+// WithCompressionWorkers 设置异步压缩的goroutine数量，多个轮转文件同时等待压缩时(比如多个文件
+// 连续轮转)并行压缩，n<=0时根据GOMAXPROCS计算，没有开启异步压缩时自动开启，队列长度为
+// DefaultCompressQueueSize。每个goroutine使用独立的压缩上下文，压缩过程中的panic只影响当前
+// 文件，不会导致goroutine退出。进程内同时执行的压缩任务数量仍然受SetMaxConcurrentCompressions
+// 的限制。
+func WithCompressionWorkers(n int) Option {
+	return func(r *Rotator) error {
+		if n <= 0 {
+			n = defaultWorkers()
+		}
+
+		r.sealWorkers = n
+		if r.sealQueueSize == 0 {
+			r.sealQueueSize = DefaultCompressQueueSize
+		}
+		return nil
+	}
+}
+
+// startSealer 启动后台封存的goroutine
+func (r *Rotator) startSealer() error {
+	workers := max(r.sealWorkers, 1)
+	strategies := make([]CompressStrategy, workers)
+	if r.cpr.compress {
+		for i := range strategies {
+			cs, err := r.newCompressStrategy()
+			if err != nil {
+				return err
+			}
+			strategies[i] = cs
+		}
+	}
+
+	r.sealQueue = make(chan string, r.sealQueueSize)
+	for _, cs := range strategies {
+		r.sealWG.Add(1)
+		go r.sealLoop(cs)
+	}
+
+	return nil
+}
+
+// enqueueSeal 将已经关闭的文件放入封存队列，队列已满时阻塞，必须持有写锁
+func (r *Rotator) enqueueSeal(path string) {
+	// 先注册封存状态，保证AwaitSealed可以等待队列中的文件
+	r.tracker.watch(path)
+	r.sealAges.add(path)
+	r.sealQueue <- path
+}
+
+// sealLoop 使用压缩策略cs依次封存队列中的文件，队列关闭之后处理完剩余的文件再退出
+func (r *Rotator) sealLoop(cs CompressStrategy) {
+	defer r.sealWG.Done()
+
+	for path := range r.sealQueue {
+		// 封存完成之后才离开队列，压缩卡住时等待时间持续增长
+		err := r.sealSafe(path, cs)
+		r.sealAges.done(path)
+		if err != nil {
+			// 封存过程中panic时sealFile没有结束封存状态
+			r.tracker.finish(path, err)
+			r.l.Printf("failed to seal %s, cause: %v", path, err)
+		}
+	}
+}
+
+// sealSafe 封存文件，将封存过程中的panic转换为错误
+func (r *Rotator) sealSafe(path string, cs CompressStrategy) (err error) {
+	defer func() {
+		if v := recover(); v != nil {
+			err = fmt.Errorf("seal %s panic: %v", path, v)
+		}
+	}()
+
+	var pause RotatePause
+	return r.seal(path, cs, &pause)
+}
+
+// drainSealer 关闭封存队列并等待队列中的文件全部封存完成
+func (r *Rotator) drainSealer() {
+	if r.sealQueue == nil {
+		return
+	}
+
+	close(r.sealQueue)
+	r.sealWG.Wait()
+}
+
+// compressQueueDepth 封存队列中等待的文件数量
+func (r *Rotator) compressQueueDepth() int {
+	if r.sealQueue == nil {
+		return 0
+	}
+
+	return len(r.sealQueue)
+}
@@ -0,0 +1,135 @@
+// Copyright 2025 TimeWtr
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package vortexrotate
+
+import (
+	"context"
+	"io"
+	"os"
+	"path/filepath"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestRotator_AsyncCompress(t *testing.T) {
+	rotator, err := NewRotator(t.TempDir(), "testdata.log",
+		WithCompress(CompressTypeGzip, GzipBestSpeed), WithAsyncCompress(0))
+	assert.NoError(t, err)
+	assert.Equal(t, DefaultCompressQueueSize, cap(rotator.sealQueue))
+
+	paths := make([]string, 0, 3)
+	for i := 0; i < 3; i++ {
+		_, err = rotator.Write([]byte("async compress test\n"))
+		assert.NoError(t, err)
+		paths = append(paths, rotator.f.Name())
+		assert.NoError(t, rotator.Rotate())
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second*5)
+	defer cancel()
+	assert.NoError(t, rotator.AwaitSealed(ctx, paths[0]))
+	assert.FileExists(t, compressFn(paths[0], CompressTypeGzip))
+
+	// 关闭时等待队列中的文件全部封存完成
+	assert.NoError(t, rotator.Close())
+	for _, path := range paths {
+		assert.FileExists(t, compressFn(path, CompressTypeGzip))
+	}
+	assert.Equal(t, 0, rotator.Stats().CompressQueueDepth)
+}
+
+func TestRotator_AsyncCompressEnqueuePause(t *testing.T) {
+	// 占用唯一的压缩名额，后台goroutine封存时阻塞
+	SetMaxConcurrentCompressions(1)
+	defer SetMaxConcurrentCompressions(0)
+	resources.acquire()
+
+	var lock sync.Mutex
+	var pauses []RotatePause
+	rotator, err := NewRotator(t.TempDir(), "testdata.log",
+		WithCompress(CompressTypeGzip, GzipBestSpeed), WithAsyncCompress(1),
+		WithRotatePauseSLO(time.Nanosecond), WithEventHandler(func(e Event) {
+			if e.Type != EventRotatePauseSLO {
+				return
+			}
+			lock.Lock()
+			defer lock.Unlock()
+			pauses = append(pauses, e.Pause)
+		}))
+	assert.NoError(t, err)
+	defer rotator.Close()
+
+	_, err = rotator.Write([]byte("enqueue pause test\n"))
+	assert.NoError(t, err)
+	assert.NoError(t, rotator.Rotate())
+	assert.Eventually(t, func() bool {
+		return rotator.compressQueueDepth() == 0
+	}, time.Second, 10*time.Millisecond)
+	_, err = rotator.Write([]byte("enqueue pause test\n"))
+	assert.NoError(t, err)
+	assert.NoError(t, rotator.Rotate())
+
+	// 队列已满时轮转阻塞在放入队列上，等待的时间计入压缩阶段
+	const blocked = 50 * time.Millisecond
+	time.AfterFunc(blocked, resources.release)
+	_, err = rotator.Write([]byte("enqueue pause test\n"))
+	assert.NoError(t, err)
+	assert.NoError(t, rotator.Rotate())
+
+	lock.Lock()
+	defer lock.Unlock()
+	if assert.Len(t, pauses, 3) {
+		assert.GreaterOrEqual(t, pauses[2].Compress, blocked/2)
+	}
+}
+
+// panicStrategy 压缩时panic的压缩策略
+type panicStrategy struct{}
+
+func (panicStrategy) Compress() error {
+	panic("compress panic")
+}
+
+func (panicStrategy) Reset(w io.Writer, f *os.File) {
+	_ = f.Close()
+}
+
+func TestRotator_CompressionWorkers(t *testing.T) {
+	rotator, err := NewRotator(t.TempDir(), "testdata.log",
+		WithCompress(CompressTypeZstd), WithCompressionWorkers(3))
+	assert.NoError(t, err)
+	assert.Equal(t, DefaultCompressQueueSize, cap(rotator.sealQueue))
+
+	paths := make([]string, 0, 6)
+	for i := 0; i < 6; i++ {
+		_, err = rotator.Write([]byte("compression workers test\n"))
+		assert.NoError(t, err)
+		paths = append(paths, rotator.f.Name())
+		assert.NoError(t, rotator.Rotate())
+	}
+
+	assert.NoError(t, rotator.Close())
+	for _, path := range paths {
+		assert.FileExists(t, compressFn(path, CompressTypeZstd))
+	}
+
+	// 压缩过程中的panic转换为错误
+	path := filepath.Join(t.TempDir(), "panic.log")
+	assert.NoError(t, os.WriteFile(path, []byte("panic\n"), ReadWriteFile))
+	assert.ErrorContains(t, rotator.sealSafe(path, panicStrategy{}), "compress panic")
+}
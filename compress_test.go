@@ -15,12 +15,14 @@
 package vortexrotate
 
 import (
+	"bytes"
+	"io"
 	"os"
 	"path/filepath"
 	"testing"
+	"time"
 
 	"github.com/stretchr/testify/assert"
-	"github.com/valyala/gozstd"
 )
 
 func TestNewGzip_Compress(t *testing.T) {
@@ -98,7 +100,7 @@ func TestNewZstd_Compress(t *testing.T) {
 		return
 	}
 
-	zstd := NewZstd(w, f, gozstd.DefaultCompressionLevel)
+	zstd := NewZstd(w, f, ZstdDefaultLevel)
 	err = zstd.Compress()
 	assert.NoError(t, err)
 	t.Log("Zstd cpr finished")
@@ -118,7 +120,7 @@ func TestNewZstd_Reset(t *testing.T) {
 		return
 	}
 
-	zstd := NewZstd(w, f, gozstd.DefaultCompressionLevel)
+	zstd := NewZstd(w, f, ZstdDefaultLevel)
 	err = zstd.Compress()
 	assert.NoError(t, err)
 	t.Log("Zstd cpr finished")
@@ -199,3 +201,96 @@ func TestNewSnappy_Reset(t *testing.T) {
 	assert.NoError(t, err)
 	t.Log("Snappy reset cpr finished")
 }
+
+func TestRecompressFile(t *testing.T) {
+	dir := t.TempDir()
+	content := bytes.Repeat([]byte("recompress test content\n"), 1024)
+	src := filepath.Join(dir, compressFn("app_20250101_0001.log", CompressTypeSnappy))
+	err := writeCompressed(src, CompressTypeSnappy, 0, bytes.NewReader(content), time.Now())
+	assert.NoError(t, err)
+
+	mtime := time.Now().Add(-time.Hour * 48)
+	assert.NoError(t, os.Chtimes(src, mtime, mtime))
+
+	dst, err := recompressFile(src, CompressTypeZstd, ZstdHighRatioLevel, renameLocal)
+	assert.NoError(t, err)
+	assert.Equal(t, filepath.Join(dir, "app_20250101_0001.log.zst"), dst)
+	assert.NoFileExists(t, src)
+
+	info, err := os.Stat(dst)
+	assert.NoError(t, err)
+	assert.True(t, info.ModTime().Equal(mtime))
+
+	f, err := os.Open(dst)
+	assert.NoError(t, err)
+	defer f.Close()
+	dr, err := newDecompressReader(compressTypeOf(dst), f)
+	assert.NoError(t, err)
+	defer dr.Close()
+	res, err := io.ReadAll(dr)
+	assert.NoError(t, err)
+	assert.Equal(t, content, res)
+}
+
+func TestNewXz_Compress(t *testing.T) {
+	dir := t.TempDir()
+	content := bytes.Repeat([]byte("xz compress test content\n"), 1024)
+	src := filepath.Join(dir, "test.log")
+	assert.NoError(t, os.WriteFile(src, content, ReadWriteFile))
+
+	_, err := NewXz(nil, nil, XzBestCompression+1)
+	assert.Error(t, err)
+
+	dst := compressFn(src, CompressTypeXz)
+	w, err := os.OpenFile(dst, os.O_CREATE|os.O_RDWR, ReadWriteFile)
+	assert.NoError(t, err)
+	f, err := os.Open(src)
+	assert.NoError(t, err)
+
+	x, err := NewXz(w, f, XzBestSpeed)
+	assert.NoError(t, err)
+	assert.NoError(t, x.Compress())
+	assert.NoError(t, w.Close())
+
+	cf, err := os.Open(dst)
+	assert.NoError(t, err)
+	defer cf.Close()
+	dr, err := newDecompressReader(compressTypeOf(dst), cf)
+	assert.NoError(t, err)
+	defer dr.Close()
+	bs, err := io.ReadAll(dr)
+	assert.NoError(t, err)
+	assert.Equal(t, content, bs)
+}
+
+func TestNewPgzip_Compress(t *testing.T) {
+	dir := t.TempDir()
+	content := bytes.Repeat([]byte("pgzip compress test content\n"), PgzipBlockSize/8)
+	src := filepath.Join(dir, "test.log")
+	assert.NoError(t, os.WriteFile(src, content, ReadWriteFile))
+
+	_, err := NewPgzip(nil, nil, GzipDefaultCompression, 0)
+	assert.Error(t, err)
+
+	dst := compressFn(src, CompressTypeGzip)
+	w, err := os.OpenFile(dst, os.O_CREATE|os.O_RDWR, ReadWriteFile)
+	assert.NoError(t, err)
+	f, err := os.Open(src)
+	assert.NoError(t, err)
+
+	p, err := NewPgzip(w, f, GzipBestSpeed, 4)
+	assert.NoError(t, err)
+	assert.NoError(t, p.Compress())
+	assert.NoError(t, w.Close())
+
+	// 输出是标准的gzip流
+	cf, err := os.Open(dst)
+	assert.NoError(t, err)
+	defer cf.Close()
+	dr, err := newDecompressReader(CompressTypeGzip, cf)
+	assert.NoError(t, err)
+	defer dr.Close()
+	bs, err := io.ReadAll(dr)
+	assert.NoError(t, err)
+	assert.Equal(t, content, bs)
+}
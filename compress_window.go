@@ -0,0 +1,114 @@
+// Copyright 2025 TimeWtr
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package vortexrotate
+
+import (
+	"errors"
+	"fmt"
+	"time"
+)
+
+// compressWindow 允许执行压缩的时间窗口
+type compressWindow struct {
+	// 窗口开始的时间，距离零点的时长
+	start time.Duration
+	// 窗口结束的时间，距离零点的时长，小于start时表示窗口跨越零点
+	end time.Duration
+	// 磁盘可用空间比例低于该值时忽略时间窗口立即压缩，0表示不检查
+	minFreeRatio float64
+	// 获取磁盘空间的函数，测试中可以替换
+	diskUsage func(path string) (free, total uint64, err error)
+}
+
+// parseClock 解析HH:MM格式的时间，返回距离零点的时长
+func parseClock(s string) (time.Duration, error) {
+	t, err := time.Parse("15:04", s)
+	if err != nil {
+		return 0, fmt.Errorf("invalid clock %q, want HH:MM", s)
+	}
+
+	return time.Duration(t.Hour())*time.Hour + time.Duration(t.Minute())*time.Minute, nil
+}
+
+// WithCompressWindow 设置后台压缩的时间窗口，start和end为HH:MM格式的本地时间，比如"01:00"和
+// "05:00"，end小于start时表示窗口跨越零点。窗口之外轮转的文件以原始文件保留，后台任务每分钟
+// 检查一次，窗口打开之后按照文件名称的顺序依次压缩。minFreeRatio大于0时，存储目录所在文件系统
+// 的可用空间比例低于该值时忽略时间窗口立即压缩，避免磁盘被未压缩的文件写满。不开启压缩时不生效
+func WithCompressWindow(start, end string, minFreeRatio float64) Option {
+	return func(r *Rotator) error {
+		s, err := parseClock(start)
+		if err != nil {
+			return err
+		}
+		e, err := parseClock(end)
+		if err != nil {
+			return err
+		}
+		if s == e {
+			return errors.New("compress window start must differ from end")
+		}
+		if minFreeRatio < 0 || minFreeRatio >= 1 {
+			return fmt.Errorf("min free ratio %v must be in [0, 1)", minFreeRatio)
+		}
+
+		r.cpr.window = &compressWindow{
+			start:        s,
+			end:          e,
+			minFreeRatio: minFreeRatio,
+			diskUsage:    diskUsage,
+		}
+		return nil
+	}
+}
+
+// contains 判断时间t是否在窗口内
+func (w *compressWindow) contains(t time.Time) bool {
+	y, m, d := t.Date()
+	clock := t.Sub(time.Date(y, m, d, 0, 0, 0, 0, t.Location()))
+	if w.start < w.end {
+		return clock >= w.start && clock < w.end
+	}
+
+	return clock >= w.start || clock < w.end
+}
+
+// lowDisk 判断dir所在文件系统的可用空间比例是否低于阈值
+func (w *compressWindow) lowDisk(dir string) bool {
+	if w.minFreeRatio <= 0 {
+		return false
+	}
+
+	free, total, err := w.diskUsage(dir)
+	if err != nil || total == 0 {
+		return false
+	}
+
+	return float64(free)/float64(total) < w.minFreeRatio
+}
+
+// compressAllowed 判断当前是否允许压缩，没有设置时间窗口、在窗口内或者磁盘空间不足时允许
+func (r *Rotator) compressAllowed() bool {
+	w := r.cpr.window
+	if w == nil || w.contains(r.now()) {
+		return true
+	}
+
+	if w.lowDisk(r.dir) {
+		r.l.Printf("disk free space of %s below %.2f%%, compress outside window", r.dir, w.minFreeRatio*100)
+		return true
+	}
+
+	return false
+}
@@ -0,0 +1,97 @@
+// Copyright 2025 TimeWtr
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package vortexrotate
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestCompressWindow_Contains(t *testing.T) {
+	at := func(hour, minute int) time.Time {
+		return time.Date(2025, 1, 1, hour, minute, 0, 0, time.Local)
+	}
+	testCases := []struct {
+		name       string
+		start, end string
+		t          time.Time
+		want       bool
+	}{
+		{name: "inside", start: "01:00", end: "05:00", t: at(3, 0), want: true},
+		{name: "start", start: "01:00", end: "05:00", t: at(1, 0), want: true},
+		{name: "end", start: "01:00", end: "05:00", t: at(5, 0), want: false},
+		{name: "outside", start: "01:00", end: "05:00", t: at(12, 30), want: false},
+		{name: "cross midnight before", start: "22:00", end: "04:00", t: at(23, 59), want: true},
+		{name: "cross midnight after", start: "22:00", end: "04:00", t: at(0, 30), want: true},
+		{name: "cross midnight outside", start: "22:00", end: "04:00", t: at(12, 0), want: false},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			r := &Rotator{}
+			assert.NoError(t, WithCompressWindow(tc.start, tc.end, 0)(r))
+			assert.Equal(t, tc.want, r.cpr.window.contains(tc.t))
+		})
+	}
+}
+
+func TestWithCompressWindow_Invalid(t *testing.T) {
+	r := &Rotator{}
+	assert.Error(t, WithCompressWindow("1:00:00", "05:00", 0)(r))
+	assert.Error(t, WithCompressWindow("25:00", "05:00", 0)(r))
+	assert.Error(t, WithCompressWindow("05:00", "05:00", 0)(r))
+	assert.Error(t, WithCompressWindow("01:00", "05:00", 1)(r))
+}
+
+func TestRotator_CompressWindow(t *testing.T) {
+	now := time.Now()
+	// 当前时间之后的窗口，轮转时不压缩
+	closed := WithCompressWindow(now.Add(2*time.Hour).Format("15:04"), now.Add(3*time.Hour).Format("15:04"), 0.1)
+	rotator, err := newRotator(t.TempDir(), "testdata.log", WithCompress(CompressTypeGzip), closed)
+	assert.NoError(t, err)
+	defer rotator.Close()
+
+	_, err = rotator.Write([]byte("compress window test\n"))
+	assert.NoError(t, err)
+	path := rotator.f.Name()
+	assert.NoError(t, rotator.Rotate())
+	gz := compressFn(path, CompressTypeGzip)
+	assert.FileExists(t, path)
+	assert.NoFileExists(t, gz)
+
+	// 窗口没有打开，磁盘空间充足，不压缩
+	rotator.cpr.window.diskUsage = func(string) (uint64, uint64, error) { return 50, 100, nil }
+	rotator.compressDelayed()
+	assert.NoFileExists(t, gz)
+
+	// 磁盘空间不足时忽略时间窗口
+	rotator.cpr.window.diskUsage = func(string) (uint64, uint64, error) { return 5, 100, nil }
+	rotator.compressDelayed()
+	assert.FileExists(t, gz)
+	assert.NoFileExists(t, path)
+
+	// 窗口打开之后轮转时立即压缩
+	rotator.cpr.window.diskUsage = func(string) (uint64, uint64, error) { return 50, 100, nil }
+	rotator.cpr.window.start = 0
+	rotator.cpr.window.end = 24*time.Hour - time.Nanosecond
+	_, err = rotator.Write([]byte("compress window test\n"))
+	assert.NoError(t, err)
+	path = rotator.f.Name()
+	assert.NoError(t, rotator.Rotate())
+	assert.FileExists(t, compressFn(path, CompressTypeGzip))
+	assert.NoFileExists(t, path)
+}
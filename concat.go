@@ -0,0 +1,131 @@
+// Copyright 2025 TimeWtr
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package vortexrotate
+
+import (
+	"compress/gzip"
+	"io"
+	"os"
+	"time"
+
+	"github.com/TimeWtr/vortexrotate/errorx"
+)
+
+// ConcatGzip 将指定日期的所有轮转文件按照序列号顺序拼接为一个多成员(multistream)的gzip数据流，
+// 输出是合法的.gz文件，gzip -d等工具解压得到当天完整的日志。gzip压缩的文件直接拼接原始字节，
+// 不需要解压和重新压缩，未压缩或者使用其他算法压缩的文件解压之后压缩为一个新的gzip成员。
+// 写入方开启了建议锁时，读取期间持有共享锁，直到Close时释放。当天没有轮转文件时返回
+// errorx.ErrSegmentNotFound
+func (ro *ReadOnly) ConcatGzip(date time.Time) (io.ReadCloser, error) {
+	segments, err := ro.List()
+	if err != nil {
+		return nil, err
+	}
+
+	day := date.Format(Layout)
+	var matched []SegmentInfo
+	for _, seg := range segments {
+		if seg.Date.Format(Layout) == day {
+			matched = append(matched, seg)
+		}
+	}
+	if len(matched) == 0 {
+		return nil, errorx.ErrSegmentNotFound
+	}
+
+	lf, err := sharedLock(ro.dir, ro.filename)
+	if err != nil {
+		return nil, err
+	}
+
+	return &gzipConcatReader{ro: ro, segments: matched, lock: lf}, nil
+}
+
+// gzipConcatReader 依次读取每一个轮转文件的gzip成员
+type gzipConcatReader struct {
+	ro       *ReadOnly
+	segments []SegmentInfo
+	// 当前正在读取的gzip成员
+	cur  io.ReadCloser
+	lock *os.File
+}
+
+func (g *gzipConcatReader) Read(p []byte) (int, error) {
+	for {
+		if g.cur == nil {
+			if len(g.segments) == 0 {
+				return 0, io.EOF
+			}
+
+			cur, err := g.open(g.segments[0])
+			if err != nil {
+				return 0, err
+			}
+			g.cur = cur
+			g.segments = g.segments[1:]
+		}
+
+		n, err := g.cur.Read(p)
+		if err == io.EOF {
+			err = g.cur.Close()
+			g.cur = nil
+		}
+		if n > 0 || err != nil {
+			return n, err
+		}
+	}
+}
+
+// open 打开轮转文件的gzip成员，gzip压缩的文件直接读取原始字节，其他文件压缩为新的gzip成员
+func (g *gzipConcatReader) open(seg SegmentInfo) (io.ReadCloser, error) {
+	if seg.CompressType == CompressTypeGzip {
+		return os.Open(seg.Path)
+	}
+
+	rc, err := g.ro.Open(seg)
+	if err != nil {
+		return nil, err
+	}
+
+	pr, pw := io.Pipe()
+	go func() {
+		gw := gzip.NewWriter(pw)
+		buf := resources.getBuffer()
+		_, err := io.CopyBuffer(gw, rc, *buf)
+		resources.putBuffer(buf)
+		if err1 := gw.Close(); err == nil {
+			err = err1
+		}
+		if err1 := rc.Close(); err == nil {
+			err = err1
+		}
+		_ = pw.CloseWithError(err)
+	}()
+
+	return pr, nil
+}
+
+func (g *gzipConcatReader) Close() error {
+	var err error
+	if g.cur != nil {
+		err = g.cur.Close()
+		g.cur = nil
+	}
+	g.segments = nil
+	closeLock(g.lock)
+	g.lock = nil
+
+	return err
+}
@@ -0,0 +1,77 @@
+// Copyright 2025 TimeWtr
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package vortexrotate
+
+import (
+	"bytes"
+	"compress/gzip"
+	"fmt"
+	"io"
+	"os"
+	"testing"
+	"time"
+
+	"github.com/TimeWtr/vortexrotate/errorx"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestReadOnly_ConcatGzip(t *testing.T) {
+	dir := t.TempDir()
+	rotator, err := newRotator(dir, "testdata.log", WithCompress(CompressTypeGzip))
+	assert.NoError(t, err)
+	defer rotator.Close()
+
+	var want bytes.Buffer
+	var archives []string
+	for i := 0; i < 3; i++ {
+		line := fmt.Sprintf("concat line %d\n", i)
+		want.WriteString(line)
+		_, err = rotator.Write([]byte(line))
+		assert.NoError(t, err)
+		archives = append(archives, compressFn(rotator.f.Name(), CompressTypeGzip))
+		assert.NoError(t, rotator.Rotate())
+	}
+	// 当前写入的文件没有压缩，压缩为新的gzip成员
+	_, err = rotator.Write([]byte("active line\n"))
+	assert.NoError(t, err)
+	want.WriteString("active line\n")
+	assert.NoError(t, rotator.Sync())
+
+	ro, err := OpenReadOnly(dir, "testdata.log")
+	assert.NoError(t, err)
+	rc, err := ro.ConcatGzip(time.Now())
+	assert.NoError(t, err)
+	bs, err := io.ReadAll(rc)
+	assert.NoError(t, err)
+	assert.NoError(t, rc.Close())
+
+	// gzip压缩的文件直接拼接原始字节
+	var prefix []byte
+	for _, archive := range archives {
+		content, err := os.ReadFile(archive)
+		assert.NoError(t, err)
+		prefix = append(prefix, content...)
+	}
+	assert.True(t, bytes.HasPrefix(bs, prefix))
+
+	gr, err := gzip.NewReader(bytes.NewReader(bs))
+	assert.NoError(t, err)
+	got, err := io.ReadAll(gr)
+	assert.NoError(t, err)
+	assert.Equal(t, want.String(), string(got))
+
+	_, err = ro.ConcatGzip(time.Now().AddDate(0, 0, -1))
+	assert.ErrorIs(t, err, errorx.ErrSegmentNotFound)
+}
@@ -18,6 +18,9 @@ import "os"
 
 const Layout = "20060102"
 
+// TmpFileExt 临时文件的后缀名，写入完成后通过rename替换为正式文件
+const TmpFileExt = ".tmp"
+
 const (
 	// DefaultPeriod 默认保存的天数，30天
 	DefaultPeriod = 30
@@ -0,0 +1,133 @@
+// Copyright 2025 TimeWtr
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package vortexrotate
+
+import (
+	"compress/gzip"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"time"
+)
+
+// DefaultCompressFlushInterval 边写边压缩模式下没有设置fsync策略时刷新压缩缓冲的间隔
+const DefaultCompressFlushInterval = time.Second
+
+// flushWriteCloser 可以刷新缓冲数据的流式压缩写入器
+type flushWriteCloser interface {
+	io.WriteCloser
+	// Flush 将缓冲的数据压缩之后写入底层文件，已经写入的数据可以被完整解压
+	Flush() error
+}
+
+// WithCompressOnWrite 开启边写边压缩，当前写入的文件从创建开始就是压缩流(比如app_20250101_0001.log.gz)，
+// 写入的内容直接压缩之后写入磁盘，轮转时只需要结束压缩流，不需要再读取原始文件重新压缩，消除了
+// 一次完整的读写。支持CompressTypeGzip、CompressTypeZstd、CompressTypeSnappy和CompressTypeS2，使用默认的压缩等级。
+// 压缩器内部会缓冲数据，按照fsync策略在fsync之前刷新压缩缓冲，没有设置fsync策略时每秒刷新一次，
+// 刷新之前进程崩溃会丢失缓冲中的数据。文件大小的限制按照压缩之前的数据大小计算，不能与WithCompress
+// 和WAL模式同时使用。
+func WithCompressOnWrite(tp int) Option {
+	return func(r *Rotator) error {
+		switch tp {
+		case CompressTypeGzip, CompressTypeZstd, CompressTypeSnappy, CompressTypeS2:
+		default:
+			return fmt.Errorf("compress on write type %d not support", tp)
+		}
+
+		r.cow = tp
+		return nil
+	}
+}
+
+// checkCompressOnWrite 检查边写边压缩与其他配置是否冲突
+func (r *Rotator) checkCompressOnWrite() error {
+	if r.cow == CompressTypeUnknown {
+		return nil
+	}
+	if r.cpr.compress {
+		return errors.New("compress on write can not be used with compress")
+	}
+	if r.wal {
+		return errors.New("wal mode does not support compress on write")
+	}
+
+	return nil
+}
+
+// newCowWriter 为新创建的文件创建压缩写入器
+func (r *Rotator) newCowWriter(f *os.File) (flushWriteCloser, error) {
+	level := gzip.DefaultCompression
+	if r.cow == CompressTypeZstd {
+		level = ZstdDefaultLevel
+	}
+
+	w, err := newCompressWriter(r.cow, level, f)
+	if err != nil {
+		return nil, err
+	}
+	setGzipHeader(w, gzipHeaderOf(f.Name(), time.Now(), r.cpr.gzipComment))
+
+	fw, ok := w.(flushWriteCloser)
+	if !ok {
+		_ = w.Close()
+		return nil, fmt.Errorf("compress type %d does not support flush", r.cow)
+	}
+
+	return fw, nil
+}
+
+// output 当前写入的目标，边写边压缩时为压缩写入器
+func (r *Rotator) output() io.Writer {
+	if r.cw != nil {
+		return r.cw
+	}
+
+	return r.f
+}
+
+// syncFile 刷新压缩缓冲之后将当前文件的数据刷新到磁盘，必须持有写锁
+func (r *Rotator) syncFile() error {
+	if r.cw != nil {
+		if err := r.cw.Flush(); err != nil {
+			return err
+		}
+	}
+
+	return r.f.Sync()
+}
+
+// closeFile 结束压缩流之后关闭当前文件，必须持有写锁
+func (r *Rotator) closeFile() error {
+	var err error
+	if r.cw != nil {
+		err = r.cw.Close()
+		r.cw = nil
+	}
+
+	return errors.Join(err, r.f.Close())
+}
+
+// flushJob 没有设置fsync策略时按照固定的时间间隔刷新压缩缓冲
+func (r *Rotator) flushJob() {
+	r.writeLock.Lock()
+	defer r.writeLock.Unlock()
+
+	if r.cw != nil && r.sig.Load() == 0 {
+		if err := r.cw.Flush(); err != nil {
+			r.l.Printf("flush compress writer error: %v", err)
+		}
+	}
+}
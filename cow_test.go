@@ -0,0 +1,71 @@
+// Copyright 2025 TimeWtr
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package vortexrotate
+
+import (
+	"io"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestRotator_CompressOnWrite(t *testing.T) {
+	for _, tp := range []int{CompressTypeGzip, CompressTypeZstd, CompressTypeSnappy, CompressTypeS2} {
+		t.Run(compressFn("type", tp), func(t *testing.T) {
+			dir := t.TempDir()
+			rotator, err := newRotator(dir, "testdata.log", WithCompressOnWrite(tp),
+				WithDoneMarker(DoneMarkerFile), WithTimeIndex(16))
+			assert.NoError(t, err)
+			defer rotator.Close()
+
+			path := rotator.f.Name()
+			assert.True(t, strings.HasSuffix(path, compressFn(".log", tp)))
+
+			want := strings.Repeat("compress on write test\n", 100)
+			_, err = rotator.Write([]byte(want))
+			assert.NoError(t, err)
+			// 刷新之后写入的内容可以被解压
+			assert.NoError(t, rotator.Sync())
+			assert.NoError(t, rotator.Rotate())
+			assert.FileExists(t, path+DoneFileExt)
+
+			ro, err := OpenReadOnly(dir, "testdata.log")
+			assert.NoError(t, err)
+			segments, err := ro.List()
+			assert.NoError(t, err)
+			assert.Len(t, segments, 2)
+			assert.Equal(t, path, segments[0].Path)
+			assert.Equal(t, tp, segments[0].CompressType)
+
+			rc, err := ro.Open(segments[0])
+			assert.NoError(t, err)
+			got, err := io.ReadAll(rc)
+			assert.NoError(t, err)
+			assert.NoError(t, rc.Close())
+			assert.Equal(t, want, string(got))
+
+			// 时间索引使用不带压缩后缀的文件名称
+			assert.FileExists(t, trimCompressExt(path)+TimeIndexExt)
+		})
+	}
+}
+
+func TestWithCompressOnWrite_Invalid(t *testing.T) {
+	_, err := newRotator(t.TempDir(), "testdata.log", WithCompressOnWrite(CompressTypeXz))
+	assert.Error(t, err)
+	_, err = newRotator(t.TempDir(), "testdata.log", WithCompressOnWrite(CompressTypeGzip), WithCompress(CompressTypeGzip))
+	assert.Error(t, err)
+}
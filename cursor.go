@@ -0,0 +1,160 @@
+// Copyright 2025 TimeWtr
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package vortexrotate
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+
+	"github.com/TimeWtr/vortexrotate/errorx"
+)
+
+// Cursor 记录在轮转文件流中的位置，消费方处理完记录之后持久化Record.Next，重启之后从持久化
+// 的位置继续读取，实现进程内的至少一次消费
+type Cursor struct {
+	// 轮转文件的序列号
+	Segment int64 `json:"segment"`
+	// 轮转文件原始内容(压缩文件解压之后)中的字节偏移
+	Offset int64 `json:"offset"`
+}
+
+func (c Cursor) String() string {
+	return fmt.Sprintf("%d:%d", c.Segment, c.Offset)
+}
+
+// Record 读取到的一条记录
+type Record struct {
+	// 记录的起始位置
+	Cursor Cursor
+	// 下一条记录的起始位置
+	Next Cursor
+	// 记录的内容，不包括分隔符，只在回调函数执行期间有效
+	Data []byte
+}
+
+// errIncomplete 记录还没有写入完整
+var errIncomplete = errors.New("incomplete record")
+
+// recordDecoder 从br中解码一条记录，返回记录的内容和在原始内容中占用的字节数，没有更多的记录时
+// 返回io.EOF，sealed表示文件已经轮转，不会再写入，没有封存的文件末尾不完整的记录返回errIncomplete
+type recordDecoder func(br *bufio.Reader, sealed bool) ([]byte, int64, error)
+
+// decodeLine 以换行符分隔的记录
+func decodeLine(br *bufio.Reader, sealed bool) ([]byte, int64, error) {
+	line, err := br.ReadBytes('\n')
+	if err == nil {
+		return bytes.TrimSuffix(line, []byte{'\n'}), int64(len(line)), nil
+	}
+	if !errors.Is(err, io.EOF) {
+		return nil, 0, err
+	}
+	if len(line) == 0 {
+		return nil, 0, io.EOF
+	}
+	if !sealed {
+		return nil, 0, errIncomplete
+	}
+
+	// 已经轮转的文件末尾没有换行符的内容作为最后一条记录
+	return line, int64(len(line)), nil
+}
+
+// ReadRecords 从cur开始按照文件顺序逐条读取以换行符分隔的记录，每一条记录调用一次fn，fn返回false
+// 时停止读取，返回下一条未读取记录的位置。cur对应的轮转文件已经被清理时从下一个存在的文件开始读取，
+// 零值的Cursor从第一个文件开始读取。正在写入的文件末尾没有换行符结尾的记录不会返回，等待写入完整
+// 之后再读取。
+func (ro *ReadOnly) ReadRecords(ctx context.Context, cur Cursor, fn func(Record) bool) (Cursor, error) {
+	return ro.readRecords(ctx, cur, decodeLine, fn)
+}
+
+func (ro *ReadOnly) readRecords(ctx context.Context, cur Cursor, decode recordDecoder,
+	fn func(Record) bool) (Cursor, error) {
+	segments, err := ro.List()
+	if err != nil {
+		return cur, err
+	}
+
+	for i, seg := range segments {
+		if seg.Sequence < cur.Segment {
+			continue
+		}
+		if seg.Sequence != cur.Segment {
+			cur = Cursor{Segment: seg.Sequence}
+		}
+
+		var stop bool
+		cur, stop, err = ro.readSegmentRecords(ctx, seg, cur, i < len(segments)-1, decode, fn)
+		if err != nil || stop {
+			return cur, err
+		}
+	}
+
+	return cur, nil
+}
+
+// readSegmentRecords 读取单个轮转文件中从cur开始的记录
+func (ro *ReadOnly) readSegmentRecords(ctx context.Context, seg SegmentInfo, cur Cursor, sealed bool,
+	decode recordDecoder, fn func(Record) bool) (Cursor, bool, error) {
+	rc, err := ro.Open(seg)
+	if err != nil {
+		return cur, true, err
+	}
+	defer func() {
+		_ = rc.Close()
+	}()
+
+	if cur.Offset > 0 {
+		n, err := io.CopyN(io.Discard, rc, cur.Offset)
+		if err != nil {
+			if errors.Is(err, io.EOF) {
+				return cur, true, fmt.Errorf("%w: cursor %s, segment size %d",
+					errorx.ErrInvalidCursor, cur, n)
+			}
+			return cur, true, err
+		}
+	}
+
+	br := bufio.NewReaderSize(rc, bufferSize)
+	for {
+		if err = ctx.Err(); err != nil {
+			return cur, true, err
+		}
+
+		data, n, err := decode(br, sealed)
+		if err != nil {
+			if errors.Is(err, io.EOF) {
+				return cur, false, nil
+			}
+			if errors.Is(err, errIncomplete) {
+				return cur, true, nil
+			}
+			return cur, true, err
+		}
+
+		rec := Record{
+			Cursor: cur,
+			Next:   Cursor{Segment: cur.Segment, Offset: cur.Offset + n},
+			Data:   data,
+		}
+		cur = rec.Next
+		if !fn(rec) {
+			return cur, true, nil
+		}
+	}
+}
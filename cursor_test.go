@@ -0,0 +1,77 @@
+// Copyright 2025 TimeWtr
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package vortexrotate
+
+import (
+	"context"
+	"fmt"
+	"testing"
+
+	"github.com/TimeWtr/vortexrotate/errorx"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestReadOnly_ReadRecords(t *testing.T) {
+	dir := t.TempDir()
+	rotator, err := newRotator(dir, "testdata.log", WithCompress(CompressTypeGzip))
+	assert.NoError(t, err)
+	defer rotator.Close()
+
+	for i := 0; i < 3; i++ {
+		_, err = rotator.Write([]byte(fmt.Sprintf("record %d-1\nrecord %d-2\n", i, i)))
+		assert.NoError(t, err)
+		assert.NoError(t, rotator.Rotate())
+	}
+	_, err = rotator.Write([]byte("record 3-1\nrecord 3-"))
+	assert.NoError(t, err)
+
+	ro, err := OpenReadOnly(dir, "testdata.log")
+	assert.NoError(t, err)
+
+	read := func(cur Cursor, limit int) ([]string, Cursor) {
+		var res []string
+		next, err := ro.ReadRecords(context.Background(), cur, func(rec Record) bool {
+			res = append(res, string(rec.Data))
+			return len(res) < limit
+		})
+		assert.NoError(t, err)
+		return res, next
+	}
+
+	// 正在写入的文件中不完整的记录不会返回
+	records, cur := read(Cursor{}, 100)
+	assert.Equal(t, []string{
+		"record 0-1", "record 0-2", "record 1-1", "record 1-2",
+		"record 2-1", "record 2-2", "record 3-1",
+	}, records)
+
+	// 从中间的位置恢复读取，跨越压缩文件
+	records, mid := read(Cursor{}, 3)
+	assert.Len(t, records, 3)
+	records, _ = read(mid, 100)
+	assert.Equal(t, []string{"record 1-2", "record 2-1", "record 2-2", "record 3-1"}, records)
+
+	// 记录写入完整之后从上次的位置继续读取
+	_, err = rotator.Write([]byte("2\n"))
+	assert.NoError(t, err)
+	records, next := read(cur, 100)
+	assert.Equal(t, []string{"record 3-2"}, records)
+	records, _ = read(next, 100)
+	assert.Empty(t, records)
+
+	_, err = ro.ReadRecords(context.Background(), Cursor{Segment: cur.Segment, Offset: 1 << 20},
+		func(Record) bool { return true })
+	assert.ErrorIs(t, err, errorx.ErrInvalidCursor)
+}
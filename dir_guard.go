@@ -0,0 +1,119 @@
+// Copyright 2025 TimeWtr
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package vortexrotate
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+)
+
+// DirOverflowAction 单个目录中的文件数量超过限制时执行的动作
+type DirOverflowAction int
+
+const (
+	// DirOverflowSplit 切换到更细粒度的目录布局，在日期目录下按照编号创建子目录，
+	// 比如：dir/20250101/001/，新的轮转文件写入到子目录中
+	DirOverflowSplit DirOverflowAction = iota + 1
+	// DirOverflowCleanup 提前触发一次过期文件清理
+	DirOverflowCleanup
+)
+
+func (a DirOverflowAction) String() string {
+	switch a {
+	case DirOverflowSplit:
+		return "split"
+	case DirOverflowCleanup:
+		return "cleanup"
+	default:
+		return "unknown"
+	}
+}
+
+// WithMaxDirEntries 设置单个目录中允许的最大文件数量，目录中的文件过多时大部分文件系统的
+// 查找和遍历性能会急剧下降，超过限制时根据action切换到更细粒度的目录布局或者提前触发清理，
+// 并发送EventDirEntriesExceeded事件说明执行的动作。
+func WithMaxDirEntries(maxEntries int, action DirOverflowAction) Option {
+	return func(r *Rotator) error {
+		if action != DirOverflowSplit && action != DirOverflowCleanup {
+			return fmt.Errorf("dir overflow action %d not support", action)
+		}
+
+		r.maxDirEntries = maxEntries
+		r.dirOverflowAction = action
+		return nil
+	}
+}
+
+// segmentDir 当前轮转文件所在的目录，文件父目录是年月日时间，目录文件数量超过限制并且
+// 开启了子目录布局时，文件写入到日期目录下编号的子目录中
+func (r *Rotator) segmentDir() string {
+	t := r.adoptTimezone()
+	if t != r.bucketDate {
+		// 跨天之后从日期目录重新开始
+		r.bucketDate = t
+		r.bucket = 0
+		r.dayEnd = nextMidnight(r.now())
+	}
+
+	if r.bucket == 0 {
+		return filepath.Join(r.dir, t)
+	}
+
+	return filepath.Join(r.dir, t, fmt.Sprintf("%03d", r.bucket))
+}
+
+// checkDirEntries 检查当前目录中的文件数量是否超过限制，超过则执行配置的动作
+func (r *Rotator) checkDirEntries() {
+	if r.maxDirEntries <= 0 {
+		return
+	}
+
+	for {
+		dir := r.segmentDir()
+		entries, err := os.ReadDir(dir)
+		if err != nil || len(entries) < r.maxDirEntries {
+			return
+		}
+
+		switch r.dirOverflowAction {
+		case DirOverflowSplit:
+			r.bucket++
+			r.emit(Event{
+				Type: EventDirEntriesExceeded,
+				Path: dir,
+				Message: fmt.Sprintf("dir %s has %d entries, exceeds limit %d, switch to sub dir %s",
+					dir, len(entries), r.maxDirEntries, r.segmentDir()),
+			})
+		case DirOverflowCleanup:
+			msg := fmt.Sprintf("dir %s has %d entries, exceeds limit %d, trigger cleanup",
+				dir, len(entries), r.maxDirEntries)
+			if r.cleanup == nil {
+				msg = fmt.Sprintf("dir %s has %d entries, exceeds limit %d, but cleanup is not configured",
+					dir, len(entries), r.maxDirEntries)
+			} else {
+				r.triggerCleanup()
+			}
+			r.emit(Event{
+				Type:    EventDirEntriesExceeded,
+				Path:    dir,
+				Message: msg,
+			})
+			return
+		default:
+			return
+		}
+	}
+}
@@ -0,0 +1,24 @@
+// Copyright 2025 TimeWtr
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+//go:build !(linux || darwin || freebsd)
+
+package vortexrotate
+
+import "errors"
+
+// diskUsage 当前系统不支持获取文件系统的空间信息
+func diskUsage(_ string) (free, total uint64, err error) {
+	return 0, 0, errors.ErrUnsupported
+}
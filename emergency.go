@@ -0,0 +1,130 @@
+// Copyright 2025 TimeWtr
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package vortexrotate
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"time"
+)
+
+// DefaultDiskCheckInterval 默认的磁盘可用空间检查间隔
+const DefaultDiskCheckInterval = 10 * time.Second
+
+// WithEmergencyCleanup 开启磁盘空间不足时的紧急清理，后台定期检查存储目录所在文件系统的可用空间，
+// 可用空间占总空间的比例低于minFreeRatio(比如0.05表示5%)时，不等待正常的清理周期，立即从最旧的
+// 文件开始删除，直到可用空间恢复到阈值以上，最新的文件不会被删除。清理完成之后发送
+// EventEmergencyCleanup事件，并调用hook(可以为nil)报告删除的文件，hook在清理任务的goroutine
+// 中同步调用，不能阻塞。
+func WithEmergencyCleanup(minFreeRatio float64, hook func(removed []FileInfo)) Option {
+	return func(r *Rotator) error {
+		if minFreeRatio <= 0 || minFreeRatio >= 1 {
+			return fmt.Errorf("min free ratio %v must be in (0, 1)", minFreeRatio)
+		}
+
+		r.minFreeRatio = minFreeRatio
+		r.onEmergencyCleanup = func(removed []FileInfo) {
+			r.emit(Event{
+				Type:    EventEmergencyCleanup,
+				Path:    r.dir,
+				Message: fmt.Sprintf("disk free space below %.2f%%, remove %d files", minFreeRatio*100, len(removed)),
+			})
+			if hook != nil {
+				hook(removed)
+			}
+		}
+		return nil
+	}
+}
+
+// lowDisk 检查磁盘可用空间是否低于阈值
+func (c *CleanUp) lowDisk() (bool, error) {
+	free, total, err := c.diskUsage(c.dir)
+	if err != nil {
+		return false, err
+	}
+	if total == 0 {
+		return false, nil
+	}
+
+	return float64(free)/float64(total) < c.minFreeRatio, nil
+}
+
+// emergencyCleanup 磁盘可用空间低于阈值时，从最旧的文件开始逐个删除，直到可用空间恢复
+func (c *CleanUp) emergencyCleanup() {
+	c.running.Lock()
+	defer c.running.Unlock()
+
+	if c.minFreeRatio <= 0 || c.dryRun {
+		return
+	}
+
+	low, err := c.lowDisk()
+	if err != nil {
+		if errors.Is(err, errors.ErrUnsupported) {
+			// 当前系统不支持获取磁盘空间，不再检查
+			c.minFreeRatio = 0
+		}
+		c.reportError(fmt.Errorf("get disk usage of %s error: %w", c.dir, err))
+		return
+	}
+	if !low {
+		return
+	}
+
+	fileInfos, err := c.listFileInfo()
+	if err != nil {
+		c.reportError(fmt.Errorf("list files in %s error: %w", c.dir, err))
+		return
+	}
+	c.sortFiles(fileInfos)
+
+	if err = c.dirLock.Lock(); err != nil {
+		c.reportError(fmt.Errorf("lock dir %s error: %w", c.dir, err))
+		return
+	}
+	var removed []FileInfo
+	for i := 0; i < len(fileInfos)-1 && low; i++ {
+		fi := fileInfos[i]
+		if c.tombstones {
+			// 磁盘已满时墓碑可能无法写入，紧急清理优先释放空间，只报告错误
+			if err = c.appendTombstones(c.tombstone(fi, DeleteEmergency)); err != nil {
+				c.reportError(err)
+			}
+		}
+		for _, path := range fi.Files {
+			if err = os.Remove(path); err != nil && !os.IsNotExist(err) {
+				c.reportError(err)
+				continue
+			}
+		}
+		for _, path := range fi.Files {
+			c.removeEmptyDirs(filepath.Dir(path))
+		}
+		removed = append(removed, fi)
+
+		if low, err = c.lowDisk(); err != nil {
+			c.reportError(fmt.Errorf("get disk usage of %s error: %w", c.dir, err))
+			break
+		}
+	}
+	_ = c.dirLock.Unlock()
+
+	if len(removed) > 0 && c.onEmergency != nil {
+		c.onEmergency(removed)
+	}
+}
@@ -0,0 +1,74 @@
+// Copyright 2025 TimeWtr
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package vortexrotate
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestCleanUp_EmergencyCleanup(t *testing.T) {
+	dir := t.TempDir()
+	var paths []string
+	for i := 1; i <= 5; i++ {
+		fn := filepath.Join(dir, "20250101", fmt.Sprintf("testdata_20250101_%04d.log", i))
+		assert.NoError(t, os.MkdirAll(filepath.Dir(fn), os.ModePerm))
+		assert.NoError(t, os.WriteFile(fn, []byte("data\n"), ReadWriteFile))
+		paths = append(paths, fn)
+	}
+
+	var events []Event
+	r := &Rotator{dir: dir, filename: "testdata", eventHandler: func(e Event) {
+		events = append(events, e)
+	}}
+	var reported []FileInfo
+	assert.Error(t, WithEmergencyCleanup(1.5, nil)(r))
+	assert.NoError(t, WithEmergencyCleanup(0.05, func(removed []FileInfo) {
+		reported = removed
+	})(r))
+
+	c := r.newCleanUp()
+	assert.NotNil(t, c)
+	// 每删除一个文件释放1%的空间，初始可用空间为2%
+	free := uint64(2)
+	c.diskUsage = func(string) (uint64, uint64, error) {
+		entries, _ := filepath.Glob(filepath.Join(dir, "*", "*.log"))
+		return free + uint64(5-len(entries)), 100, nil
+	}
+
+	c.emergencyCleanup()
+	assert.Len(t, reported, 3)
+	for i, fi := range reported {
+		assert.Equal(t, int64(i+1), fi.Sequence)
+		_, err := os.Stat(paths[i])
+		assert.True(t, os.IsNotExist(err))
+	}
+	for _, fn := range paths[3:] {
+		_, err := os.Stat(fn)
+		assert.NoError(t, err)
+	}
+	assert.Len(t, events, 1)
+	assert.Equal(t, EventEmergencyCleanup, events[0].Type)
+
+	// 可用空间充足时不删除
+	reported = nil
+	free = 50
+	c.emergencyCleanup()
+	assert.Empty(t, reported)
+}
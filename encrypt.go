@@ -0,0 +1,536 @@
+// Copyright 2025 TimeWtr
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package vortexrotate
+
+import (
+	"bufio"
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/binary"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/TimeWtr/vortexrotate/errorx"
+)
+
+// EncryptFileExt 加密文件的后缀名，追加在压缩后缀之后，比如：app_20250101_0001.log.gz.enc
+const EncryptFileExt = ".enc"
+
+const (
+	// EncryptKeySize 加密密钥的长度，使用AES-256
+	EncryptKeySize = 32
+	// EncryptChunkSize 加密数据块的大小，每个数据块单独使用AES-GCM加密和认证
+	EncryptChunkSize = 64 << 10
+	// encryptMagic 加密文件头的魔数
+	encryptMagic = "VXE1"
+	// encryptSaltSize 派生文件密钥使用的随机盐的长度
+	encryptSaltSize = 32
+	// encryptMaxKeyID 密钥ID的最大长度
+	encryptMaxKeyID = 255
+	// encryptKeyInfo 派生文件密钥时使用的上下文信息
+	encryptKeyInfo = "vortexrotate file key"
+)
+
+// KeyProvider 密钥提供者，密钥可以来自环境变量、文件或者KMS等外部系统，实现需要是并发安全的。
+// 密钥ID写入加密文件头，密钥轮换之后通过ID找到加密旧文件时使用的密钥。
+type KeyProvider interface {
+	// EncryptionKey 返回当前用于加密的密钥ID和EncryptKeySize字节的密钥，ID的长度不超过255字节
+	EncryptionKey() (id string, key []byte, err error)
+	// DecryptionKey 根据加密文件头中的密钥ID返回解密使用的密钥，找不到时返回errorx.ErrKeyNotFound
+	DecryptionKey(id string) ([]byte, error)
+}
+
+// staticKeyProvider 只有一个固定密钥的密钥提供者
+type staticKeyProvider struct {
+	id  string
+	key []byte
+}
+
+// NewStaticKeyProvider 创建只有一个固定密钥的密钥提供者，key必须是EncryptKeySize字节
+func NewStaticKeyProvider(id string, key []byte) (KeyProvider, error) {
+	if len(id) > encryptMaxKeyID {
+		return nil, fmt.Errorf("key id length %d exceeds %d", len(id), encryptMaxKeyID)
+	}
+	if len(key) != EncryptKeySize {
+		return nil, fmt.Errorf("encryption key must be %d bytes, got %d", EncryptKeySize, len(key))
+	}
+
+	return &staticKeyProvider{id: id, key: append([]byte(nil), key...)}, nil
+}
+
+func (p *staticKeyProvider) EncryptionKey() (id string, key []byte, err error) {
+	return p.id, p.key, nil
+}
+
+func (p *staticKeyProvider) DecryptionKey(id string) ([]byte, error) {
+	if id != p.id {
+		return nil, fmt.Errorf("%w: %q", errorx.ErrKeyNotFound, id)
+	}
+
+	return p.key, nil
+}
+
+// NewEnvKeyProvider 从环境变量中读取hex或者base64编码的密钥，密钥ID为环境变量的名称
+func NewEnvKeyProvider(name string) (KeyProvider, error) {
+	v, ok := os.LookupEnv(name)
+	if !ok {
+		return nil, fmt.Errorf("environment variable %s is not set", name)
+	}
+
+	key, err := decodeKey(v)
+	if err != nil {
+		return nil, fmt.Errorf("decode key from environment variable %s error: %w", name, err)
+	}
+
+	return NewStaticKeyProvider(name, key)
+}
+
+// NewFileKeyProvider 从文件中读取hex或者base64编码的密钥，密钥ID为文件名称
+func NewFileKeyProvider(path string) (KeyProvider, error) {
+	bs, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	key, err := decodeKey(string(bs))
+	if err != nil {
+		return nil, fmt.Errorf("decode key from file %s error: %w", path, err)
+	}
+
+	return NewStaticKeyProvider(filepath.Base(path), key)
+}
+
+// decodeKey 解码hex或者base64编码的密钥
+func decodeKey(s string) ([]byte, error) {
+	s = strings.TrimSpace(s)
+	if key, err := hex.DecodeString(s); err == nil && len(key) == EncryptKeySize {
+		return key, nil
+	}
+	if key, err := base64.StdEncoding.DecodeString(s); err == nil && len(key) == EncryptKeySize {
+		return key, nil
+	}
+
+	return nil, fmt.Errorf("key must be %d bytes encoded in hex or base64", EncryptKeySize)
+}
+
+// newFileAEAD 使用主密钥和随机盐派生单个文件的密钥，每个文件使用独立的密钥，数据块的nonce
+// 可以直接使用计数器而不会重复
+func newFileAEAD(key, salt []byte) (cipher.AEAD, error) {
+	if len(key) != EncryptKeySize {
+		return nil, fmt.Errorf("encryption key must be %d bytes, got %d", EncryptKeySize, len(key))
+	}
+
+	mac := hmac.New(sha256.New, key)
+	mac.Write([]byte(encryptKeyInfo))
+	mac.Write(salt)
+	block, err := aes.NewCipher(mac.Sum(nil))
+	if err != nil {
+		return nil, err
+	}
+
+	return cipher.NewGCM(block)
+}
+
+// chunkNonce 数据块的nonce，由数据块的序号和是否为最后一个数据块的标记组成，防止数据块被重排或者
+// 文件被截断
+func chunkNonce(counter uint64, final bool) []byte {
+	nonce := make([]byte, 12)
+	binary.BigEndian.PutUint64(nonce[3:11], counter)
+	if final {
+		nonce[11] = 1
+	}
+
+	return nonce
+}
+
+// encryptWriter 分块加密写入器，加密文件格式为：
+// 魔数(4字节) | 密钥ID长度(1字节) | 密钥ID | 随机盐(32字节) | 数据块...
+// 每个数据块为EncryptChunkSize字节的明文加密之后的密文和认证标签，最后一个数据块可以更短，
+// 文件头作为每个数据块的附加认证数据
+type encryptWriter struct {
+	w    io.Writer
+	aead cipher.AEAD
+	// 文件头
+	aad []byte
+	// 等待加密的明文
+	buf []byte
+	// 加密之后的密文
+	out []byte
+	// 数据块的序号
+	counter uint64
+	// 写入失败的错误，之后的写入直接返回该错误
+	err    error
+	closed bool
+}
+
+// NewEncryptWriter 创建分块加密的写入器，使用kp当前的密钥加密写入的数据，Close时写入最后一个
+// 数据块，但不会关闭w，Close之前的数据不是完整的加密文件
+func NewEncryptWriter(w io.Writer, kp KeyProvider) (io.WriteCloser, error) {
+	id, key, err := kp.EncryptionKey()
+	if err != nil {
+		return nil, err
+	}
+	if len(id) > encryptMaxKeyID {
+		return nil, fmt.Errorf("key id length %d exceeds %d", len(id), encryptMaxKeyID)
+	}
+
+	salt := make([]byte, encryptSaltSize)
+	if _, err = rand.Read(salt); err != nil {
+		return nil, err
+	}
+	aead, err := newFileAEAD(key, salt)
+	if err != nil {
+		return nil, err
+	}
+
+	header := make([]byte, 0, len(encryptMagic)+1+len(id)+encryptSaltSize)
+	header = append(header, encryptMagic...)
+	header = append(header, byte(len(id)))
+	header = append(header, id...)
+	header = append(header, salt...)
+	if _, err = w.Write(header); err != nil {
+		return nil, err
+	}
+
+	return &encryptWriter{
+		w:    w,
+		aead: aead,
+		aad:  header,
+		buf:  make([]byte, 0, EncryptChunkSize),
+	}, nil
+}
+
+func (e *encryptWriter) Write(p []byte) (int, error) {
+	if e.err != nil {
+		return 0, e.err
+	}
+	if e.closed {
+		return 0, os.ErrClosed
+	}
+
+	var n int
+	for len(p) > 0 {
+		if len(e.buf) == EncryptChunkSize {
+			// 还有后续的数据，缓冲区中的数据块不是最后一个数据块
+			if err := e.flush(false); err != nil {
+				return n, err
+			}
+		}
+		m := copy(e.buf[len(e.buf):cap(e.buf)], p)
+		e.buf = e.buf[:len(e.buf)+m]
+		p = p[m:]
+		n += m
+	}
+
+	return n, nil
+}
+
+// flush 加密缓冲区中的数据块并写入
+func (e *encryptWriter) flush(final bool) error {
+	e.out = e.aead.Seal(e.out[:0], chunkNonce(e.counter, final), e.buf, e.aad)
+	if _, err := e.w.Write(e.out); err != nil {
+		e.err = err
+		return err
+	}
+	e.counter++
+	e.buf = e.buf[:0]
+
+	return nil
+}
+
+func (e *encryptWriter) Close() error {
+	if e.err != nil || e.closed {
+		return e.err
+	}
+
+	e.closed = true
+	return e.flush(true)
+}
+
+// decryptReader 分块解密读取器，每个数据块解密并认证通过之后才返回数据
+type decryptReader struct {
+	r    io.Reader
+	aead cipher.AEAD
+	aad  []byte
+	// 读取的密文，多读取一个字节用于判断是否为最后一个数据块
+	buf []byte
+	// buf中有效的字节数
+	n int
+	// 解密之后的明文
+	out []byte
+	// 还没有被读取的明文
+	plain   []byte
+	counter uint64
+	// 是否已经读取了最后一个数据块
+	done bool
+	err  error
+}
+
+// NewDecryptReader 创建分块解密的读取器，根据文件头中的密钥ID从kp获取密钥，文件被截断、篡改
+// 或者密钥不匹配时返回errorx.ErrDecryptFailed
+func NewDecryptReader(r io.Reader, kp KeyProvider) (io.Reader, error) {
+	prefix := make([]byte, len(encryptMagic)+1)
+	if _, err := io.ReadFull(r, prefix); err != nil {
+		return nil, fmt.Errorf("%w: read header: %w", errorx.ErrDecryptFailed, err)
+	}
+	if string(prefix[:len(encryptMagic)]) != encryptMagic {
+		return nil, fmt.Errorf("%w: invalid magic", errorx.ErrDecryptFailed)
+	}
+
+	rest := make([]byte, int(prefix[len(encryptMagic)])+encryptSaltSize)
+	if _, err := io.ReadFull(r, rest); err != nil {
+		return nil, fmt.Errorf("%w: read header: %w", errorx.ErrDecryptFailed, err)
+	}
+	id, salt := string(rest[:len(rest)-encryptSaltSize]), rest[len(rest)-encryptSaltSize:]
+	key, err := kp.DecryptionKey(id)
+	if err != nil {
+		return nil, err
+	}
+	aead, err := newFileAEAD(key, salt)
+	if err != nil {
+		return nil, err
+	}
+
+	return &decryptReader{
+		r:    r,
+		aead: aead,
+		aad:  append(prefix, rest...),
+		buf:  make([]byte, EncryptChunkSize+aead.Overhead()+1),
+	}, nil
+}
+
+func (d *decryptReader) Read(p []byte) (int, error) {
+	for len(d.plain) == 0 {
+		if d.err != nil {
+			return 0, d.err
+		}
+		if d.done {
+			return 0, io.EOF
+		}
+		d.err = d.next()
+	}
+
+	n := copy(p, d.plain)
+	d.plain = d.plain[n:]
+	return n, nil
+}
+
+// next 读取并解密下一个数据块
+func (d *decryptReader) next() error {
+	n, err := io.ReadFull(d.r, d.buf[d.n:])
+	d.n += n
+	final := false
+	switch {
+	case err == nil:
+	case errors.Is(err, io.EOF), errors.Is(err, io.ErrUnexpectedEOF):
+		final = true
+	default:
+		return err
+	}
+
+	size := d.n
+	if !final {
+		size = len(d.buf) - 1
+	}
+	if size < d.aead.Overhead() {
+		return fmt.Errorf("%w: truncated chunk %d", errorx.ErrDecryptFailed, d.counter)
+	}
+
+	plain, err := d.aead.Open(d.out[:0], chunkNonce(d.counter, final), d.buf[:size], d.aad)
+	if err != nil {
+		return fmt.Errorf("%w: chunk %d: %w", errorx.ErrDecryptFailed, d.counter, err)
+	}
+	d.out, d.plain = plain, plain
+	d.counter++
+	d.n = copy(d.buf, d.buf[size:d.n])
+	d.done = final
+
+	return nil
+}
+
+// OpenEncryptedFile 打开加密的轮转文件读取原始内容，先解密，再根据去掉加密后缀之后的文件名称
+// 自动解压
+func OpenEncryptedFile(path string, kp KeyProvider) (io.ReadCloser, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+
+	dr, err := NewDecryptReader(bufio.NewReader(f), kp)
+	if err != nil {
+		_ = f.Close()
+		return nil, err
+	}
+	rc, err := newDecompressReader(compressTypeOf(strings.TrimSuffix(path, EncryptFileExt)), dr)
+	if err != nil {
+		_ = f.Close()
+		return nil, err
+	}
+
+	return &encryptedFileReader{ReadCloser: rc, f: f}, nil
+}
+
+// encryptedFileReader 关闭时同时关闭解压器和文件
+type encryptedFileReader struct {
+	io.ReadCloser
+	f *os.File
+}
+
+func (e *encryptedFileReader) Close() error {
+	err := e.ReadCloser.Close()
+	if err1 := e.f.Close(); err == nil {
+		err = err1
+	}
+
+	return err
+}
+
+// WithEncryption 开启静态加密，轮转文件在压缩(如果开启)之后使用AES-256-GCM分块加密，加密文件
+// 追加EncryptFileExt后缀，解密校验与加密之前的文件一致之后删除明文文件，校验和、完成标记等都
+// 基于加密文件。加密文件使用OpenEncryptedFile或者NewDecryptReader读取，ReadOnly等只读访问的
+// 接口不会列出加密文件。开启加密时不能保留压缩的源文件，也不能与每日打包同时使用。
+func WithEncryption(kp KeyProvider) Option {
+	return func(r *Rotator) error {
+		if kp == nil {
+			return errors.New("key provider must not be nil")
+		}
+		if _, _, err := kp.EncryptionKey(); err != nil {
+			return fmt.Errorf("get encryption key error: %w", err)
+		}
+
+		r.encryption = kp
+		return nil
+	}
+}
+
+// checkEncryption 检查静态加密与其他配置的兼容性
+func (r *Rotator) checkEncryption() error {
+	if r.encryption == nil {
+		return nil
+	}
+
+	switch {
+	case r.cpr.keepSource:
+		return errors.New("encryption can not keep plaintext source after compress")
+	case r.bundle != 0:
+		return errors.New("encryption does not support daily bundle")
+	default:
+		return nil
+	}
+}
+
+// encryptFile 加密封存的文件，先写入临时文件，解密校验与明文一致之后rename为正式文件并删除明文，
+// 返回加密文件的路径
+func (r *Rotator) encryptFile(path string) (string, error) {
+	dst := path + EncryptFileExt
+	tmp := dst + TmpFileExt
+	if err := writeEncrypted(path, tmp, r.encryption); err != nil {
+		_ = os.Remove(tmp)
+		return "", err
+	}
+
+	if err := verifyEncrypted(path, tmp, r.encryption); err != nil {
+		_ = os.Remove(tmp)
+		return "", err
+	}
+
+	if err := r.rename(tmp, dst); err != nil {
+		_ = os.Remove(tmp)
+		return "", err
+	}
+
+	if err := os.Remove(path); err != nil && !os.IsNotExist(err) {
+		return "", err
+	}
+
+	return dst, nil
+}
+
+// writeEncrypted 将src加密之后写入文件dst中，上一次加密过程中退出时残留的dst被截断之后重新写入
+func writeEncrypted(src, dst string, kp KeyProvider) error {
+	in, err := os.Open(src)
+	if err != nil {
+		return err
+	}
+	defer func() {
+		_ = in.Close()
+	}()
+
+	out, err := os.OpenFile(dst, os.O_CREATE|os.O_TRUNC|os.O_WRONLY|openNoFollow, ReadWriteFile)
+	if err != nil {
+		return err
+	}
+	defer func() {
+		_ = out.Close()
+	}()
+
+	bw := bufio.NewWriter(out)
+	ew, err := NewEncryptWriter(bw, kp)
+	if err != nil {
+		return err
+	}
+
+	buf := resources.getBuffer()
+	defer resources.putBuffer(buf)
+	if _, err = io.CopyBuffer(ew, in, *buf); err != nil {
+		return err
+	}
+	if err = ew.Close(); err != nil {
+		return err
+	}
+	if err = bw.Flush(); err != nil {
+		return err
+	}
+	if err = out.Sync(); err != nil {
+		return err
+	}
+
+	return out.Close()
+}
+
+// verifyEncrypted 解密加密文件，与明文文件逐字节比较
+func verifyEncrypted(src, encrypted string, kp KeyProvider) error {
+	sf, err := os.Open(src)
+	if err != nil {
+		return err
+	}
+	defer func() {
+		_ = sf.Close()
+	}()
+
+	ef, err := os.Open(encrypted)
+	if err != nil {
+		return err
+	}
+	defer func() {
+		_ = ef.Close()
+	}()
+
+	dr, err := NewDecryptReader(bufio.NewReader(ef), kp)
+	if err != nil {
+		return err
+	}
+
+	return equalReaders(sf, dr)
+}
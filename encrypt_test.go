@@ -0,0 +1,240 @@
+// Copyright 2025 TimeWtr
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package vortexrotate
+
+import (
+	"bytes"
+	"crypto/rand"
+	"encoding/base64"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/TimeWtr/vortexrotate/errorx"
+	"github.com/stretchr/testify/assert"
+)
+
+// testKeyProvider 测试使用的随机密钥
+func testKeyProvider(t *testing.T, id string) KeyProvider {
+	key := make([]byte, EncryptKeySize)
+	_, err := rand.Read(key)
+	assert.NoError(t, err)
+
+	kp, err := NewStaticKeyProvider(id, key)
+	assert.NoError(t, err)
+	return kp
+}
+
+// encryptBytes 加密data并返回加密之后的内容
+func encryptBytes(t *testing.T, kp KeyProvider, data []byte) []byte {
+	var buf bytes.Buffer
+	ew, err := NewEncryptWriter(&buf, kp)
+	assert.NoError(t, err)
+	_, err = ew.Write(data)
+	assert.NoError(t, err)
+	assert.NoError(t, ew.Close())
+
+	return buf.Bytes()
+}
+
+func TestEncrypt_RoundTrip(t *testing.T) {
+	kp := testKeyProvider(t, "k1")
+	for _, size := range []int{0, 1, EncryptChunkSize - 1, EncryptChunkSize, EncryptChunkSize + 1, 3*EncryptChunkSize + 17} {
+		t.Run(fmt.Sprintf("size-%d", size), func(t *testing.T) {
+			data := make([]byte, size)
+			_, _ = rand.Read(data)
+			enc := encryptBytes(t, kp, data)
+
+			dr, err := NewDecryptReader(bytes.NewReader(enc), kp)
+			assert.NoError(t, err)
+			got, err := io.ReadAll(dr)
+			assert.NoError(t, err)
+			assert.Equal(t, data, got)
+		})
+	}
+}
+
+func TestEncrypt_Tamper(t *testing.T) {
+	kp := testKeyProvider(t, "k1")
+	data := bytes.Repeat([]byte("hello world\n"), EncryptChunkSize/4)
+	enc := encryptBytes(t, kp, data)
+	headerLen := len(encryptMagic) + 1 + len("k1") + encryptSaltSize
+	chunkLen := EncryptChunkSize + 16
+
+	testCases := []struct {
+		name string
+		data []byte
+	}{
+		{name: "flip", data: func() []byte {
+			bs := bytes.Clone(enc)
+			bs[len(bs)-1] ^= 1
+			return bs
+		}()},
+		{name: "truncate at chunk boundary", data: enc[:headerLen+chunkLen]},
+		{name: "truncate in chunk", data: enc[:len(enc)-3]},
+		{name: "header only", data: enc[:headerLen]},
+		{name: "swap header key id", data: func() []byte {
+			bs := bytes.Clone(enc)
+			bs[len(encryptMagic)+1] = 'x'
+			return bs
+		}()},
+	}
+
+	kpx := &multiKeyProvider{kps: []KeyProvider{kp, testKeyProvider(t, "x1")}}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			dr, err := NewDecryptReader(bytes.NewReader(tc.data), kpx)
+			if err == nil {
+				_, err = io.ReadAll(dr)
+			}
+			assert.ErrorIs(t, err, errorx.ErrDecryptFailed)
+		})
+	}
+
+	_, err := NewDecryptReader(bytes.NewReader(enc), testKeyProvider(t, "k2"))
+	assert.ErrorIs(t, err, errorx.ErrKeyNotFound)
+	_, err = NewDecryptReader(bytes.NewReader(data), kp)
+	assert.ErrorIs(t, err, errorx.ErrDecryptFailed)
+}
+
+// multiKeyProvider 按照密钥ID查找多个密钥，模拟密钥轮换
+type multiKeyProvider struct {
+	kps []KeyProvider
+}
+
+func (m *multiKeyProvider) EncryptionKey() (id string, key []byte, err error) {
+	return m.kps[0].EncryptionKey()
+}
+
+func (m *multiKeyProvider) DecryptionKey(id string) ([]byte, error) {
+	for _, kp := range m.kps {
+		if key, err := kp.DecryptionKey(id); err == nil {
+			return key, nil
+		}
+	}
+
+	return nil, errorx.ErrKeyNotFound
+}
+
+func TestKeyProvider(t *testing.T) {
+	key := make([]byte, EncryptKeySize)
+	_, _ = rand.Read(key)
+
+	t.Setenv("VORTEX_TEST_KEY", hex.EncodeToString(key))
+	kp, err := NewEnvKeyProvider("VORTEX_TEST_KEY")
+	assert.NoError(t, err)
+	id, got, err := kp.EncryptionKey()
+	assert.NoError(t, err)
+	assert.Equal(t, "VORTEX_TEST_KEY", id)
+	assert.Equal(t, key, got)
+
+	path := filepath.Join(t.TempDir(), "app.key")
+	assert.NoError(t, os.WriteFile(path, []byte(base64.StdEncoding.EncodeToString(key)+"\n"), 0o600))
+	kp, err = NewFileKeyProvider(path)
+	assert.NoError(t, err)
+	got, err = kp.DecryptionKey("app.key")
+	assert.NoError(t, err)
+	assert.Equal(t, key, got)
+
+	t.Setenv("VORTEX_TEST_KEY", "short")
+	_, err = NewEnvKeyProvider("VORTEX_TEST_KEY")
+	assert.Error(t, err)
+	_, err = NewEnvKeyProvider("VORTEX_TEST_KEY_NOT_SET")
+	assert.Error(t, err)
+	_, err = NewStaticKeyProvider("k1", key[:16])
+	assert.Error(t, err)
+}
+
+func TestRotator_Encryption(t *testing.T) {
+	kp := testKeyProvider(t, "k1")
+	rotator, err := NewRotator(t.TempDir(), "testdata.log",
+		WithCompress(CompressTypeGzip, GzipBestSpeed),
+		WithEncryption(kp),
+		WithChecksum(),
+		WithSealOnClose())
+	assert.NoError(t, err)
+
+	content := bytes.Repeat([]byte("secret line\n"), 10000)
+	_, err = rotator.Write(content)
+	assert.NoError(t, err)
+	path := rotator.f.Name()
+	assert.NoError(t, rotator.Close())
+
+	archive := compressFn(path, CompressTypeGzip)
+	_, err = os.Stat(archive)
+	assert.True(t, os.IsNotExist(err))
+	_, err = os.Stat(path)
+	assert.True(t, os.IsNotExist(err))
+	_, err = os.Stat(archive + EncryptFileExt + ChecksumFileExt)
+	assert.NoError(t, err)
+
+	rc, err := OpenEncryptedFile(archive+EncryptFileExt, kp)
+	assert.NoError(t, err)
+	got, err := io.ReadAll(rc)
+	assert.NoError(t, err)
+	assert.NoError(t, rc.Close())
+	assert.Equal(t, content, got)
+
+	_, err = NewRotator(t.TempDir(), "testdata.log",
+		WithCompress(CompressTypeGzip), WithRemoveSource(false), WithEncryption(kp))
+	assert.Error(t, err)
+	_, err = NewRotator(t.TempDir(), "testdata.log", WithEncryption(nil))
+	assert.Error(t, err)
+}
+
+func TestRotator_EncryptionWithoutCompress(t *testing.T) {
+	kp := testKeyProvider(t, "k1")
+	rotator, err := NewRotator(t.TempDir(), "testdata.log", WithEncryption(kp), WithSealOnClose())
+	assert.NoError(t, err)
+
+	_, err = rotator.Write([]byte("hello\n"))
+	assert.NoError(t, err)
+	path := rotator.f.Name()
+	assert.NoError(t, rotator.Close())
+
+	_, err = os.Stat(path)
+	assert.True(t, os.IsNotExist(err))
+	rc, err := OpenEncryptedFile(path+EncryptFileExt, kp)
+	assert.NoError(t, err)
+	defer rc.Close()
+	got, err := io.ReadAll(rc)
+	assert.NoError(t, err)
+	assert.Equal(t, "hello\n", string(got))
+}
+
+func TestRotator_EncryptionStaleTmp(t *testing.T) {
+	kp := testKeyProvider(t, "k1")
+	rotator, err := NewRotator(t.TempDir(), "testdata.log", WithEncryption(kp), WithSealOnClose())
+	assert.NoError(t, err)
+
+	_, err = rotator.Write([]byte("hello\n"))
+	assert.NoError(t, err)
+	path := rotator.f.Name()
+	// 上一次加密过程中退出时残留的临时文件不影响加密
+	tmp := path + EncryptFileExt + TmpFileExt
+	assert.NoError(t, os.WriteFile(tmp, []byte("stale encrypted content"), ReadWriteFile))
+	assert.NoError(t, rotator.Close())
+
+	assert.NoFileExists(t, tmp)
+	rc, err := OpenEncryptedFile(path+EncryptFileExt, kp)
+	assert.NoError(t, err)
+	defer rc.Close()
+	got, err := io.ReadAll(rc)
+	assert.NoError(t, err)
+	assert.Equal(t, "hello\n", string(got))
+}
@@ -27,6 +27,46 @@ var (
 
 var ErrFilename = errors.New("filename must contain exactly one '.' character")
 
+var (
+	ErrSymlink          = errors.New("refuse to follow symlink")
+	ErrDirWorldWritable = errors.New("directory is world-writable")
+	ErrSegmentExists    = errors.New("segment file already exists")
+	ErrSegmentNotFound  = errors.New("segment file not found")
+	ErrInvalidCursor    = errors.New("cursor offset out of segment range")
+	ErrCorruptRecord    = errors.New("corrupt wal record")
+	ErrWALMode          = errors.New("write is not allowed in wal mode")
+	ErrNotWALMode       = errors.New("append is only allowed in wal mode")
+	ErrInjectedFault    = errors.New("injected fault")
+	ErrArchiveMismatch  = errors.New("compressed archive does not match source")
+	ErrExportFormat     = errors.New("unknown export format")
+)
+
+var (
+	// ErrDiskFull 磁盘空间不足
+	ErrDiskFull = errors.New("no space left on device")
+	// ErrDirUnwritable 存储目录没有写权限或者位于只读文件系统
+	ErrDirUnwritable = errors.New("directory is not writable")
+	// ErrCompressFailed 压缩轮转文件或者校验压缩文件失败
+	ErrCompressFailed = errors.New("compress failed")
+	// ErrUploadFailed 上传轮转文件失败
+	ErrUploadFailed = errors.New("upload failed")
+	// ErrQueueFull 队列已满，无法再接收新的任务
+	ErrQueueFull = errors.New("queue is full")
+	// ErrWALConflict WAL模式与其他配置(比如写入内容的转换函数)冲突
+	ErrWALConflict = errors.New("wal mode conflicts with option")
+	// ErrRecordTooLarge WAL记录的长度超过限制
+	ErrRecordTooLarge = errors.New("wal record too large")
+	// ErrClosed 轮转器已经关闭，与ErrRotateClosed是同一个错误
+	ErrClosed = ErrRotateClosed
+)
+
+var (
+	// ErrKeyNotFound 密钥提供者中找不到加密文件使用的密钥
+	ErrKeyNotFound = errors.New("encryption key not found")
+	// ErrDecryptFailed 加密文件格式错误、被截断或者被篡改，无法解密
+	ErrDecryptFailed = errors.New("decrypt failed")
+)
+
 type Error struct {
 	err error
 }
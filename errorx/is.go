@@ -0,0 +1,58 @@
+// Copyright 2025 TimeWtr
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package errorx
+
+import (
+	"errors"
+	"io/fs"
+	"syscall"
+)
+
+// IsDiskFull 错误链中是否包含磁盘空间不足的错误，包括ErrDiskFull和系统返回的ENOSPC
+func IsDiskFull(err error) bool {
+	return errors.Is(err, ErrDiskFull) || errors.Is(err, syscall.ENOSPC)
+}
+
+// IsDirUnwritable 错误链中是否包含目录不可写的错误，包括ErrDirUnwritable、权限错误和只读文件系统
+func IsDirUnwritable(err error) bool {
+	return errors.Is(err, ErrDirUnwritable) || errors.Is(err, fs.ErrPermission) ||
+		errors.Is(err, syscall.EROFS)
+}
+
+// IsCompressFailed 错误链中是否包含压缩失败的错误，压缩文件校验不一致也属于压缩失败
+func IsCompressFailed(err error) bool {
+	return errors.Is(err, ErrCompressFailed) || errors.Is(err, ErrArchiveMismatch)
+}
+
+// IsUploadFailed 错误链中是否包含上传失败的错误
+func IsUploadFailed(err error) bool {
+	return errors.Is(err, ErrUploadFailed)
+}
+
+// IsClosed 错误链中是否包含轮转器已经关闭的错误
+func IsClosed(err error) bool {
+	return errors.Is(err, ErrClosed)
+}
+
+// IsQueueFull 错误链中是否包含队列已满的错误
+func IsQueueFull(err error) bool {
+	return errors.Is(err, ErrQueueFull)
+}
+
+// IsWALMisuse 错误链中是否包含WAL模式使用错误，包括配置冲突、记录过长以及写入方式与模式不匹配
+func IsWALMisuse(err error) bool {
+	return errors.Is(err, ErrWALConflict) || errors.Is(err, ErrRecordTooLarge) ||
+		errors.Is(err, ErrWALMode) || errors.Is(err, ErrNotWALMode)
+}
@@ -0,0 +1,82 @@
+// Copyright 2025 TimeWtr
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package errorx
+
+import (
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"syscall"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestIs(t *testing.T) {
+	testCases := []struct {
+		name string
+		is   func(error) bool
+		errs []error
+	}{
+		{
+			name: "disk full",
+			is:   IsDiskFull,
+			errs: []error{ErrDiskFull, &os.PathError{Op: "write", Path: "x.log", Err: syscall.ENOSPC}},
+		},
+		{
+			name: "dir unwritable",
+			is:   IsDirUnwritable,
+			errs: []error{ErrDirUnwritable, &os.PathError{Op: "open", Path: "x.log", Err: fs.ErrPermission}},
+		},
+		{
+			name: "compress failed",
+			is:   IsCompressFailed,
+			errs: []error{ErrCompressFailed, ErrArchiveMismatch},
+		},
+		{
+			name: "upload failed",
+			is:   IsUploadFailed,
+			errs: []error{ErrUploadFailed},
+		},
+		{
+			name: "closed",
+			is:   IsClosed,
+			errs: []error{ErrClosed, ErrRotateClosed},
+		},
+		{
+			name: "queue full",
+			is:   IsQueueFull,
+			errs: []error{ErrQueueFull},
+		},
+		{
+			name: "wal misuse",
+			is:   IsWALMisuse,
+			errs: []error{ErrWALConflict, ErrRecordTooLarge, ErrWALMode, ErrNotWALMode},
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			for _, err := range tc.errs {
+				assert.True(t, tc.is(err))
+				assert.True(t, tc.is(fmt.Errorf("wrapped: %w", err)))
+				assert.True(t, tc.is(errors.Join(errors.New("other"), err)))
+			}
+			assert.False(t, tc.is(nil))
+			assert.False(t, tc.is(errors.New("other")))
+		})
+	}
+}
@@ -0,0 +1,134 @@
+// Copyright 2025 TimeWtr
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package vortexrotate
+
+import (
+	"fmt"
+	"time"
+)
+
+// EventType 轮转器对外发出的事件类型
+type EventType int
+
+const (
+	// EventRotatePauseSLO 单次轮转阻塞写入的时间超过了配置的SLO
+	EventRotatePauseSLO EventType = iota + 1
+	// EventRepair 初始化时检测到目录不一致并自动执行了修复
+	EventRepair
+	// EventDirEntriesExceeded 单个目录中的文件数量超过了限制
+	EventDirEntriesExceeded
+	// EventEmergencyCleanup 磁盘可用空间低于阈值，执行了紧急清理
+	EventEmergencyCleanup
+	// EventCleanupDryRun 清理演练模式下计算出了需要清理的文件
+	EventCleanupDryRun
+	// EventCleanupError 清理过期文件的过程中发生了错误
+	EventCleanupError
+	// EventVerifyFailed 删除源文件之前校验压缩文件失败，压缩文件已经移动到隔离目录，源文件保留
+	EventVerifyFailed
+	// EventTimezoneChange 检测到主机时区发生了变化，或者切换到了新的时区
+	EventTimezoneChange
+	// EventCompressSkipped 磁盘可用空间不足以容纳压缩文件，跳过了压缩
+	EventCompressSkipped
+	// EventQuarantine 校验或者解压失败的归档文件被移动到了隔离目录
+	EventQuarantine
+	// EventAudit 启动审计发现了序列号重复、同名文件冲突或者校验和不一致等问题
+	EventAudit
+	// EventUploadFailed 上传封存完成的文件失败，本地的文件保留
+	EventUploadFailed
+)
+
+func (t EventType) String() string {
+	switch t {
+	case EventRotatePauseSLO:
+		return "rotate_pause_slo"
+	case EventRepair:
+		return "repair"
+	case EventDirEntriesExceeded:
+		return "dir_entries_exceeded"
+	case EventEmergencyCleanup:
+		return "emergency_cleanup"
+	case EventCleanupDryRun:
+		return "cleanup_dry_run"
+	case EventCleanupError:
+		return "cleanup_error"
+	case EventVerifyFailed:
+		return "verify_failed"
+	case EventTimezoneChange:
+		return "timezone_change"
+	case EventCompressSkipped:
+		return "compress_skipped"
+	case EventQuarantine:
+		return "quarantine"
+	case EventAudit:
+		return "audit"
+	case EventUploadFailed:
+		return "upload_failed"
+	default:
+		return "unknown"
+	}
+}
+
+// Event 轮转器事件，用于告警、观测和调优
+type Event struct {
+	// 事件类型
+	Type EventType
+	// 事件发生的时间
+	Time time.Time
+	// 事件相关的文件路径
+	Path string
+	// 事件的描述信息
+	Message string
+	// 轮转阻塞的耗时明细，只在EventRotatePauseSLO事件中有效
+	Pause RotatePause
+	// 事件相关的错误，只在错误类的事件中有效
+	Err error
+}
+
+// EventHandler 事件处理函数，在轮转器内部同步调用，不能阻塞，也不能在处理函数中
+// 调用Rotator的写入/轮转方法，否则会造成死锁
+type EventHandler func(Event)
+
+// RotatePause 单次轮转过程中写入被阻塞的耗时明细
+type RotatePause struct {
+	// 关闭旧文件的耗时
+	Close time.Duration
+	// 压缩(或提交压缩任务)的耗时
+	Compress time.Duration
+	// 创建目录的耗时
+	Mkdir time.Duration
+	// 打开新文件的耗时
+	Open time.Duration
+	// 总耗时，即写入被阻塞的时间
+	Total time.Duration
+}
+
+func (p RotatePause) String() string {
+	return fmt.Sprintf("total: %s, close: %s, compress: %s, mkdir: %s, open: %s",
+		p.Total, p.Close, p.Compress, p.Mkdir, p.Open)
+}
+
+// emit 发送事件，未设置事件处理函数时输出到日志
+func (r *Rotator) emit(e Event) {
+	if e.Time.IsZero() {
+		e.Time = time.Now()
+	}
+
+	if r.eventHandler == nil {
+		r.l.Printf("[%s] %s", e.Type, e.Message)
+		return
+	}
+
+	r.eventHandler(e)
+}
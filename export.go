@@ -0,0 +1,265 @@
+// Copyright 2025 TimeWtr
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package vortexrotate
+
+import (
+	"archive/tar"
+	"archive/zip"
+	"compress/gzip"
+	"context"
+	"fmt"
+	"io"
+	"path"
+	"strings"
+	"time"
+
+	"github.com/TimeWtr/vortexrotate/errorx"
+)
+
+// ExportFormat 导出的归档格式
+type ExportFormat int
+
+const (
+	// ExportTarGz gzip压缩的tar归档
+	ExportTarGz ExportFormat = iota + 1
+	// ExportZip zip归档，Windows等平台不需要额外的工具就可以直接打开
+	ExportZip
+	// ExportTarZst zstd压缩的tar归档，压缩比和解压速度都优于tar.gz
+	ExportTarZst
+)
+
+// Ext 归档格式对应的文件后缀名
+func (f ExportFormat) Ext() string {
+	switch f {
+	case ExportTarGz:
+		return ".tar.gz"
+	case ExportZip:
+		return ".zip"
+	case ExportTarZst:
+		return ".tar.zst"
+	default:
+		return ""
+	}
+}
+
+func (f ExportFormat) String() string {
+	switch f {
+	case ExportTarGz:
+		return "tar.gz"
+	case ExportZip:
+		return "zip"
+	case ExportTarZst:
+		return "tar.zst"
+	default:
+		return "unknown"
+	}
+}
+
+// ParseExportFormat 解析归档格式的名称，支持tar.gz(tgz)、tar.zst(tzst)和zip
+func ParseExportFormat(s string) (ExportFormat, error) {
+	switch strings.ToLower(s) {
+	case "tar.gz", "tgz":
+		return ExportTarGz, nil
+	case "tar.zst", "tzst":
+		return ExportTarZst, nil
+	case "zip":
+		return ExportZip, nil
+	default:
+		return 0, fmt.Errorf("%w %q", errorx.ErrExportFormat, s)
+	}
+}
+
+// ExportDay 将指定日期的所有轮转文件按照序列号顺序写入一个归档文件，归档中的每一个文件都是解压
+// 之后的原始日志，路径为<日期>/<文件名>，用于生成问题排查时需要的日志包。归档以流的方式写入w，
+// 不会在磁盘上生成临时文件，tar格式需要预先知道文件大小，压缩的轮转文件会解压两次。当天没有轮转
+// 文件时返回errorx.ErrSegmentNotFound
+func (ro *ReadOnly) ExportDay(ctx context.Context, date time.Time, w io.Writer, format ExportFormat) error {
+	day := date.Format(Layout)
+	matched, err := ro.daySegments(day)
+	if err != nil {
+		return err
+	}
+	if len(matched) == 0 {
+		return errorx.ErrSegmentNotFound
+	}
+
+	return ro.export(ctx, day, matched, w, format)
+}
+
+// daySegments 指定日期(YYYYMMDD)的所有轮转文件
+func (ro *ReadOnly) daySegments(day string) ([]SegmentInfo, error) {
+	segments, err := ro.List()
+	if err != nil {
+		return nil, err
+	}
+
+	var matched []SegmentInfo
+	for _, seg := range segments {
+		if seg.Date.Format(Layout) == day {
+			matched = append(matched, seg)
+		}
+	}
+
+	return matched, nil
+}
+
+// export 将轮转文件按照给定的顺序写入归档
+func (ro *ReadOnly) export(ctx context.Context, day string, segments []SegmentInfo, w io.Writer,
+	format ExportFormat) error {
+	switch format {
+	case ExportTarGz:
+		return ro.exportTar(ctx, day, segments, gzip.NewWriter(w))
+	case ExportTarZst:
+		zw, err := newCompressWriter(CompressTypeZstd, ZstdDefaultLevel, w)
+		if err != nil {
+			return err
+		}
+		return ro.exportTar(ctx, day, segments, zw)
+	case ExportZip:
+		return ro.exportZip(ctx, day, segments, w)
+	default:
+		return fmt.Errorf("%w %d", errorx.ErrExportFormat, format)
+	}
+}
+
+// exportName 轮转文件在归档中的路径
+func exportName(day string, seg SegmentInfo) string {
+	return path.Join(day, trimCompressExt(seg.Name))
+}
+
+// exportTar 将轮转文件写入tar归档，cw为tar流的压缩写入器，返回之前关闭cw
+func (ro *ReadOnly) exportTar(ctx context.Context, day string, segments []SegmentInfo, cw io.WriteCloser) error {
+	tw := tar.NewWriter(cw)
+	for _, seg := range segments {
+		if err := ctx.Err(); err != nil {
+			_ = cw.Close()
+			return err
+		}
+
+		size, err := ro.rawSize(ctx, seg)
+		if err == nil {
+			err = tw.WriteHeader(&tar.Header{
+				Name:    exportName(day, seg),
+				Mode:    int64(ReadWriteFile),
+				Size:    size,
+				ModTime: seg.ModTime,
+			})
+		}
+		if err == nil {
+			err = ro.copySegment(ctx, tw, seg, size)
+		}
+		if err != nil {
+			_ = cw.Close()
+			return err
+		}
+	}
+
+	if err := tw.Close(); err != nil {
+		_ = cw.Close()
+		return err
+	}
+
+	return cw.Close()
+}
+
+func (ro *ReadOnly) exportZip(ctx context.Context, day string, segments []SegmentInfo, w io.Writer) error {
+	zw := zip.NewWriter(w)
+	for _, seg := range segments {
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+
+		fw, err := zw.CreateHeader(&zip.FileHeader{
+			Name:     exportName(day, seg),
+			Method:   zip.Deflate,
+			Modified: seg.ModTime,
+		})
+		if err != nil {
+			return err
+		}
+		size := int64(-1)
+		if seg.CompressType == CompressTypeUnknown {
+			size = seg.Size
+		}
+		if err = ro.copySegment(ctx, fw, seg, size); err != nil {
+			return err
+		}
+	}
+
+	return zw.Close()
+}
+
+// rawSize 轮转文件解压之后的大小，未压缩的文件直接使用文件大小
+func (ro *ReadOnly) rawSize(ctx context.Context, seg SegmentInfo) (int64, error) {
+	if seg.CompressType == CompressTypeUnknown {
+		return seg.Size, nil
+	}
+
+	rc, err := ro.Open(seg)
+	if err != nil {
+		return 0, err
+	}
+	defer func() {
+		_ = rc.Close()
+	}()
+
+	return io.Copy(io.Discard, ctxReader{ctx: ctx, r: rc})
+}
+
+// copySegment 将轮转文件的原始内容写入w，size>=0时只写入size字节，未压缩的文件在List之后
+// 可能仍然在写入，只导出List时的大小。复制过程中ctx取消时返回ctx.Err()
+func (ro *ReadOnly) copySegment(ctx context.Context, w io.Writer, seg SegmentInfo, size int64) error {
+	rc, err := ro.Open(seg)
+	if err != nil {
+		return err
+	}
+	defer func() {
+		_ = rc.Close()
+	}()
+
+	r := ctxReader{ctx: ctx, r: rc}
+	if size >= 0 {
+		_, err = io.CopyN(w, r, size)
+		return err
+	}
+
+	_, err = io.Copy(w, r)
+	return err
+}
+
+// ctxReader 每次读取之前检查ctx，导出较大的轮转文件时可以在复制过程中取消
+type ctxReader struct {
+	ctx context.Context
+	r   io.Reader
+}
+
+func (c ctxReader) Read(p []byte) (int, error) {
+	if err := c.ctx.Err(); err != nil {
+		return 0, err
+	}
+
+	return c.r.Read(p)
+}
+
+// ExportDay 将当前轮转器指定日期的所有轮转文件写入一个归档文件，详见ReadOnly.ExportDay
+func (r *Rotator) ExportDay(ctx context.Context, date time.Time, w io.Writer, format ExportFormat) error {
+	ro := &ReadOnly{
+		dir:      r.dir,
+		filename: r.filename,
+		re:       segmentRegexp(r.filename),
+	}
+
+	return ro.ExportDay(ctx, date, w, format)
+}
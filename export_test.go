@@ -0,0 +1,148 @@
+// Copyright 2025 TimeWtr
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package vortexrotate
+
+import (
+	"archive/tar"
+	"archive/zip"
+	"bytes"
+	"compress/gzip"
+	"context"
+	"fmt"
+	"io"
+	"testing"
+	"time"
+
+	"github.com/TimeWtr/vortexrotate/errorx"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestReadOnly_ExportDay(t *testing.T) {
+	dir := t.TempDir()
+	rotator, err := newRotator(dir, "testdata.log", WithCompress(CompressTypeZstd))
+	assert.NoError(t, err)
+	defer rotator.Close()
+
+	date := time.Now().Format(Layout)
+	want := make(map[string]string)
+	for i := 1; i <= 3; i++ {
+		line := fmt.Sprintf("export line %d\n", i)
+		_, err = rotator.Write([]byte(line))
+		assert.NoError(t, err)
+		want[fmt.Sprintf("%s/testdata_%s_%04d.log", date, date, i)] = line
+		if i < 3 {
+			assert.NoError(t, rotator.Rotate())
+		}
+	}
+
+	ro, err := OpenReadOnly(dir, "testdata.log")
+	assert.NoError(t, err)
+
+	var buf bytes.Buffer
+	assert.NoError(t, ro.ExportDay(context.Background(), time.Now(), &buf, ExportTarGz))
+	gr, err := gzip.NewReader(&buf)
+	assert.NoError(t, err)
+	tr := tar.NewReader(gr)
+	got := make(map[string]string)
+	for {
+		hdr, err := tr.Next()
+		if err == io.EOF {
+			break
+		}
+		assert.NoError(t, err)
+		bs, err := io.ReadAll(tr)
+		assert.NoError(t, err)
+		got[hdr.Name] = string(bs)
+	}
+	assert.Equal(t, want, got)
+
+	buf.Reset()
+	assert.NoError(t, ro.ExportDay(context.Background(), time.Now(), &buf, ExportZip))
+	zr, err := zip.NewReader(bytes.NewReader(buf.Bytes()), int64(buf.Len()))
+	assert.NoError(t, err)
+	got = make(map[string]string)
+	for _, f := range zr.File {
+		rc, err := f.Open()
+		assert.NoError(t, err)
+		bs, err := io.ReadAll(rc)
+		assert.NoError(t, err)
+		assert.NoError(t, rc.Close())
+		got[f.Name] = string(bs)
+	}
+	assert.Equal(t, want, got)
+
+	// 轮转器导出的内容与只读方式一致
+	var fromRotator bytes.Buffer
+	assert.NoError(t, rotator.ExportDay(context.Background(), time.Now(), &fromRotator, ExportZip))
+	assert.Equal(t, buf.Len(), fromRotator.Len())
+
+	err = ro.ExportDay(context.Background(), time.Now().AddDate(0, 0, -1), &buf, ExportZip)
+	assert.ErrorIs(t, err, errorx.ErrSegmentNotFound)
+	err = ro.ExportDay(context.Background(), time.Now(), &buf, ExportFormat(0))
+	assert.ErrorIs(t, err, errorx.ErrExportFormat)
+}
+
+// cancelWriter 第一次写入之后取消ctx
+type cancelWriter struct {
+	cancel context.CancelFunc
+	n      int
+}
+
+func (w *cancelWriter) Write(p []byte) (int, error) {
+	w.n += len(p)
+	w.cancel()
+	return len(p), nil
+}
+
+func TestReadOnly_ExportCancel(t *testing.T) {
+	dir := t.TempDir()
+	rotator, err := newRotator(dir, "testdata.log", WithCompress(CompressTypeGzip))
+	assert.NoError(t, err)
+	_, err = rotator.Write(bytes.Repeat([]byte("export cancel line\n"), 1<<14))
+	assert.NoError(t, err)
+	assert.NoError(t, rotator.Rotate())
+	assert.NoError(t, rotator.Close())
+
+	ro, err := OpenReadOnly(dir, "testdata.log")
+	assert.NoError(t, err)
+	segs, err := ro.List()
+	assert.NoError(t, err)
+
+	// 复制同一个轮转文件的过程中取消，不会写完整个文件
+	assert.Equal(t, CompressTypeGzip, segs[0].CompressType)
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	w := &cancelWriter{cancel: cancel}
+	err = ro.copySegment(ctx, w, segs[0], -1)
+	assert.ErrorIs(t, err, context.Canceled)
+	assert.Less(t, w.n, 1<<14*len("export cancel line\n"))
+}
+
+func TestParseExportFormat(t *testing.T) {
+	for s, want := range map[string]ExportFormat{
+		"zip": ExportZip, "ZIP": ExportZip, "tar.gz": ExportTarGz, "tgz": ExportTarGz,
+		"tar.zst": ExportTarZst, "tzst": ExportTarZst,
+	} {
+		f, err := ParseExportFormat(s)
+		assert.NoError(t, err)
+		assert.Equal(t, want, f)
+	}
+	assert.Equal(t, ".zip", ExportZip.Ext())
+	assert.Equal(t, ".tar.gz", ExportTarGz.Ext())
+	assert.Equal(t, ".tar.zst", ExportTarZst.Ext())
+
+	_, err := ParseExportFormat("tar.xz")
+	assert.ErrorIs(t, err, errorx.ErrExportFormat)
+}
@@ -0,0 +1,160 @@
+// Copyright 2025 TimeWtr
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package vortexrotate
+
+import (
+	"math/rand"
+	"sync"
+	"time"
+
+	"github.com/TimeWtr/vortexrotate/errorx"
+)
+
+// FaultInjector 故障注入器，用于在预发环境中模拟磁盘写入失败、压缩缓慢、压缩失败和上传失败等故障，验证
+// 告警是否正常触发以及轮转器的故障恢复能力。所有方法都可以在运行期间并发调用，nil表示不注入故障。
+type FaultInjector struct {
+	lock sync.Mutex
+	// 剩余需要失败的写入次数
+	failWrites int
+	// 写入失败时返回的错误
+	writeErr error
+	// 每次压缩之前的延迟
+	compressDelay time.Duration
+	// 压缩失败的概率
+	compressFailRatio float64
+	// 上传失败的概率
+	uploadFailRatio float64
+	// 生成随机数判断压缩和上传是否失败
+	rand *rand.Rand
+}
+
+// NewFaultInjector 创建不注入任何故障的故障注入器
+func NewFaultInjector() *FaultInjector {
+	return &FaultInjector{
+		rand: rand.New(rand.NewSource(time.Now().UnixNano())),
+	}
+}
+
+// FailWrites 接下来的n次写入失败，写入的内容不会落盘，err为nil时返回errorx.ErrInjectedFault
+func (fi *FaultInjector) FailWrites(n int, err error) {
+	if err == nil {
+		err = errorx.ErrInjectedFault
+	}
+
+	fi.lock.Lock()
+	defer fi.lock.Unlock()
+
+	fi.failWrites = n
+	fi.writeErr = err
+}
+
+// DelayCompress 每次压缩之前等待d，d<=0时取消延迟
+func (fi *FaultInjector) DelayCompress(d time.Duration) {
+	fi.lock.Lock()
+	defer fi.lock.Unlock()
+
+	fi.compressDelay = d
+}
+
+// FailCompress 压缩以probability的概率失败，返回errorx.ErrInjectedFault，源文件保留，
+// probability的取值范围为[0, 1]
+func (fi *FaultInjector) FailCompress(probability float64) {
+	fi.lock.Lock()
+	defer fi.lock.Unlock()
+
+	fi.compressFailRatio = probability
+}
+
+// FailUploads 上传以probability的概率失败，返回errorx.ErrInjectedFault，上传器不会被调用，
+// 本地文件保留并发送EventUploadFailed事件，probability的取值范围为[0, 1]
+func (fi *FaultInjector) FailUploads(probability float64) {
+	fi.lock.Lock()
+	defer fi.lock.Unlock()
+
+	fi.uploadFailRatio = probability
+}
+
+// Reset 取消所有注入的故障
+func (fi *FaultInjector) Reset() {
+	fi.lock.Lock()
+	defer fi.lock.Unlock()
+
+	fi.failWrites = 0
+	fi.writeErr = nil
+	fi.compressDelay = 0
+	fi.compressFailRatio = 0
+	fi.uploadFailRatio = 0
+}
+
+// writeFault 返回本次写入需要注入的错误
+func (fi *FaultInjector) writeFault() error {
+	if fi == nil {
+		return nil
+	}
+
+	fi.lock.Lock()
+	defer fi.lock.Unlock()
+
+	if fi.failWrites <= 0 {
+		return nil
+	}
+	fi.failWrites--
+	return fi.writeErr
+}
+
+// compressFault 执行注入的压缩延迟，返回本次压缩需要注入的错误
+func (fi *FaultInjector) compressFault() error {
+	if fi == nil {
+		return nil
+	}
+
+	fi.lock.Lock()
+	delay := fi.compressDelay
+	fail := fi.compressFailRatio > 0 && fi.rand.Float64() < fi.compressFailRatio
+	fi.lock.Unlock()
+
+	if delay > 0 {
+		time.Sleep(delay)
+	}
+	if fail {
+		return errorx.ErrInjectedFault
+	}
+
+	return nil
+}
+
+// uploadFault 返回本次上传需要注入的错误
+func (fi *FaultInjector) uploadFault() error {
+	if fi == nil {
+		return nil
+	}
+
+	fi.lock.Lock()
+	defer fi.lock.Unlock()
+
+	if fi.uploadFailRatio > 0 && fi.rand.Float64() < fi.uploadFailRatio {
+		return errorx.ErrInjectedFault
+	}
+
+	return nil
+}
+
+// WithFaultInjector 设置故障注入器，只应该在测试和预发环境中使用
+func WithFaultInjector(fi *FaultInjector) Option {
+	return func(r *Rotator) error {
+		r.faults = fi
+		return nil
+	}
+}
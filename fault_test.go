@@ -0,0 +1,134 @@
+// Copyright 2025 TimeWtr
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package vortexrotate
+
+import (
+	"context"
+	"os"
+	"slices"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/TimeWtr/vortexrotate/errorx"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestFaultInjector_Write(t *testing.T) {
+	fi := NewFaultInjector()
+	rotator, err := newRotator(t.TempDir(), "testdata.log", WithFaultInjector(fi))
+	assert.NoError(t, err)
+	defer rotator.Close()
+
+	fi.FailWrites(2, nil)
+	for i := 0; i < 2; i++ {
+		_, err = rotator.Write([]byte("lost\n"))
+		assert.ErrorIs(t, err, errorx.ErrInjectedFault)
+	}
+	_, err = rotator.Write([]byte("kept\n"))
+	assert.NoError(t, err)
+
+	bs, err := os.ReadFile(rotator.f.Name())
+	assert.NoError(t, err)
+	assert.Equal(t, "kept\n", string(bs))
+}
+
+func TestFaultInjector_Compress(t *testing.T) {
+	fi := NewFaultInjector()
+	rotator, err := newRotator(t.TempDir(), "testdata.log",
+		WithCompress(CompressTypeGzip), WithFaultInjector(fi))
+	assert.NoError(t, err)
+	defer rotator.Close()
+
+	// 压缩失败时保留源文件，下一次写入时恢复
+	fi.FailCompress(1)
+	_, err = rotator.Write([]byte("line\n"))
+	assert.NoError(t, err)
+	path := rotator.f.Name()
+	err = rotator.Rotate()
+	assert.ErrorIs(t, err, errorx.ErrInjectedFault)
+	assert.True(t, errorx.IsCompressFailed(err))
+	assert.FileExists(t, path)
+	assert.NoFileExists(t, compressFn(path, CompressTypeGzip))
+
+	fi.Reset()
+	_, err = rotator.Write([]byte("line\n"))
+	assert.NoError(t, err)
+	assert.Equal(t, uint64(1), rotator.Stats().Rotations[RotateReasonErrorRecovery])
+
+	const delay = 50 * time.Millisecond
+	fi.DelayCompress(delay)
+	path = rotator.f.Name()
+	begin := time.Now()
+	assert.NoError(t, rotator.Rotate())
+	assert.GreaterOrEqual(t, time.Since(begin), delay)
+	assert.FileExists(t, compressFn(path, CompressTypeGzip))
+}
+
+func TestFaultInjector_Upload(t *testing.T) {
+	registerTestBackends(t)
+
+	fi := NewFaultInjector()
+	var (
+		lock   sync.Mutex
+		events []Event
+	)
+	failures := func() []Event {
+		lock.Lock()
+		defer lock.Unlock()
+		return slices.Clone(events)
+	}
+	rotator, err := newRotator(t.TempDir(), "testdata.log",
+		WithCompress(CompressTypeGzip), WithUploader("test-mem", nil), WithFaultInjector(fi),
+		WithEventHandler(func(e Event) {
+			if e.Type == EventUploadFailed {
+				lock.Lock()
+				events = append(events, e)
+				lock.Unlock()
+			}
+		}))
+	assert.NoError(t, err)
+	defer rotator.Close()
+	rotator.uploadBackoff = time.Millisecond
+
+	// 全部重试失败时保留本地文件和.upload标记并发送事件，上传器不会收到文件
+	fi.FailUploads(1)
+	_, err = rotator.Write([]byte("line\n"))
+	assert.NoError(t, err)
+	path := compressFn(rotator.f.Name(), CompressTypeGzip)
+	assert.NoError(t, rotator.Rotate())
+	assert.Eventually(t, func() bool {
+		return len(failures()) == 1
+	}, 5*time.Second, 10*time.Millisecond)
+	assert.NoError(t, rotator.awaitUploads(context.Background()))
+	assert.FileExists(t, path)
+	assert.FileExists(t, path+UploadPendingExt)
+	assert.ErrorIs(t, failures()[0].Err, errorx.ErrInjectedFault)
+	assert.True(t, errorx.IsUploadFailed(failures()[0].Err))
+	assert.Equal(t, uint64(1), rotator.Stats().UploadFailures)
+	testUploader.lock.Lock()
+	assert.NotContains(t, testUploader.paths, path)
+	testUploader.lock.Unlock()
+
+	// 恢复之后reconcile重新上传并删除.upload标记
+	fi.Reset()
+	rotator.retryUploads()
+	assert.NoError(t, rotator.awaitUploads(context.Background()))
+	assert.NoFileExists(t, path+UploadPendingExt)
+	testUploader.lock.Lock()
+	assert.Contains(t, testUploader.paths, path)
+	testUploader.lock.Unlock()
+	assert.Len(t, failures(), 1)
+}
@@ -0,0 +1,67 @@
+// Copyright 2025 TimeWtr
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package vortexrotate
+
+import (
+	"slices"
+	"sync"
+)
+
+// Feature 当前构建包含的可选功能，依赖较重(比如cgo)的实现放在单独的模块中，导入之后在init中注册，
+// 测试用的实现放在带构建标签的文件中，核心的轮转库不依赖这些实现，使用方可以在运行时查询当前构建包含的功能
+type Feature string
+
+const (
+	// FeatureZstdCgo 基于cgo的zstd实现，开启cgo并且导入zstdcgo模块时可用
+	FeatureZstdCgo Feature = "zstd-cgo"
+	// FeatureTestHooks 内部的同步点，使用vortextest构建标签时可用
+	FeatureTestHooks Feature = "test-hooks"
+)
+
+var (
+	featuresLock sync.RWMutex
+	features     = make(map[Feature]struct{})
+)
+
+// registerFeature 注册当前构建中可用的功能，在带构建标签的文件的init或者实现的注册函数中调用
+func registerFeature(f Feature) {
+	featuresLock.Lock()
+	defer featuresLock.Unlock()
+
+	features[f] = struct{}{}
+}
+
+// HasFeature 判断当前构建是否包含功能f
+func HasFeature(f Feature) bool {
+	featuresLock.RLock()
+	defer featuresLock.RUnlock()
+
+	_, ok := features[f]
+	return ok
+}
+
+// Features 返回当前构建包含的所有可选功能，按照名称排序
+func Features() []Feature {
+	featuresLock.RLock()
+	defer featuresLock.RUnlock()
+
+	res := make([]Feature, 0, len(features))
+	for f := range features {
+		res = append(res, f)
+	}
+	slices.Sort(res)
+
+	return res
+}
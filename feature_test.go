@@ -0,0 +1,37 @@
+// Copyright 2025 TimeWtr
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package vortexrotate
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestFeatures(t *testing.T) {
+	assert.Equal(t, zstdCodecOf(ZstdBackendDefault) != pureZstd{}, HasFeature(FeatureZstdCgo))
+	assert.False(t, HasFeature("not-exist"))
+
+	fs := Features()
+	for _, f := range fs {
+		assert.True(t, HasFeature(f))
+	}
+	assert.IsNonDecreasing(t, fs)
+
+	if !HasFeature(FeatureZstdCgo) {
+		_, err := newRotator(t.TempDir(), "testdata.log", WithZstdBackend(ZstdBackendCgo))
+		assert.ErrorContains(t, err, string(FeatureZstdCgo))
+	}
+}
@@ -0,0 +1,42 @@
+// Copyright 2025 TimeWtr
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+//go:build !unix
+
+package vortexrotate
+
+import "os"
+
+// openNoFollow 非unix系统不支持O_NOFOLLOW，通过Lstat检查符号链接
+const openNoFollow = 0
+
+// checkWorldWritable 非unix系统的权限模型不同，不做检查
+func checkWorldWritable(_ string) error {
+	return nil
+}
+
+// syncDir 非unix系统不支持fsync目录
+func syncDir(_ string) error {
+	return nil
+}
+
+// flock 非unix系统不支持建议锁
+func flock(_ *os.File, _ bool) error {
+	return nil
+}
+
+// funlock 非unix系统不支持建议锁
+func funlock(_ *os.File) error {
+	return nil
+}
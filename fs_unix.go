@@ -0,0 +1,82 @@
+// Copyright 2025 TimeWtr
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+//go:build unix
+
+package vortexrotate
+
+import (
+	"errors"
+	"os"
+	"syscall"
+
+	"github.com/TimeWtr/vortexrotate/errorx"
+)
+
+// openNoFollow 打开文件时不跟随符号链接，路径的最后一级是符号链接时返回ELOOP
+const openNoFollow = syscall.O_NOFOLLOW
+
+// checkWorldWritable 检查目录是否对所有用户可写，共享目录中的其他用户可以通过预先创建
+// 符号链接的方式，诱导以root运行的服务覆盖任意文件，设置了粘滞位的目录除外
+func checkWorldWritable(dir string) error {
+	info, err := os.Stat(dir)
+	if err != nil {
+		return err
+	}
+
+	// 设置了粘滞位的目录(比如/tmp)中其他用户不能删除或者重命名不属于自己的文件，轮转文件通过
+	// O_EXCL|O_NOFOLLOW创建，预先创建的符号链接不会被跟随
+	if info.Mode().Perm()&0o002 != 0 && info.Mode()&os.ModeSticky == 0 {
+		return errorx.ErrDirWorldWritable
+	}
+
+	return nil
+}
+
+// syncDir fsync目录，保证目录中文件的创建、rename和删除持久化，不支持fsync目录的文件系统忽略
+func syncDir(dir string) error {
+	d, err := os.Open(dir)
+	if err != nil {
+		return err
+	}
+	defer func() {
+		_ = d.Close()
+	}()
+
+	if err = d.Sync(); err != nil && !errors.Is(err, syscall.EINVAL) && !errors.Is(err, syscall.ENOTSUP) {
+		return err
+	}
+
+	return nil
+}
+
+// flock 对文件加建议锁，exclusive为true时加排他锁，否则加共享锁，锁被占用时阻塞等待
+func flock(f *os.File, exclusive bool) error {
+	how := syscall.LOCK_SH
+	if exclusive {
+		how = syscall.LOCK_EX
+	}
+
+	for {
+		err := syscall.Flock(int(f.Fd()), how)
+		if err != syscall.EINTR {
+			return err
+		}
+	}
+}
+
+// funlock 释放文件的建议锁
+func funlock(f *os.File) error {
+	return syscall.Flock(int(f.Fd()), syscall.LOCK_UN)
+}
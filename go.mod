@@ -4,9 +4,11 @@ go 1.23.4
 
 require (
 	github.com/golang/snappy v1.0.0
+	github.com/klauspost/compress v1.18.0
+	github.com/klauspost/pgzip v1.2.6
 	github.com/robfig/cron/v3 v3.0.1
 	github.com/stretchr/testify v1.10.0
-	github.com/valyala/gozstd v1.21.2
+	github.com/ulikunitz/xz v0.5.15
 	golang.org/x/sync v0.14.0
 )
 
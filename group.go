@@ -0,0 +1,196 @@
+// Copyright 2025 TimeWtr
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package vortexrotate
+
+import (
+	"errors"
+	"fmt"
+	"path/filepath"
+	"sync"
+
+	"github.com/TimeWtr/vortexrotate/errorx"
+)
+
+// RotatorGroup 一组相关文件的轮转器，比如：app.log、err.log和audit.log，同一组的文件共享
+// 写入锁和序列号，任意一个文件触发轮转(大小、行数、定时或者手动)时所有文件在同一时刻一起轮转，
+// 使用相同的序列号，保证同一组文件的轮转文件一一对应，方便离线关联分析。
+type RotatorGroup struct {
+	// 分组名称
+	name string
+	// 所有成员，按照创建的顺序排列
+	members []*Rotator
+	// 文件名称 -> 成员
+	byName map[string]*Rotator
+	// 共享的写入锁
+	lock *sync.RWMutex
+	// 共享的序列号
+	seq *sequence
+	// 当前使用的序列号，必须持有写入锁
+	cur uint32
+}
+
+// NewRotatorGroup 创建轮转分组，group为分组名称，序列号持久化在dir/group.seq文件中，filenames
+// 为分组中的文件名称，格式与NewRotator一致，不能包含重复的基础名称，opts应用到所有的成员。
+// 分组中的成员不会执行自动修复，重新分配序列号会破坏文件之间的对应关系。
+func NewRotatorGroup(dir, group string, filenames []string, opts ...Option) (*RotatorGroup, error) {
+	if len(filenames) == 0 {
+		return nil, errors.New("rotator group must contain at least one file")
+	}
+
+	dir, err := normalizeDir(dir)
+	if err != nil {
+		return nil, err
+	}
+
+	names := make([]string, 0, len(filenames))
+	seen := make(map[string]struct{}, len(filenames))
+	for _, fn := range filenames {
+		name, _, err1 := splitFilename(fn)
+		if err1 != nil {
+			return nil, err1
+		}
+		if _, ok := seen[name]; ok {
+			return nil, fmt.Errorf("duplicate file name %s in rotator group", name)
+		}
+		seen[name] = struct{}{}
+		names = append(names, name)
+	}
+	if _, ok := seen[group]; ok {
+		return nil, fmt.Errorf("group name %s conflicts with file name", group)
+	}
+
+	seq, err := newGroupSequence(dir, group, names)
+	if err != nil {
+		return nil, err
+	}
+	cur, err := seq.Next()
+	if err != nil {
+		return nil, err
+	}
+
+	g := &RotatorGroup{
+		name:   group,
+		byName: make(map[string]*Rotator, len(filenames)),
+		lock:   &sync.RWMutex{},
+		seq:    seq,
+		cur:    cur,
+	}
+	for _, fn := range filenames {
+		member, err1 := newRotator(dir, fn, append(opts[:len(opts):len(opts)], withGroup(g))...)
+		if err1 != nil {
+			_ = g.Close()
+			return nil, err1
+		}
+		g.members = append(g.members, member)
+		g.byName[fn] = member
+	}
+
+	return g, nil
+}
+
+// withGroup 将轮转器加入分组
+func withGroup(g *RotatorGroup) Option {
+	return func(r *Rotator) error {
+		r.group = g
+		r.writeLock = g.lock
+		r.autoRepair = false
+		return nil
+	}
+}
+
+// Member 获取分组中指定文件名称的轮转器，用于写入，不存在时返回nil，成员不能单独关闭，
+// 需要通过RotatorGroup.Close统一关闭
+func (g *RotatorGroup) Member(filename string) *Rotator {
+	return g.byName[filename]
+}
+
+// Rotate 立即轮转分组中的所有文件
+func (g *RotatorGroup) Rotate() error {
+	g.lock.Lock()
+	defer g.lock.Unlock()
+
+	for _, m := range g.members {
+		if m.sig.Load() == 1 {
+			return errorx.ErrRotateClosed
+		}
+	}
+
+	return g.rotateLocked(RotateReasonManual)
+}
+
+// Close 关闭分组中的所有轮转器
+func (g *RotatorGroup) Close() error {
+	errs := make([]error, 0, len(g.members))
+	for _, m := range g.members {
+		errs = append(errs, m.Close())
+	}
+
+	return errors.Join(errs...)
+}
+
+// rotateLocked 使用新的序列号轮转分组中的所有文件，必须持有写入锁
+func (g *RotatorGroup) rotateLocked(reason RotateReason) error {
+	seq, err := g.seq.Next()
+	if err != nil {
+		return err
+	}
+	g.cur = seq
+
+	errs := make([]error, 0, len(g.members))
+	for _, m := range g.members {
+		if m.f == nil {
+			// 已经关闭
+			continue
+		}
+
+		errs = append(errs, m.rotateFile(reason))
+		m.resetStrategy()
+	}
+
+	return errors.Join(errs...)
+}
+
+// nextSeq 分配新文件的序列号，分组轮转时使用分组当前的序列号
+func (r *Rotator) nextSeq() (uint32, error) {
+	if r.group != nil {
+		return r.group.cur, nil
+	}
+
+	return r.seq.Next()
+}
+
+// newGroupSequence 创建分组共享的序列号生成器，序列号文件缺失或者损坏时扫描所有成员的轮转文件
+func newGroupSequence(dir, group string, names []string) (*sequence, error) {
+	s := &sequence{
+		dir:      dir,
+		filename: group,
+		path:     filepath.Join(dir, group+SeqFileExt),
+	}
+
+	next, err := s.load()
+	if err != nil {
+		next = 1
+		for _, name := range names {
+			n, err1 := (&sequence{dir: dir, filename: name}).rescan()
+			if err1 != nil {
+				return nil, err1
+			}
+			next = max(next, n)
+		}
+	}
+	s.next = next
+
+	return s, nil
+}
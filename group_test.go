@@ -0,0 +1,66 @@
+// Copyright 2025 TimeWtr
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package vortexrotate
+
+import (
+	"fmt"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestRotatorGroup(t *testing.T) {
+	dir := t.TempDir()
+	group, err := NewRotatorGroup(dir, "app",
+		[]string{"access.log", "error.log", "audit.log"}, WithMaxLines(2))
+	assert.NoError(t, err)
+
+	access := group.Member("access.log")
+	errLog := group.Member("error.log")
+	audit := group.Member("audit.log")
+	assert.Nil(t, group.Member("unknown.log"))
+
+	// access.log达到行数限制时，所有文件一起轮转
+	for i := 0; i < 3; i++ {
+		_, err = access.Write([]byte("access\n"))
+		assert.NoError(t, err)
+	}
+	_, err = errLog.Write([]byte("error\n"))
+	assert.NoError(t, err)
+	assert.NoError(t, group.Rotate())
+	_, err = audit.Write([]byte("audit\n"))
+	assert.NoError(t, err)
+	assert.NoError(t, group.Close())
+
+	date := time.Now().Format(Layout)
+	for _, name := range []string{"access", "error", "audit"} {
+		for seq := 1; seq <= 3; seq++ {
+			assert.FileExists(t, filepath.Join(dir, date, fmt.Sprintf("%s_%s_%04d.log", name, date, seq)))
+		}
+	}
+	assert.Equal(t, uint64(1), errLog.Stats().Rotations[RotateReasonLines])
+	assert.Equal(t, uint64(1), audit.Stats().Rotations[RotateReasonManual])
+
+	// 重新创建分组时从持久化的序列号继续分配
+	group, err = NewRotatorGroup(dir, "app", []string{"access.log", "error.log"})
+	assert.NoError(t, err)
+	assert.Equal(t, uint32(4), group.cur)
+	assert.NoError(t, group.Close())
+
+	_, err = NewRotatorGroup(dir, "app", []string{"access.log", "access.err"})
+	assert.Error(t, err)
+}
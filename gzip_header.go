@@ -0,0 +1,107 @@
+// Copyright 2025 TimeWtr
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package vortexrotate
+
+import (
+	"compress/gzip"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"time"
+	"unicode/utf8"
+
+	"github.com/klauspost/pgzip"
+)
+
+// gzipOSUnknown gzip文件头中的操作系统类型，与标准库的默认值一致
+const gzipOSUnknown = 255
+
+// WithGzipComment gzip压缩时在文件头中写入注释，comment为空时写入"host=<主机名> app=<基础文件名称>"，
+// 注释只能包含Latin-1字符。无论是否设置该选项，gzip文件头中都会写入原始的文件名称(不包括压缩后缀)
+// 和修改时间，下游工具(gzip -N、zcat -l等)可以据此还原文件名称和时间戳，与WithCompress的顺序无关。
+func WithGzipComment(comment string) Option {
+	return func(r *Rotator) error {
+		if comment == "" {
+			host, err := os.Hostname()
+			if err != nil {
+				return fmt.Errorf("get hostname for gzip comment error: %w", err)
+			}
+			comment = fmt.Sprintf("host=%s app=%s", host, r.filename)
+		}
+		if !isLatin1(comment) {
+			return errors.New("gzip comment must contain only Latin-1 characters")
+		}
+
+		r.cpr.gzipComment = comment
+		return nil
+	}
+}
+
+// gzipHeaderOf 生成gzip文件头，name为原始文件的路径，只保留文件名称，gzip文件头只支持Latin-1
+// 字符，文件名称中包含其他字符时不写入文件名称
+func gzipHeaderOf(name string, mtime time.Time, comment string) gzip.Header {
+	h := gzip.Header{
+		ModTime: mtime,
+		Comment: comment,
+		OS:      gzipOSUnknown,
+	}
+	if name = filepath.Base(trimCompressExt(name)); isLatin1(name) {
+		h.Name = name
+	}
+
+	return h
+}
+
+// setGzipHeader 设置gzip写入器的文件头，必须在写入任何数据之前调用，其他类型的写入器直接忽略
+func setGzipHeader(w any, h gzip.Header) {
+	switch w := w.(type) {
+	case *gzip.Writer:
+		w.Header = h
+	case *pgzip.Writer:
+		w.Header = pgzip.Header{
+			Comment: h.Comment,
+			Extra:   h.Extra,
+			ModTime: h.ModTime,
+			Name:    h.Name,
+			OS:      h.OS,
+		}
+	case *Gzip:
+		setGzipHeader(w.w, h)
+	case *Pgzip:
+		setGzipHeader(w.w, h)
+	}
+}
+
+// setSealHeader 封存时根据源文件设置gzip文件头，获取源文件信息失败时使用当前时间
+func (r *Rotator) setSealHeader(cs CompressStrategy, f *os.File) {
+	mtime := time.Now()
+	if info, err := f.Stat(); err == nil {
+		mtime = info.ModTime()
+	}
+
+	setGzipHeader(cs, gzipHeaderOf(f.Name(), mtime, r.cpr.gzipComment))
+}
+
+// isLatin1 字符串是否只包含Latin-1字符
+func isLatin1(s string) bool {
+	for _, c := range s {
+		if c == utf8.RuneError || c > 0xff {
+			return false
+		}
+	}
+
+	return true
+}
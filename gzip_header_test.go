@@ -0,0 +1,110 @@
+// Copyright 2025 TimeWtr
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package vortexrotate
+
+import (
+	"compress/gzip"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// readGzipHeader 读取gzip文件的文件头
+func readGzipHeader(t *testing.T, path string) gzip.Header {
+	f, err := os.Open(path)
+	assert.NoError(t, err)
+	defer f.Close()
+
+	gr, err := gzip.NewReader(f)
+	assert.NoError(t, err)
+	defer gr.Close()
+
+	return gr.Header
+}
+
+func TestRotator_GzipHeader(t *testing.T) {
+	for _, workers := range []int{0, 2} {
+		t.Run(fmt.Sprintf("workers-%d", workers), func(t *testing.T) {
+			rotator, err := NewRotator(t.TempDir(), "testdata.log",
+				WithGzipComment("host=web-01 app=billing"),
+				WithCompressOptions(CompressTypeGzip, CompressOptions{Gzip: GzipOptions{Workers: workers}}),
+				WithSealOnClose())
+			assert.NoError(t, err)
+
+			_, err = rotator.Write([]byte("hello\n"))
+			assert.NoError(t, err)
+			path := rotator.f.Name()
+			info, err := os.Stat(path)
+			assert.NoError(t, err)
+			assert.NoError(t, rotator.Close())
+
+			h := readGzipHeader(t, compressFn(path, CompressTypeGzip))
+			assert.Equal(t, filepath.Base(path), h.Name)
+			assert.Equal(t, "host=web-01 app=billing", h.Comment)
+			assert.Equal(t, info.ModTime().Unix(), h.ModTime.Unix())
+		})
+	}
+}
+
+func TestRotator_GzipHeaderCow(t *testing.T) {
+	rotator, err := NewRotator(t.TempDir(), "testdata.log",
+		WithCompressOnWrite(CompressTypeGzip), WithGzipComment(""))
+	assert.NoError(t, err)
+
+	_, err = rotator.Write([]byte("hello\n"))
+	assert.NoError(t, err)
+	path := rotator.f.Name()
+	assert.NoError(t, rotator.Close())
+
+	host, err := os.Hostname()
+	assert.NoError(t, err)
+	h := readGzipHeader(t, path)
+	assert.Equal(t, strings.TrimSuffix(filepath.Base(path), ".gz"), h.Name)
+	assert.Equal(t, fmt.Sprintf("host=%s app=testdata", host), h.Comment)
+	assert.False(t, h.ModTime.IsZero())
+}
+
+func TestGzipHeaderOf(t *testing.T) {
+	now := time.Now()
+	h := gzipHeaderOf("/var/log/app_20250101_0001.log.gz", now, "")
+	assert.Equal(t, "app_20250101_0001.log", h.Name)
+	assert.Equal(t, now, h.ModTime)
+
+	// gzip文件头只支持Latin-1字符
+	h = gzipHeaderOf("/var/log/日志_20250101_0001.log", now, "")
+	assert.Empty(t, h.Name)
+
+	_, err := NewRotator(t.TempDir(), "testdata.log", WithGzipComment("主机"))
+	assert.Error(t, err)
+}
+
+func TestRecompress_GzipHeader(t *testing.T) {
+	dir := t.TempDir()
+	src := filepath.Join(dir, "testdata_20250101_0001.log.zst")
+	mtime := time.Now().Add(-time.Hour)
+	assert.NoError(t, writeCompressed(src, CompressTypeZstd, ZstdDefaultLevel, strings.NewReader("hello\n"), mtime))
+	assert.NoError(t, os.Chtimes(src, mtime, mtime))
+
+	dst, err := recompressFile(src, CompressTypeGzip, GzipDefaultCompression, renameLocal)
+	assert.NoError(t, err)
+	h := readGzipHeader(t, dst)
+	assert.Equal(t, "testdata_20250101_0001.log", h.Name)
+	assert.Equal(t, mtime.Unix(), h.ModTime.Unix())
+}
@@ -0,0 +1,38 @@
+// Copyright 2025 TimeWtr
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package vortexrotate
+
+// hookPoint 内部的同步点，只有使用vortextest构建标签编译时才可以注册回调，用于在测试中确定性地
+// 交错执行写入、轮转、压缩和清理，正常编译时hook为空函数，没有额外的开销
+type hookPoint int
+
+const (
+	// hookBeforeWrite 获取写锁之后，写入文件之前
+	hookBeforeWrite hookPoint = iota + 1
+	// hookBeforeRotate 持有写锁，关闭旧文件之前
+	hookBeforeRotate
+	// hookAfterRotate 持有写锁，新文件打开之后
+	hookAfterRotate
+	// hookBeforeCompress 封存过程中压缩文件之前
+	hookBeforeCompress
+	// hookAfterCompress 封存过程中压缩文件之后
+	hookAfterCompress
+	// hookBeforeCleanup 清理任务开始之前
+	hookBeforeCleanup
+	// hookBeforeRemove 清理任务删除每一个文件之前
+	hookBeforeRemove
+	// hookAfterCleanup 清理任务完成之后
+	hookAfterCleanup
+)
@@ -0,0 +1,75 @@
+// Copyright 2025 TimeWtr
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+//go:build vortextest
+
+package vortexrotate
+
+import "sync"
+
+// HookPoint 内部的同步点，只在使用vortextest构建标签编译时导出，用于编写并发问题的回归测试：
+//
+//	go test -tags vortextest ./...
+type HookPoint = hookPoint
+
+const (
+	HookBeforeWrite    = hookBeforeWrite
+	HookBeforeRotate   = hookBeforeRotate
+	HookAfterRotate    = hookAfterRotate
+	HookBeforeCompress = hookBeforeCompress
+	HookAfterCompress  = hookAfterCompress
+	HookBeforeCleanup  = hookBeforeCleanup
+	HookBeforeRemove   = hookBeforeRemove
+	HookAfterCleanup   = hookAfterCleanup
+)
+
+var (
+	hooksLock sync.RWMutex
+	hooks     = make(map[hookPoint]func(path string))
+)
+
+func init() {
+	registerFeature(FeatureTestHooks)
+}
+
+// SetHook 注册同步点的回调，path为同步点相关的文件路径，回调在同步点所在的goroutine中同步执行，
+// 可以通过阻塞回调来控制执行的交错顺序，fn为nil时删除回调
+func SetHook(point HookPoint, fn func(path string)) {
+	hooksLock.Lock()
+	defer hooksLock.Unlock()
+
+	if fn == nil {
+		delete(hooks, point)
+		return
+	}
+	hooks[point] = fn
+}
+
+// ResetHooks 删除所有同步点的回调
+func ResetHooks() {
+	hooksLock.Lock()
+	defer hooksLock.Unlock()
+
+	hooks = make(map[hookPoint]func(path string))
+}
+
+func hook(point hookPoint, path string) {
+	hooksLock.RLock()
+	fn := hooks[point]
+	hooksLock.RUnlock()
+
+	if fn != nil {
+		fn(path)
+	}
+}
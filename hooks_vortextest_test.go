@@ -0,0 +1,130 @@
+// Copyright 2025 TimeWtr
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+//go:build vortextest
+
+package vortexrotate
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"sync"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// TestHooks_CleanupDuringSeal 封存(压缩)过程中执行清理，不能删除正在压缩的文件
+func TestHooks_CleanupDuringSeal(t *testing.T) {
+	defer ResetHooks()
+
+	dir := t.TempDir()
+	rotator, err := newRotator(dir, "testdata.log",
+		WithCompress(CompressTypeGzip),
+		WithMaxCount(1))
+	assert.NoError(t, err)
+	defer rotator.Close()
+
+	sealing := make(chan string)
+	resume := make(chan struct{})
+	SetHook(HookBeforeCompress, func(path string) {
+		sealing <- path
+		<-resume
+	})
+
+	_, err = rotator.Write([]byte("line 1\n"))
+	assert.NoError(t, err)
+
+	var wg sync.WaitGroup
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		assert.NoError(t, rotator.Rotate())
+	}()
+
+	path := <-sealing
+	var removed []string
+	SetHook(HookBeforeRemove, func(path string) {
+		removed = append(removed, path)
+	})
+	rotator.cleanup.cleanExpiredFiles()
+	assert.NotContains(t, removed, path)
+	_, err = os.Stat(path)
+	assert.NoError(t, err)
+
+	close(resume)
+	wg.Wait()
+	_, err = os.Stat(compressFn(path, CompressTypeGzip))
+	assert.NoError(t, err)
+}
+
+// TestHooks_WriteOrder 同步点按照写入、轮转的顺序触发
+func TestHooks_WriteOrder(t *testing.T) {
+	defer ResetHooks()
+
+	var events []string
+	for _, point := range []HookPoint{HookBeforeWrite, HookBeforeRotate, HookAfterRotate} {
+		SetHook(point, func(string) {
+			events = append(events, fmt.Sprintf("%d", point))
+		})
+	}
+
+	rotator, err := newRotator(t.TempDir(), "testdata.log")
+	assert.NoError(t, err)
+	_, err = rotator.Write([]byte("line\n"))
+	assert.NoError(t, err)
+	assert.NoError(t, rotator.Rotate())
+	assert.NoError(t, rotator.Close())
+
+	assert.Equal(t, []string{
+		fmt.Sprintf("%d", HookBeforeWrite),
+		fmt.Sprintf("%d", HookBeforeRotate),
+		fmt.Sprintf("%d", HookAfterRotate),
+	}, events)
+}
+
+// TestHooks_TombstoneBeforeRemove 删除文件之前墓碑已经写入清单
+func TestHooks_TombstoneBeforeRemove(t *testing.T) {
+	defer ResetHooks()
+
+	dir := t.TempDir()
+	rotator, err := newRotator(dir, "testdata.log",
+		WithRotate(64, _Second),
+		WithMaxCount(2),
+		WithTombstones(0))
+	assert.NoError(t, err)
+	defer rotator.Close()
+
+	for i := 0; i < 10; i++ {
+		_, err = rotator.Write([]byte(fmt.Sprintf("line %02d, rotate by size\n", i)))
+		assert.NoError(t, err)
+	}
+
+	var removed, missing []string
+	SetHook(HookBeforeRemove, func(path string) {
+		removed = append(removed, path)
+		ts, err := Tombstones(dir, "testdata.log")
+		assert.NoError(t, err)
+		for _, tomb := range ts {
+			if tomb.Name == filepath.Base(path) {
+				return
+			}
+		}
+		missing = append(missing, path)
+	})
+	assert.NoError(t, rotator.CleanNow())
+	assert.NotEmpty(t, removed)
+	assert.Empty(t, missing)
+}
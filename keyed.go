@@ -0,0 +1,482 @@
+// Copyright 2025 TimeWtr
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package vortexrotate
+
+import (
+	"errors"
+	"fmt"
+	"log"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/TimeWtr/vortexrotate/errorx"
+)
+
+// labelValueRegexp 标签值只能包含字母、数字、'.'、'_'和'-'，防止路径穿越
+var labelValueRegexp = regexp.MustCompile(`^[A-Za-z0-9._-]+$`)
+
+// placeholderRegexp 目录模板中的占位符，比如：{tenant}
+var placeholderRegexp = regexp.MustCompile(`\{([A-Za-z0-9_]+)\}`)
+
+// EvictionPolicy 所有租户的总大小超过限制时选择删除文件的策略
+type EvictionPolicy int
+
+const (
+	// EvictProportional 按照租户的权重成比例地删除，每次从占用空间与权重之比最大的租户中删除
+	// 最旧的文件，单个租户的写入量暴增时不会挤占其他租户的保存时长
+	EvictProportional EvictionPolicy = iota
+	// EvictOldest 从所有租户中最旧的文件开始删除
+	EvictOldest
+)
+
+// KeyedOption 多租户轮转器的配置
+type KeyedOption func(*KeyedRotator) error
+
+// WithTenantOptions 设置每个租户轮转器的配置，比如压缩、轮转和保存策略，保存策略在每个租户的
+// 目录中单独生效
+func WithTenantOptions(opts ...Option) KeyedOption {
+	return func(k *KeyedRotator) error {
+		k.opts = append(k.opts, opts...)
+		return nil
+	}
+}
+
+// WithGlobalMaxTotalSize 设置所有租户轮转文件的最大总大小，超过限制时按照WithEvictionPolicy
+// 设置的策略删除文件，直到总大小低于限制，每个租户最新的文件不会被删除
+func WithGlobalMaxTotalSize(size int64) KeyedOption {
+	return func(k *KeyedRotator) error {
+		if size <= 0 {
+			return errors.New("global max total size must be greater than 0")
+		}
+
+		k.maxTotalSize = size
+		return nil
+	}
+}
+
+// WithEvictionPolicy 设置所有租户的总大小超过限制时选择删除文件的策略，默认为EvictProportional
+func WithEvictionPolicy(policy EvictionPolicy) KeyedOption {
+	return func(k *KeyedRotator) error {
+		if policy != EvictProportional && policy != EvictOldest {
+			return errors.New("unknown eviction policy")
+		}
+
+		k.policy = policy
+		return nil
+	}
+}
+
+// WithTenantWeights 设置租户的权重，键为根据目录模板生成的租户目录(使用'/'分隔)，比如模板为
+// "{tenant}/{region}"时为"a/cn-1"，没有设置权重的租户权重为1，按照EvictProportional删除时
+// 每个租户可以占用的空间与权重成正比
+func WithTenantWeights(weights map[string]float64) KeyedOption {
+	return func(k *KeyedRotator) error {
+		for tenant, w := range weights {
+			if w <= 0 {
+				return fmt.Errorf("weight of tenant %s must be greater than 0", tenant)
+			}
+		}
+
+		k.weights = weights
+		return nil
+	}
+}
+
+// WithGlobalCleanInterval 设置检查所有租户总大小的时间间隔，默认为DefaultCleanInterval
+func WithGlobalCleanInterval(interval time.Duration) KeyedOption {
+	return func(k *KeyedRotator) error {
+		if interval <= 0 {
+			return errors.New("global clean interval must be greater than 0")
+		}
+
+		k.interval = interval
+		return nil
+	}
+}
+
+// WithKeyedEventHandler 设置多租户轮转器的事件处理函数，后台检查总大小失败时发送EventCleanupError
+// 事件，未设置时事件输出到日志中。租户轮转器的事件处理函数通过WithTenantOptions等租户配置设置。
+func WithKeyedEventHandler(h EventHandler) KeyedOption {
+	return func(k *KeyedRotator) error {
+		k.eventHandler = h
+		return nil
+	}
+}
+
+// KeyedRotator 多租户轮转器，按照标签值将不同租户的日志写入不同的目录，目录布局由模板决定，
+// 比如模板为"{tenant}"时，租户a的轮转文件位于dir/a/{date}/...，每个租户使用独立的轮转器，
+// 保存策略在每个租户的目录中单独生效，同时可以限制所有租户的总大小。
+type KeyedRotator struct {
+	// 存储的根目录
+	dir string
+	// 目录模板
+	template string
+	// 模板中的标签名称
+	labels []string
+	// 匹配租户目录的正则表达式
+	re *regexp.Regexp
+	// 基础的文件名称
+	filename string
+	// 基础的文件名称，不包括后缀名
+	name string
+	// 租户轮转器的配置
+	opts []Option
+	// 所有租户轮转文件的最大总大小，0表示不限制
+	maxTotalSize int64
+	// 检查总大小的时间间隔
+	interval time.Duration
+	// 总大小超过限制时选择删除文件的策略
+	policy EvictionPolicy
+	// 租户目录 -> 权重
+	weights map[string]float64
+	// 租户目录 -> 轮转器
+	rotators map[string]*Rotator
+	// 加锁保护
+	lock sync.Mutex
+	// 关闭信号
+	sig chan struct{}
+	// 后台定时任务
+	sched *scheduler
+	// 保证只关闭一次
+	closeOnce sync.Once
+	// 保证总大小的清理串行执行
+	running sync.Mutex
+	// 事件处理函数，nil表示输出到日志中
+	eventHandler EventHandler
+	// 日志
+	l *log.Logger
+}
+
+// NewKeyedRotator 创建多租户轮转器，template为相对于dir的目录模板，使用{label}表示标签，比如：
+// "{tenant}"或者"{tenant}/{region}"，filename为基础文件名称，格式与NewRotator一致。
+func NewKeyedRotator(dir, template, filename string, opts ...KeyedOption) (*KeyedRotator, error) {
+	name, _, err := splitFilename(filename)
+	if err != nil {
+		return nil, err
+	}
+
+	dir, err = normalizeDir(dir)
+	if err != nil {
+		return nil, err
+	}
+
+	k := &KeyedRotator{
+		dir:      dir,
+		template: filepath.ToSlash(filepath.Clean(template)),
+		filename: filename,
+		name:     name,
+		interval: DefaultCleanInterval,
+		rotators: make(map[string]*Rotator),
+		sig:      make(chan struct{}),
+		sched:    newScheduler(),
+		l:        log.New(os.Stdout, "", log.LstdFlags),
+	}
+	if err = k.parseTemplate(); err != nil {
+		return nil, err
+	}
+
+	for _, opt := range opts {
+		if err = opt(k); err != nil {
+			return nil, err
+		}
+	}
+
+	if k.maxTotalSize > 0 {
+		if err = k.sched.every("global-limit", k.interval, 0, k.enforceGlobalLimit); err != nil {
+			return nil, err
+		}
+		k.sched.trigger("global-limit")
+		k.sched.start()
+	}
+
+	return k, nil
+}
+
+// parseTemplate 解析目录模板，生成匹配租户目录的正则表达式
+func (k *KeyedRotator) parseTemplate() error {
+	if k.template == "." || k.template == ".." || strings.HasPrefix(k.template, "../") ||
+		filepath.IsAbs(k.template) || strings.ContainsAny(k.template, "*?[") {
+		return fmt.Errorf("invalid dir template %s", k.template)
+	}
+
+	var pattern strings.Builder
+	pattern.WriteString("^")
+	last := 0
+	for _, loc := range placeholderRegexp.FindAllStringSubmatchIndex(k.template, -1) {
+		pattern.WriteString(regexp.QuoteMeta(k.template[last:loc[0]]))
+		pattern.WriteString(`([A-Za-z0-9._-]+)`)
+		k.labels = append(k.labels, k.template[loc[2]:loc[3]])
+		last = loc[1]
+	}
+	pattern.WriteString(regexp.QuoteMeta(k.template[last:]))
+	pattern.WriteString("$")
+
+	if len(k.labels) == 0 {
+		return fmt.Errorf("dir template %s must contain at least one label", k.template)
+	}
+
+	k.re = regexp.MustCompile(pattern.String())
+	return nil
+}
+
+// render 使用标签值生成租户目录，相对于根目录
+func (k *KeyedRotator) render(labels map[string]string) (string, error) {
+	var err error
+	rel := placeholderRegexp.ReplaceAllStringFunc(k.template, func(s string) string {
+		label := s[1 : len(s)-1]
+		v, ok := labels[label]
+		if !ok {
+			err = fmt.Errorf("missing label %s", label)
+			return ""
+		}
+		if !labelValueRegexp.MatchString(v) || v == "." || v == ".." {
+			err = fmt.Errorf("invalid value %q of label %s", v, label)
+			return ""
+		}
+		return v
+	})
+	if err != nil {
+		return "", err
+	}
+
+	return filepath.FromSlash(rel), nil
+}
+
+// Rotator 获取标签值对应的租户轮转器，不存在时创建
+func (k *KeyedRotator) Rotator(labels map[string]string) (*Rotator, error) {
+	rel, err := k.render(labels)
+	if err != nil {
+		return nil, err
+	}
+
+	k.lock.Lock()
+	defer k.lock.Unlock()
+
+	select {
+	case <-k.sig:
+		return nil, errorx.ErrRotateClosed
+	default:
+	}
+
+	if r, ok := k.rotators[rel]; ok {
+		return r, nil
+	}
+
+	r, err := NewRotator(filepath.Join(k.dir, rel), k.filename, k.opts...)
+	if err != nil {
+		return nil, err
+	}
+	k.rotators[rel] = r
+
+	return r, nil
+}
+
+// Write 将内容写入标签值对应的租户轮转器
+func (k *KeyedRotator) Write(labels map[string]string, p []byte) (int, error) {
+	r, err := k.Rotator(labels)
+	if err != nil {
+		return 0, err
+	}
+
+	return r.Write(p)
+}
+
+// Close 停止后台任务并关闭所有租户的轮转器
+func (k *KeyedRotator) Close() error {
+	var errs []error
+	k.closeOnce.Do(func() {
+		close(k.sig)
+		<-k.sched.stop()
+
+		k.lock.Lock()
+		defer k.lock.Unlock()
+		for _, r := range k.rotators {
+			errs = append(errs, r.Close())
+		}
+	})
+
+	return errors.Join(errs...)
+}
+
+// enforceGlobalLimit 定时检查所有租户的总大小，失败时发送事件
+func (k *KeyedRotator) enforceGlobalLimit() {
+	if _, err := k.EnforceGlobalLimit(); err != nil {
+		k.reportError(err)
+	}
+}
+
+// reportError 发送检查总大小或者删除文件失败的事件
+func (k *KeyedRotator) reportError(err error) {
+	e := Event{
+		Type:    EventCleanupError,
+		Time:    time.Now(),
+		Path:    k.dir,
+		Message: fmt.Sprintf("global limit cleanup error: %v", err),
+		Err:     err,
+	}
+	if k.eventHandler == nil {
+		k.l.Printf("[%s] %s", e.Type, e.Message)
+		return
+	}
+
+	k.eventHandler(e)
+}
+
+// tenantFiles 单个租户目录中可以被删除的轮转文件
+type tenantFiles struct {
+	// 租户目录，相对于根目录
+	rel string
+	// 租户目录的清理器，用于删除文件
+	cleaner *CleanUp
+	// 从旧到新排序的文件，不包括最新的文件
+	files []FileInfo
+	// 所有文件的总大小，包括最新的文件
+	size int64
+}
+
+// listTenants 扫描根目录中所有匹配目录模板的租户目录，包括当前进程中没有打开的租户
+func (k *KeyedRotator) listTenants() ([]*tenantFiles, error) {
+	pattern := placeholderRegexp.ReplaceAllString(k.template, "*")
+	dirs, err := filepath.Glob(filepath.Join(k.dir, filepath.FromSlash(pattern)))
+	if err != nil {
+		return nil, err
+	}
+
+	tenants := make([]*tenantFiles, 0, len(dirs))
+	for _, dir := range dirs {
+		rel, err := filepath.Rel(k.dir, dir)
+		if err != nil || !k.re.MatchString(filepath.ToSlash(rel)) {
+			continue
+		}
+		if info, err := os.Lstat(dir); err != nil || !info.IsDir() {
+			continue
+		}
+
+		c := NewFileCountCleanUp(dir, k.name, 0, 0)
+		c.SetErrorHandler(k.reportError)
+		k.lock.Lock()
+		if r, ok := k.rotators[rel]; ok {
+			if r.cleanup != nil {
+				// 使用租户自己的清理器，与租户的清理任务串行执行，同时按照租户的配置记录墓碑
+				c = r.cleanup
+			} else {
+				c.dirLock = r.dirLock
+			}
+		}
+		k.lock.Unlock()
+
+		files, err := c.listFileInfo()
+		if err != nil {
+			return nil, err
+		}
+		c.sortFiles(files)
+
+		t := &tenantFiles{rel: rel, cleaner: c}
+		for _, fi := range files {
+			t.size += fi.Size
+		}
+		if len(files) > 0 {
+			t.files = files[:len(files)-1]
+		}
+		tenants = append(tenants, t)
+	}
+
+	return tenants, nil
+}
+
+// EnforceGlobalLimit 检查所有租户轮转文件的总大小，超过WithGlobalMaxTotalSize的限制时按照
+// 删除策略删除文件，直到总大小低于限制，返回删除的文件
+func (k *KeyedRotator) EnforceGlobalLimit() ([]FileInfo, error) {
+	if k.maxTotalSize <= 0 {
+		return nil, nil
+	}
+
+	k.running.Lock()
+	defer k.running.Unlock()
+
+	tenants, err := k.listTenants()
+	if err != nil {
+		return nil, err
+	}
+
+	var total int64
+	for _, t := range tenants {
+		total += t.size
+	}
+
+	var removed []FileInfo
+	for total > k.maxTotalSize {
+		t := k.pickTenant(tenants)
+		if t == nil {
+			// 所有租户都只剩下最新的文件
+			break
+		}
+
+		fi := t.files[0]
+		t.cleaner.running.Lock()
+		t.cleaner.remove([]FileInfo{fi}, []DeleteReason{DeleteMaxTotalSize})
+		t.cleaner.running.Unlock()
+		t.files = t.files[1:]
+		t.size -= fi.Size
+		total -= fi.Size
+		removed = append(removed, fi)
+	}
+
+	return removed, nil
+}
+
+// pickTenant 根据删除策略选择下一个删除文件的租户，没有可以删除的文件时返回nil
+func (k *KeyedRotator) pickTenant(tenants []*tenantFiles) *tenantFiles {
+	var picked *tenantFiles
+	for _, t := range tenants {
+		if len(t.files) == 0 {
+			continue
+		}
+		if picked == nil {
+			picked = t
+			continue
+		}
+
+		if k.policy == EvictProportional {
+			usage, pickedUsage := float64(t.size)/k.weight(t), float64(picked.size)/k.weight(picked)
+			if usage != pickedUsage {
+				if usage > pickedUsage {
+					picked = t
+				}
+				continue
+			}
+		}
+
+		// 按照最旧的文件选择，占用比例相同时也按照最旧的文件选择
+		if t.files[0].age().Before(picked.files[0].age()) {
+			picked = t
+		}
+	}
+
+	return picked
+}
+
+// weight 租户的权重，没有设置时为1
+func (k *KeyedRotator) weight(t *tenantFiles) float64 {
+	if w, ok := k.weights[filepath.ToSlash(t.rel)]; ok {
+		return w
+	}
+
+	return 1
+}
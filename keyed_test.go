@@ -0,0 +1,214 @@
+// Copyright 2025 TimeWtr
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package vortexrotate
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestKeyedRotator_Render(t *testing.T) {
+	k, err := NewKeyedRotator(t.TempDir(), "tenants/{tenant}/{region}", "app.log")
+	assert.NoError(t, err)
+	defer k.Close()
+	assert.Equal(t, []string{"tenant", "region"}, k.labels)
+
+	rel, err := k.render(map[string]string{"tenant": "a", "region": "cn-1"})
+	assert.NoError(t, err)
+	assert.Equal(t, filepath.Join("tenants", "a", "cn-1"), rel)
+
+	_, err = k.render(map[string]string{"tenant": "a"})
+	assert.Error(t, err)
+	_, err = k.render(map[string]string{"tenant": "..", "region": "cn-1"})
+	assert.Error(t, err)
+	_, err = k.render(map[string]string{"tenant": "a/b", "region": "cn-1"})
+	assert.Error(t, err)
+
+	for _, tmpl := range []string{"tenants", "../{tenant}", "/{tenant}", "{tenant}*"} {
+		_, err = NewKeyedRotator(t.TempDir(), tmpl, "app.log")
+		assert.Error(t, err, tmpl)
+	}
+}
+
+func TestKeyedRotator_Write(t *testing.T) {
+	dir := t.TempDir()
+	k, err := NewKeyedRotator(dir, "{tenant}", "app.log")
+	assert.NoError(t, err)
+
+	for _, tenant := range []string{"a", "b"} {
+		_, err = k.Write(map[string]string{"tenant": tenant}, []byte("hello "+tenant+"\n"))
+		assert.NoError(t, err)
+	}
+	r1, err := k.Rotator(map[string]string{"tenant": "a"})
+	assert.NoError(t, err)
+	r2, err := k.Rotator(map[string]string{"tenant": "a"})
+	assert.NoError(t, err)
+	assert.Same(t, r1, r2)
+	assert.NoError(t, k.Close())
+
+	date := time.Now().Format(Layout)
+	for _, tenant := range []string{"a", "b"} {
+		bs, err := os.ReadFile(filepath.Join(dir, tenant, date, fmt.Sprintf("app_%s_0001.log", date)))
+		assert.NoError(t, err)
+		assert.Equal(t, "hello "+tenant+"\n", string(bs))
+	}
+}
+
+func TestKeyedRotator_GlobalLimit(t *testing.T) {
+	dir := t.TempDir()
+	now := time.Now()
+	// 租户a有3个旧文件，租户b有2个较新的文件，每个文件100字节
+	for tenant, ages := range map[string][]int{"a": {5, 4, 3}, "b": {2, 1}} {
+		date := now.Format(Layout)
+		for i, age := range ages {
+			path := filepath.Join(dir, tenant, date, fmt.Sprintf("app_%s_%04d.log", date, i+1))
+			assert.NoError(t, os.MkdirAll(filepath.Dir(path), os.ModePerm))
+			assert.NoError(t, os.WriteFile(path, make([]byte, 100), ReadWriteFile))
+			mtime := now.Add(-time.Duration(age) * time.Hour)
+			assert.NoError(t, os.Chtimes(path, mtime, mtime))
+		}
+	}
+
+	k, err := NewKeyedRotator(dir, "{tenant}", "app.log",
+		WithGlobalMaxTotalSize(300), WithGlobalCleanInterval(time.Hour), WithEvictionPolicy(EvictOldest))
+	assert.NoError(t, err)
+	defer k.Close()
+
+	// 启动时立即检查，删除全局最旧的文件，每个租户最新的文件保留
+	assert.Eventually(t, func() bool {
+		_, err1 := os.Stat(filepath.Join(dir, "a", now.Format(Layout),
+			fmt.Sprintf("app_%s_0002.log", now.Format(Layout))))
+		return os.IsNotExist(err1)
+	}, time.Second, time.Millisecond*10)
+
+	date := now.Format(Layout)
+	assert.NoFileExists(t, filepath.Join(dir, "a", date, fmt.Sprintf("app_%s_0001.log", date)))
+	assert.FileExists(t, filepath.Join(dir, "a", date, fmt.Sprintf("app_%s_0003.log", date)))
+	assert.FileExists(t, filepath.Join(dir, "b", date, fmt.Sprintf("app_%s_0001.log", date)))
+	assert.FileExists(t, filepath.Join(dir, "b", date, fmt.Sprintf("app_%s_0002.log", date)))
+
+	removed, err := k.EnforceGlobalLimit()
+	assert.NoError(t, err)
+	assert.Empty(t, removed)
+}
+
+func TestKeyedRotator_GlobalLimitError(t *testing.T) {
+	dir := t.TempDir()
+	// 日期非法的轮转文件使扫描租户目录时报告错误
+	path := filepath.Join(dir, "a", "app_20241399_0001.log")
+	assert.NoError(t, os.MkdirAll(filepath.Dir(path), os.ModePerm))
+	assert.NoError(t, os.WriteFile(path, make([]byte, 100), ReadWriteFile))
+
+	var events []Event
+	var lock sync.Mutex
+	k, err := NewKeyedRotator(dir, "{tenant}", "app.log",
+		WithGlobalMaxTotalSize(300), WithGlobalCleanInterval(time.Hour),
+		WithKeyedEventHandler(func(e Event) {
+			lock.Lock()
+			defer lock.Unlock()
+			events = append(events, e)
+		}))
+	assert.NoError(t, err)
+	defer k.Close()
+
+	// 启动时立即检查总大小
+	assert.Eventually(t, func() bool {
+		lock.Lock()
+		defer lock.Unlock()
+		return len(events) > 0
+	}, time.Second, time.Millisecond*10)
+
+	lock.Lock()
+	defer lock.Unlock()
+	assert.Equal(t, EventCleanupError, events[0].Type)
+	assert.Equal(t, dir, events[0].Path)
+	assert.ErrorContains(t, events[0].Err, "parse date")
+}
+
+func TestKeyedRotator_ProportionalEviction(t *testing.T) {
+	date := time.Now().Format(Layout)
+	// 租户a有2个最旧的文件，租户b有4个较新的文件，每个文件100字节
+	prepare := func(t *testing.T) string {
+		dir := t.TempDir()
+		now := time.Now()
+		for tenant, ages := range map[string][]int{"a": {10, 9}, "b": {4, 3, 2, 1}} {
+			for i, age := range ages {
+				path := filepath.Join(dir, tenant, date, fmt.Sprintf("app_%s_%04d.log", date, i+1))
+				assert.NoError(t, os.MkdirAll(filepath.Dir(path), os.ModePerm))
+				assert.NoError(t, os.WriteFile(path, make([]byte, 100), ReadWriteFile))
+				mtime := now.Add(-time.Duration(age) * time.Hour)
+				assert.NoError(t, os.Chtimes(path, mtime, mtime))
+			}
+		}
+		return dir
+	}
+	segment := func(dir, tenant string, seq int) string {
+		return filepath.Join(dir, tenant, date, fmt.Sprintf("app_%s_%04d.log", date, seq))
+	}
+
+	testCases := []struct {
+		name    string
+		opts    []KeyedOption
+		removed []string
+	}{
+		{
+			name:    "equal weights",
+			removed: []string{"b/1", "b/2"},
+		},
+		{
+			name:    "weighted",
+			opts:    []KeyedOption{WithTenantWeights(map[string]float64{"b": 3})},
+			removed: []string{"a/1", "b/1"},
+		},
+		{
+			name:    "oldest",
+			opts:    []KeyedOption{WithEvictionPolicy(EvictOldest)},
+			removed: []string{"a/1", "b/1"},
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			dir := prepare(t)
+			// 不设置总大小限制，避免后台检查提前删除文件
+			k, err := NewKeyedRotator(dir, "{tenant}", "app.log", tc.opts...)
+			assert.NoError(t, err)
+			defer k.Close()
+			k.maxTotalSize = 400
+
+			removed, err := k.EnforceGlobalLimit()
+			assert.NoError(t, err)
+			var got []string
+			for _, fi := range removed {
+				got = append(got, filepath.Join(fi.UpDir, fi.Name))
+			}
+			var want []string
+			for _, r := range tc.removed {
+				want = append(want, segment(dir, r[:1], int(r[2]-'0')))
+			}
+			assert.Equal(t, want, got)
+		})
+	}
+
+	_, err := NewKeyedRotator(t.TempDir(), "{tenant}", "app.log",
+		WithTenantWeights(map[string]float64{"a": 0}))
+	assert.Error(t, err)
+}
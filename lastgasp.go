@@ -0,0 +1,119 @@
+// Copyright 2025 TimeWtr
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package vortexrotate
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"os/signal"
+	"slices"
+	"syscall"
+	"time"
+)
+
+// LastGaspTimeout 进程退出前刷新数据时等待异步写入队列写完以及等待写锁的最长时间，超时放弃，
+// 避免写锁被崩溃的goroutine持有时阻塞进程退出
+const LastGaspTimeout = time.Millisecond * 200
+
+// lastGaspSignals 刷新数据之后按照默认行为终止进程的信号
+var lastGaspSignals = []os.Signal{syscall.SIGTERM, os.Interrupt, syscall.SIGHUP}
+
+// WithLastGaspFlush 进程被信号终止之前刷新暂存的数据：收到SIGTERM/SIGINT/SIGHUP时刷新转换函数
+// 和边写边压缩的缓冲并fsync当前文件，然后重新向进程发送该信号按照默认行为退出。同时开启
+// WithSignalShutdown时SIGTERM/SIGINT由优雅关闭处理。panic需要在main和各个goroutine的入口处
+// defer FlushOnPanic。对象的终结器(runtime.SetFinalizer)在进程退出时不会执行，不能用于兜底，
+// 因此不使用。同步模式下不能使用该选项，可以直接调用LastGasp。
+func WithLastGaspFlush() Option {
+	return func(r *Rotator) error {
+		r.lastGasp = true
+		return nil
+	}
+}
+
+// FlushOnPanic 发生panic时刷新暂存的数据并fsync当前文件，然后继续panic，必须直接以defer的
+// 方式调用才能捕获panic：
+//
+//	defer rotator.FlushOnPanic()
+func (r *Rotator) FlushOnPanic() {
+	v := recover()
+	if v == nil {
+		return
+	}
+
+	if err := r.LastGasp(); err != nil {
+		r.l.Printf("failed to flush on panic, cause: %v", err)
+	}
+	panic(v)
+}
+
+// LastGasp 进程即将退出时尽力刷新暂存的数据：异步写入队列中的内容、转换函数缓冲的内容、边写边
+// 压缩的缓冲，并fsync当前文件，不封存也不关闭文件。等待异步写入队列写完和获取写锁总共最多等待
+// LastGaspTimeout，队列没有写完时仍然刷新已经写入的内容并返回错误，获取写锁超时返回错误，可以在
+// 自定义的致命错误处理函数(比如调用os.Exit之前)中调用。
+func (r *Rotator) LastGasp() error {
+	deadline := time.Now().Add(LastGaspTimeout)
+	var drainErr error
+	if r.writeQueue != nil && !r.writeQueue.waitUntil(deadline) {
+		drainErr = fmt.Errorf("async write queue not drained within %s, %d writes pending",
+			LastGaspTimeout, r.writeQueue.len())
+	}
+	for !r.writeLock.TryLock() {
+		if time.Now().After(deadline) {
+			return errors.Join(drainErr, fmt.Errorf("wait for write lock timeout after %s", LastGaspTimeout))
+		}
+		time.Sleep(time.Millisecond)
+	}
+	defer r.writeLock.Unlock()
+
+	if r.sig.Load() == 1 || r.f == nil || r.broken {
+		return drainErr
+	}
+
+	if err := r.flushTransformers(); err != nil {
+		return errors.Join(drainErr, err)
+	}
+
+	return errors.Join(drainErr, r.syncFile())
+}
+
+// watchLastGasp 监听终止信号，刷新数据之后重新发送信号
+func (r *Rotator) watchLastGasp() {
+	sigs := lastGaspSignals
+	if r.signalShutdown {
+		sigs = slices.DeleteFunc(slices.Clone(sigs), func(sig os.Signal) bool {
+			return slices.Contains(shutdownSignals, sig)
+		})
+	}
+
+	ch := make(chan os.Signal, 1)
+	signal.Notify(ch, sigs...)
+	go func() {
+		defer signal.Stop(ch)
+
+		select {
+		case <-r.done:
+		case sig := <-ch:
+			signal.Stop(ch)
+			r.l.Printf("receive signal %s, flush before exit", sig)
+			if err := r.LastGasp(); err != nil {
+				r.l.Printf("failed to flush before exit, cause: %v", err)
+			}
+			if err := raise(sig); err != nil {
+				r.l.Printf("failed to raise signal %s, cause: %v", sig, err)
+			}
+		}
+	}()
+}
@@ -0,0 +1,121 @@
+// Copyright 2025 TimeWtr
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package vortexrotate
+
+import (
+	"compress/gzip"
+	"io"
+	"os"
+	"runtime"
+	"syscall"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// readFlushed 读取边写边压缩的文件中已经刷新的内容，文件还没有写入gzip的结尾
+func readFlushed(t *testing.T, path string) string {
+	f, err := os.Open(path)
+	assert.NoError(t, err)
+	defer f.Close()
+
+	gr, err := gzip.NewReader(f)
+	if err != nil {
+		return ""
+	}
+	bs, err := io.ReadAll(gr)
+	assert.ErrorIs(t, err, io.ErrUnexpectedEOF)
+	return string(bs)
+}
+
+func TestRotator_FlushOnPanic(t *testing.T) {
+	rotator, err := NewRotator(t.TempDir(), "testdata.log", WithCompressOnWrite(CompressTypeGzip))
+	assert.NoError(t, err)
+	defer rotator.Close()
+
+	_, err = rotator.Write([]byte("before panic\n"))
+	assert.NoError(t, err)
+	path := rotator.f.Name()
+	assert.Empty(t, readFlushed(t, path))
+
+	assert.PanicsWithValue(t, "boom", func() {
+		defer rotator.FlushOnPanic()
+		panic("boom")
+	})
+	assert.Equal(t, "before panic\n", readFlushed(t, path))
+
+	// 写锁被占用时超时放弃
+	rotator.writeLock.Lock()
+	assert.Error(t, rotator.LastGasp())
+	rotator.writeLock.Unlock()
+}
+
+func TestRotator_LastGaspAsyncWrite(t *testing.T) {
+	rotator, err := NewRotator(t.TempDir(), "testdata.log",
+		WithCompressOnWrite(CompressTypeGzip), WithAsyncWrite(0, false))
+	assert.NoError(t, err)
+	defer rotator.Close()
+	path := rotator.f.Name()
+
+	// 写锁被占用时队列中的内容不能写入，超时返回错误
+	rotator.writeLock.Lock()
+	_, err = rotator.Write([]byte("queued\n"))
+	assert.NoError(t, err)
+	assert.ErrorContains(t, rotator.LastGasp(), "not drained")
+
+	// 先写完队列中的内容再刷新
+	time.AfterFunc(20*time.Millisecond, rotator.writeLock.Unlock)
+	assert.NoError(t, rotator.LastGasp())
+	assert.Equal(t, "queued\n", readFlushed(t, path))
+}
+
+func TestRotator_LastGaspSignal(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("signal is not supported on windows")
+	}
+
+	raised := make(chan os.Signal, 1)
+	old := raise
+	raise = func(sig os.Signal) error {
+		raised <- sig
+		return nil
+	}
+	defer func() {
+		raise = old
+	}()
+
+	rotator, err := NewRotator(t.TempDir(), "testdata.log",
+		WithCompressOnWrite(CompressTypeGzip), WithLastGaspFlush())
+	assert.NoError(t, err)
+	defer rotator.Close()
+
+	_, err = rotator.Write([]byte("before signal\n"))
+	assert.NoError(t, err)
+	rotator.writeLock.RLock()
+	path := rotator.f.Name()
+	rotator.writeLock.RUnlock()
+
+	p, err := os.FindProcess(os.Getpid())
+	assert.NoError(t, err)
+	assert.NoError(t, p.Signal(syscall.SIGHUP))
+	select {
+	case sig := <-raised:
+		assert.Equal(t, syscall.SIGHUP, sig)
+	case <-time.After(time.Second * 5):
+		t.Fatal("signal not handled")
+	}
+	assert.Equal(t, "before signal\n", readFlushed(t, path))
+}
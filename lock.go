@@ -0,0 +1,98 @@
+// Copyright 2025 TimeWtr
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package vortexrotate
+
+import (
+	"os"
+	"path/filepath"
+)
+
+// LockFileExt 建议锁文件的后缀名
+const LockFileExt = ".lock"
+
+// WithAdvisoryLock 开启目录级别的建议锁，写入方在封存、删除文件时持有dir/filename.lock的排他锁，
+// 通过OpenReadOnly读取文件的一方在读取期间持有共享锁，保证读取方不会读到正在被删除的文件。
+// 注意：读取方持有共享锁期间，写入方的封存操作会阻塞等待。
+func WithAdvisoryLock() Option {
+	return func(r *Rotator) error {
+		r.advisoryLock = true
+		return nil
+	}
+}
+
+// dirLock 目录级别的建议锁
+type dirLock struct {
+	f *os.File
+}
+
+func lockPath(dir, name string) string {
+	return filepath.Join(dir, name+LockFileExt)
+}
+
+// openDirLock 打开(不存在时创建)建议锁文件
+func openDirLock(dir, name string) (*dirLock, error) {
+	f, err := os.OpenFile(lockPath(dir, name), os.O_CREATE|os.O_RDWR|openNoFollow, ReadWriteFile)
+	if err != nil {
+		return nil, err
+	}
+
+	return &dirLock{f: f}, nil
+}
+
+// Lock 获取排他锁，nil表示没有开启建议锁
+func (l *dirLock) Lock() error {
+	if l == nil {
+		return nil
+	}
+
+	return flock(l.f, true)
+}
+
+// Unlock 释放排他锁
+func (l *dirLock) Unlock() error {
+	if l == nil {
+		return nil
+	}
+
+	return funlock(l.f)
+}
+
+// Close 关闭建议锁文件
+func (l *dirLock) Close() error {
+	if l == nil {
+		return nil
+	}
+
+	return l.f.Close()
+}
+
+// sharedLock 读取方以只读的方式打开建议锁文件并获取共享锁，锁文件不存在时说明写入方没有开启
+// 建议锁，返回nil
+func sharedLock(dir, name string) (*os.File, error) {
+	f, err := os.OpenFile(lockPath(dir, name), os.O_RDONLY|openNoFollow, 0)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil, nil
+		}
+		return nil, err
+	}
+
+	if err = flock(f, false); err != nil {
+		_ = f.Close()
+		return nil, err
+	}
+
+	return f, nil
+}
@@ -0,0 +1,286 @@
+// Copyright 2025 TimeWtr
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package vortexrotate
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+)
+
+// ManifestFileExt 归档清单的后缀名
+const ManifestFileExt = ".manifest"
+
+// ManifestEntry 归档清单中的一条记录，对应一个封存完成的文件
+type ManifestEntry struct {
+	// 相对于存储目录的路径
+	Name string `json:"name"`
+	// 文件大小
+	Size int64 `json:"size"`
+	// SHA-256校验和(十六进制)
+	Checksum string `json:"sha256"`
+	// 封存的时间
+	Sealed time.Time `json:"sealed"`
+	// 二次压缩之前的文件路径(相对于存储目录)，只在二次压缩生成的记录中有效
+	RecompressedFrom string `json:"recompressed_from,omitempty"`
+}
+
+// WithManifest 在存储目录中维护归档清单dir/filename.manifest，每个封存完成的文件(压缩、加密、
+// 校验和等流程全部完成之后)追加一条JSON记录，包括文件路径、大小和SHA-256校验和，二次压缩之后追加
+// 转换后文件的记录。清单与文件系统不一致时(比如进程在封存过程中崩溃)由Repair按照文件系统重建
+func WithManifest() Option {
+	return func(r *Rotator) error {
+		r.manifest = true
+		return nil
+	}
+}
+
+// Manifest 读取存储目录中的归档清单，filename为基础文件名称，格式与NewRotator一致，比如：app.log，
+// 同一个文件的多条记录以最后一条为准，二次压缩之后源文件的记录被转换后文件的记录替换，按照路径排序
+func Manifest(dir, filename string) ([]ManifestEntry, error) {
+	name, _, err := splitFilename(filename)
+	if err != nil {
+		return nil, err
+	}
+
+	dir, err = normalizeDir(dir)
+	if err != nil {
+		return nil, err
+	}
+
+	return readManifest(manifestPath(dir, name))
+}
+
+// manifestPath 归档清单的路径
+func manifestPath(dir, name string) string {
+	return filepath.Join(dir, name+ManifestFileExt)
+}
+
+// readManifest 读取归档清单并合并同一个文件的多条记录
+func readManifest(path string) ([]ManifestEntry, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer func() {
+		_ = f.Close()
+	}()
+
+	entries := make(map[string]ManifestEntry)
+	sc := bufio.NewScanner(f)
+	for sc.Scan() {
+		line := bytes.TrimSpace(sc.Bytes())
+		if len(line) == 0 {
+			continue
+		}
+
+		var e ManifestEntry
+		if err = json.Unmarshal(line, &e); err != nil {
+			return nil, fmt.Errorf("parse manifest file %s error: %w", path, err)
+		}
+		if e.RecompressedFrom != "" {
+			delete(entries, e.RecompressedFrom)
+		}
+		entries[e.Name] = e
+	}
+	if err = sc.Err(); err != nil {
+		return nil, err
+	}
+
+	res := make([]ManifestEntry, 0, len(entries))
+	for _, e := range entries {
+		res = append(res, e)
+	}
+	sort.Slice(res, func(i, j int) bool {
+		return res[i].Name < res[j].Name
+	})
+
+	return res, nil
+}
+
+// appendManifest 在归档清单末尾追加记录并fsync，清单不存在时创建
+func appendManifest(path string, entries ...ManifestEntry) error {
+	var buf bytes.Buffer
+	enc := json.NewEncoder(&buf)
+	for _, e := range entries {
+		if err := enc.Encode(e); err != nil {
+			return err
+		}
+	}
+
+	return appendDurable(path, buf.Bytes())
+}
+
+// writeManifest 通过写临时文件+rename的方式重写归档清单，每个文件只保留一条记录
+func writeManifest(path string, entries []ManifestEntry, rename renamer) error {
+	var buf bytes.Buffer
+	enc := json.NewEncoder(&buf)
+	for _, e := range entries {
+		if err := enc.Encode(e); err != nil {
+			return err
+		}
+	}
+
+	return writeFileAtomic(path, buf.Bytes(), rename)
+}
+
+// manifestEntry 生成封存完成的文件的清单记录，sum为nil时读取文件计算校验和
+func manifestEntry(dir, path string, sum []byte) (ManifestEntry, error) {
+	info, err := os.Stat(path)
+	if err != nil {
+		return ManifestEntry{}, err
+	}
+	if sum == nil {
+		if sum, err = fileChecksum(path); err != nil {
+			return ManifestEntry{}, err
+		}
+	}
+
+	rel, err := filepath.Rel(dir, path)
+	if err != nil {
+		return ManifestEntry{}, err
+	}
+
+	return ManifestEntry{
+		Name:     filepath.ToSlash(rel),
+		Size:     info.Size(),
+		Checksum: hex.EncodeToString(sum),
+		Sealed:   info.ModTime(),
+	}, nil
+}
+
+// recordManifest 将封存完成的文件追加到归档清单，path为写入完成标记之后文件的最终路径
+func (r *Rotator) recordManifest(path string, sum []byte) error {
+	if !r.manifest {
+		return nil
+	}
+
+	e, err := manifestEntry(r.dir, path, sum)
+	if err != nil {
+		return err
+	}
+
+	r.manifestLock.Lock()
+	defer r.manifestLock.Unlock()
+
+	return appendManifest(manifestPath(r.dir, r.filename), e)
+}
+
+// recordRecompress 二次压缩之后在归档清单中追加转换后文件的记录，记录中保存源文件的路径，
+// 读取清单时源文件的记录被替换，清单不存在(没有开启归档清单)时忽略
+func recordRecompress(dir, src, dst string) error {
+	name := segmentBaseName(filepath.Base(src))
+	if name == "" {
+		return nil
+	}
+
+	path := manifestPath(dir, name)
+	if _, err := os.Lstat(path); err != nil {
+		if os.IsNotExist(err) {
+			return nil
+		}
+		return err
+	}
+
+	e, err := manifestEntry(dir, dst, nil)
+	if err != nil {
+		return err
+	}
+	rel, err := filepath.Rel(dir, src)
+	if err != nil {
+		return err
+	}
+	e.RecompressedFrom = filepath.ToSlash(rel)
+
+	return appendManifest(path, e)
+}
+
+// isArtifactSidecar 判断文件是否是轮转文件的关联文件，而不是轮转文件本身
+func isArtifactSidecar(fn string) bool {
+	for _, ext := range []string{ChecksumFileExt, DoneFileExt, TimeIndexExt, TmpFileExt, UploadPendingExt} {
+		if strings.HasSuffix(fn, ext) {
+			return true
+		}
+	}
+
+	return false
+}
+
+// sealedArtifacts 扫描目录，返回所有已经封存完成的轮转文件：压缩或者加密之后的文件，以及带有
+// 完成标记、校验和文件或者位于完成目录中的原始文件。同一个轮转文件有多个版本时(比如保留了源文件)
+// 只返回名称最长的版本
+func sealedArtifacts(dir, name string) ([]string, error) {
+	re := segmentRegexp(name)
+	groups := make(map[string]string)
+	err := filepath.WalkDir(dir, func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if isQuarantineDir(dir, path, d) {
+			return filepath.SkipDir
+		}
+		if d.IsDir() {
+			return nil
+		}
+
+		fn := d.Name()
+		matches := re.FindStringSubmatch(fn)
+		if len(matches) == 0 || isArtifactSidecar(fn) {
+			return nil
+		}
+		if fn == matches[0] && !rawSealed(path) {
+			return nil
+		}
+
+		key := filepath.Join(filepath.Dir(path), matches[0])
+		if len(fn) > len(filepath.Base(groups[key])) {
+			groups[key] = path
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	res := make([]string, 0, len(groups))
+	for _, path := range groups {
+		res = append(res, path)
+	}
+	sort.Strings(res)
+
+	return res, nil
+}
+
+// rawSealed 判断没有压缩的原始轮转文件是否已经封存完成
+func rawSealed(path string) bool {
+	if filepath.Base(filepath.Dir(path)) == DoneDirName {
+		return true
+	}
+	for _, ext := range []string{DoneFileExt, ChecksumFileExt} {
+		if _, err := os.Lstat(path + ext); err == nil {
+			return true
+		}
+	}
+
+	return false
+}
@@ -0,0 +1,185 @@
+// Copyright 2025 TimeWtr
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package vortexrotate
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestRotator_Manifest(t *testing.T) {
+	dir := t.TempDir()
+	rotator, err := newRotator(dir, "testdata.log",
+		WithCompress(CompressTypeGzip), WithDoneMarker(DoneMarkerDir), WithManifest())
+	assert.NoError(t, err)
+	defer rotator.Close()
+
+	var sealed []string
+	for i := 0; i < 2; i++ {
+		_, err = rotator.Write([]byte("manifest test\n"))
+		assert.NoError(t, err)
+		path := rotator.f.Name()
+		assert.NoError(t, rotator.Rotate())
+		sealed = append(sealed, filepath.Join(filepath.Dir(path), DoneDirName,
+			compressFn(filepath.Base(path), CompressTypeGzip)))
+	}
+
+	entries, err := Manifest(dir, "testdata.log")
+	assert.NoError(t, err)
+	assert.Len(t, entries, len(sealed))
+	for i, e := range entries {
+		rel, err := filepath.Rel(dir, sealed[i])
+		assert.NoError(t, err)
+		assert.Equal(t, filepath.ToSlash(rel), e.Name)
+
+		bs, err := os.ReadFile(sealed[i])
+		assert.NoError(t, err)
+		sum := sha256.Sum256(bs)
+		assert.Equal(t, hex.EncodeToString(sum[:]), e.Checksum)
+		assert.Equal(t, int64(len(bs)), e.Size)
+	}
+}
+
+func TestRepair_Manifest(t *testing.T) {
+	dir := t.TempDir()
+	files := []string{
+		"20250101/app_20250101_0001.log.gz",
+		"20250101/app_20250101_0002.log.gz",
+		"20250101/app_20250101_0003.log.gz",
+		"20250101/app_20250101_0003.log.gz" + ChecksumFileExt,
+		// 没有完成标记的原始文件是正在写入的文件，不属于已封存的文件
+		"20250101/app_20250101_0004.log",
+	}
+	for _, fn := range files {
+		path := filepath.Join(dir, fn)
+		assert.NoError(t, os.MkdirAll(filepath.Dir(path), os.ModePerm))
+		assert.NoError(t, os.WriteFile(path, []byte(fn), ReadWriteFile))
+	}
+	seq := &sequence{path: filepath.Join(dir, "app"+SeqFileExt)}
+	assert.NoError(t, seq.store(5))
+
+	first, err := manifestEntry(dir, filepath.Join(dir, files[0]), nil)
+	assert.NoError(t, err)
+	stale := first
+	stale.Name = "20250101/app_20250101_0009.log.gz"
+	changed, err := manifestEntry(dir, filepath.Join(dir, files[1]), nil)
+	assert.NoError(t, err)
+	changed.Size++
+	path := manifestPath(dir, "app")
+	assert.NoError(t, appendManifest(path, first, stale, changed))
+	assert.NoError(t, os.WriteFile(path+TmpFileExt, nil, ReadWriteFile))
+	assert.True(t, needRepair(dir, "app"))
+
+	report, err := Repair(dir, "app.log")
+	assert.NoError(t, err)
+	counts := make(map[RepairActionType]int)
+	for _, action := range report.Actions {
+		counts[action.Type]++
+	}
+	assert.Equal(t, 1, counts[RepairRemoveTmp])
+	// 目录开启了校验和，缺失校验和文件的已封存文件重新计算
+	assert.Equal(t, 2, counts[RepairChecksum])
+	assert.Equal(t, 1, counts[RepairManifest])
+	for _, fn := range files[:2] {
+		assertChecksum(t, filepath.Join(dir, fn))
+	}
+	assert.NoFileExists(t, filepath.Join(dir, files[4]+ChecksumFileExt))
+
+	entries, err := Manifest(dir, "app.log")
+	assert.NoError(t, err)
+	assert.Len(t, entries, 3)
+	for i, e := range entries {
+		expected, err := manifestEntry(dir, filepath.Join(dir, files[i]), nil)
+		assert.NoError(t, err)
+		assert.Equal(t, expected.Name, e.Name)
+		assert.Equal(t, expected.Size, e.Size)
+		assert.Equal(t, expected.Checksum, e.Checksum)
+	}
+
+	// 目录已经一致，再次修复不执行任何动作
+	report, err = Repair(dir, "app.log")
+	assert.NoError(t, err)
+	assert.Empty(t, report.Actions)
+}
+
+// assertManifest 校验归档清单中的记录与文件一致
+func assertManifest(t *testing.T, dir string, paths []string) {
+	entries, err := Manifest(dir, "testdata.log")
+	assert.NoError(t, err)
+	assert.Len(t, entries, len(paths))
+	for i, e := range entries {
+		expected, err := manifestEntry(dir, paths[i], nil)
+		assert.NoError(t, err)
+		assert.Equal(t, expected.Name, e.Name)
+		assert.Equal(t, expected.Size, e.Size)
+		assert.Equal(t, expected.Checksum, e.Checksum)
+	}
+}
+
+func TestRecompress_Manifest(t *testing.T) {
+	testCases := []struct {
+		name string
+		opts []Option
+		// 执行二次压缩
+		recompress func(t *testing.T, r *Rotator)
+	}{
+		{
+			name: "offline",
+			recompress: func(t *testing.T, r *Rotator) {
+				assert.NoError(t, r.Close())
+				report, err := Recompress(r.dir, CompressTypeGzip, CompressTypeZstd, RecompressOptions{})
+				assert.NoError(t, err)
+				assert.Equal(t, 2, report.Converted)
+			},
+		},
+		{
+			name: "scheduled",
+			opts: []Option{WithRecompress(0, CompressTypeZstd, ZstdDefaultLevel, CompressTypeGzip)},
+			recompress: func(t *testing.T, r *Rotator) {
+				r.recompressOld()
+				assert.NoError(t, r.Close())
+			},
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			dir := t.TempDir()
+			rotator, err := newRotator(dir, "testdata.log",
+				append(tc.opts, WithCompress(CompressTypeGzip), WithManifest())...)
+			assert.NoError(t, err)
+
+			var paths []string
+			for i := 0; i < 2; i++ {
+				_, err = rotator.Write([]byte("recompress manifest test\n"))
+				assert.NoError(t, err)
+				paths = append(paths, rotator.f.Name())
+				assert.NoError(t, rotator.Rotate())
+			}
+			tc.recompress(t, rotator)
+
+			// 源文件的记录被转换之后的文件的记录替换
+			for i, path := range paths {
+				paths[i] = compressFn(path, CompressTypeZstd)
+			}
+			assertManifest(t, dir, paths)
+		})
+	}
+}
@@ -0,0 +1,76 @@
+// Copyright 2025 TimeWtr
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package vortexrotate
+
+import (
+	"time"
+)
+
+// midnightJobName 零点强制轮转任务的名称
+const midnightJobName = "midnight"
+
+// midnightSchedule 每天零点执行的时间表，零点按照注册任务时轮转器使用的时区计算，
+// 之后切换了时区时由写入之前的日期检查保证按照新的时区轮转
+type midnightSchedule struct {
+	loc *time.Location
+}
+
+func (s midnightSchedule) Next(t time.Time) time.Time {
+	return nextMidnight(t.In(s.loc))
+}
+
+// nextMidnight t所在时区的下一个零点
+func nextMidnight(t time.Time) time.Time {
+	y, m, d := t.Date()
+	return time.Date(y, m, d+1, 0, 0, 0, 0, t.Location())
+}
+
+// WithMidnightRotate 每天零点(按照轮转器使用的时区)强制执行一次轮转，与轮转策略无关，
+// 按小时轮转、只按大小轮转或者自定义cron表达式时都保证单个文件不会跨越两个自然日。
+// 后台任务在零点封存非空的当前文件，同时每次写入之前检查日期，任务因为调度延迟还没有
+// 执行时先轮转再写入，同步模式下只在写入时检查
+func WithMidnightRotate() Option {
+	return func(r *Rotator) error {
+		r.midnightRotate = true
+		return nil
+	}
+}
+
+// midnightJob 零点强制轮转的后台任务，当前文件为空时跳过，由下一次写入轮转
+func (r *Rotator) midnightJob() {
+	r.writeLock.Lock()
+	defer r.writeLock.Unlock()
+	if r.sig.Load() == 1 || r.f == nil || r.broken || r.offset == 0 {
+		return
+	}
+
+	if err := r.rotateAtMidnight(); err != nil {
+		r.l.Printf("midnight rotate error: %v", err)
+	}
+}
+
+// rotateAtMidnight 当前文件的日期已经过去时执行跨天轮转，必须持有写锁
+func (r *Rotator) rotateAtMidnight() error {
+	if time.Now().Before(r.dayEnd) {
+		return nil
+	}
+	if r.bucketDate == r.currentDate() {
+		// 时区向后调整等原因导致日期还没有变化，重新计算下一个零点
+		r.dayEnd = nextMidnight(r.now())
+		return nil
+	}
+
+	return r.rotate(RotateReasonRollover)
+}
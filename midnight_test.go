@@ -0,0 +1,103 @@
+// Copyright 2025 TimeWtr
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package vortexrotate
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestNextMidnight(t *testing.T) {
+	loc := time.FixedZone("UTC+8", 8*3600)
+	now := time.Date(2025, 1, 31, 23, 59, 59, 0, loc)
+	assert.Equal(t, time.Date(2025, 2, 1, 0, 0, 0, 0, loc), nextMidnight(now))
+	assert.Equal(t, time.Date(2025, 2, 2, 0, 0, 0, 0, loc), nextMidnight(nextMidnight(now)))
+
+	s := midnightSchedule{loc: loc}
+	utc := time.Date(2025, 1, 1, 15, 30, 0, 0, time.UTC)
+	assert.Equal(t, time.Date(2025, 1, 2, 0, 0, 0, 0, loc), s.Next(utc))
+}
+
+func TestRotator_WithMidnightRotate(t *testing.T) {
+	for _, opts := range [][]Option{
+		{WithSizeRotate(1024)},
+		{WithRotate(1024, Hour)},
+		{WithTimeRotate(Hour), WithSynchronousMode()},
+	} {
+		rotator, err := newRotator(t.TempDir(), "testdata.log", append(opts, WithMidnightRotate())...)
+		assert.NoError(t, err)
+		assert.False(t, rotator.dayEnd.IsZero())
+
+		_, err = rotator.Write([]byte("midnight rotate test\n"))
+		assert.NoError(t, err)
+		active := rotator.f.Name()
+
+		// 日期没有变化时不轮转，只重新计算下一个零点
+		rotator.writeLock.Lock()
+		rotator.dayEnd = time.Now().Add(-time.Second)
+		rotator.writeLock.Unlock()
+		_, err = rotator.Write([]byte("midnight rotate test\n"))
+		assert.NoError(t, err)
+		assert.Equal(t, active, rotator.f.Name())
+		assert.True(t, rotator.dayEnd.After(time.Now()))
+
+		// 模拟跨天，写入之前先轮转
+		rotator.writeLock.Lock()
+		rotator.bucketDate = "20000101"
+		rotator.dayEnd = time.Now().Add(-time.Second)
+		rotator.writeLock.Unlock()
+		_, err = rotator.Write([]byte("midnight rotate test\n"))
+		assert.NoError(t, err)
+		assert.NotEqual(t, active, rotator.f.Name())
+		assert.Equal(t, rotator.currentDate(), rotator.bucketDate)
+		assert.Equal(t, uint64(1), rotator.Stats().Rotations[RotateReasonRollover])
+		assert.NoError(t, rotator.Close())
+	}
+}
+
+func TestRotator_MidnightJob(t *testing.T) {
+	rotator, err := newRotator(t.TempDir(), "testdata.log", WithSizeRotate(1024), WithMidnightRotate())
+	assert.NoError(t, err)
+	defer rotator.Close()
+
+	// 当前文件为空时跳过
+	rotator.writeLock.Lock()
+	rotator.bucketDate = "20000101"
+	rotator.dayEnd = time.Now().Add(-time.Second)
+	rotator.writeLock.Unlock()
+	active := rotator.f.Name()
+	rotator.midnightJob()
+	assert.Equal(t, active, rotator.f.Name())
+
+	_, err = rotator.Write([]byte("midnight rotate test\n"))
+	assert.NoError(t, err)
+	active = rotator.f.Name()
+	rotator.writeLock.Lock()
+	rotator.bucketDate = "20000101"
+	rotator.dayEnd = time.Now().Add(-time.Second)
+	rotator.writeLock.Unlock()
+	rotator.midnightJob()
+	assert.NotEqual(t, active, rotator.f.Name())
+
+	var found bool
+	for _, job := range rotator.Jobs() {
+		if job.Name == midnightJobName {
+			found = true
+		}
+	}
+	assert.True(t, found)
+}
@@ -0,0 +1,221 @@
+// Copyright 2025 TimeWtr
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package vortexrotate
+
+import (
+	"errors"
+	"io"
+	"os"
+	"path/filepath"
+	"syscall"
+	"time"
+)
+
+const (
+	// NetworkFSCheckInterval 网络文件系统上检查触发文件的时间间隔，网络文件系统的属性缓存导致
+	// 频繁检查没有意义，并且每次检查都是一次网络请求
+	NetworkFSCheckInterval = 5 * time.Second
+	// NetworkFSDiskCheckInterval 网络文件系统上检查磁盘可用空间的时间间隔
+	NetworkFSDiskCheckInterval = time.Minute
+	// networkFSRenameRetry 网络文件系统上rename遇到临时错误时的重试次数
+	networkFSRenameRetry = 3
+	// networkFSRenameBackoff 网络文件系统上rename重试的间隔
+	networkFSRenameBackoff = 100 * time.Millisecond
+)
+
+// WithNetworkFS 声明存储目录位于NFS/SMB等网络文件系统上，Linux系统上初始化时会自动检测，其他系统
+// 或者检测失败时可以通过该选项显式开启。网络文件系统上调整以下默认行为：
+// 1. 没有设置fsync策略时默认使用SyncOnRotate，保证文件封存之前数据已经提交到服务端
+// 2. 检查触发文件和磁盘可用空间的间隔延长，减少网络请求
+// 3. rename遇到ESTALE/EBUSY等临时错误时重试，跨设备时回退为复制+删除
+func WithNetworkFS() Option {
+	return func(r *Rotator) error {
+		r.networkFS = true
+		return nil
+	}
+}
+
+// initNetworkFS 检测存储目录是否位于网络文件系统上，并调整默认配置
+func (r *Rotator) initNetworkFS() {
+	if !r.networkFS && isNetworkFS(r.dir) {
+		r.networkFS = true
+		r.l.Printf("directory %s is on a network filesystem, adjust defaults", r.dir)
+	}
+	if !r.networkFS {
+		return
+	}
+
+	if !r.syncPolicySet {
+		r.syncPolicy = SyncOnRotate
+	}
+}
+
+// triggerInterval 检查触发文件的时间间隔
+func (r *Rotator) triggerInterval() time.Duration {
+	if r.networkFS {
+		return NetworkFSCheckInterval
+	}
+
+	return TriggerCheckInterval
+}
+
+// renamer 重命名文件的函数，轮转器的流程使用r.rename，不属于轮转器的独立流程使用renameLocal
+type renamer func(src, dst string) error
+
+// rename 持久化地重命名文件，网络文件系统上遇到临时错误时重试，跨设备时回退为复制+删除
+func (r *Rotator) rename(src, dst string) error {
+	return durableRename(src, dst, r.networkFS)
+}
+
+// renameLocal 按照本地文件系统持久化地重命名文件，用于Repair、Recompress等独立的流程
+func renameLocal(src, dst string) error {
+	return durableRename(src, dst, false)
+}
+
+// durableRename 重命名文件之后fsync目标文件所在的目录(跨目录移动时同时fsync源目录)，保证崩溃
+// 重启之后rename的结果不会丢失。networkFS为true时遇到临时错误重试，跨设备时回退为复制+删除
+func durableRename(src, dst string, networkFS bool) error {
+	if err := renameRetry(src, dst, networkFS); err != nil {
+		return err
+	}
+
+	if err := syncDir(filepath.Dir(dst)); err != nil {
+		return err
+	}
+	if filepath.Dir(src) != filepath.Dir(dst) {
+		return syncDir(filepath.Dir(src))
+	}
+
+	return nil
+}
+
+// appendDurable 将data追加到path的末尾并fsync，path不存在时创建并fsync所在的目录，用于只追加的清单文件
+func appendDurable(path string, data []byte) error {
+	_, statErr := os.Lstat(path)
+	f, err := os.OpenFile(path, os.O_CREATE|os.O_APPEND|os.O_WRONLY|openNoFollow, ReadWriteFile)
+	if err != nil {
+		return err
+	}
+	if _, err = f.Write(data); err == nil {
+		err = f.Sync()
+	}
+	if cerr := f.Close(); err == nil {
+		err = cerr
+	}
+	if err == nil && os.IsNotExist(statErr) {
+		// 新创建的文件需要fsync目录
+		err = syncDir(filepath.Dir(path))
+	}
+
+	return err
+}
+
+// writeFileAtomic 先写入临时文件并fsync，再通过rename原子替换path，崩溃之后path要么是旧的内容，
+// 要么是完整的新内容
+func writeFileAtomic(path string, data []byte, rename renamer) error {
+	tmp := path + TmpFileExt
+	f, err := os.OpenFile(tmp, os.O_CREATE|os.O_WRONLY|os.O_TRUNC|openNoFollow, ReadWriteFile)
+	if err != nil {
+		return err
+	}
+	if _, err = f.Write(data); err == nil {
+		err = f.Sync()
+	}
+	if cerr := f.Close(); err == nil {
+		err = cerr
+	}
+	if err == nil {
+		err = rename(tmp, path)
+	}
+	if err != nil {
+		_ = os.Remove(tmp)
+	}
+
+	return err
+}
+
+// renameRetry 重命名文件，网络文件系统上遇到临时错误时重试，跨设备时回退为复制+删除
+func renameRetry(src, dst string, networkFS bool) error {
+	err := os.Rename(src, dst)
+	if err == nil || !networkFS {
+		return err
+	}
+
+	for i := 0; i < networkFSRenameRetry && isTransientRenameErr(err); i++ {
+		time.Sleep(networkFSRenameBackoff * time.Duration(i+1))
+		if err = os.Rename(src, dst); err == nil {
+			return nil
+		}
+	}
+
+	if errors.Is(err, syscall.EXDEV) {
+		return copyRemove(src, dst)
+	}
+
+	return err
+}
+
+// isTransientRenameErr 网络文件系统上可以重试的rename错误
+func isTransientRenameErr(err error) bool {
+	return errors.Is(err, syscall.ESTALE) || errors.Is(err, syscall.EBUSY)
+}
+
+// copyRemove 复制文件之后删除源文件，用于无法rename的场景
+func copyRemove(src, dst string) error {
+	in, err := os.Open(src)
+	if err != nil {
+		return err
+	}
+	defer func() {
+		_ = in.Close()
+	}()
+
+	info, err := in.Stat()
+	if err != nil {
+		return err
+	}
+
+	tmp := dst + TmpFileExt
+	out, err := os.OpenFile(tmp, os.O_CREATE|os.O_TRUNC|os.O_WRONLY|openNoFollow, info.Mode().Perm())
+	if err != nil {
+		return err
+	}
+	if _, err = io.Copy(out, in); err != nil {
+		_ = out.Close()
+		_ = os.Remove(tmp)
+		return err
+	}
+	if err = out.Sync(); err != nil {
+		_ = out.Close()
+		_ = os.Remove(tmp)
+		return err
+	}
+	if err = out.Close(); err != nil {
+		_ = os.Remove(tmp)
+		return err
+	}
+	if err = os.Chtimes(tmp, info.ModTime(), info.ModTime()); err != nil {
+		_ = os.Remove(tmp)
+		return err
+	}
+
+	// 同一个目录中的rename不会跨设备
+	if err = os.Rename(tmp, dst); err != nil {
+		_ = os.Remove(tmp)
+		return err
+	}
+
+	return os.Remove(src)
+}
@@ -0,0 +1,47 @@
+// Copyright 2025 TimeWtr
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+//go:build linux
+
+package vortexrotate
+
+import "syscall"
+
+// 常见网络文件系统的magic number，参考linux/magic.h
+const (
+	nfsSuperMagic    = 0x6969
+	smbSuperMagic    = 0x517b
+	cifsSuperMagic   = 0xff534d42
+	smb2SuperMagic   = 0xfe534d42
+	cephSuperMagic   = 0x00c36400
+	afsSuperMagic    = 0x5346414f
+	v9fsSuperMagic   = 0x01021997
+	lustreSuperMagic = 0x0bd00bd0
+)
+
+// isNetworkFS 通过statfs返回的文件系统类型检测目录是否位于网络文件系统上
+func isNetworkFS(dir string) bool {
+	var st syscall.Statfs_t
+	if err := syscall.Statfs(dir, &st); err != nil {
+		return false
+	}
+
+	switch uint32(st.Type) {
+	case nfsSuperMagic, smbSuperMagic, cifsSuperMagic, smb2SuperMagic, cephSuperMagic,
+		afsSuperMagic, v9fsSuperMagic, lustreSuperMagic:
+		return true
+	default:
+		return false
+	}
+}
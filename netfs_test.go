@@ -0,0 +1,79 @@
+// Copyright 2025 TimeWtr
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package vortexrotate
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestRotator_NetworkFS(t *testing.T) {
+	rotator, err := newRotator(t.TempDir(), "testdata.log", WithNetworkFS())
+	assert.NoError(t, err)
+	assert.True(t, rotator.networkFS)
+	assert.Equal(t, SyncOnRotate, rotator.syncPolicy)
+	assert.Equal(t, NetworkFSCheckInterval, rotator.triggerInterval())
+	assert.NoError(t, rotator.Close())
+
+	// 显式设置的fsync策略不会被修改
+	rotator, err = newRotator(t.TempDir(), "testdata.log",
+		WithNetworkFS(), WithSyncPolicy(SyncNever))
+	assert.NoError(t, err)
+	assert.Equal(t, SyncNever, rotator.syncPolicy)
+	assert.NoError(t, rotator.Close())
+}
+
+func TestCopyRemove(t *testing.T) {
+	dir := t.TempDir()
+	src := filepath.Join(dir, "src.log")
+	dst := filepath.Join(dir, "dst.log")
+	assert.NoError(t, os.WriteFile(src, []byte("content"), ReadWriteFile))
+	mtime := time.Now().Add(-time.Hour).Truncate(time.Second)
+	assert.NoError(t, os.Chtimes(src, mtime, mtime))
+
+	assert.NoError(t, copyRemove(src, dst))
+	_, err := os.Stat(src)
+	assert.True(t, os.IsNotExist(err))
+	bs, err := os.ReadFile(dst)
+	assert.NoError(t, err)
+	assert.Equal(t, "content", string(bs))
+	info, err := os.Stat(dst)
+	assert.NoError(t, err)
+	assert.True(t, info.ModTime().Equal(mtime))
+}
+
+func TestDurableRename(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "app.seq")
+	assert.NoError(t, writeFileAtomic(path, []byte("1\n"), renameLocal))
+	assert.NoError(t, writeFileAtomic(path, []byte("2\n"), renameLocal))
+	bs, err := os.ReadFile(path)
+	assert.NoError(t, err)
+	assert.Equal(t, "2\n", string(bs))
+	_, err = os.Stat(path + TmpFileExt)
+	assert.True(t, os.IsNotExist(err))
+
+	// 跨目录移动
+	sub := filepath.Join(dir, DoneDirName)
+	assert.NoError(t, os.Mkdir(sub, 0o755))
+	assert.NoError(t, durableRename(path, filepath.Join(sub, "app.seq"), false))
+	_, err = os.Stat(filepath.Join(sub, "app.seq"))
+	assert.NoError(t, err)
+	assert.Error(t, durableRename(path, filepath.Join(sub, "app.seq"), true))
+}
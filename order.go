@@ -0,0 +1,70 @@
+// Copyright 2025 TimeWtr
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package vortexrotate
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+const (
+	// OrderFileExt 排序标记文件的后缀名
+	OrderFileExt = ".order"
+	// orderSequence 按照序列号排序的标记内容
+	orderSequence = "sequence"
+)
+
+// WithMonotonicOrder 开启单调排序，轮转文件的先后顺序只由序列号决定，与文件名称中的日期无关。
+// 序列号持久化在序列号文件中，不受系统时间的影响，系统时间回拨或者跳变时，新文件的日期可能早于
+// 旧文件，但是序列号始终递增。开启之后在存储目录中写入dir/filename.order标记文件，只读访问、
+// 清理等按照顺序处理文件的流程检测到标记文件之后按照序列号排序，文件名称中的日期只用于阅读和
+// 按照日期的保存策略。已有的目录中如果存在进程重启导致的重复序列号，需要先执行Repair
+func WithMonotonicOrder() Option {
+	return func(r *Rotator) error {
+		r.monotonic = true
+		return nil
+	}
+}
+
+// writeOrderFile 写入排序标记文件，已经存在时不重复写入
+func writeOrderFile(dir, name string, rename renamer) error {
+	if monotonicOrder(dir, name) {
+		return nil
+	}
+
+	return writeFileAtomic(filepath.Join(dir, name+OrderFileExt), []byte(orderSequence+"\n"), rename)
+}
+
+// monotonicOrder 判断目录中的轮转文件是否按照序列号排序
+func monotonicOrder(dir, name string) bool {
+	bs, err := os.ReadFile(filepath.Join(dir, name+OrderFileExt))
+	if err != nil {
+		return false
+	}
+
+	return strings.TrimSpace(string(bs)) == orderSequence
+}
+
+// segmentBefore 判断轮转文件a是否早于b，monotonic为true时只比较序列号，否则先比较日期，
+// 相同日期的文件比较序列号
+func segmentBefore(dateA time.Time, seqA int64, dateB time.Time, seqB int64, monotonic bool) bool {
+	if !monotonic && !dateA.Equal(dateB) {
+		return dateA.Before(dateB)
+	}
+
+	return seqA < seqB
+}
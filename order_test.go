@@ -0,0 +1,88 @@
+// Copyright 2025 TimeWtr
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package vortexrotate
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestMonotonicOrder(t *testing.T) {
+	testCases := []struct {
+		name      string
+		monotonic bool
+		want      []int64
+	}{
+		{
+			name: "date order",
+			want: []int64{2, 1},
+		},
+		{
+			name:      "sequence order",
+			monotonic: true,
+			want:      []int64{1, 2},
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			dir := t.TempDir()
+			// 系统时间回拨，序列号更大的文件日期更早
+			for _, fn := range []string{"20250102/testdata_20250102_0001.log", "20250101/testdata_20250101_0002.log"} {
+				path := filepath.Join(dir, fn)
+				assert.NoError(t, os.MkdirAll(filepath.Dir(path), os.ModePerm))
+				assert.NoError(t, os.WriteFile(path, []byte("order test\n"), ReadWriteFile))
+			}
+			if tc.monotonic {
+				assert.NoError(t, writeOrderFile(dir, "testdata", renameLocal))
+				// 重复写入不报错
+				assert.NoError(t, writeOrderFile(dir, "testdata", renameLocal))
+			}
+
+			ro, err := OpenReadOnly(dir, "testdata.log")
+			assert.NoError(t, err)
+			segments, err := ro.List()
+			assert.NoError(t, err)
+			var got []int64
+			for _, seg := range segments {
+				got = append(got, seg.Sequence)
+			}
+			assert.Equal(t, tc.want, got)
+
+			c := NewFileCountCleanUp(dir, "testdata", 1, 0)
+			files, err := c.listFileInfo()
+			assert.NoError(t, err)
+			c.sortFiles(files)
+			got = got[:0]
+			for _, fi := range files {
+				got = append(got, fi.Sequence)
+			}
+			assert.Equal(t, tc.want, got)
+		})
+	}
+}
+
+func TestRotator_MonotonicOrder(t *testing.T) {
+	dir := t.TempDir()
+	rotator, err := newRotator(dir, "testdata.log", WithMonotonicOrder(), WithMaxCount(1))
+	assert.NoError(t, err)
+	defer rotator.Close()
+
+	assert.True(t, monotonicOrder(dir, "testdata"))
+	assert.True(t, rotator.cleanup.monotonic)
+}
@@ -0,0 +1,281 @@
+// Copyright 2025 TimeWtr
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package vortexrotate
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/TimeWtr/vortexrotate/errorx"
+)
+
+// DoneMarkerMode 文件封存完成之后通知日志采集器的方式
+type DoneMarkerMode int
+
+const (
+	// DoneMarkerNone 不写入完成标记
+	DoneMarkerNone DoneMarkerMode = iota
+	// DoneMarkerFile 在文件旁边写入一个空的<文件名>.done标记文件
+	DoneMarkerFile
+	// DoneMarkerDir 将文件移动到所在目录下的done/子目录中，保留的源文件和校验和文件一起移动
+	DoneMarkerDir
+)
+
+const (
+	// DoneFileExt 完成标记文件的后缀名
+	DoneFileExt = ".done"
+	// DoneDirName 完成目录的名称
+	DoneDirName = "done"
+)
+
+// WithDoneMarker 设置文件封存(压缩等流程全部完成)之后的完成标记，很多日志采集器以标记文件
+// 或者固定目录作为采集的约定，采集器只需要处理带有完成标记的文件，不会读取到写入中的文件。
+func WithDoneMarker(mode DoneMarkerMode) Option {
+	return func(r *Rotator) error {
+		r.doneMarker = mode
+		return nil
+	}
+}
+
+// seal 封存已经轮转的文件，依次执行压缩和写入完成标记等流程，cs为执行压缩的策略，pause用于
+// 记录各个阶段的耗时，开启了延迟压缩时交给后台任务封存。封存流程结束时通过tracker通知
+// AwaitSealed的调用方，延迟压缩和等待上传的文件在压缩、上传完成之后才通知
+func (r *Rotator) seal(path string, cs CompressStrategy, pause *RotatePause) error {
+	// 先注册封存状态，上传或者延迟压缩期间AwaitSealed可以等待
+	r.tracker.watch(path)
+	if r.deferCompress(path) {
+		r.tracker.deferSeal(path)
+		return nil
+	}
+
+	return r.sealFile(path, cs, pause)
+}
+
+// sealFile 立即封存文件，失败或者不需要上传时结束封存状态，需要上传时由上传流程结束
+func (r *Rotator) sealFile(path string, cs CompressStrategy, pause *RotatePause) error {
+	var err error
+	r.profile(ProfileSeal, func() {
+		err = r.sealNow(path, cs, pause)
+	})
+	if err != nil || r.uploader == nil {
+		r.tracker.finish(path, err)
+	}
+
+	return err
+}
+
+// sealNow 执行封存的各个阶段
+func (r *Rotator) sealNow(path string, cs CompressStrategy, pause *RotatePause) error {
+	if err := r.dirLock.Lock(); err != nil {
+		return err
+	}
+	defer func() {
+		_ = r.dirLock.Unlock()
+	}()
+
+	artifact := path
+	var sum []byte
+	if r.shouldCompress(path) && r.reserveSpace(path) {
+		r.l.Printf("rotate old file %s", path)
+		begin := time.Now()
+		var err error
+		hook(hookBeforeCompress, path)
+		r.profile(ProfileCompress, func() {
+			sum, err = r.cps(cs, path)
+		})
+		hook(hookAfterCompress, path)
+		pause.Compress = time.Since(begin)
+		if err != nil {
+			return fmt.Errorf("%w: %s: %w", errorx.ErrCompressFailed, path, err)
+		}
+		artifact = compressFn(path, r.cpr.compressType)
+		if !r.cpr.keepSource {
+			// 压缩文件将成为唯一的副本，删除源文件之前解压校验
+			if err = verifyArchive(path, artifact, r.cpr.compressType); err != nil {
+				if qerr := r.quarantine(artifact, err); qerr != nil {
+					r.l.Printf("failed to quarantine %s, cause: %v", artifact, qerr)
+					_ = os.Remove(artifact)
+				}
+				r.emit(Event{
+					Type:    EventVerifyFailed,
+					Path:    artifact,
+					Message: fmt.Sprintf("verify %s error: %v, keep source %s", artifact, err, path),
+					Err:     err,
+				})
+				return fmt.Errorf("%w: %s: %w", errorx.ErrCompressFailed, artifact, err)
+			}
+		}
+		r.collectCompress(path, artifact)
+		if !r.cpr.keepSource {
+			if err = os.Remove(path); err != nil && !os.IsNotExist(err) {
+				return err
+			}
+		}
+	}
+
+	if r.encryption != nil {
+		enc, err := r.encryptFile(artifact)
+		if err != nil {
+			return fmt.Errorf("encrypt %s error: %w", artifact, err)
+		}
+		artifact, sum = enc, nil
+	}
+
+	if r.checksum {
+		if sum == nil {
+			var err error
+			if sum, err = fileChecksum(artifact); err != nil {
+				return err
+			}
+		}
+		if err := writeChecksum(artifact, sum, r.rename); err != nil {
+			return err
+		}
+	}
+	if r.uploader != nil {
+		// 上传成功之后才写入完成标记，采集器不会处理还没有上传的文件
+		return r.queueUpload(uploadJob{path: artifact, source: path, sum: sum})
+	}
+
+	if err := r.markDone(artifact, path); err != nil {
+		return err
+	}
+
+	return r.recordManifest(r.donePath(artifact), sum)
+}
+
+// shouldCompress 判断轮转文件是否需要压缩，小于最小压缩大小的文件不压缩
+func (r *Rotator) shouldCompress(path string) bool {
+	if !r.cpr.compress {
+		return false
+	}
+	if r.cpr.minSize == 0 {
+		return true
+	}
+
+	info, err := os.Stat(path)
+	if err != nil {
+		// 交给压缩流程返回错误
+		return true
+	}
+
+	return info.Size() >= r.cpr.minSize
+}
+
+// verifyArchive 解压压缩文件，与源文件逐字节比较，内容不一致时返回errorx.ErrArchiveMismatch
+func verifyArchive(src, archive string, tp int) error {
+	sf, err := os.Open(src)
+	if err != nil {
+		return err
+	}
+	defer func() {
+		_ = sf.Close()
+	}()
+
+	af, err := os.Open(archive)
+	if err != nil {
+		return err
+	}
+	defer func() {
+		_ = af.Close()
+	}()
+
+	dr, err := newDecompressReader(tp, af)
+	if err != nil {
+		return err
+	}
+	defer func() {
+		_ = dr.Close()
+	}()
+
+	return equalReaders(sf, dr)
+}
+
+// equalReaders 比较两个数据流的内容是否一致
+func equalReaders(a, b io.Reader) error {
+	bufA, bufB := resources.getBuffer(), resources.getBuffer()
+	defer func() {
+		resources.putBuffer(bufA)
+		resources.putBuffer(bufB)
+	}()
+
+	for {
+		n, errA := io.ReadFull(a, *bufA)
+		if errA != nil && errA != io.EOF && errA != io.ErrUnexpectedEOF {
+			return errA
+		}
+		m, errB := io.ReadFull(b, *bufB)
+		if errB != nil && errB != io.EOF && errB != io.ErrUnexpectedEOF {
+			return errB
+		}
+		if n != m || !bytes.Equal((*bufA)[:n], (*bufB)[:m]) {
+			return errorx.ErrArchiveMismatch
+		}
+		if errA != nil {
+			// 两个数据流在相同的位置结束
+			return nil
+		}
+	}
+}
+
+// donePath 写入完成标记之后文件的最终路径
+func (r *Rotator) donePath(path string) string {
+	if r.doneMarker == DoneMarkerDir {
+		return filepath.Join(filepath.Dir(path), DoneDirName, filepath.Base(path))
+	}
+
+	return path
+}
+
+// markDone 为封存完成的文件写入完成标记，source为压缩之前的源文件，移动到完成目录时保留的源文件
+// 一起移动，不在原目录中残留
+func (r *Rotator) markDone(path, source string) error {
+	switch r.doneMarker {
+	case DoneMarkerFile:
+		f, err := os.OpenFile(path+DoneFileExt, os.O_CREATE|os.O_EXCL|os.O_WRONLY|openNoFollow, ReadWriteFile)
+		if err != nil {
+			if os.IsExist(err) {
+				return nil
+			}
+			return err
+		}
+		return f.Close()
+	case DoneMarkerDir:
+		doneDir := filepath.Join(filepath.Dir(path), DoneDirName)
+		if err := mkdirNoFollow(r.dir, doneDir); err != nil {
+			return err
+		}
+		if r.checksum {
+			// 校验和文件先于轮转文件移动，采集器看到轮转文件时校验和文件已经就绪
+			sidecar := path + ChecksumFileExt
+			if err := r.rename(sidecar, filepath.Join(doneDir, filepath.Base(sidecar))); err != nil {
+				return err
+			}
+		}
+		if source != path {
+			err := r.rename(source, filepath.Join(doneDir, filepath.Base(source)))
+			if err != nil && !os.IsNotExist(err) {
+				return err
+			}
+		}
+		return r.rename(path, filepath.Join(doneDir, filepath.Base(path)))
+	default:
+		return nil
+	}
+}
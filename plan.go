@@ -0,0 +1,356 @@
+// Copyright 2025 TimeWtr
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package vortexrotate
+
+import (
+	"errors"
+	"os"
+	"time"
+
+	"github.com/robfig/cron/v3"
+)
+
+const (
+	// DefaultPlanDays 默认模拟的天数
+	DefaultPlanDays = 7
+	// DefaultPlanCompressRatio 默认估计的压缩比(压缩之后的大小/原始大小)，文本日志通常在10%左右
+	DefaultPlanCompressRatio = 0.1
+)
+
+// PlanConfig 轮转和清理模拟的配置
+type PlanConfig struct {
+	// 基础文件名称，格式与NewRotator一致，比如：app.log
+	Filename string
+	// 轮转器的配置，与NewRotator使用相同的Option，只读取其中的轮转、压缩和保存策略，
+	// 不要传入WithWorkloadCapture等创建时就会写入文件的Option
+	Options []Option
+	// 预计的写入速率(字节/秒)
+	ByteRate float64
+	// 预计的压缩比(压缩之后的大小/原始大小)，<=0时使用DefaultPlanCompressRatio，不开启压缩时不生效
+	CompressRatio float64
+	// 模拟的天数，<=0时使用DefaultPlanDays
+	Days int
+	// 模拟的起始时间，零值表示当前时间
+	Now time.Time
+}
+
+// PlanDay 模拟过程中一天结束时的目录状态
+type PlanDay struct {
+	// 日期
+	Date time.Time
+	// 当天新增的轮转文件数量
+	Created int
+	// 当天清理的轮转文件数量
+	Removed int
+	// 当天结束时目录中的轮转文件数量，包括正在写入的文件
+	Segments int
+	// 当天结束时目录中所有文件的总大小
+	TotalBytes int64
+}
+
+// PlanReport 轮转和清理的模拟结果
+type PlanReport struct {
+	// 下一次清理将要删除的已有文件
+	NextCleanup []FileInfo
+	// 下一次轮转的时间，模拟的时间范围内不会轮转时为零值
+	NextRotation time.Time
+	// 下一次轮转的原因
+	NextRotationReason RotateReason
+	// 按天记录的目录变化
+	Days []PlanDay
+}
+
+// Plan 模拟轮转和清理，不会创建、修改或者删除任何文件。根据cfg中的轮转器配置和目录dir中已有的
+// 文件，计算下一次清理将要删除的文件、下一次轮转的时间，并按照预计的写入速率模拟未来若干天内
+// 目录的变化，用于上线之前评估保存策略和磁盘容量。模拟从一个新的空文件开始写入，定时轮转与
+// 轮转器的行为一致，文件大小没有达到最大大小的RotateSizeThreshold时跳过，清理按照
+// DefaultCleanInterval的间隔执行。
+func Plan(cfg PlanConfig, dir string) (*PlanReport, error) {
+	name, _, err := splitFilename(cfg.Filename)
+	if err != nil {
+		return nil, err
+	}
+	dir, err = normalizeDir(dir)
+	if err != nil {
+		return nil, err
+	}
+	if cfg.ByteRate < 0 {
+		return nil, errors.New("byte rate must not be negative")
+	}
+
+	r := &Rotator{maxSize: DefaultMaxSize}
+	for _, opt := range cfg.Options {
+		if err = opt(r); err != nil {
+			return nil, err
+		}
+	}
+	if !IsNil(r.stg) {
+		// 只读取配置，不需要后台的定时任务
+		r.stg.Close()
+	}
+	_ = r.capture.Close()
+
+	// 自定义的轮转策略无法模拟定时轮转，只模拟按照大小轮转
+	var sched cron.Schedule
+	ms, mix := r.stg.(*MixStrategy)
+	ts, timeOnly := r.stg.(*TimeStrategy)
+	if IsNil(r.stg) || mix || timeOnly {
+		tp := Hour
+		switch {
+		case mix:
+			tp = ms.tp
+		case timeOnly:
+			tp = ts.tp
+		}
+		spec, err := timingSpec(tp)
+		if err != nil {
+			return nil, err
+		}
+		if r.rotateCron != "" {
+			spec = r.rotateCron
+		}
+		if sched, err = parseCron(spec); err != nil {
+			return nil, err
+		}
+	}
+
+	c := NewFileCountCleanUp(dir, name, r.maxCount, r.period)
+	c.maxTotalSize = r.maxTotalSize
+	c.maxAge = r.maxAge
+	c.tiers = r.tiers
+	c.monotonic = c.monotonic || r.monotonic
+
+	var files []FileInfo
+	if _, err = os.Stat(dir); err == nil {
+		if files, err = c.listFileInfo(); err != nil {
+			return nil, err
+		}
+	} else if !os.IsNotExist(err) {
+		return nil, err
+	}
+	c.sortFiles(files)
+
+	now := cfg.Now
+	if now.IsZero() {
+		now = time.Now()
+	}
+	report := &PlanReport{NextCleanup: c.expired(files, now)}
+
+	sim := &planSim{
+		cfg:      cfg,
+		cleanup:  c,
+		sched:    sched,
+		timeOnly: timeOnly,
+		midnight: r.midnightRotate,
+		maxSize:  float64(r.maxSize),
+		ratio:    1,
+		files:    removeFiles(files, report.NextCleanup),
+		report:   report,
+	}
+	if r.cpr.compress || r.cow != CompressTypeUnknown {
+		sim.ratio = cfg.CompressRatio
+		if sim.ratio <= 0 {
+			sim.ratio = DefaultPlanCompressRatio
+		}
+	}
+	for _, fi := range sim.files {
+		sim.seq = max(sim.seq, fi.Sequence)
+	}
+	days := cfg.Days
+	if days <= 0 {
+		days = DefaultPlanDays
+	}
+	sim.run(now, days)
+
+	return report, nil
+}
+
+// planSim 轮转和清理的模拟过程
+type planSim struct {
+	cfg     PlanConfig
+	cleanup *CleanUp
+	// 定时轮转的时间表，nil表示不定时轮转
+	sched cron.Schedule
+	// 是否只按照时间轮转
+	timeOnly bool
+	// 是否在每天零点强制轮转
+	midnight bool
+	// 单个文件的最大大小
+	maxSize float64
+	// 封存之后的文件大小与原始大小的比例
+	ratio float64
+	// 目录中已经封存的文件，从旧到新排列
+	files []FileInfo
+	// 最大的序列号
+	seq int64
+	// 当前文件开始写入的时间
+	start time.Time
+	// 当天的统计
+	day    PlanDay
+	report *PlanReport
+}
+
+// run 从now开始模拟days天，直到最后一天结束
+func (s *planSim) run(now time.Time, days int) {
+	s.start = now
+	s.day = PlanDay{Date: startOfDay(now)}
+	end := s.day.Date.AddDate(0, 0, days)
+	nextTimed := s.nextTimed(now)
+	nextClean := now.Add(DefaultCleanInterval)
+	nextDay := s.day.Date.AddDate(0, 0, 1)
+
+	for !nextDay.After(end) {
+		t := nextDay
+		for _, ev := range []time.Time{s.nextSize(), nextTimed, nextClean} {
+			if !ev.IsZero() && ev.Before(t) {
+				t = ev
+			}
+		}
+
+		if next := s.nextSize(); !next.IsZero() && !next.After(t) {
+			s.rotate(t, RotateReasonSize)
+		}
+		if !nextTimed.IsZero() && !nextTimed.After(t) {
+			if size := s.activeSize(t); (s.timeOnly && size > 0) || (!s.timeOnly && size >= RotateSizeThreshold*s.maxSize) {
+				reason := RotateReasonScheduled
+				if !startOfDay(s.start).Equal(startOfDay(t)) {
+					reason = RotateReasonRollover
+				}
+				s.rotate(t, reason)
+			}
+			nextTimed = s.nextTimed(t)
+		}
+		if !nextClean.After(t) {
+			s.clean(t)
+			nextClean = t.Add(DefaultCleanInterval)
+		}
+		if !nextDay.After(t) {
+			if s.midnight && s.activeSize(t) > 0 && !startOfDay(s.start).Equal(startOfDay(t)) {
+				// 零点的强制轮转计入前一天
+				s.rotate(t, RotateReasonRollover)
+			}
+			s.closeDay(t)
+			nextDay = nextDay.AddDate(0, 0, 1)
+		}
+	}
+}
+
+// nextTimed 时间t之后的下一次定时轮转时间
+func (s *planSim) nextTimed(t time.Time) time.Time {
+	if s.sched == nil {
+		return time.Time{}
+	}
+
+	return s.sched.Next(t)
+}
+
+// nextSize 当前文件达到最大大小的时间，不写入数据或者只按照时间轮转时为零值
+func (s *planSim) nextSize() time.Time {
+	if s.cfg.ByteRate <= 0 || s.timeOnly {
+		return time.Time{}
+	}
+
+	d := time.Duration(s.maxSize / s.cfg.ByteRate * float64(time.Second))
+	return s.start.Add(max(d, time.Millisecond))
+}
+
+// activeSize 当前文件在时间t的大小
+func (s *planSim) activeSize(t time.Time) float64 {
+	return s.cfg.ByteRate * t.Sub(s.start).Seconds()
+}
+
+// active 正在写入的文件，清理时总是保留
+func (s *planSim) active(t time.Time) FileInfo {
+	return FileInfo{
+		Date:     fileDate(s.start),
+		Sequence: s.seq + 1,
+		Size:     int64(s.activeSize(t)),
+		ModTime:  t,
+	}
+}
+
+// rotate 封存当前文件并开始写入新的文件
+func (s *planSim) rotate(t time.Time, reason RotateReason) {
+	fi := s.active(t)
+	fi.Size = int64(float64(fi.Size) * s.ratio)
+	s.files = append(s.files, fi)
+	s.seq++
+	s.start = t
+	s.day.Created++
+
+	if s.report.NextRotation.IsZero() {
+		s.report.NextRotation = t
+		s.report.NextRotationReason = reason
+	}
+}
+
+// clean 执行一次清理
+func (s *planSim) clean(t time.Time) {
+	files := append(s.files[:len(s.files):len(s.files)], s.active(t))
+	removed := s.cleanup.expired(files, t)
+	s.files = removeFiles(s.files, removed)
+	s.day.Removed += len(removed)
+}
+
+// closeDay 记录当天结束时的目录状态
+func (s *planSim) closeDay(t time.Time) {
+	s.day.Segments = len(s.files) + 1
+	s.day.TotalBytes = int64(s.activeSize(t))
+	for _, fi := range s.files {
+		s.day.TotalBytes += fi.Size
+	}
+	s.report.Days = append(s.report.Days, s.day)
+	s.day = PlanDay{Date: startOfDay(t)}
+}
+
+// startOfDay 时间t所在日期的零点
+func startOfDay(t time.Time) time.Time {
+	y, m, d := t.Date()
+	return time.Date(y, m, d, 0, 0, 0, 0, t.Location())
+}
+
+// fileDate 时间t写入的文件名称中的日期，与解析文件名称得到的日期一致
+func fileDate(t time.Time) time.Time {
+	date, _ := time.Parse(Layout, t.Format(Layout))
+	return date
+}
+
+// planFileKey 模拟过程中唯一标识一个文件
+type planFileKey struct {
+	upDir string
+	name  string
+	seq   int64
+}
+
+// removeFiles 从files中去掉removed中的文件
+func removeFiles(files, removed []FileInfo) []FileInfo {
+	if len(removed) == 0 {
+		return files
+	}
+
+	keys := make(map[planFileKey]struct{}, len(removed))
+	for _, fi := range removed {
+		keys[planFileKey{fi.UpDir, fi.Name, fi.Sequence}] = struct{}{}
+	}
+
+	res := make([]FileInfo, 0, len(files))
+	for _, fi := range files {
+		if _, ok := keys[planFileKey{fi.UpDir, fi.Name, fi.Sequence}]; !ok {
+			res = append(res, fi)
+		}
+	}
+
+	return res
+}
@@ -0,0 +1,135 @@
+// Copyright 2025 TimeWtr
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package vortexrotate
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestPlan(t *testing.T) {
+	dir := t.TempDir()
+	for i := 1; i <= 5; i++ {
+		date := fmt.Sprintf("2024120%d", i)
+		path := filepath.Join(dir, date, fmt.Sprintf("testdata_%s_%04d.log", date, i))
+		assert.NoError(t, os.MkdirAll(filepath.Dir(path), os.ModePerm))
+		assert.NoError(t, os.WriteFile(path, []byte("plan test\n"), ReadWriteFile))
+	}
+
+	now := time.Date(2025, 1, 1, 0, 30, 0, 0, time.Local)
+	report, err := Plan(PlanConfig{
+		Filename: "testdata.log",
+		Options:  []Option{WithRotate(1000, Day), WithMaxCount(3)},
+		// 每小时写满一个文件
+		ByteRate: 1000.0 / 3600,
+		Days:     2,
+		Now:      now,
+	}, dir)
+	assert.NoError(t, err)
+
+	// 已有的5个文件中最旧的2个将被清理
+	assert.Len(t, report.NextCleanup, 2)
+	assert.Equal(t, int64(1), report.NextCleanup[0].Sequence)
+	assert.Equal(t, int64(2), report.NextCleanup[1].Sequence)
+	// 模拟过程中没有修改目录
+	for i := 1; i <= 5; i++ {
+		date := fmt.Sprintf("2024120%d", i)
+		assert.FileExists(t, filepath.Join(dir, date, fmt.Sprintf("testdata_%s_%04d.log", date, i)))
+	}
+
+	assert.Equal(t, now.Add(time.Hour), report.NextRotation)
+	assert.Equal(t, RotateReasonSize, report.NextRotationReason)
+
+	// 零点的定时轮转因为文件太小被跳过
+	assert.Len(t, report.Days, 2)
+	assert.Equal(t, time.Date(2025, 1, 1, 0, 0, 0, 0, time.Local), report.Days[0].Date)
+	assert.Equal(t, 23, report.Days[0].Created)
+	assert.Equal(t, 24, report.Days[1].Created)
+	assert.Equal(t, 3, report.Days[1].Segments)
+	assert.Equal(t, report.Days[1].Created, report.Days[1].Removed)
+}
+
+func TestPlan_EmptyDir(t *testing.T) {
+	now := time.Date(2025, 1, 1, 12, 0, 0, 0, time.Local)
+	report, err := Plan(PlanConfig{
+		Filename: "testdata.log",
+		Options:  []Option{WithRotate(1<<20, Hour), WithCompress(CompressTypeGzip)},
+		// 每小时写入最大大小的90%
+		ByteRate: float64(1<<20) * 0.9 / 3600,
+		Now:      now,
+	}, filepath.Join(t.TempDir(), "not-exist"))
+	assert.NoError(t, err)
+	assert.Empty(t, report.NextCleanup)
+	assert.Len(t, report.Days, DefaultPlanDays)
+	// 文件大小超过最大大小的RotateSizeThreshold，每个整点轮转一次
+	assert.Equal(t, now.Add(time.Hour), report.NextRotation)
+	assert.Equal(t, RotateReasonScheduled, report.NextRotationReason)
+	last := report.Days[len(report.Days)-1]
+	assert.Equal(t, 0, last.Removed)
+	assert.Greater(t, last.TotalBytes, int64(0))
+}
+
+func TestPlan_TimeRotate(t *testing.T) {
+	now := time.Date(2025, 1, 1, 0, 30, 0, 0, time.Local)
+	report, err := Plan(PlanConfig{
+		Filename: "testdata.log",
+		Options:  []Option{WithTimeRotate(Hour)},
+		// 写入量远小于最大大小，仍然每个整点轮转一次
+		ByteRate: 1,
+		Days:     2,
+		Now:      now,
+	}, t.TempDir())
+	assert.NoError(t, err)
+	assert.Equal(t, time.Date(2025, 1, 1, 1, 0, 0, 0, time.Local), report.NextRotation)
+	assert.Equal(t, RotateReasonScheduled, report.NextRotationReason)
+	// 零点的定时轮转计入前一天
+	assert.Equal(t, 24, report.Days[0].Created)
+	assert.Equal(t, 24, report.Days[1].Created)
+}
+
+func TestPlan_MidnightRotate(t *testing.T) {
+	now := time.Date(2025, 1, 1, 0, 0, 0, 0, time.Local)
+	report, err := Plan(PlanConfig{
+		Filename: "testdata.log",
+		Options:  []Option{WithSizeRotate(1024 * 1024), WithMidnightRotate()},
+		ByteRate: 1,
+		Days:     3,
+		Now:      now,
+	}, t.TempDir())
+	assert.NoError(t, err)
+	// 写入量远小于最大大小，每天零点仍然轮转一次
+	for _, day := range report.Days {
+		assert.Equal(t, 1, day.Created)
+	}
+}
+
+func TestPlan_RotateCron(t *testing.T) {
+	now := time.Date(2025, 1, 1, 0, 0, 0, 0, time.Local)
+	report, err := Plan(PlanConfig{
+		Filename: "testdata.log",
+		Options:  []Option{WithTimeRotate(Hour), WithRotateCron("0 */15 * * * *")},
+		ByteRate: 1,
+		Days:     1,
+		Now:      now,
+	}, t.TempDir())
+	assert.NoError(t, err)
+	assert.Equal(t, now.Add(15*time.Minute), report.NextRotation)
+	assert.Equal(t, 96, report.Days[0].Created)
+}
@@ -0,0 +1,74 @@
+// Copyright 2025 TimeWtr
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package vortexrotate
+
+import "time"
+
+// 预设配置是经过测试的常用配置组合，作为NewRotator的一个配置使用，放在其他配置之前时可以被后面的
+// 配置覆盖，比如：NewRotator(dir, "app.log", PresetLowDisk(), WithMaxTotalSize(10<<30))
+
+// PresetHighThroughput 高吞吐场景的预设配置：单个文件512MB并且每小时定时轮转，使用S2快速压缩，
+// 异步压缩并且根据GOMAXPROCS并行压缩，轮转时执行fsync，保留最近7天的文件
+func PresetHighThroughput() Option {
+	return preset(
+		WithRotate(512*1024*1024, Hour),
+		WithCompress(CompressTypeS2),
+		WithAsyncCompress(DefaultCompressQueueSize),
+		WithCompressionWorkers(0),
+		WithSyncPolicy(SyncOnRotate),
+		WithPeriod(7),
+	)
+}
+
+// PresetLowDisk 磁盘空间受限场景的预设配置：单个文件32MB并且每小时定时轮转，使用高压缩比的zstd压缩
+// 所有文件，所有文件的总大小不超过1GB，保留最近3天的文件，磁盘可用空间低于10%时紧急清理，每5秒执行
+// 一次fsync
+func PresetLowDisk() Option {
+	return preset(
+		WithRotate(32*1024*1024, Hour),
+		WithCompress(CompressTypeZstd, 19),
+		WithMaxTotalSize(1<<30),
+		WithPeriod(3),
+		WithEmergencyCleanup(0.1, nil),
+		WithSyncPolicy(SyncInterval, 5*time.Second),
+	)
+}
+
+// PresetCompliance 审计合规场景的预设配置：每天定时轮转，每次写入之后执行fsync，使用gzip压缩并写入
+// 校验和文件，保留最近一年的文件，删除文件时记录永久保存的墓碑，启动时审计存储目录
+func PresetCompliance() Option {
+	return preset(
+		WithRotate(DefaultMaxSize, Day),
+		WithCompress(CompressTypeGzip, GzipBestCompression),
+		WithChecksum(),
+		WithSyncPolicy(SyncEveryWrite),
+		WithPeriod(365),
+		WithTombstones(0),
+		WithStartupAudit(nil, false),
+	)
+}
+
+// preset 将多个配置组合为一个配置，按照顺序应用
+func preset(opts ...Option) Option {
+	return func(r *Rotator) error {
+		for _, opt := range opts {
+			if err := opt(r); err != nil {
+				return err
+			}
+		}
+
+		return nil
+	}
+}
@@ -0,0 +1,76 @@
+// Copyright 2025 TimeWtr
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package vortexrotate
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestPreset(t *testing.T) {
+	testCases := []struct {
+		name   string
+		preset Option
+		tp     int
+		policy SyncPolicy
+	}{
+		{
+			name:   "high throughput",
+			preset: PresetHighThroughput(),
+			tp:     CompressTypeS2,
+			policy: SyncOnRotate,
+		},
+		{
+			name:   "low disk",
+			preset: PresetLowDisk(),
+			tp:     CompressTypeZstd,
+			policy: SyncInterval,
+		},
+		{
+			name:   "compliance",
+			preset: PresetCompliance(),
+			tp:     CompressTypeGzip,
+			policy: SyncEveryWrite,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			rotator, err := newRotator(t.TempDir(), "testdata.log", tc.preset)
+			assert.NoError(t, err)
+			assert.Equal(t, tc.tp, rotator.cpr.compressType)
+			assert.Equal(t, tc.policy, rotator.syncPolicy)
+			assert.NotNil(t, rotator.cleanup)
+
+			_, err = rotator.Write([]byte("preset test\n"))
+			assert.NoError(t, err)
+			path := rotator.f.Name()
+			assert.NoError(t, rotator.Rotate())
+			assert.NoError(t, rotator.Close())
+			assert.FileExists(t, compressFn(path, tc.tp))
+			assert.NoFileExists(t, path)
+		})
+	}
+}
+
+func TestPreset_Override(t *testing.T) {
+	rotator, err := newRotator(t.TempDir(), "testdata.log", PresetLowDisk(), WithMaxTotalSize(10<<30))
+	assert.NoError(t, err)
+	defer rotator.Close()
+
+	assert.Equal(t, int64(10<<30), rotator.maxTotalSize)
+	assert.Equal(t, uint16(3), rotator.period)
+}
@@ -0,0 +1,69 @@
+// Copyright 2025 TimeWtr
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package vortexrotate
+
+import (
+	"context"
+	"runtime/pprof"
+)
+
+// 后台任务的pprof标签
+const (
+	// ProfileLabelKey pprof标签的名称
+	ProfileLabelKey = "vortexrotate"
+	// ProfileCompress 压缩任务
+	ProfileCompress = "compress"
+	// ProfileRecompress 二次压缩任务
+	ProfileRecompress = "recompress"
+	// ProfileSeal 封存任务，包括压缩、校验、加密、上传以及写入完成标记
+	ProfileSeal = "seal"
+	// ProfileCleanup 清理任务，包括磁盘空间不足时的紧急清理
+	ProfileCleanup = "cleanup"
+	// ProfileUpload 上传任务
+	ProfileUpload = "upload"
+	// ProfileBundle 每日打包任务
+	ProfileBundle = "bundle"
+	// ProfileReconcile 封存上一次退出时遗留文件的任务
+	ProfileReconcile = "reconcile"
+)
+
+// WithProfilingLabels 为轮转器的后台任务(封存、压缩、上传、清理、打包等)添加pprof标签，标签名称为vortexrotate，
+// 标签值为任务类型，宿主应用的CPU profile中可以按照标签区分轮转器各个子系统的开销，比如排查
+// 每个整点CPU升高的原因。
+func WithProfilingLabels() Option {
+	return func(r *Rotator) error {
+		r.profiling = true
+		return nil
+	}
+}
+
+// profile 执行后台任务，开启pprof标签时为当前goroutine添加任务标签，执行完成后恢复原有的标签
+func (r *Rotator) profile(task string, fn func()) {
+	if !r.profiling {
+		fn()
+		return
+	}
+
+	pprof.Do(context.Background(), pprof.Labels(ProfileLabelKey, task, "filename", r.filename), func(context.Context) {
+		fn()
+	})
+}
+
+// labeled 返回添加了任务标签的任务函数，用于注册到调度器中
+func (r *Rotator) labeled(task string, fn func()) func() {
+	return func() {
+		r.profile(task, fn)
+	}
+}
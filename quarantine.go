@@ -0,0 +1,151 @@
+// Copyright 2025 TimeWtr
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package vortexrotate
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"time"
+)
+
+const (
+	// QuarantineDirName 隔离目录的名称，位于存储目录下
+	QuarantineDirName = "quarantine"
+	// DefaultQuarantineRetention 隔离文件默认的保存时间，比正常的轮转文件保存更久，便于排查
+	DefaultQuarantineRetention = 30 * 24 * time.Hour
+)
+
+// WithQuarantineRetention 设置隔离文件的保存时间，从隔离时开始计算，0表示永久保存。校验或者解压
+// 失败的归档文件会连同校验和、完成标记移动到存储目录下的quarantine/子目录，不会和正常的文件混在
+// 一起，也不会被直接删除，隔离文件不计入其他保存策略，由清理任务按照该时间单独清理，默认为
+// DefaultQuarantineRetention，没有配置任何保存策略时隔离文件不会被清理。
+func WithQuarantineRetention(d time.Duration) Option {
+	return func(r *Rotator) error {
+		if d < 0 {
+			return errors.New("quarantine retention must not be negative")
+		}
+
+		r.quarantineRetention = d
+		return nil
+	}
+}
+
+// isQuarantineDir 判断遍历到的目录是否为存储目录下的隔离目录
+func isQuarantineDir(root, path string, d fs.DirEntry) bool {
+	return d.IsDir() && d.Name() == QuarantineDirName && filepath.Dir(path) == root
+}
+
+// quarantine 将无法校验或者解压的归档文件及其关联文件移动到隔离目录，修改时间更新为隔离的时间，
+// 并发送EventQuarantine事件
+func (r *Rotator) quarantine(path string, cause error) error {
+	dir := filepath.Join(r.dir, QuarantineDirName)
+	if err := mkdirNoFollow(r.dir, dir); err != nil {
+		return err
+	}
+
+	now := time.Now()
+	dst := filepath.Join(dir, filepath.Base(path))
+	if _, err := os.Lstat(dst); err == nil {
+		// 同一个文件多次被隔离时追加隔离的时间
+		dst = fmt.Sprintf("%s.%d", dst, now.UnixNano())
+	}
+	if err := r.rename(path, dst); err != nil {
+		return err
+	}
+	_ = os.Chtimes(dst, now, now)
+
+	for _, ext := range []string{ChecksumFileExt, DoneFileExt} {
+		sidecar := path + ext
+		if _, err := os.Lstat(sidecar); err != nil {
+			continue
+		}
+		if err := r.rename(sidecar, dst+ext); err != nil {
+			r.l.Printf("failed to quarantine %s, cause: %v", sidecar, err)
+			continue
+		}
+		_ = os.Chtimes(dst+ext, now, now)
+	}
+
+	r.emit(Event{
+		Type:    EventQuarantine,
+		Time:    now,
+		Path:    dst,
+		Message: fmt.Sprintf("quarantine %s to %s, cause: %v", path, dst, cause),
+		Err:     cause,
+	})
+
+	return nil
+}
+
+// checkArchive 完整解压归档文件，判断文件本身是否损坏
+func checkArchive(path string) error {
+	f, err := os.Open(path)
+	if err != nil {
+		return err
+	}
+	defer func() {
+		_ = f.Close()
+	}()
+
+	dr, err := newDecompressReader(compressTypeOf(path), f)
+	if err != nil {
+		return err
+	}
+	defer func() {
+		_ = dr.Close()
+	}()
+
+	buf := resources.getBuffer()
+	defer resources.putBuffer(buf)
+	_, err = io.CopyBuffer(io.Discard, dr, *buf)
+	return err
+}
+
+// cleanQuarantine 删除隔离时间超过保存时间的隔离文件
+func (c *CleanUp) cleanQuarantine(now time.Time) {
+	if c.quarantine <= 0 {
+		return
+	}
+
+	dir := filepath.Join(c.dir, QuarantineDirName)
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		if !os.IsNotExist(err) {
+			c.reportError(fmt.Errorf("list quarantine dir %s error: %w", dir, err))
+		}
+		return
+	}
+
+	deadline := now.Add(-c.quarantine)
+	for _, e := range entries {
+		if e.IsDir() || !(c.re.MatchString(e.Name()) || c.bundles && bundleRegexp.MatchString(e.Name())) {
+			continue
+		}
+		info, err := e.Info()
+		if err != nil || !info.ModTime().Before(deadline) {
+			continue
+		}
+
+		path := filepath.Join(dir, e.Name())
+		hook(hookBeforeRemove, path)
+		if err = os.Remove(path); err != nil && !os.IsNotExist(err) {
+			c.reportError(err)
+		}
+	}
+}
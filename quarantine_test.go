@@ -0,0 +1,91 @@
+// Copyright 2025 TimeWtr
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package vortexrotate
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestRotator_QuarantineRecompress(t *testing.T) {
+	dir := t.TempDir()
+	var events []Event
+	rotator, err := newRotator(dir, "testdata.log",
+		WithRecompress(time.Hour, CompressTypeZstd, ZstdDefaultLevel),
+		WithChecksum(),
+		WithEventHandler(func(e Event) {
+			events = append(events, e)
+		}))
+	assert.NoError(t, err)
+	defer rotator.Close()
+
+	// 无法解压的旧归档文件连同校验和文件一起被隔离
+	day := time.Now().AddDate(0, 0, -2).Format(Layout)
+	path := filepath.Join(dir, fmt.Sprintf("testdata_%s_0001.log.snappy", day))
+	assert.NoError(t, os.WriteFile(path, []byte("not a snappy stream"), ReadWriteFile))
+	assert.NoError(t, os.WriteFile(path+ChecksumFileExt, []byte("sum\n"), ReadWriteFile))
+	old := time.Now().Add(-time.Hour * 48)
+	assert.NoError(t, os.Chtimes(path, old, old))
+
+	rotator.recompressOld()
+	target := filepath.Join(dir, QuarantineDirName, filepath.Base(path))
+	assert.NoFileExists(t, path)
+	assert.FileExists(t, target)
+	assert.FileExists(t, target+ChecksumFileExt)
+	assert.Len(t, events, 1)
+	assert.Equal(t, EventQuarantine, events[0].Type)
+	assert.Equal(t, target, events[0].Path)
+
+	// 隔离文件不参与其他的处理流程
+	files, err := NewFileCountCleanUp(dir, "testdata", 0, 0).listFileInfo()
+	assert.NoError(t, err)
+	assert.Len(t, files, 1)
+	assert.Equal(t, []string{rotator.f.Name()}, files[0].Files)
+	rotator.recompressOld()
+	assert.Len(t, events, 1)
+}
+
+func TestCleanUp_Quarantine(t *testing.T) {
+	dir := t.TempDir()
+	rotator, err := newRotator(dir, "testdata.log", WithMaxCount(10),
+		WithQuarantineRetention(time.Hour*24))
+	assert.NoError(t, err)
+	defer rotator.Close()
+
+	qdir := filepath.Join(dir, QuarantineDirName)
+	assert.NoError(t, os.MkdirAll(qdir, os.ModePerm))
+	expired := filepath.Join(qdir, "testdata_20250101_0001.log.gz")
+	fresh := filepath.Join(qdir, "testdata_20250101_0002.log.gz")
+	other := filepath.Join(qdir, "other_20250101_0001.log.gz")
+	for _, path := range []string{expired, fresh, other} {
+		assert.NoError(t, os.WriteFile(path, []byte("corrupt"), ReadWriteFile))
+	}
+	old := time.Now().Add(-time.Hour * 48)
+	assert.NoError(t, os.Chtimes(expired, old, old))
+	assert.NoError(t, os.Chtimes(other, old, old))
+
+	assert.NoError(t, rotator.CleanNow())
+	assert.NoFileExists(t, expired)
+	assert.FileExists(t, fresh)
+	assert.FileExists(t, other)
+
+	_, err = newRotator(t.TempDir(), "testdata.log", WithQuarantineRetention(-time.Hour))
+	assert.Error(t, err)
+}
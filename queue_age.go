@@ -0,0 +1,83 @@
+// Copyright 2025 TimeWtr
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package vortexrotate
+
+import (
+	"sync"
+	"time"
+)
+
+// ageTracker 记录等待处理的文件进入队列的时间，用于计算队列中最早的文件已经等待的时间。
+// 流量较低时队列深度一直很小，只观测深度无法发现处理卡住的情况，等待时间持续增长说明
+// 后台任务已经停滞。零值可以直接使用
+type ageTracker struct {
+	lock sync.Mutex
+	// 文件路径 -> 进入队列的时间
+	items map[string]time.Time
+}
+
+// add 记录文件进入队列
+func (t *ageTracker) add(path string) {
+	t.lock.Lock()
+	defer t.lock.Unlock()
+
+	if t.items == nil {
+		t.items = make(map[string]time.Time)
+	}
+	t.items[path] = time.Now()
+}
+
+// done 文件已经离开队列
+func (t *ageTracker) done(path string) {
+	t.lock.Lock()
+	defer t.lock.Unlock()
+
+	delete(t.items, path)
+}
+
+// contains 判断文件是否在队列中
+func (t *ageTracker) contains(path string) bool {
+	t.lock.Lock()
+	defer t.lock.Unlock()
+
+	_, ok := t.items[path]
+	return ok
+}
+
+// size 队列中的文件数量
+func (t *ageTracker) size() int {
+	t.lock.Lock()
+	defer t.lock.Unlock()
+
+	return len(t.items)
+}
+
+// oldest 队列中最早的文件已经等待的时间，队列为空时返回0
+func (t *ageTracker) oldest() time.Duration {
+	t.lock.Lock()
+	defer t.lock.Unlock()
+
+	var first time.Time
+	for _, at := range t.items {
+		if first.IsZero() || at.Before(first) {
+			first = at
+		}
+	}
+	if first.IsZero() {
+		return 0
+	}
+
+	return time.Since(first)
+}
@@ -0,0 +1,116 @@
+// Copyright 2025 TimeWtr
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package vortexrotate
+
+import (
+	"context"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestAgeTracker(t *testing.T) {
+	var tracker ageTracker
+	assert.Equal(t, time.Duration(0), tracker.oldest())
+
+	tracker.add("a.log")
+	time.Sleep(20 * time.Millisecond)
+	tracker.add("b.log")
+	assert.GreaterOrEqual(t, tracker.oldest(), 20*time.Millisecond)
+
+	assert.True(t, tracker.contains("a.log"))
+	assert.Equal(t, 2, tracker.size())
+
+	tracker.done("a.log")
+	assert.False(t, tracker.contains("a.log"))
+	assert.Less(t, tracker.oldest(), 20*time.Millisecond)
+	tracker.done("b.log")
+	tracker.done("not-exist.log")
+	assert.Equal(t, time.Duration(0), tracker.oldest())
+	assert.Equal(t, 0, tracker.size())
+}
+
+func TestWriteRing_Age(t *testing.T) {
+	q := newWriteRing(4)
+	assert.Equal(t, time.Duration(0), q.age())
+
+	assert.NoError(t, q.push([]byte("a"), false))
+	time.Sleep(20 * time.Millisecond)
+	assert.NoError(t, q.push([]byte("b"), false))
+	assert.GreaterOrEqual(t, q.age(), 20*time.Millisecond)
+
+	_, ok := q.pop()
+	assert.True(t, ok)
+	assert.Less(t, q.age(), 20*time.Millisecond)
+	_, ok = q.pop()
+	assert.True(t, ok)
+	assert.Equal(t, time.Duration(0), q.age())
+}
+
+// blockingUploader 测试使用的上传器，关闭release之前上传一直阻塞
+type blockingUploader struct {
+	release chan struct{}
+}
+
+func (u blockingUploader) Upload(ctx context.Context, _ string) error {
+	select {
+	case <-u.release:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+func TestRotator_QueueAges(t *testing.T) {
+	registerTestBackends(t)
+	rotator, err := newRotator(t.TempDir(), "testdata.log",
+		WithAsyncWrite(0, false), WithAsyncCompress(0), WithCompress(blockingType))
+	assert.NoError(t, err)
+	defer rotator.Close()
+
+	stats := rotator.Stats()
+	assert.Equal(t, time.Duration(0), stats.WriteQueueAge)
+	assert.Equal(t, time.Duration(0), stats.CompressQueueAge)
+	assert.Equal(t, time.Duration(0), stats.UploadQueueAge)
+
+	// 压缩卡住时正在封存的文件仍然计入等待时间
+	_, err = rotator.Write([]byte("compress age test\n"))
+	assert.NoError(t, err)
+	rotator.awaitWrites()
+	path := rotator.f.Name()
+	assert.NoError(t, rotator.Rotate())
+	assert.Eventually(t, func() bool {
+		return rotator.compressQueueDepth() == 0 && rotator.Stats().CompressQueueAge >= 20*time.Millisecond
+	}, time.Second, 10*time.Millisecond)
+
+	close(testBlocking.release)
+	assert.NoError(t, rotator.AwaitSealed(context.Background(), path))
+	assert.Equal(t, time.Duration(0), rotator.Stats().CompressQueueAge)
+
+	// 上传卡住时等待时间持续增长
+	up := blockingUploader{release: make(chan struct{})}
+	rotator.uploader = up
+	rotator.startUploader()
+	assert.True(t, rotator.enqueueUpload(uploadJob{path: filepath.Join(rotator.dir, "stalled.log")}))
+	assert.Eventually(t, func() bool {
+		return rotator.Stats().UploadQueueAge >= 20*time.Millisecond
+	}, time.Second, 10*time.Millisecond)
+
+	close(up.release)
+	assert.NoError(t, rotator.awaitUploads(context.Background()))
+	assert.Equal(t, time.Duration(0), rotator.Stats().UploadQueueAge)
+}
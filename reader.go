@@ -0,0 +1,354 @@
+// Copyright 2025 TimeWtr
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package vortexrotate
+
+import (
+	"bufio"
+	"context"
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strconv"
+	"time"
+)
+
+// maxScanLineSize 逐行读取时允许的最大行长度
+const maxScanLineSize = 16 * 1024 * 1024
+
+// SegmentInfo 轮转文件的信息
+type SegmentInfo struct {
+	// 文件名称
+	Name string
+	// 文件路径
+	Path string
+	// 文件日期(年月日)
+	Date time.Time
+	// 文件序列号
+	Sequence int64
+	// 文件在磁盘上的大小
+	Size int64
+	// 文件的修改时间
+	ModTime time.Time
+	// 压缩类型，未压缩为CompressTypeUnknown
+	CompressType int
+}
+
+// Match 搜索命中的一行内容
+type Match struct {
+	// 所在的轮转文件
+	Segment SegmentInfo
+	// 行号，从1开始
+	Line int64
+	// 行内容，不包括换行符
+	Text string
+}
+
+// VerifyResult 单个轮转文件的校验结果
+type VerifyResult struct {
+	Segment SegmentInfo
+	// 校验失败的原因，成功时为nil
+	Err error
+}
+
+// DirStats 存储目录的统计信息
+type DirStats struct {
+	// 轮转文件数量
+	Segments int
+	// 压缩文件数量
+	Compressed int
+	// 所有文件在磁盘上的总大小
+	TotalBytes int64
+	// 压缩文件在磁盘上的总大小
+	CompressedBytes int64
+	// 最早的文件修改时间
+	Oldest time.Time
+	// 最新的文件修改时间
+	Newest time.Time
+}
+
+// ReadOnly 只读访问已有的轮转目录，提供文件列表、读取、搜索、校验和统计的能力，
+// 不会在目录中创建、修改或者删除任何文件，适用于不能影响写入方的旁路读取程序。
+type ReadOnly struct {
+	// 文件存储目录
+	dir string
+	// 基础的文件名称
+	filename string
+	// 正则匹配文件名中的日期和序号
+	re *regexp.Regexp
+	// 是否只按照序列号排序
+	monotonic bool
+}
+
+// OpenReadOnly 以只读的方式打开轮转目录，filename为基础文件名称，格式与NewRotator一致
+func OpenReadOnly(dir, filename string) (*ReadOnly, error) {
+	name, _, err := splitFilename(filename)
+	if err != nil {
+		return nil, err
+	}
+
+	dir, err = normalizeDir(dir)
+	if err != nil {
+		return nil, err
+	}
+
+	info, err := os.Stat(dir)
+	if err != nil {
+		return nil, err
+	}
+	if !info.IsDir() {
+		return nil, &fs.PathError{Op: "open", Path: dir, Err: fs.ErrInvalid}
+	}
+
+	return &ReadOnly{
+		dir:       dir,
+		filename:  name,
+		re:        segmentRegexp(name),
+		monotonic: monotonicOrder(dir, name),
+	}, nil
+}
+
+// List 按照日期和序列号升序列出所有的轮转文件，目录开启了单调排序时只按照序列号升序，同一个文件同时存在原始文件和压缩文件时
+// (压缩进行中)，只返回原始文件
+func (ro *ReadOnly) List() ([]SegmentInfo, error) {
+	segments := make(map[string]SegmentInfo)
+	err := filepath.WalkDir(ro.dir, func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if isQuarantineDir(ro.dir, path, d) {
+			return filepath.SkipDir
+		}
+		if d.IsDir() {
+			return nil
+		}
+
+		const matchesLen = 3
+		matches := ro.re.FindStringSubmatch(d.Name())
+		if len(matches) < matchesLen {
+			return nil
+		}
+
+		tp := compressTypeOf(d.Name())
+		if d.Name() != matches[0] && tp == CompressTypeUnknown {
+			// 临时文件、标记文件等关联文件
+			return nil
+		}
+
+		date, err := time.Parse(Layout, matches[1])
+		if err != nil {
+			return nil
+		}
+		seq, err := strconv.ParseInt(matches[2], 10, 64)
+		if err != nil {
+			return nil
+		}
+		info, err := d.Info()
+		if err != nil {
+			return nil
+		}
+
+		key := filepath.Join(filepath.Dir(path), matches[0])
+		if exist, ok := segments[key]; ok && exist.CompressType == CompressTypeUnknown {
+			return nil
+		}
+		segments[key] = SegmentInfo{
+			Name:         d.Name(),
+			Path:         path,
+			Date:         date,
+			Sequence:     seq,
+			Size:         info.Size(),
+			ModTime:      info.ModTime(),
+			CompressType: tp,
+		}
+
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	res := make([]SegmentInfo, 0, len(segments))
+	for _, seg := range segments {
+		res = append(res, seg)
+	}
+	sort.Slice(res, func(i, j int) bool {
+		return segmentBefore(res[i].Date, res[i].Sequence, res[j].Date, res[j].Sequence, ro.monotonic)
+	})
+
+	return res, nil
+}
+
+// Open 打开轮转文件读取原始内容，压缩文件自动解压，写入方开启了建议锁时，读取期间持有共享锁，
+// 直到Close时释放，保证读取期间文件不会被写入方删除
+func (ro *ReadOnly) Open(seg SegmentInfo) (io.ReadCloser, error) {
+	lf, err := sharedLock(ro.dir, ro.filename)
+	if err != nil {
+		return nil, err
+	}
+
+	f, err := os.Open(seg.Path)
+	if err != nil {
+		closeLock(lf)
+		return nil, err
+	}
+
+	dr, err := newDecompressReader(seg.CompressType, f)
+	if err != nil {
+		_ = f.Close()
+		closeLock(lf)
+		return nil, err
+	}
+
+	return &segmentReader{ReadCloser: dr, f: f, lock: lf}, nil
+}
+
+// closeLock 释放共享锁并关闭锁文件
+func closeLock(lf *os.File) {
+	if lf == nil {
+		return
+	}
+
+	_ = funlock(lf)
+	_ = lf.Close()
+}
+
+// segmentReader 关闭时同时关闭解压器和文件，并释放共享锁
+type segmentReader struct {
+	io.ReadCloser
+	f    *os.File
+	lock *os.File
+}
+
+func (s *segmentReader) Close() error {
+	err := s.ReadCloser.Close()
+	if err1 := s.f.Close(); err == nil {
+		err = err1
+	}
+	closeLock(s.lock)
+
+	return err
+}
+
+// Search 按照文件顺序逐行搜索所有轮转文件，每一个命中的行调用一次fn，fn返回false时停止搜索
+func (ro *ReadOnly) Search(ctx context.Context, pattern *regexp.Regexp, fn func(Match) bool) error {
+	segments, err := ro.List()
+	if err != nil {
+		return err
+	}
+
+	for _, seg := range segments {
+		stop, err := ro.searchSegment(ctx, seg, pattern, fn)
+		if err != nil {
+			return err
+		}
+		if stop {
+			return nil
+		}
+	}
+
+	return nil
+}
+
+func (ro *ReadOnly) searchSegment(ctx context.Context, seg SegmentInfo, pattern *regexp.Regexp,
+	fn func(Match) bool) (bool, error) {
+	rc, err := ro.Open(seg)
+	if err != nil {
+		return false, err
+	}
+	defer func() {
+		_ = rc.Close()
+	}()
+
+	sc := bufio.NewScanner(rc)
+	sc.Buffer(make([]byte, bufferSize), maxScanLineSize)
+	var line int64
+	for sc.Scan() {
+		line++
+		if err = ctx.Err(); err != nil {
+			return true, err
+		}
+		if !pattern.Match(sc.Bytes()) {
+			continue
+		}
+		if !fn(Match{Segment: seg, Line: line, Text: sc.Text()}) {
+			return true, nil
+		}
+	}
+
+	return false, sc.Err()
+}
+
+// Verify 校验所有的压缩文件是否可以完整解压，返回每个压缩文件的校验结果
+func (ro *ReadOnly) Verify(ctx context.Context) ([]VerifyResult, error) {
+	segments, err := ro.List()
+	if err != nil {
+		return nil, err
+	}
+
+	res := make([]VerifyResult, 0, len(segments))
+	for _, seg := range segments {
+		if err = ctx.Err(); err != nil {
+			return res, err
+		}
+		if seg.CompressType == CompressTypeUnknown {
+			continue
+		}
+		res = append(res, VerifyResult{Segment: seg, Err: ro.verifySegment(seg)})
+	}
+
+	return res, nil
+}
+
+func (ro *ReadOnly) verifySegment(seg SegmentInfo) error {
+	rc, err := ro.Open(seg)
+	if err != nil {
+		return err
+	}
+	defer func() {
+		_ = rc.Close()
+	}()
+
+	_, err = io.Copy(io.Discard, rc)
+	return err
+}
+
+// Stats 统计存储目录中轮转文件的数量和大小
+func (ro *ReadOnly) Stats() (DirStats, error) {
+	segments, err := ro.List()
+	if err != nil {
+		return DirStats{}, err
+	}
+
+	var stats DirStats
+	for _, seg := range segments {
+		stats.Segments++
+		stats.TotalBytes += seg.Size
+		if seg.CompressType != CompressTypeUnknown {
+			stats.Compressed++
+			stats.CompressedBytes += seg.Size
+		}
+		if stats.Oldest.IsZero() || seg.ModTime.Before(stats.Oldest) {
+			stats.Oldest = seg.ModTime
+		}
+		if seg.ModTime.After(stats.Newest) {
+			stats.Newest = seg.ModTime
+		}
+	}
+
+	return stats, nil
+}
@@ -0,0 +1,118 @@
+// Copyright 2025 TimeWtr
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package vortexrotate
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"regexp"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestReadOnly(t *testing.T) {
+	dir := t.TempDir()
+	rotator, err := newRotator(dir, "testdata.log", WithCompress(CompressTypeZstd))
+	assert.NoError(t, err)
+
+	for i := 0; i < 3; i++ {
+		_, err = rotator.Write([]byte(fmt.Sprintf("segment %d line 1\nsegment %d line 2\n", i, i)))
+		assert.NoError(t, err)
+		rotator.writeLock.Lock()
+		assert.NoError(t, rotator.rotate(RotateReasonManual))
+		rotator.writeLock.Unlock()
+	}
+	rotator.Close()
+
+	ro, err := OpenReadOnly(dir, "testdata.log")
+	assert.NoError(t, err)
+
+	segments, err := ro.List()
+	assert.NoError(t, err)
+	// 3个轮转的文件，同时存在原始文件和压缩文件时只返回原始文件，以及最后一个写入中的空文件
+	assert.Len(t, segments, 4)
+	for i := 1; i < len(segments); i++ {
+		assert.Less(t, segments[i-1].Sequence, segments[i].Sequence)
+	}
+
+	rc, err := ro.Open(segments[0])
+	assert.NoError(t, err)
+	content, err := io.ReadAll(rc)
+	assert.NoError(t, err)
+	assert.NoError(t, rc.Close())
+	assert.Equal(t, "segment 0 line 1\nsegment 0 line 2\n", string(content))
+
+	var matches []Match
+	err = ro.Search(context.Background(), regexp.MustCompile("line 2"), func(m Match) bool {
+		matches = append(matches, m)
+		return true
+	})
+	assert.NoError(t, err)
+	assert.Len(t, matches, 3)
+	assert.Equal(t, int64(2), matches[0].Line)
+
+	stats, err := ro.Stats()
+	assert.NoError(t, err)
+	assert.Equal(t, 4, stats.Segments)
+
+	results, err := ro.Verify(context.Background())
+	assert.NoError(t, err)
+	for _, res := range results {
+		assert.NoError(t, res.Err)
+	}
+}
+
+func TestReadOnly_AdvisoryLock(t *testing.T) {
+	dir := t.TempDir()
+	rotator, err := newRotator(dir, "testdata.log", WithAdvisoryLock())
+	assert.NoError(t, err)
+	defer rotator.Close()
+
+	_, err = rotator.Write([]byte("advisory lock test\n"))
+	assert.NoError(t, err)
+
+	ro, err := OpenReadOnly(dir, "testdata.log")
+	assert.NoError(t, err)
+	segments, err := ro.List()
+	assert.NoError(t, err)
+	assert.Len(t, segments, 1)
+
+	rc, err := ro.Open(segments[0])
+	assert.NoError(t, err)
+
+	sealed := make(chan error, 1)
+	go func() {
+		rotator.writeLock.Lock()
+		defer rotator.writeLock.Unlock()
+		sealed <- rotator.rotate(RotateReasonManual)
+	}()
+
+	select {
+	case <-sealed:
+		t.Fatal("seal should wait for the shared lock held by reader")
+	case <-time.After(time.Millisecond * 50):
+	}
+
+	assert.NoError(t, rc.Close())
+	select {
+	case err = <-sealed:
+		assert.NoError(t, err)
+	case <-time.After(time.Second):
+		t.Fatal("seal should continue after reader closed")
+	}
+}
@@ -0,0 +1,553 @@
+// Copyright 2025 TimeWtr
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package vortexrotate
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"time"
+
+	"github.com/TimeWtr/vortexrotate/errorx"
+)
+
+const (
+	// DefaultRecompressCron 二次压缩任务默认的执行时间，每天凌晨3点
+	DefaultRecompressCron = "0 0 3 * * *"
+	// ZstdHighRatioLevel zstd高压缩比等级
+	ZstdHighRatioLevel = 19
+)
+
+// recompressConfig 二次压缩的配置
+type recompressConfig struct {
+	// 超过该时间的归档文件才执行二次压缩
+	after time.Duration
+	// 源压缩类型
+	from []int
+	// 目标压缩类型
+	to int
+	// 目标压缩等级
+	level int
+}
+
+// WithRecompress 开启旧归档文件的二次压缩，定时任务在每天凌晨3点扫描存储目录，将修改时间超过
+// after的、使用快速压缩算法(fromTypes，默认为snappy)压缩的归档文件，重新压缩为高压缩比的
+// 格式(比如zstd-19)，在业务低峰期用CPU换取长期的存储空间。
+func WithRecompress(after time.Duration, toType, level int, fromTypes ...int) Option {
+	return func(r *Rotator) error {
+		if !validCompressType(toType) {
+			return fmt.Errorf("recompress to type %d not support", toType)
+		}
+		if len(fromTypes) == 0 {
+			fromTypes = []int{CompressTypeSnappy}
+		}
+		for _, tp := range fromTypes {
+			if tp == toType {
+				return errors.New("recompress from type must differ from to type")
+			}
+		}
+
+		r.recompress = &recompressConfig{
+			after: after,
+			from:  fromTypes,
+			to:    toType,
+			level: level,
+		}
+		return nil
+	}
+}
+
+// recompressOld 扫描存储目录，对满足条件的旧归档文件执行二次压缩
+func (r *Rotator) recompressOld() {
+	r.archiveLock.Lock()
+	defer r.archiveLock.Unlock()
+
+	cfg := r.recompress
+	re := segmentRegexp(r.filename)
+	deadline := time.Now().Add(-cfg.after)
+	var candidates []string
+	err := filepath.WalkDir(r.dir, func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if isQuarantineDir(r.dir, path, d) {
+			return filepath.SkipDir
+		}
+		if d.IsDir() || !re.MatchString(d.Name()) {
+			return nil
+		}
+
+		tp := compressTypeOf(path)
+		matched := false
+		for _, from := range cfg.from {
+			matched = matched || tp == from
+		}
+		if !matched {
+			return nil
+		}
+
+		info, err := d.Info()
+		if err != nil {
+			return nil
+		}
+		if info.ModTime().Before(deadline) {
+			candidates = append(candidates, path)
+		}
+
+		return nil
+	})
+	if err != nil {
+		r.l.Printf("recompress: walk dir %s error: %v", r.dir, err)
+		return
+	}
+
+	for _, path := range candidates {
+		r.recompressOne(path)
+	}
+}
+
+// recompressOne 持有目录的建议锁对单个归档文件执行二次压缩，读取方不会读到正在被替换的文件
+func (r *Rotator) recompressOne(path string) {
+	cfg := r.recompress
+	if err := r.dirLock.Lock(); err != nil {
+		r.l.Printf("recompress: lock dir %s error: %v", r.dir, err)
+		return
+	}
+	defer func() {
+		_ = r.dirLock.Unlock()
+	}()
+
+	dst, err := recompressFile(path, cfg.to, cfg.level, r.rename)
+	if err != nil {
+		r.l.Printf("recompress: recompress %s error: %v", path, err)
+		if cerr := checkArchive(path); cerr != nil {
+			// 归档文件本身已经损坏，隔离之后不再重复尝试
+			if qerr := r.quarantine(path, cerr); qerr != nil {
+				r.l.Printf("recompress: quarantine %s error: %v", path, qerr)
+			}
+		}
+		return
+	}
+	r.l.Printf("recompress: %s -> %s", path, dst)
+	if err = moveSidecars(path, dst, r.rename); err != nil {
+		r.l.Printf("recompress: update sidecars of %s error: %v", dst, err)
+	}
+	r.manifestLock.Lock()
+	err = recordRecompress(r.dir, path, dst)
+	r.manifestLock.Unlock()
+	if err != nil {
+		r.l.Printf("recompress: update manifest of %s error: %v", dst, err)
+	}
+}
+
+// recompressFile 将归档文件解压之后重新压缩为目标格式，先写入临时文件并校验内容，完成之后rename
+// 为正式文件并删除源文件，目标文件保留源文件的修改时间，不影响按照时间执行的清理策略
+func recompressFile(path string, toType, level int, rename renamer) (string, error) {
+	resources.acquire()
+	defer resources.release()
+
+	info, err := os.Stat(path)
+	if err != nil {
+		return "", err
+	}
+
+	src, err := os.Open(path)
+	if err != nil {
+		return "", err
+	}
+	defer func() {
+		_ = src.Close()
+	}()
+
+	dr, err := newDecompressReader(compressTypeOf(path), src)
+	if err != nil {
+		return "", err
+	}
+	defer func() {
+		_ = dr.Close()
+	}()
+
+	dst := compressFn(trimCompressExt(path), toType)
+	tmp := dst + TmpFileExt
+	if err = writeCompressed(tmp, toType, level, dr, info.ModTime()); err != nil {
+		_ = os.Remove(tmp)
+		return "", err
+	}
+
+	if err = os.Chtimes(tmp, info.ModTime(), info.ModTime()); err != nil {
+		_ = os.Remove(tmp)
+		return "", err
+	}
+
+	// 临时文件已经fsync，解压之后与源文件的内容一致才替换，rename会fsync所在的目录，
+	// 新文件持久化之后才删除源文件
+	if err = verifyRecompressed(path, tmp, toType); err != nil {
+		_ = os.Remove(tmp)
+		return "", err
+	}
+
+	if err = rename(tmp, dst); err != nil {
+		_ = os.Remove(tmp)
+		return "", err
+	}
+
+	if err = os.Remove(path); err != nil {
+		return dst, err
+	}
+
+	return dst, syncDir(filepath.Dir(path))
+}
+
+// verifyRecompressed 比较源归档文件与转换之后的文件tmp解压之后的内容，toType为tmp的压缩类型
+func verifyRecompressed(src, tmp string, toType int) error {
+	sf, err := os.Open(src)
+	if err != nil {
+		return err
+	}
+	defer func() {
+		_ = sf.Close()
+	}()
+
+	sr, err := newDecompressReader(compressTypeOf(src), sf)
+	if err != nil {
+		return err
+	}
+	defer func() {
+		_ = sr.Close()
+	}()
+
+	tf, err := os.Open(tmp)
+	if err != nil {
+		return err
+	}
+	defer func() {
+		_ = tf.Close()
+	}()
+
+	tr, err := newDecompressReader(toType, tf)
+	if err != nil {
+		return err
+	}
+	defer func() {
+		_ = tr.Close()
+	}()
+
+	return equalReaders(sr, tr)
+}
+
+// writeCompressed 将r中的数据压缩之后写入新创建的文件path中，mtime为gzip文件头中的修改时间
+func writeCompressed(path string, tp, level int, r io.Reader, mtime time.Time) error {
+	out, err := os.OpenFile(path, os.O_CREATE|os.O_EXCL|os.O_WRONLY|openNoFollow, ReadWriteFile)
+	if err != nil {
+		return err
+	}
+	defer func() {
+		_ = out.Close()
+	}()
+
+	cw, err := newCompressWriter(tp, level, out)
+	if err != nil {
+		return err
+	}
+	setGzipHeader(cw, gzipHeaderOf(strings.TrimSuffix(path, TmpFileExt), mtime, ""))
+
+	buf := resources.getBuffer()
+	defer resources.putBuffer(buf)
+	if _, err = io.CopyBuffer(cw, r, *buf); err != nil {
+		_ = cw.Close()
+		return err
+	}
+
+	if err = cw.Close(); err != nil {
+		return err
+	}
+
+	if err = out.Sync(); err != nil {
+		return err
+	}
+
+	return out.Close()
+}
+
+// RecompressOptions 离线转换归档文件格式的参数
+type RecompressOptions struct {
+	// 基础文件名称，格式与NewRotator一致，比如：app.log，为空时转换目录中所有的轮转文件
+	Filename string
+	// 目标压缩等级，0表示目标压缩类型的默认等级
+	Level int
+	// 只转换修改时间早于该时间的归档文件，零值表示不限制
+	Before time.Time
+	// 是否只计算需要转换的文件，不执行转换
+	DryRun bool
+}
+
+// RecompressResult 单个归档文件的转换结果
+type RecompressResult struct {
+	// 源归档文件路径
+	Source string
+	// 转换之后的归档文件路径
+	Target string
+	// 源归档文件大小
+	SourceSize int64
+	// 转换之后的归档文件大小，演练模式下为0
+	TargetSize int64
+	// 转换失败的原因，失败时源文件保持不变
+	Err error
+}
+
+// RecompressReport 转换报告
+type RecompressReport struct {
+	// 所有候选文件的转换结果，按照路径排序
+	Files []RecompressResult
+	// 转换成功的文件数量
+	Converted int
+	// 转换失败的文件数量
+	Failed int
+}
+
+// Recompress 将存储目录中fromType格式的归档文件转换为toType格式，比如将历史的.gz文件统一转换为.zst，
+// 每个文件先解压并压缩到临时文件，完成之后rename为正式文件再删除源文件，中途失败或者进程崩溃时
+// 源文件保持不变，残留的临时文件在下一次启动时清理。转换之后的文件保留源文件的修改时间，不影响按照
+// 时间执行的清理策略。源文件的关联文件同步更新：
+// 1. 校验和文件(.sha256)重新计算
+// 2. 完成标记文件(.done)重命名
+// 3. 当天的汇总文件(YYYYMMDD.summary.json)中的压缩后大小和压缩比重新计算
+// 4. 存在归档清单(<name>.manifest)时追加转换之后的文件的记录，替换源文件的记录
+// 单个文件转换失败时继续转换其余的文件，失败的原因记录在报告中。
+func Recompress(dir string, fromType, toType int, opts RecompressOptions) (*RecompressReport, error) {
+	for _, tp := range []int{fromType, toType} {
+		if !validCompressType(tp) {
+			return nil, fmt.Errorf("recompress type %d not support", tp)
+		}
+	}
+	if fromType == toType {
+		return nil, errors.New("recompress from type must differ from to type")
+	}
+
+	re := anySegmentRegexp
+	if opts.Filename != "" {
+		name, _, err := splitFilename(opts.Filename)
+		if err != nil {
+			return nil, err
+		}
+		re = segmentRegexp(name)
+	}
+
+	dir, err := normalizeDir(dir)
+	if err != nil {
+		return nil, err
+	}
+
+	level := opts.Level
+	if level == 0 {
+		level = defaultCompressLevel(toType)
+	}
+
+	report := &RecompressReport{}
+	err = filepath.WalkDir(dir, func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if d.IsDir() || !re.MatchString(d.Name()) || compressTypeOf(path) != fromType {
+			return nil
+		}
+
+		info, err := d.Info()
+		if err != nil {
+			return nil
+		}
+		if !opts.Before.IsZero() && !info.ModTime().Before(opts.Before) {
+			return nil
+		}
+
+		report.Files = append(report.Files, RecompressResult{
+			Source:     path,
+			Target:     compressFn(trimCompressExt(path), toType),
+			SourceSize: info.Size(),
+		})
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	if opts.DryRun {
+		return report, nil
+	}
+
+	for i := range report.Files {
+		res := &report.Files[i]
+		res.TargetSize, res.Err = convertArchive(dir, res.Source, res.Target, toType, level)
+		if res.Err != nil {
+			report.Failed++
+			continue
+		}
+		report.Converted++
+	}
+
+	return report, nil
+}
+
+// anySegmentRegexp 匹配任意基础文件名称的轮转文件
+var anySegmentRegexp = regexp.MustCompile(`^.+_(\d{8})_(\d{4,})\.log`)
+
+// segmentBaseName 轮转文件名称中的基础文件名称，比如app_20250101_0001.log.gz为app，不是轮转文件时返回空
+func segmentBaseName(fn string) string {
+	matches := anySegmentRegexp.FindStringSubmatch(fn)
+	if len(matches) < 3 {
+		return ""
+	}
+
+	return strings.TrimSuffix(matches[0], fmt.Sprintf("_%s_%s.log", matches[1], matches[2]))
+}
+
+// defaultCompressLevel 压缩类型的默认压缩等级
+func defaultCompressLevel(tp int) int {
+	switch tp {
+	case CompressTypeGzip:
+		return GzipDefaultCompression
+	case CompressTypeZstd:
+		return ZstdDefaultLevel
+	case CompressTypeXz:
+		return XzDefaultCompression
+	default:
+		return 0
+	}
+}
+
+// convertArchive 转换单个归档文件并更新关联文件，返回转换之后的文件大小
+func convertArchive(dir, src, dst string, toType, level int) (int64, error) {
+	if _, err := os.Lstat(dst); err == nil {
+		return 0, fmt.Errorf("%w: %s", errorx.ErrSegmentExists, dst)
+	}
+
+	info, err := os.Stat(src)
+	if err != nil {
+		return 0, err
+	}
+
+	lock, err := lockArchive(dir, src)
+	if err != nil {
+		return 0, err
+	}
+	defer func() {
+		_ = lock.Unlock()
+		_ = lock.Close()
+	}()
+
+	if _, err = recompressFile(src, toType, level, renameLocal); err != nil {
+		return 0, fmt.Errorf("%w: %s: %w", errorx.ErrCompressFailed, src, err)
+	}
+
+	dstInfo, err := os.Stat(dst)
+	if err != nil {
+		return 0, err
+	}
+	if err = moveSidecars(src, dst, renameLocal); err != nil {
+		return dstInfo.Size(), err
+	}
+	if err = recordRecompress(dir, src, dst); err != nil {
+		return dstInfo.Size(), err
+	}
+
+	return dstInfo.Size(), updateSummary(dir, filepath.Base(src), dstInfo.Size()-info.Size(), renameLocal)
+}
+
+// lockArchive 获取归档文件所属轮转器的目录建议锁，与开启了建议锁的轮转器以及读取方互斥，
+// 锁文件不存在时说明轮转器没有开启建议锁，返回nil
+func lockArchive(dir, src string) (*dirLock, error) {
+	name := segmentBaseName(filepath.Base(src))
+	if name == "" {
+		return nil, nil
+	}
+	if _, err := os.Lstat(lockPath(dir, name)); err != nil {
+		if os.IsNotExist(err) {
+			return nil, nil
+		}
+		return nil, err
+	}
+
+	l, err := openDirLock(dir, name)
+	if err != nil {
+		return nil, err
+	}
+	if err = l.Lock(); err != nil {
+		_ = l.Close()
+		return nil, err
+	}
+
+	return l, nil
+}
+
+// moveSidecars 归档文件转换格式之后更新关联文件：重新计算校验和文件，重命名完成标记文件
+func moveSidecars(src, dst string, rename renamer) error {
+	if _, err := os.Stat(src + ChecksumFileExt); err == nil {
+		sum, err := fileChecksum(dst)
+		if err != nil {
+			return err
+		}
+		if err = writeChecksum(dst, sum, rename); err != nil {
+			return err
+		}
+		if err = os.Remove(src + ChecksumFileExt); err != nil && !os.IsNotExist(err) {
+			return err
+		}
+	}
+
+	if err := rename(src+DoneFileExt, dst+DoneFileExt); err != nil && !os.IsNotExist(err) {
+		return err
+	}
+
+	return nil
+}
+
+// updateSummary 归档文件大小变化之后重新计算文件所在日期的汇总文件，汇总文件不存在时忽略
+func updateSummary(dir, name string, delta int64, rename renamer) error {
+	matches := anySegmentRegexp.FindStringSubmatch(name)
+	if len(matches) < 2 {
+		return nil
+	}
+
+	path := filepath.Join(dir, matches[1]+SummaryFileExt)
+	bs, err := os.ReadFile(path)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil
+		}
+		return err
+	}
+
+	var s DailySummary
+	if err = json.Unmarshal(bs, &s); err != nil {
+		return fmt.Errorf("parse summary file %s error: %w", path, err)
+	}
+	if s.CompressedBytes == 0 {
+		// 当天的文件没有压缩过，汇总文件中没有压缩后的大小
+		return nil
+	}
+
+	s.CompressedBytes = max(s.CompressedBytes+delta, 0)
+	s.CompressionRatio = 0
+	if bs, err = encodeSummary(s); err != nil {
+		return err
+	}
+
+	return writeFileAtomic(path, bs, rename)
+}
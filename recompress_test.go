@@ -0,0 +1,150 @@
+// Copyright 2025 TimeWtr
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package vortexrotate
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/TimeWtr/vortexrotate/errorx"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestRecompress(t *testing.T) {
+	dir := t.TempDir()
+	rotator, err := newRotator(dir, "testdata.log",
+		WithCompress(CompressTypeGzip), WithChecksum(), WithDoneMarker(DoneMarkerFile))
+	assert.NoError(t, err)
+	var expected strings.Builder
+	for i := 0; i < 3; i++ {
+		line := fmt.Sprintf("recompress line %d\n", i)
+		expected.WriteString(line)
+		_, err = rotator.Write([]byte(line))
+		assert.NoError(t, err)
+		assert.NoError(t, rotator.Rotate())
+	}
+	assert.NoError(t, rotator.Close())
+
+	ro, err := OpenReadOnly(dir, "testdata.log")
+	assert.NoError(t, err)
+	segments, err := ro.List()
+	assert.NoError(t, err)
+	var gzBytes int64
+	for _, seg := range segments {
+		if seg.CompressType == CompressTypeGzip {
+			gzBytes += seg.Size
+		}
+	}
+	summary := filepath.Join(dir, time.Now().Format(Layout)+SummaryFileExt)
+	bs, err := json.Marshal(DailySummary{RawBytes: 1024, CompressedBytes: gzBytes})
+	assert.NoError(t, err)
+	assert.NoError(t, os.WriteFile(summary, bs, ReadWriteFile))
+
+	_, err = Recompress(dir, CompressTypeGzip, CompressTypeGzip, RecompressOptions{})
+	assert.Error(t, err)
+
+	// 演练模式不修改任何文件
+	report, err := Recompress(dir, CompressTypeGzip, CompressTypeZstd,
+		RecompressOptions{Filename: "testdata.log", DryRun: true})
+	assert.NoError(t, err)
+	assert.Len(t, report.Files, 3)
+	assert.Zero(t, report.Converted)
+	for _, res := range report.Files {
+		assert.FileExists(t, res.Source)
+		assert.NoFileExists(t, res.Target)
+	}
+
+	report, err = Recompress(dir, CompressTypeGzip, CompressTypeZstd, RecompressOptions{Filename: "testdata.log"})
+	assert.NoError(t, err)
+	assert.Equal(t, 3, report.Converted)
+	assert.Zero(t, report.Failed)
+	var zstBytes int64
+	for _, res := range report.Files {
+		assert.NoError(t, res.Err)
+		assert.NoFileExists(t, res.Source)
+		assert.NoFileExists(t, res.Source+ChecksumFileExt)
+		assert.NoFileExists(t, res.Source+DoneFileExt)
+		assert.FileExists(t, res.Target+DoneFileExt)
+		assert.Equal(t, ".zst", filepath.Ext(res.Target))
+
+		sum, err := fileChecksum(res.Target)
+		assert.NoError(t, err)
+		content, err := os.ReadFile(res.Target + ChecksumFileExt)
+		assert.NoError(t, err)
+		assert.Equal(t, fmt.Sprintf("%x  %s\n", sum, filepath.Base(res.Target)), string(content))
+		zstBytes += res.TargetSize
+	}
+
+	// 转换之后的文件可以正常读取
+	segments, err = ro.List()
+	assert.NoError(t, err)
+	var actual strings.Builder
+	for _, seg := range segments {
+		assert.NotEqual(t, CompressTypeGzip, seg.CompressType)
+		rc, err := ro.Open(seg)
+		assert.NoError(t, err)
+		_, err = io.Copy(&actual, rc)
+		assert.NoError(t, err)
+		assert.NoError(t, rc.Close())
+	}
+	assert.Equal(t, expected.String(), actual.String())
+
+	bs, err = os.ReadFile(summary)
+	assert.NoError(t, err)
+	var s DailySummary
+	assert.NoError(t, json.Unmarshal(bs, &s))
+	assert.Equal(t, zstBytes, s.CompressedBytes)
+	assert.InDelta(t, 1024/float64(zstBytes), s.CompressionRatio, 1e-9)
+}
+
+func TestRecompress_Verify(t *testing.T) {
+	dir := t.TempDir()
+	src := filepath.Join(dir, "app_20250101_0001.log.gz")
+	mtime := time.Now()
+	assert.NoError(t, writeCompressed(src, CompressTypeGzip, GzipDefaultCompression,
+		strings.NewReader("recompress verify\n"), mtime))
+
+	// 内容一致时校验通过
+	same := filepath.Join(dir, "app_20250101_0001.log.zst"+TmpFileExt)
+	assert.NoError(t, writeCompressed(same, CompressTypeZstd, ZstdDefaultLevel,
+		strings.NewReader("recompress verify\n"), mtime))
+	assert.NoError(t, verifyRecompressed(src, same, CompressTypeZstd))
+
+	// 内容不一致时不替换源文件
+	other := filepath.Join(dir, "other.zst"+TmpFileExt)
+	assert.NoError(t, writeCompressed(other, CompressTypeZstd, ZstdDefaultLevel,
+		strings.NewReader("recompress mismatch\n"), mtime))
+	assert.ErrorIs(t, verifyRecompressed(src, other, CompressTypeZstd), errorx.ErrArchiveMismatch)
+
+	assert.Equal(t, "app", segmentBaseName(filepath.Base(src)))
+	assert.Equal(t, "", segmentBaseName("app.lock"))
+
+	// 轮转器没有开启建议锁时不加锁，开启之后持有目录的排他锁
+	l, err := lockArchive(dir, src)
+	assert.NoError(t, err)
+	assert.Nil(t, l)
+	assert.NoError(t, os.WriteFile(lockPath(dir, "app"), nil, ReadWriteFile))
+	l, err = lockArchive(dir, src)
+	assert.NoError(t, err)
+	assert.NotNil(t, l)
+	assert.NoError(t, l.Unlock())
+	assert.NoError(t, l.Close())
+}
@@ -0,0 +1,147 @@
+// Copyright 2025 TimeWtr
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package vortexrotate
+
+import (
+	"io/fs"
+	"path/filepath"
+	"sort"
+	"time"
+)
+
+const (
+	// ReconcileInterval 检查遗留的未封存文件的时间间隔
+	ReconcileInterval = time.Hour
+	// reconcileJobName 检查遗留文件的后台任务名称
+	reconcileJobName = "reconcile"
+)
+
+// needReconcile 是否需要定时检查遗留的未封存文件或者等待上传的文件
+func (r *Rotator) needReconcile() bool {
+	return r.sealLeftovers() || r.uploader != nil
+}
+
+// sealLeftovers 是否需要封存遗留的未封存文件，进程在轮转之后、压缩(或加密)之前退出时，原始的
+// 轮转文件会一直留在目录中。开启了延迟压缩或者压缩时间窗口时由延迟压缩的扫描任务处理。
+func (r *Rotator) sealLeftovers() bool {
+	if r.cpr.compress {
+		return r.cpr.delay == 0 && r.cpr.window == nil
+	}
+
+	return r.encryption != nil
+}
+
+// leftoverFiles 按照文件名称升序返回存储目录中修改时间早于before的未封存的原始轮转文件，不包括
+// 当前写入的文件、封存中的文件、压缩之后保留的源文件以及小于最小压缩大小的文件
+func (r *Rotator) leftoverFiles(before time.Time) ([]string, error) {
+	r.writeLock.RLock()
+	if r.f == nil {
+		// 轮转器已经关闭
+		r.writeLock.RUnlock()
+		return nil, nil
+	}
+	active := r.f.Name()
+	r.writeLock.RUnlock()
+
+	re := segmentRegexp(r.filename)
+	var files []string
+	err := filepath.WalkDir(r.dir, func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if isQuarantineDir(r.dir, path, d) {
+			return filepath.SkipDir
+		}
+		if d.IsDir() || path == active || r.tracker.pending(path) {
+			return nil
+		}
+
+		// 只处理原始的轮转文件，不包括压缩文件、校验和文件等关联文件
+		matches := re.FindStringSubmatch(d.Name())
+		if len(matches) == 0 || matches[0] != d.Name() {
+			return nil
+		}
+
+		info, err := d.Info()
+		if err != nil || !info.ModTime().Before(before) {
+			return nil
+		}
+		if info.Size() < r.cpr.minSize && r.encryption == nil {
+			// 不压缩的小文件封存之后仍然是原始文件
+			return nil
+		}
+		if r.cpr.keepSource && archived(path, r.cpr.compressType) {
+			return nil
+		}
+
+		files = append(files, path)
+		return nil
+	})
+	sort.Strings(files)
+
+	return files, err
+}
+
+// reconcile 封存进程上一次退出时遗留的未封存文件，启动时执行一次，之后定时执行，同时重试之前
+// 因为磁盘空间不足等原因没有完成封存的文件以及上传失败的文件。启动时只处理创建轮转器之前的
+// 文件，之后只处理至少一个检查间隔之前的文件，不与正常的封存流程竞争
+func (r *Rotator) reconcile() {
+	if r.uploader != nil {
+		r.retryUploads()
+	}
+	if !r.sealLeftovers() {
+		return
+	}
+
+	r.archiveLock.Lock()
+	defer r.archiveLock.Unlock()
+
+	before := time.Now().Add(-ReconcileInterval)
+	if before.Before(r.createdAt) {
+		before = r.createdAt
+	}
+	files, err := r.leftoverFiles(before)
+	if err != nil {
+		r.l.Printf("reconcile: walk dir %s error: %v", r.dir, err)
+		return
+	}
+	if len(files) == 0 {
+		return
+	}
+
+	var cs CompressStrategy
+	if r.cpr.compress {
+		if cs, err = r.newCompressStrategy(); err != nil {
+			r.l.Printf("reconcile: create compress strategy error: %v", err)
+			return
+		}
+	}
+
+	for _, path := range files {
+		if r.sig.Load() == 1 {
+			return
+		}
+		if r.cpr.compress && !r.reserveSpace(path) {
+			// 磁盘空间不足，等待清理之后下一次检查再封存
+			return
+		}
+
+		r.l.Printf("reconcile: seal leftover file %s", path)
+		var pause RotatePause
+		if err = r.sealFile(path, cs, &pause); err != nil {
+			r.l.Printf("reconcile: seal %s error: %v", path, err)
+		}
+	}
+}
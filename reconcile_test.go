@@ -0,0 +1,99 @@
+// Copyright 2025 TimeWtr
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package vortexrotate
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// leftoverFile 模拟进程在轮转之后、压缩之前退出时遗留的原始轮转文件
+func leftoverFile(t *testing.T, dir string) string {
+	path := filepath.Join(dir, "testdata_"+time.Now().Format(Layout)+"_0001.log")
+	assert.NoError(t, os.WriteFile(path, []byte("leftover segment\n"), ReadWriteFile))
+	past := time.Now().Add(-time.Minute)
+	assert.NoError(t, os.Chtimes(path, past, past))
+
+	return path
+}
+
+func TestRotator_Reconcile(t *testing.T) {
+	dir := t.TempDir()
+	path := leftoverFile(t, dir)
+
+	rotator, err := newRotator(dir, "testdata.log", WithCompress(CompressTypeGzip))
+	assert.NoError(t, err)
+	defer rotator.Close()
+
+	gz := compressFn(path, CompressTypeGzip)
+	assert.Eventually(t, func() bool {
+		_, err := os.Stat(gz)
+		return err == nil
+	}, 5*time.Second, 10*time.Millisecond)
+	assert.NoFileExists(t, path)
+
+	// 当前写入的文件不会被封存
+	assert.FileExists(t, rotator.f.Name())
+}
+
+func TestRotator_ReconcileSynchronous(t *testing.T) {
+	dir := t.TempDir()
+	path := leftoverFile(t, dir)
+
+	rotator, err := newRotator(dir, "testdata.log",
+		WithCompress(CompressTypeGzip),
+		WithSynchronousMode(),
+	)
+	assert.NoError(t, err)
+	defer rotator.Close()
+
+	assert.FileExists(t, compressFn(path, CompressTypeGzip))
+	assert.NoFileExists(t, path)
+}
+
+func TestRotator_ReconcileSkip(t *testing.T) {
+	testCases := []struct {
+		name string
+		opts []Option
+	}{
+		{
+			name: "without compress",
+		},
+		{
+			name: "compress delay",
+			opts: []Option{
+				WithCompress(CompressTypeGzip),
+				WithCompressDelay(time.Hour),
+			},
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			dir := t.TempDir()
+			path := leftoverFile(t, dir)
+
+			rotator, err := newRotator(dir, "testdata.log", tc.opts...)
+			assert.NoError(t, err)
+			assert.False(t, rotator.needReconcile())
+			assert.NoError(t, rotator.Close())
+			assert.FileExists(t, path)
+		})
+	}
+}
@@ -0,0 +1,269 @@
+// Copyright 2025 TimeWtr
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package vortexrotate
+
+import (
+	"compress/gzip"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/TimeWtr/vortexrotate/errorx"
+)
+
+// DefaultUploadTimeout 单次上传的默认超时时间
+const DefaultUploadTimeout = 5 * time.Minute
+
+// CompressorFactory 第三方的压缩实现，通过RegisterCompressor注册之后与内置的压缩类型一样用于
+// 轮转文件的压缩、校验、读取和重新压缩
+type CompressorFactory interface {
+	// Ext 压缩文件的后缀名，包括'.'，比如：".lz4"
+	Ext() string
+	// NewWriter 创建压缩写入器，Close时刷新所有数据，但不能关闭w
+	NewWriter(w io.Writer, level int) (io.WriteCloser, error)
+	// NewReader 创建解压读取器
+	NewReader(r io.Reader) (io.ReadCloser, error)
+}
+
+// Uploader 将封存完成的轮转文件上传到远端存储
+type Uploader interface {
+	// Upload 上传文件，path为封存之后(压缩、加密之后)的文件路径
+	Upload(ctx context.Context, path string) error
+}
+
+// UploaderFactory 根据参数创建上传器，参数的含义由上传器自行定义，比如：bucket、prefix等
+type UploaderFactory func(params map[string]string) (Uploader, error)
+
+// registeredCompressor 注册的压缩实现，内置的压缩类型也通过注册表描述，没有factory
+type registeredCompressor struct {
+	name    string
+	factory CompressorFactory
+	// level 没有指定压缩级别时使用的默认级别
+	level int
+	// strategy 根据轮转器的配置创建压缩策略，同时校验压缩级别
+	strategy func(r *Rotator, level int) (CompressStrategy, error)
+}
+
+var (
+	registryLock sync.RWMutex
+	// 压缩实现的名称与压缩类型的映射，包括内置的压缩类型
+	compressorNames = map[string]int{
+		"gzip":   CompressTypeGzip,
+		"zstd":   CompressTypeZstd,
+		"snappy": CompressTypeSnappy,
+		"xz":     CompressTypeXz,
+		"s2":     CompressTypeS2,
+	}
+	// 压缩实现，key为压缩类型，第三方实现的压缩类型在注册时分配
+	compressors = map[int]registeredCompressor{
+		CompressTypeGzip:   {name: "gzip", level: gzip.DefaultCompression, strategy: newGzipStrategy},
+		CompressTypeZstd:   {name: "zstd", level: ZstdDefaultLevel, strategy: newZstdStrategy},
+		CompressTypeSnappy: {name: "snappy", strategy: newSnappyStrategy},
+		CompressTypeXz:     {name: "xz", level: XzDefaultCompression, strategy: newXzStrategy},
+		CompressTypeS2:     {name: "s2", strategy: newS2Strategy},
+	}
+	// 下一个分配给第三方压缩实现的压缩类型
+	nextCompressType = _maxCompressType + 1
+	// 上传器的名称与工厂函数的映射
+	uploaders = make(map[string]UploaderFactory)
+)
+
+// RegisterCompressor 注册名称为name的压缩实现，返回分配的压缩类型，可以直接用于WithCompress等
+// 按照压缩类型配置的选项，也可以通过WithCompressName按照名称引用，通常在第三方模块的init中调用。
+// 名称和压缩文件的后缀名都不能与已经注册的实现重复，第三方实现不支持压缩类型专属的选项
+// (CompressOptions)以及边写边压缩。
+func RegisterCompressor(name string, factory CompressorFactory) (int, error) {
+	if name == "" || factory == nil {
+		return 0, errors.New("compressor name and factory must not be empty")
+	}
+	ext := factory.Ext()
+	if len(ext) < 2 || ext[0] != '.' || strings.ContainsAny(ext, `/\`) {
+		return 0, fmt.Errorf("invalid compressor ext %q", ext)
+	}
+
+	registryLock.Lock()
+	defer registryLock.Unlock()
+
+	if _, ok := compressorNames[name]; ok {
+		return 0, fmt.Errorf("compressor %q already registered", name)
+	}
+	for _, reserved := range []string{TmpFileExt, ChecksumFileExt, DoneFileExt, EncryptFileExt, TombstoneFileExt, UploadPendingExt} {
+		if ext == reserved {
+			return 0, fmt.Errorf("compressor ext %q is reserved", ext)
+		}
+	}
+	for tp := _minCompressType; tp <= _maxCompressType; tp++ {
+		if compressFn("", tp) == ext {
+			return 0, fmt.Errorf("compressor ext %q already registered", ext)
+		}
+	}
+	for _, c := range compressors {
+		if c.factory != nil && c.factory.Ext() == ext {
+			return 0, fmt.Errorf("compressor ext %q already registered", ext)
+		}
+	}
+
+	tp := nextCompressType
+	nextCompressType++
+	compressorNames[name] = tp
+	compressors[tp] = registeredCompressor{
+		name:    name,
+		factory: factory,
+		strategy: func(_ *Rotator, level int) (CompressStrategy, error) {
+			return &streamCompressor{tp: tp, level: level}, nil
+		},
+	}
+	return tp, nil
+}
+
+// CompressTypeByName 根据名称获取压缩类型，包括内置的压缩类型(gzip、zstd、snappy、xz、s2)和第三方
+// 注册的压缩实现
+func CompressTypeByName(name string) (int, bool) {
+	registryLock.RLock()
+	defer registryLock.RUnlock()
+
+	tp, ok := compressorNames[name]
+	return tp, ok
+}
+
+// WithCompressName 按照名称开启压缩，名称可以是内置的压缩类型或者通过RegisterCompressor注册的
+// 名称，其他与WithCompress相同
+func WithCompressName(name string, level ...int) Option {
+	return func(r *Rotator) error {
+		tp, ok := CompressTypeByName(name)
+		if !ok {
+			return fmt.Errorf("%w: compressor %q is not registered", errorx.ErrCompressType, name)
+		}
+
+		return WithCompress(tp, level...)(r)
+	}
+}
+
+// compressCodecOf 获取压缩类型的注册信息，包括内置的压缩类型
+func compressCodecOf(tp int) (registeredCompressor, bool) {
+	registryLock.RLock()
+	defer registryLock.RUnlock()
+
+	c, ok := compressors[tp]
+	return c, ok
+}
+
+// compressorOf 获取第三方注册的压缩实现
+func compressorOf(tp int) (CompressorFactory, bool) {
+	c, ok := compressCodecOf(tp)
+	return c.factory, ok && c.factory != nil
+}
+
+// registeredCompressTypes 返回所有第三方注册的压缩类型
+func registeredCompressTypes() []int {
+	registryLock.RLock()
+	defer registryLock.RUnlock()
+
+	res := make([]int, 0, len(compressors))
+	for tp, c := range compressors {
+		if c.factory != nil {
+			res = append(res, tp)
+		}
+	}
+	return res
+}
+
+// validCompressType 判断是否为内置或者已经注册的压缩类型
+func validCompressType(tp int) bool {
+	_, ok := compressCodecOf(tp)
+	return ok
+}
+
+// streamCompressor 第三方压缩实现的压缩策略，每次压缩时创建新的压缩写入器
+type streamCompressor struct {
+	tp    int
+	level int
+	w     io.Writer
+	f     *os.File
+}
+
+func (s *streamCompressor) Reset(w io.Writer, f *os.File) {
+	s.w, s.f = w, f
+}
+
+func (s *streamCompressor) Compress() error {
+	if s.f == nil {
+		return os.ErrClosed
+	}
+	defer func() {
+		_ = s.f.Close()
+	}()
+
+	cw, err := newCompressWriter(s.tp, s.level, s.w)
+	if err != nil {
+		return err
+	}
+
+	buf := resources.getBuffer()
+	defer resources.putBuffer(buf)
+	if _, err = io.CopyBuffer(cw, s.f, *buf); err != nil {
+		_ = cw.Close()
+		return err
+	}
+
+	return cw.Close()
+}
+
+// RegisterUploader 注册名称为name的上传器，通过WithUploader按照名称引用，通常在第三方模块的init
+// 中调用，名称不能重复
+func RegisterUploader(name string, factory UploaderFactory) error {
+	if name == "" || factory == nil {
+		return errors.New("uploader name and factory must not be empty")
+	}
+
+	registryLock.Lock()
+	defer registryLock.Unlock()
+
+	if _, ok := uploaders[name]; ok {
+		return fmt.Errorf("uploader %q already registered", name)
+	}
+	uploaders[name] = factory
+	return nil
+}
+
+// WithUploader 使用名称为name的上传器上传封存完成的轮转文件，params传递给上传器的工厂函数。
+// 封存时在文件旁边写入<文件名>.upload标记并放入后台上传队列，不阻塞轮转，失败时按照
+// UploadAttempts重试，上传成功之后才写入完成标记和归档清单并删除.upload标记。全部重试失败时
+// 发送EventUploadFailed事件，本地的文件和.upload标记保留，由reconcile定时重新上传。上传使用
+// 的上下文在轮转器关闭时取消，关闭时先等待队列中的文件(包括WithSealOnClose封存的文件)各上传
+// 一次，Shutdown的ctx到期时提前取消，没有上传完成的文件在下一次启动时上传，同步模式下封存时
+// 直接上传一次，失败的文件在下一次启动时重试。
+func WithUploader(name string, params map[string]string) Option {
+	return func(r *Rotator) error {
+		registryLock.RLock()
+		factory, ok := uploaders[name]
+		registryLock.RUnlock()
+		if !ok {
+			return fmt.Errorf("uploader %q is not registered", name)
+		}
+
+		u, err := factory(params)
+		if err != nil {
+			return fmt.Errorf("create uploader %q error: %w", name, err)
+		}
+		r.uploader = u
+		return nil
+	}
+}
@@ -0,0 +1,223 @@
+// Copyright 2025 TimeWtr
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package vortexrotate
+
+import (
+	"context"
+	"errors"
+	"io"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/TimeWtr/vortexrotate/errorx"
+	"github.com/stretchr/testify/assert"
+)
+
+// identityCompressor 不压缩数据的测试压缩实现
+type identityCompressor struct{}
+
+func (identityCompressor) Ext() string { return ".id" }
+
+func (identityCompressor) NewWriter(w io.Writer, _ int) (io.WriteCloser, error) {
+	return nopWriteCloser{w}, nil
+}
+
+func (identityCompressor) NewReader(r io.Reader) (io.ReadCloser, error) {
+	return io.NopCloser(r), nil
+}
+
+// blockingCompressor 关闭release之前压缩一直阻塞的测试压缩实现
+type blockingCompressor struct {
+	identityCompressor
+	release chan struct{}
+}
+
+func (blockingCompressor) Ext() string { return ".block" }
+
+func (c blockingCompressor) NewWriter(w io.Writer, level int) (io.WriteCloser, error) {
+	<-c.release
+	return c.identityCompressor.NewWriter(w, level)
+}
+
+type nopWriteCloser struct {
+	io.Writer
+}
+
+func (nopWriteCloser) Close() error { return nil }
+
+// memUploader 记录上传路径的测试上传器
+type memUploader struct {
+	lock  sync.Mutex
+	paths []string
+	err   error
+}
+
+func (u *memUploader) Upload(_ context.Context, path string) error {
+	u.lock.Lock()
+	defer u.lock.Unlock()
+
+	if u.err != nil {
+		return u.err
+	}
+	u.paths = append(u.paths, path)
+	return nil
+}
+
+var (
+	registerOnce sync.Once
+	identityType int
+	blockingType int
+	testUploader = &memUploader{}
+	testBlocking = blockingCompressor{release: make(chan struct{})}
+)
+
+func registerTestBackends(t *testing.T) {
+	registerOnce.Do(func() {
+		var err error
+		identityType, err = RegisterCompressor("test-identity", identityCompressor{})
+		assert.NoError(t, err)
+		blockingType, err = RegisterCompressor("test-blocking", testBlocking)
+		assert.NoError(t, err)
+		assert.NoError(t, RegisterUploader("test-mem", func(params map[string]string) (Uploader, error) {
+			if params["fail"] != "" {
+				return &memUploader{err: errors.New(params["fail"])}, nil
+			}
+			return testUploader, nil
+		}))
+	})
+}
+
+func TestRegisterCompressor(t *testing.T) {
+	registerTestBackends(t)
+
+	tp, ok := CompressTypeByName("test-identity")
+	assert.True(t, ok)
+	assert.Equal(t, identityType, tp)
+	assert.Greater(t, tp, _maxCompressType)
+	tp, ok = CompressTypeByName("gzip")
+	assert.True(t, ok)
+	assert.Equal(t, CompressTypeGzip, tp)
+
+	_, err := RegisterCompressor("test-identity", identityCompressor{})
+	assert.Error(t, err)
+	_, err = RegisterCompressor("", identityCompressor{})
+	assert.Error(t, err)
+	_, err = RegisterCompressor("other", extCompressor(".gz"))
+	assert.Error(t, err)
+	_, err = RegisterCompressor("other", extCompressor(EncryptFileExt))
+	assert.Error(t, err)
+	_, err = RegisterCompressor("other", extCompressor("lz4"))
+	assert.Error(t, err)
+
+	_, err = newRotator(t.TempDir(), "testdata.log", WithCompressName("not-exist"))
+	assert.ErrorIs(t, err, errorx.ErrCompressType)
+}
+
+type extCompressor string
+
+func (e extCompressor) Ext() string { return string(e) }
+
+func (extCompressor) NewWriter(w io.Writer, _ int) (io.WriteCloser, error) {
+	return nopWriteCloser{w}, nil
+}
+
+func (extCompressor) NewReader(r io.Reader) (io.ReadCloser, error) {
+	return io.NopCloser(r), nil
+}
+
+func TestRotator_RegisteredCompressor(t *testing.T) {
+	registerTestBackends(t)
+
+	dir := t.TempDir()
+	rotator, err := newRotator(dir, "testdata.log", WithCompressName("test-identity"), WithChecksum())
+	assert.NoError(t, err)
+
+	_, err = rotator.Write([]byte("registered compressor test\n"))
+	assert.NoError(t, err)
+	path := rotator.f.Name()
+	assert.NoError(t, rotator.Rotate())
+	assert.NoError(t, rotator.Close())
+	assert.NoFileExists(t, path)
+	assert.FileExists(t, path+".id")
+	assert.Equal(t, identityType, compressTypeOf(path+".id"))
+
+	ro, err := OpenReadOnly(dir, "testdata.log")
+	assert.NoError(t, err)
+	segs, err := ro.List()
+	assert.NoError(t, err)
+	assert.Equal(t, identityType, segs[0].CompressType)
+	rc, err := ro.Open(segs[0])
+	assert.NoError(t, err)
+	data, err := io.ReadAll(rc)
+	assert.NoError(t, err)
+	assert.NoError(t, rc.Close())
+	assert.Equal(t, "registered compressor test\n", string(data))
+}
+
+func TestRotator_WithUploader(t *testing.T) {
+	registerTestBackends(t)
+
+	assert.Error(t, RegisterUploader("test-mem", func(map[string]string) (Uploader, error) { return nil, nil }))
+	_, err := newRotator(t.TempDir(), "testdata.log", WithUploader("not-exist", nil))
+	assert.Error(t, err)
+
+	rotator, err := newRotator(t.TempDir(), "testdata.log",
+		WithCompress(CompressTypeGzip), WithUploader("test-mem", nil))
+	assert.NoError(t, err)
+	_, err = rotator.Write([]byte("uploader test\n"))
+	assert.NoError(t, err)
+	path := rotator.f.Name()
+	assert.NoError(t, rotator.Rotate())
+	assert.NoError(t, rotator.awaitUploads(context.Background()))
+	assert.NoError(t, rotator.Close())
+
+	testUploader.lock.Lock()
+	assert.Contains(t, testUploader.paths, compressFn(path, CompressTypeGzip))
+	testUploader.lock.Unlock()
+	assert.Equal(t, uint64(1), rotator.Stats().Uploads)
+
+	// 上传失败时保留本地文件并发送事件
+	var events []Event
+	rotator, err = newRotator(t.TempDir(), "testdata.log",
+		WithCompress(CompressTypeGzip),
+		WithUploader("test-mem", map[string]string{"fail": "bucket not found"}),
+		WithEventHandler(func(e Event) {
+			events = append(events, e)
+		}))
+	assert.NoError(t, err)
+	rotator.uploadBackoff = time.Millisecond
+	_, err = rotator.Write([]byte("uploader test\n"))
+	assert.NoError(t, err)
+	path = rotator.f.Name()
+	assert.NoError(t, rotator.Rotate())
+	assert.NoError(t, rotator.awaitUploads(context.Background()))
+	assert.NoError(t, rotator.Close())
+
+	assert.FileExists(t, compressFn(path, CompressTypeGzip))
+	assert.FileExists(t, compressFn(path, CompressTypeGzip)+UploadPendingExt)
+	assert.Equal(t, uint64(1), rotator.Stats().UploadFailures)
+	assert.Len(t, events, 1)
+	assert.Equal(t, EventUploadFailed, events[0].Type)
+	assert.True(t, errorx.IsUploadFailed(events[0].Err))
+
+	res, err := SelfTest(context.Background(), t.TempDir(), WithUploader("test-mem", nil))
+	assert.NoError(t, err)
+	for _, step := range res.Steps {
+		if step.Name == SelfTestUpload {
+			assert.False(t, step.Skipped)
+		}
+	}
+}
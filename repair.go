@@ -0,0 +1,351 @@
+// Copyright 2025 TimeWtr
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package vortexrotate
+
+import (
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// RepairActionType 修复动作类型
+type RepairActionType string
+
+const (
+	// RepairRemoveTmp 删除崩溃残留的临时文件
+	RepairRemoveTmp RepairActionType = "remove_tmp"
+	// RepairResequence 序列号重复的文件重新分配序列号
+	RepairResequence RepairActionType = "resequence"
+	// RepairSeqFile 重建序列号文件
+	RepairSeqFile RepairActionType = "seq_file"
+	// RepairChecksum 为缺失校验和文件的已封存文件重新计算校验和
+	RepairChecksum RepairActionType = "checksum"
+	// RepairManifest 按照文件系统重建归档清单
+	RepairManifest RepairActionType = "manifest"
+)
+
+// RepairAction 修复过程中执行的一个动作
+type RepairAction struct {
+	// 动作类型
+	Type RepairActionType
+	// 操作的文件路径
+	Path string
+	// 重命名之后的路径，只在RepairResequence中有效
+	Target string
+}
+
+func (a RepairAction) String() string {
+	if a.Target != "" {
+		return fmt.Sprintf("%s: %s -> %s", a.Type, a.Path, a.Target)
+	}
+
+	return fmt.Sprintf("%s: %s", a.Type, a.Path)
+}
+
+// RepairReport 修复报告，记录修复过程中执行的所有动作
+type RepairReport struct {
+	Actions []RepairAction
+}
+
+// Repair 修复目录中的不一致状态，filename为基础文件名称，格式与NewRotator一致，比如：app.log，
+// 修复的内容包括：
+// 1. 删除崩溃时残留的临时文件
+// 2. 序列号重复的文件(进程多次重启导致序列号从头分配)重新分配新的序列号，同一个文件的压缩
+// 文件等关联文件一起重命名
+// 3. 序列号文件缺失、损坏或者落后于目录中的最大序列号时重建序列号文件
+// 4. 目录开启了校验和(存在.sha256文件)时，为缺失校验和文件的已封存文件重新计算校验和
+// 5. 归档清单存在时按照文件系统重建：删除已经不存在的文件的记录，大小发生变化以及缺失
+// 记录的已封存文件重新计算校验和
+func Repair(dir, filename string) (*RepairReport, error) {
+	name, _, err := splitFilename(filename)
+	if err != nil {
+		return nil, err
+	}
+
+	dir, err = normalizeDir(dir)
+	if err != nil {
+		return nil, err
+	}
+
+	return repair(dir, name, renameLocal)
+}
+
+// segmentGroup 同一个轮转文件的所有关联文件，比如：x.log和x.log.gz
+type segmentGroup struct {
+	// 轮转文件名称，不包括压缩等后缀
+	base string
+	// 所在目录
+	upDir string
+	// 文件日期
+	date time.Time
+	// 序列号
+	seq uint64
+	// 所有关联文件的名称
+	files []string
+}
+
+func repair(dir, name string, rename renamer) (*RepairReport, error) {
+	report := &RepairReport{}
+	re := segmentRegexp(name)
+	groups := make(map[string]*segmentGroup)
+	var tmpFiles []string
+	err := filepath.WalkDir(dir, func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if isQuarantineDir(dir, path, d) {
+			return filepath.SkipDir
+		}
+		if d.IsDir() {
+			return nil
+		}
+
+		fn := d.Name()
+		if fn == name+SeqFileExt+TmpFileExt || fn == name+ManifestFileExt+TmpFileExt {
+			tmpFiles = append(tmpFiles, path)
+			return nil
+		}
+
+		const matchesLen = 3
+		matches := re.FindStringSubmatch(fn)
+		if len(matches) < matchesLen {
+			return nil
+		}
+
+		if strings.HasSuffix(fn, TmpFileExt) {
+			tmpFiles = append(tmpFiles, path)
+			return nil
+		}
+
+		date, err := time.Parse(Layout, matches[1])
+		if err != nil {
+			return nil
+		}
+		seq, err := strconv.ParseUint(matches[2], 10, 32)
+		if err != nil {
+			return nil
+		}
+
+		key := filepath.Join(filepath.Dir(path), matches[0])
+		g, ok := groups[key]
+		if !ok {
+			g = &segmentGroup{
+				base:  matches[0],
+				upDir: filepath.Dir(path),
+				date:  date,
+				seq:   seq,
+			}
+			groups[key] = g
+		}
+		g.files = append(g.files, fn)
+
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	for _, tmp := range tmpFiles {
+		if err = os.Remove(tmp); err != nil && !os.IsNotExist(err) {
+			return report, err
+		}
+		report.Actions = append(report.Actions, RepairAction{Type: RepairRemoveTmp, Path: tmp})
+	}
+
+	sorted := make([]*segmentGroup, 0, len(groups))
+	var maxSeq uint64
+	for _, g := range groups {
+		sorted = append(sorted, g)
+		if g.seq > maxSeq {
+			maxSeq = g.seq
+		}
+	}
+	sort.Slice(sorted, func(i, j int) bool {
+		if !sorted[i].date.Equal(sorted[j].date) {
+			return sorted[i].date.Before(sorted[j].date)
+		}
+		if sorted[i].seq != sorted[j].seq {
+			return sorted[i].seq < sorted[j].seq
+		}
+		return sorted[i].upDir < sorted[j].upDir
+	})
+
+	// 序列号重复的文件，保留日期最早的文件，其余的重新分配序列号
+	seen := make(map[uint64]struct{}, len(sorted))
+	for _, g := range sorted {
+		if _, ok := seen[g.seq]; !ok {
+			seen[g.seq] = struct{}{}
+			continue
+		}
+
+		maxSeq++
+		actions, err := resequence(g, name, maxSeq, rename)
+		report.Actions = append(report.Actions, actions...)
+		if err != nil {
+			return report, err
+		}
+	}
+
+	seq := &sequence{
+		dir:      dir,
+		filename: name,
+		path:     filepath.Join(dir, name+SeqFileExt),
+		rename:   rename,
+	}
+	next, err := seq.load()
+	if err != nil && maxSeq == 0 {
+		// 全新的目录，序列号文件在第一次分配序列号时创建
+		return report, nil
+	}
+	if err != nil || uint64(next) <= maxSeq {
+		if err = seq.store(uint32(maxSeq) + 1); err != nil {
+			return report, err
+		}
+		report.Actions = append(report.Actions, RepairAction{Type: RepairSeqFile, Path: seq.path})
+	}
+
+	// 重新分配序列号之后文件名称可能已经变化，重新扫描已封存的文件
+	artifacts, err := sealedArtifacts(dir, name)
+	if err != nil {
+		return report, err
+	}
+	actions, err := repairChecksums(dir, artifacts, rename)
+	report.Actions = append(report.Actions, actions...)
+	if err != nil {
+		return report, err
+	}
+	actions, err = repairManifest(dir, name, artifacts, rename)
+	report.Actions = append(report.Actions, actions...)
+
+	return report, err
+}
+
+// repairChecksums 目录中存在校验和文件时，为缺失校验和文件的已封存文件重新计算校验和，
+// 已经存在的校验和文件不覆盖，校验和不一致交给Verify报告
+func repairChecksums(dir string, artifacts []string, rename renamer) ([]RepairAction, error) {
+	var missing []string
+	enabled := false
+	for _, path := range artifacts {
+		if _, err := os.Lstat(path + ChecksumFileExt); err == nil {
+			enabled = true
+			continue
+		}
+		missing = append(missing, path)
+	}
+	if !enabled {
+		return nil, nil
+	}
+
+	actions := make([]RepairAction, 0, len(missing))
+	for _, path := range missing {
+		sum, err := fileChecksum(path)
+		if err != nil {
+			return actions, err
+		}
+		if err = writeChecksum(path, sum, rename); err != nil {
+			return actions, err
+		}
+		actions = append(actions, RepairAction{Type: RepairChecksum, Path: path + ChecksumFileExt})
+	}
+
+	return actions, nil
+}
+
+// repairManifest 归档清单存在时按照文件系统重建，大小没有变化的记录原样保留
+func repairManifest(dir, name string, artifacts []string, rename renamer) ([]RepairAction, error) {
+	path := manifestPath(dir, name)
+	entries, err := readManifest(path)
+	if os.IsNotExist(err) {
+		return nil, nil
+	}
+	if err != nil {
+		// 清单损坏，全部重新计算
+		entries = nil
+	}
+
+	known := make(map[string]ManifestEntry, len(entries))
+	for _, e := range entries {
+		known[e.Name] = e
+	}
+
+	changed := err != nil || len(entries) != len(artifacts)
+	rebuilt := make([]ManifestEntry, 0, len(artifacts))
+	for _, p := range artifacts {
+		rel, err := filepath.Rel(dir, p)
+		if err != nil {
+			return nil, err
+		}
+		e, ok := known[filepath.ToSlash(rel)]
+		if ok {
+			if info, err := os.Stat(p); err == nil && info.Size() == e.Size {
+				rebuilt = append(rebuilt, e)
+				continue
+			}
+		}
+
+		if e, err = manifestEntry(dir, p, nil); err != nil {
+			return nil, err
+		}
+		rebuilt = append(rebuilt, e)
+		changed = true
+	}
+	if !changed {
+		return nil, nil
+	}
+
+	if err = writeManifest(path, rebuilt, rename); err != nil {
+		return nil, err
+	}
+
+	return []RepairAction{{Type: RepairManifest, Path: path}}, nil
+}
+
+// resequence 为轮转文件及其关联文件分配新的序列号
+func resequence(g *segmentGroup, name string, seq uint64, rename renamer) ([]RepairAction, error) {
+	t := g.date.Format(Layout)
+	newBase := fmt.Sprintf("%s_%s_%04d.log", name, t, seq)
+	actions := make([]RepairAction, 0, len(g.files))
+	for _, fn := range g.files {
+		src := filepath.Join(g.upDir, fn)
+		dst := filepath.Join(g.upDir, newBase+strings.TrimPrefix(fn, g.base))
+		if err := rename(src, dst); err != nil {
+			return actions, err
+		}
+		actions = append(actions, RepairAction{Type: RepairResequence, Path: src, Target: dst})
+	}
+
+	return actions, nil
+}
+
+// needRepair 不扫描目录，只根据序列号文件快速判断目录是否处于不一致状态：
+// 序列号文件缺失、损坏或者残留了序列号或者归档清单的临时文件
+func needRepair(dir, name string) bool {
+	path := filepath.Join(dir, name+SeqFileExt)
+	if _, err := os.Stat(path + TmpFileExt); err == nil {
+		return true
+	}
+	if _, err := os.Stat(manifestPath(dir, name) + TmpFileExt); err == nil {
+		return true
+	}
+
+	seq := &sequence{path: path}
+	_, err := seq.load()
+	return err != nil
+}
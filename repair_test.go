@@ -0,0 +1,68 @@
+// Copyright 2025 TimeWtr
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package vortexrotate
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestRepair(t *testing.T) {
+	dir := t.TempDir()
+	files := []string{
+		"20250101/app_20250101_0001.log",
+		"20250101/app_20250101_0001.log.gz",
+		"20250101/app_20250101_0002.log.gz.tmp",
+		"20250102/app_20250102_0001.log",
+		"20250102/app_20250102_0002.log",
+		"app.seq.tmp",
+	}
+	for _, fn := range files {
+		path := filepath.Join(dir, fn)
+		assert.Nil(t, os.MkdirAll(filepath.Dir(path), os.ModePerm))
+		assert.Nil(t, os.WriteFile(path, []byte(fn), ReadWriteFile))
+	}
+	assert.True(t, needRepair(dir, "app"))
+
+	report, err := Repair(dir, "app.log")
+	assert.Nil(t, err)
+
+	counts := make(map[RepairActionType]int)
+	for _, action := range report.Actions {
+		counts[action.Type]++
+	}
+	assert.Equal(t, 2, counts[RepairRemoveTmp])
+	assert.Equal(t, 1, counts[RepairResequence])
+	assert.Equal(t, 1, counts[RepairSeqFile])
+
+	// 日期较早的文件保留原有序列号，重复的文件分配新的序列号
+	assert.FileExists(t, filepath.Join(dir, "20250101/app_20250101_0001.log.gz"))
+	assert.FileExists(t, filepath.Join(dir, "20250102/app_20250102_0003.log"))
+	assert.NoFileExists(t, filepath.Join(dir, "20250102/app_20250102_0001.log"))
+	assert.NoFileExists(t, filepath.Join(dir, "app.seq.tmp"))
+
+	seq, err := newSequence(dir, "app")
+	assert.Nil(t, err)
+	assert.Equal(t, uint32(4), seq.Load())
+	assert.False(t, needRepair(dir, "app"))
+
+	// 目录已经一致，再次修复不执行任何动作
+	report, err = Repair(dir, "app.log")
+	assert.Nil(t, err)
+	assert.Empty(t, report.Actions)
+}
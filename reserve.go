@@ -0,0 +1,80 @@
+// Copyright 2025 TimeWtr
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package vortexrotate
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+
+	"github.com/TimeWtr/vortexrotate/errorx"
+)
+
+// DefaultCompressReserveRatio 压缩前预留空间的默认比例，压缩文件在最坏情况下与源文件大小相当
+const DefaultCompressReserveRatio = 1.0
+
+// WithCompressReserve 开启压缩前的磁盘空间预留检查，压缩之前检查存储目录所在文件系统的可用空间，
+// 可用空间小于源文件大小*ratio(最坏情况下压缩文件的大小，ratio<=0时使用DefaultCompressReserveRatio)
+// 时跳过本次压缩，以原始文件封存，发送EventCompressSkipped事件并立即触发一次清理，避免压缩过程
+// 本身写满磁盘(ENOSPC)导致写入失败。开启了延迟压缩或者压缩时间窗口时，跳过的文件在下一次扫描时
+// 重新尝试压缩。当前系统不支持获取磁盘空间时不检查，不开启压缩时不生效
+func WithCompressReserve(ratio float64) Option {
+	return func(r *Rotator) error {
+		if ratio <= 0 {
+			ratio = DefaultCompressReserveRatio
+		}
+
+		r.cpr.reserveRatio = ratio
+		r.cpr.diskUsage = diskUsage
+		return nil
+	}
+}
+
+// reserveSpace 检查是否有足够的磁盘空间压缩path，空间不足时发送事件并触发清理
+func (r *Rotator) reserveSpace(path string) bool {
+	if r.cpr.reserveRatio <= 0 {
+		return true
+	}
+
+	info, err := os.Stat(path)
+	if err != nil {
+		// 交给压缩流程返回错误
+		return true
+	}
+	free, _, err := r.cpr.diskUsage(filepath.Dir(path))
+	if err != nil {
+		return true
+	}
+
+	need := uint64(float64(info.Size()) * r.cpr.reserveRatio)
+	if free >= need {
+		return true
+	}
+
+	msg := fmt.Sprintf("disk free space %d bytes is less than reserved %d bytes, skip compress %s", free, need, path)
+	if r.cleanup != nil {
+		r.triggerCleanup()
+	} else {
+		msg += ", cleanup is not configured"
+	}
+	r.emit(Event{
+		Type:    EventCompressSkipped,
+		Path:    path,
+		Message: msg,
+		Err:     errorx.ErrDiskFull,
+	})
+
+	return false
+}
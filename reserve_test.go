@@ -0,0 +1,54 @@
+// Copyright 2025 TimeWtr
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package vortexrotate
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestRotator_CompressReserve(t *testing.T) {
+	var events []Event
+	rotator, err := newRotator(t.TempDir(), "testdata.log", WithCompress(CompressTypeGzip),
+		WithCompressReserve(0), WithEventHandler(func(e Event) {
+			events = append(events, e)
+		}))
+	assert.NoError(t, err)
+	defer rotator.Close()
+	assert.Equal(t, DefaultCompressReserveRatio, rotator.cpr.reserveRatio)
+
+	// 可用空间不足，跳过压缩，保留原始文件
+	rotator.cpr.diskUsage = func(string) (uint64, uint64, error) { return 8, 100, nil }
+	_, err = rotator.Write([]byte("compress reserve test\n"))
+	assert.NoError(t, err)
+	path := rotator.f.Name()
+	assert.NoError(t, rotator.Rotate())
+	assert.FileExists(t, path)
+	assert.NoFileExists(t, compressFn(path, CompressTypeGzip))
+	assert.Len(t, events, 1)
+	assert.Equal(t, EventCompressSkipped, events[0].Type)
+	assert.Equal(t, path, events[0].Path)
+
+	// 可用空间充足时正常压缩
+	rotator.cpr.diskUsage = func(string) (uint64, uint64, error) { return 1 << 20, 1 << 30, nil }
+	_, err = rotator.Write([]byte("compress reserve test\n"))
+	assert.NoError(t, err)
+	path = rotator.f.Name()
+	assert.NoError(t, rotator.Rotate())
+	assert.NoFileExists(t, path)
+	assert.FileExists(t, compressFn(path, CompressTypeGzip))
+	assert.Len(t, events, 1)
+}
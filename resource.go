@@ -0,0 +1,159 @@
+// Copyright 2025 TimeWtr
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package vortexrotate
+
+import (
+	"io"
+	"runtime"
+	"sync"
+)
+
+// DefaultWorkerCPUFraction 后台工作任务默认占用的CPU比例，默认允许同时执行的压缩任务数量为
+// GOMAXPROCS的一半，最少为1，在0.5核等CPU受限的容器中不需要手动调整
+const DefaultWorkerCPUFraction = 0.5
+
+// resources 进程内所有轮转器共享的压缩资源，多个轮转器同时运行时共享缓冲区和zstd压缩上下文，
+// 并限制同时执行的压缩任务数量，从而限制压缩占用的总内存，而不是每个轮转器各自分配。
+var resources = newResourceManager()
+
+// SetMaxConcurrentCompressions 设置进程内所有轮转器同时执行的压缩任务的最大数量，
+// 每个压缩任务会占用一个128KB的读缓冲区以及对应压缩算法的上下文，n <= 0时恢复默认值，
+// 默认值根据GOMAXPROCS动态计算，运行时调整GOMAXPROCS(比如automaxprocs)后自动生效
+func SetMaxConcurrentCompressions(n int) {
+	resources.setLimit(n)
+}
+
+// defaultWorkers 根据当前的GOMAXPROCS计算后台工作任务的默认数量
+func defaultWorkers() int {
+	n := int(float64(runtime.GOMAXPROCS(0)) * DefaultWorkerCPUFraction)
+	if n < 1 {
+		n = 1
+	}
+
+	return n
+}
+
+// resourceManager 进程内共享的压缩资源管理器
+type resourceManager struct {
+	// 读文件的缓冲区池
+	buffers sync.Pool
+	// zstd实现和压缩参数 -> zstd压缩上下文池
+	zstdWriters sync.Map
+	// 保护zstd字典
+	dictLock sync.RWMutex
+	// 字典ID -> 已经加载的zstd字典
+	zstdDicts map[uint32][]byte
+	// 保护并发限制
+	lock sync.Mutex
+	cond *sync.Cond
+	// 同时执行的压缩任务的最大数量，0表示根据GOMAXPROCS动态计算
+	limit int
+	// 正在执行的压缩任务数量
+	running int
+}
+
+func newResourceManager() *resourceManager {
+	m := &resourceManager{
+		buffers: sync.Pool{
+			New: func() interface{} {
+				bs := make([]byte, bufferSize)
+				return &bs
+			},
+		},
+	}
+	m.cond = sync.NewCond(&m.lock)
+
+	return m
+}
+
+func (m *resourceManager) setLimit(n int) {
+	m.lock.Lock()
+	defer m.lock.Unlock()
+
+	if n < 0 {
+		n = 0
+	}
+	m.limit = n
+	m.cond.Broadcast()
+}
+
+// effectiveLimit 当前生效的并发限制，必须持有锁
+func (m *resourceManager) effectiveLimit() int {
+	if m.limit > 0 {
+		return m.limit
+	}
+
+	return defaultWorkers()
+}
+
+// acquire 获取一个压缩任务的执行名额，超过最大数量时阻塞等待
+func (m *resourceManager) acquire() {
+	m.lock.Lock()
+	defer m.lock.Unlock()
+
+	for m.running >= m.effectiveLimit() {
+		m.cond.Wait()
+	}
+	m.running++
+}
+
+// release 释放压缩任务的执行名额
+func (m *resourceManager) release() {
+	m.lock.Lock()
+	defer m.lock.Unlock()
+
+	m.running--
+	// GOMAXPROCS可能在运行时发生变化，唤醒所有等待方重新计算并发限制
+	m.cond.Broadcast()
+}
+
+// getBuffer 获取一个读文件的缓冲区
+func (m *resourceManager) getBuffer() *[]byte {
+	bs, _ := m.buffers.Get().(*[]byte)
+	return bs
+}
+
+// putBuffer 归还读文件的缓冲区
+func (m *resourceManager) putBuffer(bs *[]byte) {
+	m.buffers.Put(bs)
+}
+
+// zstdPoolKey zstd压缩上下文池的键，使用解析之后的实现类型而不是注册的实现，注册的实现
+// 不一定可以比较
+type zstdPoolKey struct {
+	backend ZstdBackend
+	params  ZstdParams
+}
+
+// getZstdWriter 获取指定实现和压缩参数的zstd压缩上下文，并重置输出，backend必须是
+// resolveZstdBackend解析之后的实现类型
+func (m *resourceManager) getZstdWriter(backend ZstdBackend, w io.Writer, p ZstdParams) (ZstdWriter, error) {
+	v, _ := m.zstdWriters.LoadOrStore(zstdPoolKey{backend: backend, params: p}, &sync.Pool{})
+	pool, _ := v.(*sync.Pool)
+	zw, ok := pool.Get().(ZstdWriter)
+	if !ok {
+		return zstdCodecOf(backend).NewWriter(w, p)
+	}
+
+	zw.Reset(w)
+	return zw, nil
+}
+
+// putZstdWriter 归还zstd压缩上下文，调用方需要先执行Close
+func (m *resourceManager) putZstdWriter(backend ZstdBackend, zw ZstdWriter, p ZstdParams) {
+	v, _ := m.zstdWriters.LoadOrStore(zstdPoolKey{backend: backend, params: p}, &sync.Pool{})
+	pool, _ := v.(*sync.Pool)
+	pool.Put(zw)
+}
@@ -0,0 +1,72 @@
+// Copyright 2025 TimeWtr
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package vortexrotate
+
+import (
+	"runtime"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestResourceManager_Limit(t *testing.T) {
+	m := newResourceManager()
+	m.setLimit(1)
+	m.acquire()
+
+	acquired := make(chan struct{})
+	go func() {
+		m.acquire()
+		close(acquired)
+	}()
+
+	select {
+	case <-acquired:
+		t.Fatal("acquire should block when limit reached")
+	case <-time.After(time.Millisecond * 20):
+	}
+
+	m.release()
+	select {
+	case <-acquired:
+	case <-time.After(time.Second):
+		t.Fatal("acquire should succeed after release")
+	}
+	m.release()
+}
+
+func TestResourceManager_Buffer(t *testing.T) {
+	m := newResourceManager()
+	bs := m.getBuffer()
+	assert.Equal(t, bufferSize, len(*bs))
+	m.putBuffer(bs)
+}
+
+func TestDefaultWorkers(t *testing.T) {
+	old := runtime.GOMAXPROCS(1)
+	defer runtime.GOMAXPROCS(old)
+	assert.Equal(t, 1, defaultWorkers())
+
+	runtime.GOMAXPROCS(8)
+	assert.Equal(t, 4, defaultWorkers())
+
+	m := newResourceManager()
+	assert.Equal(t, 4, m.effectiveLimit())
+	m.setLimit(2)
+	assert.Equal(t, 2, m.effectiveLimit())
+	m.setLimit(0)
+	assert.Equal(t, 4, m.effectiveLimit())
+}
@@ -0,0 +1,107 @@
+// Copyright 2025 TimeWtr
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package vortexrotate
+
+import (
+	"fmt"
+	"time"
+)
+
+// RetentionTier 分层保存策略中的一层，文件的保存时长(当前时间减去文件的最后写入时间)不超过Within
+// 时属于该层，每Every时长只保留一个文件，Every为0表示保留该层的所有文件
+type RetentionTier struct {
+	// 该层覆盖的最大保存时长
+	Within time.Duration
+	// 每个时间段保留一个文件，0表示全部保留
+	Every time.Duration
+}
+
+// WithTieredRetention 设置分层(祖父-父-子)保存策略，层按照Within从小到大排列，比如：
+//
+//	WithTieredRetention(
+//		RetentionTier{Within: 24 * time.Hour},                       // 24小时内的文件全部保留
+//		RetentionTier{Within: 7 * 24 * time.Hour, Every: time.Hour}, // 7天内每小时保留一个
+//		RetentionTier{Within: 90 * 24 * time.Hour, Every: 24 * time.Hour}, // 90天内每天保留一个
+//	)
+//
+// 每个时间段保留最新的文件，时间段按照UTC对齐，超过最后一层保存时长的文件全部删除。
+func WithTieredRetention(tiers ...RetentionTier) Option {
+	return func(r *Rotator) error {
+		for i, tier := range tiers {
+			if tier.Within <= 0 || tier.Every < 0 {
+				return fmt.Errorf("invalid retention tier %d, within: %s, every: %s", i, tier.Within, tier.Every)
+			}
+			if i > 0 && tier.Within <= tiers[i-1].Within {
+				return fmt.Errorf("retention tiers must be sorted by within, tier %d: %s <= %s",
+					i, tier.Within, tiers[i-1].Within)
+			}
+		}
+
+		r.tiers = tiers
+		return nil
+	}
+}
+
+// tierBucket 分层保存策略中的一个时间段
+type tierBucket struct {
+	tier  int
+	start time.Time
+}
+
+// markTiered 根据分层保存策略标记需要删除的文件，fileInfos按照从旧到新排序，最后一个文件(最新的
+// 文件)总是保留，removed与fileInfos中除最新文件之外的文件一一对应
+func (c *CleanUp) markTiered(fileInfos []FileInfo, removed []bool, now time.Time) {
+	seen := make(map[tierBucket]struct{})
+	for i := len(fileInfos) - 1; i >= 0; i-- {
+		bucket, ok := c.tierBucket(fileInfos[i], now)
+		if i == len(fileInfos)-1 {
+			// 最新的文件占据所在的时间段
+			if ok {
+				seen[bucket] = struct{}{}
+			}
+			continue
+		}
+		if !ok {
+			removed[i] = true
+			continue
+		}
+		if bucket.start.IsZero() {
+			continue
+		}
+		if _, exist := seen[bucket]; exist {
+			removed[i] = true
+			continue
+		}
+		seen[bucket] = struct{}{}
+	}
+}
+
+// tierBucket 返回文件所属的时间段，不属于任何一层时返回false，属于全部保留的层时时间段的起始
+// 时间为零值
+func (c *CleanUp) tierBucket(fi FileInfo, now time.Time) (tierBucket, bool) {
+	t := fi.age()
+	age := now.Sub(t)
+	for i, tier := range c.tiers {
+		if age > tier.Within {
+			continue
+		}
+		if tier.Every == 0 {
+			return tierBucket{tier: i}, true
+		}
+		return tierBucket{tier: i, start: t.Truncate(tier.Every)}, true
+	}
+
+	return tierBucket{}, false
+}
@@ -0,0 +1,93 @@
+// Copyright 2025 TimeWtr
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package vortexrotate
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestCleanUp_TieredRetention(t *testing.T) {
+	now := time.Date(2025, 3, 31, 12, 0, 0, 0, time.UTC)
+	// 最近40天每30分钟一个文件
+	var files []FileInfo
+	for ts := now.Add(-40 * 24 * time.Hour); !ts.After(now); ts = ts.Add(30 * time.Minute) {
+		files = append(files, FileInfo{
+			Date:     ts.Truncate(24 * time.Hour),
+			Sequence: int64(len(files) + 1),
+			ModTime:  ts,
+		})
+	}
+
+	c := NewFileCountCleanUp(t.TempDir(), "app", 0, 0)
+	c.tiers = []RetentionTier{
+		{Within: 24 * time.Hour},
+		{Within: 7 * 24 * time.Hour, Every: time.Hour},
+		{Within: 30 * 24 * time.Hour, Every: 24 * time.Hour},
+	}
+	removed := c.expired(files, now)
+	removedSeq := make(map[int64]struct{}, len(removed))
+	for _, fi := range removed {
+		removedSeq[fi.Sequence] = struct{}{}
+	}
+
+	kept := make(map[tierBucket]int)
+	for _, fi := range files {
+		if _, ok := removedSeq[fi.Sequence]; ok {
+			continue
+		}
+		age := now.Sub(fi.ModTime)
+		// 超过最后一层的文件全部删除
+		assert.LessOrEqual(t, age, 30*24*time.Hour)
+		switch {
+		case age <= 24*time.Hour:
+		case age <= 7*24*time.Hour:
+			kept[tierBucket{tier: 1, start: fi.ModTime.Truncate(time.Hour)}]++
+		default:
+			kept[tierBucket{tier: 2, start: fi.ModTime.Truncate(24 * time.Hour)}]++
+		}
+	}
+	for bucket, n := range kept {
+		assert.Equal(t, 1, n, bucket)
+	}
+
+	// 24小时内的文件全部保留
+	var recent int
+	for _, fi := range files {
+		if _, ok := removedSeq[fi.Sequence]; !ok && now.Sub(fi.ModTime) <= 24*time.Hour {
+			recent++
+		}
+	}
+	assert.Equal(t, 49, recent)
+	// 每小时一个(6天)，每天一个(23天)，层的边界处时间段可能被拆分到两层中
+	assert.GreaterOrEqual(t, len(kept), 6*24+23)
+	assert.LessOrEqual(t, len(kept), 6*24+23+2)
+}
+
+func TestWithTieredRetention(t *testing.T) {
+	r := &Rotator{}
+	assert.Error(t, WithTieredRetention(RetentionTier{Within: 0})(r))
+	assert.Error(t, WithTieredRetention(
+		RetentionTier{Within: time.Hour},
+		RetentionTier{Within: time.Minute},
+	)(r))
+	assert.NoError(t, WithTieredRetention(
+		RetentionTier{Within: time.Hour},
+		RetentionTier{Within: 24 * time.Hour, Every: time.Hour},
+	)(r))
+	assert.Len(t, r.tiers, 2)
+}
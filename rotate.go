@@ -15,74 +15,75 @@
 package vortexrotate
 
 import (
-	"compress/gzip"
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"errors"
 	"fmt"
+	"io"
+	"io/fs"
 	"log"
 	"os"
+	"os/signal"
+	"path/filepath"
 	"strings"
 	"sync"
 	"sync/atomic"
 	"time"
 
 	"github.com/TimeWtr/vortexrotate/errorx"
-	"github.com/valyala/gozstd"
 )
 
 var (
-	r             *Rotator
+	singleton     *Rotator
 	onceWithError OnceWithError
 )
 
 type Option func(*Rotator) error
 
-// WithCompress 开启压缩，压缩算法提供gzip、zstd和snappy三种算法，
+// WithCompress 开启压缩，压缩算法提供gzip、zstd、snappy和xz四种算法，
 // 当压缩算法为gzip时，可以设置压缩等级/级别，如果不设置，默认压缩级别
-// 为gzip.DefaultCompression
+// 为gzip.DefaultCompression，当压缩算法为zstd时，压缩等级为ZstdMinLevel~ZstdMaxLevel，
+// 如果不设置，默认压缩级别为ZstdDefaultLevel，当压缩算法为xz时，压缩等级为XzBestSpeed~XzBestCompression，
+// 如果不设置，默认压缩级别为XzDefaultCompression，需要设置压缩等级之外的参数时使用WithCompressOptions。
+// 压缩类型通过注册表解析，也可以是RegisterCompressor返回的第三方压缩类型
 func WithCompress(tp int, level ...int) Option {
 	return func(r *Rotator) error {
 		r.cpr.compress = true
-		if tp < _minCompressType || tp > _maxCompressType {
+		c, ok := compressCodecOf(tp)
+		if !ok {
 			return errorx.ErrCompressType
 		}
 
 		r.cpr.compressType = tp
-		var compressLevel int
+		compressLevel := c.level
 		if len(level) > 0 {
 			compressLevel = level[0]
-		} else if tp == CompressTypeGzip {
-			compressLevel = gzip.DefaultCompression
 		}
+		r.cpr.level = compressLevel
 
-		switch tp {
-		case CompressTypeGzip:
-			cs, err := NewGzip(nil, r.f, compressLevel)
-			if err != nil {
-				return err
-			}
-			r.cpr.cs = cs
-		case CompressTypeZstd:
-			r.cpr.cs = NewZstd(nil, r.f, gozstd.DefaultCompressionLevel)
-		case CompressTypeSnappy:
-			r.cpr.cs = NewSnappy(nil, r.f)
-		default:
+		cs, err := c.strategy(r, compressLevel)
+		if err != nil {
+			return err
 		}
-
+		r.cpr.cs = cs
 		return nil
 	}
 }
 
-// WithPeriod 设置保存周期
+// WithPeriod 设置保存周期(天)，日期早于保存周期的轮转文件会被后台清理任务删除，需要更精确的
+// 保存时长时使用WithMaxAge
 func WithPeriod(period uint16) Option {
 	return func(r *Rotator) error {
-		r.cleanup.period = period
+		r.period = period
 		return nil
 	}
 }
 
-// WithMaxCount 设置保存的最大文件数量
-func WithMaxCount(_ uint16) Option {
-	return func(_ *Rotator) error {
-		// TODO 处理CleanUp初始化
+// WithMaxCount 设置保存的最大文件数量，超过数量的最旧的轮转文件会被后台清理任务删除
+func WithMaxCount(maxCount uint16) Option {
+	return func(r *Rotator) error {
+		r.maxCount = uint64(maxCount)
 		return nil
 	}
 }
@@ -107,6 +108,134 @@ func WithRotate(maxSize uint64, tp TimingType) Option {
 	}
 }
 
+// WithTimeRotate 只按照时间轮转，定时轮转的时间类型与WithRotate相同，每到轮转时间只要当前文件写入了
+// 数据就执行轮转，不限制单个文件的大小，适用于写入量小但是需要按固定周期切分文件的场景
+func WithTimeRotate(tp TimingType) Option {
+	return func(r *Rotator) error {
+		stg, err := NewTimeStrategy(tp)
+		if err != nil {
+			return err
+		}
+		r.stg = stg
+
+		return nil
+	}
+}
+
+// WithRotateCron 通过cron表达式(支持秒级，比如"0 */15 * * * *"表示每15分钟)设置定时轮转的时间，
+// 替换WithRotate和WithTimeRotate中时间类型对应的时间表，可以与二者组合使用，不能与WithSizeRotate
+// 以及自定义的轮转策略组合使用，表达式在设置时校验。每到轮转时间只要当前文件写入了数据就执行轮转，
+// 与WithRotate组合使用时不再要求文件大小达到最大大小的一定比例
+func WithRotateCron(spec string) Option {
+	return func(r *Rotator) error {
+		if _, err := parseCron(spec); err != nil {
+			return fmt.Errorf("invalid rotate cron %q: %w", spec, err)
+		}
+
+		r.rotateCron = spec
+		return nil
+	}
+}
+
+// applyRotateCron 按照WithRotateCron设置的cron表达式重新创建定时轮转策略
+func (r *Rotator) applyRotateCron() error {
+	if r.rotateCron == "" {
+		return nil
+	}
+
+	var stg RotateStrategy
+	var err error
+	switch s := r.stg.(type) {
+	case *MixStrategy:
+		stg, err = newMixStrategy(s.maxSize, s.tp, r.rotateCron)
+	case *TimeStrategy:
+		stg, err = newTimeStrategy(s.tp, r.rotateCron)
+	default:
+		return fmt.Errorf("rotate cron %q requires a timed rotate strategy", r.rotateCron)
+	}
+	if err != nil {
+		return err
+	}
+
+	r.stg.Close()
+	r.stg = stg
+	return nil
+}
+
+// WithRotateStrategy 使用自定义的轮转策略，比如通过Or/And组合的策略，策略在关闭轮转器时关闭。
+// 定时轮转的通知通过NotifyRotate发送，不能与WithRotateCron组合使用
+func WithRotateStrategy(stg RotateStrategy) Option {
+	return func(r *Rotator) error {
+		if IsNil(stg) {
+			return errors.New("rotate strategy must not be nil")
+		}
+		r.stg = stg
+
+		return nil
+	}
+}
+
+// WithSizeRotate 只按照文件大小轮转，maxSize设置单个文件写入的最大字节，超过限制后立即执行轮转，
+// 不执行定时轮转，也不启动定时轮转相关的后台任务
+func WithSizeRotate(maxSize uint64) Option {
+	return func(r *Rotator) error {
+		if maxSize == 0 {
+			return errors.New("max size must be greater than 0")
+		}
+		r.stg = NewSizeStrategy(maxSize)
+		r.maxSize = maxSize
+
+		return nil
+	}
+}
+
+// WithEventHandler 设置事件处理函数，轮转器内部的告警、状态变更等事件都会通过
+// 该函数通知调用方，未设置时事件输出到日志中
+func WithEventHandler(h EventHandler) Option {
+	return func(r *Rotator) error {
+		r.eventHandler = h
+		return nil
+	}
+}
+
+// WithRotatePauseSLO 设置单次轮转阻塞写入的时间上限，比如50ms，当轮转过程中写入被阻塞的
+// 时间超过该值时，发送EventRotatePauseSLO告警事件，事件中包含关闭文件、压缩、创建目录和
+// 打开新文件各个阶段的耗时明细，用于指导调优。默认为0，不做检查。
+func WithRotatePauseSLO(slo time.Duration) Option {
+	return func(r *Rotator) error {
+		r.pauseSLO = slo
+		return nil
+	}
+}
+
+// WithAutoRepair 设置初始化时是否自动修复目录，默认开启，当检测到序列号文件缺失、损坏
+// 或者残留临时文件时，执行Repair修复目录中的不一致状态，并发送EventRepair事件
+func WithAutoRepair(enable bool) Option {
+	return func(r *Rotator) error {
+		r.autoRepair = enable
+		return nil
+	}
+}
+
+// WithAllowWorldWritable 允许文件存储目录对所有用户可写，默认不允许，存储目录对所有用户
+// 可写时初始化返回errorx.ErrDirWorldWritable，防止以root运行的服务在共享目录中遭受符号链接攻击，
+// 设置了粘滞位的目录(比如/tmp)不受限制
+func WithAllowWorldWritable() Option {
+	return func(r *Rotator) error {
+		r.allowWorldWritable = true
+		return nil
+	}
+}
+
+// WithMaxLines 设置单个文件写入的最大行数，比如每100万行轮转一次，写入时通过统计'\n'的数量
+// 计算行数，适用于下游按照行数批量处理的场景，可以与文件大小、定时轮转同时生效。
+func WithMaxLines(maxLines uint64) Option {
+	return func(r *Rotator) error {
+		r.maxLines = maxLines
+		return nil
+	}
+}
+
 // Rotator 轮转器入口，执行真正的轮转和写入操作
 // 根据轮转策略确定是否执行轮转，轮转策略包括：根据文件大小、定时以及混合策略，
 // 如果需要轮转，根据新的文件名称执行轮转操作。文件轮转后根据压缩策略确定是否执行压缩操作，
@@ -122,71 +251,396 @@ type Rotator struct {
 	f *os.File
 	// 执行轮转的策略
 	stg RotateStrategy
-	// 文件写入锁保护
-	writeLock sync.RWMutex
+	// 文件写入锁保护，分组轮转时同一组的轮转器共享写入锁
+	writeLock *sync.RWMutex
 	// 压缩配置
 	cpr Compress
 	// 清理过期文件的配置
 	cleanup *CleanUp
+	// 保存的最大文件数量，0表示不限制
+	maxCount uint64
+	// 保存的周期(天)，0表示不限制
+	period uint16
+	// 所有轮转文件的最大总大小，0表示不限制
+	maxTotalSize int64
+	// 轮转文件的最大保存时长，0表示不限制
+	maxAge time.Duration
+	// 分层保存策略
+	tiers []RetentionTier
+	// 磁盘可用空间的最低比例
+	minFreeRatio float64
+	// 紧急清理完成之后的回调
+	onEmergencyCleanup func([]FileInfo)
+	// 是否开启清理的演练模式
+	cleanupDryRun bool
+	// 演练模式下的回调
+	onCleanupDryRun func([]FileInfo)
+	// 调度清理任务的cron表达式
+	cleanupCron string
 	// 关闭信号
 	sig atomic.Int32
-	// 轮转计数器
-	counter atomic.Uint32
+	// 关闭之后通知后台任务退出
+	done chan struct{}
+	// 保证只关闭一次
+	closeOnce sync.Once
+	// 关闭之后后台任务全部执行完成时关闭
+	drained <-chan struct{}
+	// 是否安装退出信号的处理函数
+	signalShutdown bool
+	// 收到退出信号之后优雅关闭的超时时间
+	shutdownTimeout time.Duration
+	// 收到退出信号之后是否封存当前写入的文件
+	finalRotate bool
+	// 关闭时是否封存当前写入的文件
+	sealOnClose bool
+	// 关闭的结果
+	closeErr error
+	// 父级上下文，取消时关闭轮转器
+	ctx context.Context
+	// 取消父级上下文的关闭回调
+	stopCtx func() bool
+	// 静态加密的密钥提供者，nil表示不加密
+	encryption KeyProvider
+	// 需要重定向到轮转文件的标准输出流
+	stdioStreams StdioStream
+	// 标准输出流的重定向状态
+	stdio *stdioCapture
+	// 是否在进程被信号终止之前刷新数据
+	lastGasp bool
+	// 隔离文件的保存时间，0表示永久保存
+	quarantineRetention time.Duration
+	// 轮转器的创建时间
+	createdAt time.Time
+	// 定时轮转的cron表达式
+	rotateCron string
+	// 异步写入队列的长度，0表示同步写入
+	writeQueueSize int
+	// 异步写入队列已满时是否直接返回错误
+	writeFailFast bool
+	// 异步写入队列
+	writeQueue *writeRing
+	// 等待后台写入的goroutine退出
+	writerWG sync.WaitGroup
+	// 后台写入失败的次数
+	asyncWriteErrors atomic.Uint64
+	// 是否在每天零点强制轮转
+	midnightRotate bool
+	// 当前日期目录对应的下一个零点
+	dayEnd time.Time
+	// 上传封存完成的文件的上传器
+	uploader Uploader
+	// 上传成功的文件数量
+	uploads atomic.Uint64
+	// 上传失败的文件数量
+	uploadFailures atomic.Uint64
+	// 上传队列中的文件进入队列的时间
+	uploadAges ageTracker
+	// 后台上传队列
+	uploadQueue chan uploadJob
+	// 保护上传队列的发送和关闭
+	uploadLock sync.Mutex
+	// 上传队列是否已经关闭
+	uploadClosed bool
+	// 等待后台上传的goroutine退出
+	uploadWG sync.WaitGroup
+	// 上传使用的上下文，轮转器关闭之后取消
+	uploadCtx context.Context
+	// 取消上传使用的上下文
+	stopUpload context.CancelFunc
+	// 上传失败之后第一次重试的等待时间，之后每次翻倍
+	uploadBackoff time.Duration
+	// 删除文件时是否在清单中记录墓碑
+	tombstones bool
+	// 墓碑的保存时间，0表示永久保存
+	tombstoneRetention time.Duration
+	// 是否在初始化时审计存储目录
+	audit bool
+	// 审计报告的处理函数
+	onAudit func(*AuditReport)
+	// 审计时是否隔离冲突的文件
+	auditQuarantine bool
+	// 轮转文件的序列号
+	seq *sequence
 	// 日志
 	l *log.Logger
 	// 文件的最大写入字节
 	maxSize uint64
+	// 事件处理函数
+	eventHandler EventHandler
+	// 单次轮转阻塞写入的时间上限
+	pauseSLO time.Duration
+	// 初始化时是否自动修复目录
+	autoRepair bool
+	// 文件封存之后的完成标记
+	doneMarker DoneMarkerMode
+	// 跟踪文件的封存流程
+	tracker *sealTracker
+	// 内存预算
+	budget *memoryBudget
+	// 二次压缩的配置
+	recompress *recompressConfig
+	// 后台定时任务调度器
+	sched *scheduler
+	// 后台定时任务的随机抖动
+	jobJitter time.Duration
+	// 是否为后台任务添加pprof标签
+	profiling bool
+	// 是否开启目录级别的建议锁
+	advisoryLock bool
+	// 目录级别的建议锁
+	dirLock *dirLock
+	// 是否开启触发文件轮转
+	rotateTrigger bool
+	// 每日汇总信息的统计
+	summary *summaryCollector
+	// 单个文件允许写入的最大行数，0表示不限制
+	maxLines uint64
+	// 当前文件已经写入的行数
+	lines uint64
+	// 当前文件的序列号
+	fileSeq int64
+	// 当前文件已经写入的字节数
+	offset int64
+	// 是否开启WAL模式
+	wal bool
+	// 稀疏时间索引的间隔(字节)，0表示不记录索引
+	indexEvery int64
+	// 当前文件的时间索引文件
+	index *os.File
+	// 下一个索引项的最小偏移
+	nextIndex int64
+	// 当前文件的最后一次写入是否没有以换行符结尾
+	midLine bool
+	// 执行fsync的策略
+	syncPolicy SyncPolicy
+	// 按照时间间隔执行fsync的间隔
+	syncInterval time.Duration
+	// 是否显式设置了fsync的策略
+	syncPolicySet bool
+	// 存储目录是否位于网络文件系统上
+	networkFS bool
+	// 写入内容的转换函数链
+	transformers []Transformer
+	// 连续重复行的折叠
+	dedup *lineDedup
+	// 暂存了内容的转换函数
+	flushers []transformFlusher
+	// 是否允许存储目录对所有用户可写
+	allowWorldWritable bool
+	// 单个目录中允许的最大文件数量
+	maxDirEntries int
+	// 目录文件数量超过限制时执行的动作
+	dirOverflowAction DirOverflowAction
+	// 当前的子目录编号，0表示直接写入日期目录
+	bucket int
+	// 子目录编号对应的日期
+	bucketDate string
+	// 是否检测主机时区的变化
+	tzTracking bool
+	// 目录和文件命名使用的时区，nil表示time.Local
+	loc *time.Location
+	// 检测到变化之后等待切换的时区
+	pendingLoc *time.Location
+	// 上一次轮转失败，当前文件已经关闭
+	broken bool
+	// 各个原因触发的轮转次数
+	rotations [rotateReasonCount]atomic.Uint64
+	// 阻塞等待写锁的写入方数量
+	blockedWriters atomic.Int64
+	// 背压等级变化时的回调
+	onBackpressure BackpressureHandler
+	// 上一次检查的背压等级
+	lastBackpressure float64
+	// 准入控制的日志级别解析函数，nil表示不开启准入控制
+	admission LevelParser
+	// 准入控制丢弃的写入数量
+	droppedWrites atomic.Uint64
+	// zstd压缩的实现
+	zstdBackend ZstdBackend
+	// zstd压缩使用的字典ID，0表示不使用字典
+	zstdDict uint32
+	// 所属的轮转分组，nil表示独立轮转
+	group *RotatorGroup
+	// 异步压缩队列的长度，0表示同步压缩
+	sealQueueSize int
+	// 异步压缩的goroutine数量
+	sealWorkers int
+	// 异步压缩队列
+	sealQueue chan string
+	// 异步压缩队列中等待的文件进入队列的时间
+	sealAges ageTracker
+	// 等待异步压缩的goroutine退出
+	sealWG sync.WaitGroup
+	// 故障注入器，nil表示不注入故障
+	faults *FaultInjector
+	// 写入负载的记录，nil表示不记录
+	capture *workloadRecorder
+	// 是否为封存的文件生成校验和文件
+	checksum bool
+	// 是否维护归档清单
+	manifest bool
+	// 归档清单的追加锁
+	manifestLock sync.Mutex
+	// 边写边压缩的压缩类型，CompressTypeUnknown表示不开启
+	cow int
+	// 边写边压缩的压缩写入器，nil表示直接写入文件
+	cw flushWriteCloser
+	// 是否只按照序列号排序轮转文件
+	monotonic bool
+	// 延迟压缩的扫描任务是否正在执行
+	delayRunning atomic.Bool
+	// 保证修改已封存文件的后台任务(延迟压缩、二次压缩、每日打包)互斥执行
+	archiveLock sync.Mutex
+	// 每日打包的归档格式，0表示不打包
+	bundle ExportFormat
+	// 是否开启同步模式，同步模式下不启动任何后台goroutine
+	synchronous bool
 }
 
-// NewRotator 生产环境单例模式
+// NewRotator 创建轮转器，每次调用都返回独立的实例，同一个进程中可以为不同的日志(比如访问日志、
+// 错误日志、审计日志)创建多个使用不同策略的轮转器，多个实例不能使用相同的目录和文件名称。
 func NewRotator(dir, filename string, opts ...Option) (*Rotator, error) {
+	return newRotator(dir, filename, opts...)
+}
+
+// NewSingletonRotator 创建进程内唯一的轮转器实例，只有第一次调用时的参数生效，后续调用
+// 直接返回第一次创建的实例和错误。
+func NewSingletonRotator(dir, filename string, opts ...Option) (*Rotator, error) {
 	onceWithError.Do(func() error {
 		rotator, err := newRotator(dir, filename, opts...)
 		if err != nil {
 			return err
 		}
 
-		r = rotator
+		singleton = rotator
 		return nil
 	})
 
-	return r, onceWithError.err
+	return singleton, onceWithError.Err()
 }
 
-func newRotator(dir, filename string, opts ...Option) (*Rotator, error) {
-	const fileNameSliLength = 2
-	sli := strings.Split(filename, ".")
-	if len(sli) != fileNameSliLength {
-		return nil, errorx.ErrFilename
+func newRotator(dir, filename string, opts ...Option) (_ *Rotator, err error) {
+	name, ext, err := splitFilename(filename)
+	if err != nil {
+		return nil, err
+	}
+
+	dir, err = normalizeDir(dir)
+	if err != nil {
+		return nil, err
 	}
 
 	rotator := &Rotator{
-		dir:       dir,
-		filename:  sli[0],
-		ext:       sli[1],
-		writeLock: sync.RWMutex{},
-		l:         log.New(os.Stdout, "", log.LstdFlags),
-		maxSize:   DefaultMaxSize,
+		dir:        dir,
+		filename:   name,
+		ext:        ext,
+		writeLock:  &sync.RWMutex{},
+		l:          log.New(os.Stdout, "", log.LstdFlags),
+		maxSize:    DefaultMaxSize,
+		autoRepair: true,
+		tracker:    newSealTracker(),
+		sched:      newScheduler(),
+		done:       make(chan struct{}),
+		createdAt:  time.Now(),
+
+		quarantineRetention: DefaultQuarantineRetention,
 	}
 
 	rotator.sig.Store(0)
-	rotator.counter.Store(1)
+	defer func() {
+		if err != nil {
+			rotator.closeAcquired()
+		}
+	}()
 
-	if err := rotator.mkdirAll(); err != nil {
+	for _, opt := range opts {
+		if err = opt(rotator); err != nil {
+			return nil, err
+		}
+	}
+	if err = rotator.checkWAL(); err != nil {
+		return nil, err
+	}
+	if err = rotator.checkCompressOnWrite(); err != nil {
+		return nil, err
+	}
+	if err = rotator.checkEncryption(); err != nil {
+		return nil, err
+	}
+	if err = rotator.checkSynchronous(); err != nil {
 		return nil, err
 	}
 
-	f, err := os.OpenFile(rotator.newFile(), os.O_CREATE|os.O_RDWR|os.O_APPEND, ReadWriteFile)
-	if err != nil {
+	if err = rotator.mkdirAll(); err != nil {
 		return nil, err
 	}
-	rotator.f = f
 
-	for _, opt := range opts {
-		if err1 := opt(rotator); err1 != nil {
+	rotator.initNetworkFS()
+
+	if !rotator.allowWorldWritable {
+		if err = checkWorldWritable(dir); err != nil {
+			return nil, err
+		}
+	}
+
+	if rotator.autoRepair && needRepair(dir, name) {
+		report, err1 := repair(dir, name, rotator.rename)
+		if err1 != nil {
 			return nil, err1
 		}
+		if len(report.Actions) > 0 {
+			rotator.emit(Event{
+				Type:    EventRepair,
+				Path:    dir,
+				Message: fmt.Sprintf("repair directory %s, actions: %v", dir, report.Actions),
+			})
+		}
+	}
+	if rotator.audit {
+		if err = rotator.startupAudit(); err != nil {
+			return nil, err
+		}
+	}
+
+	if rotator.group != nil {
+		rotator.seq = rotator.group.seq
+	} else {
+		seq, err := newSequence(dir, rotator.filename)
+		if err != nil {
+			return nil, err
+		}
+		seq.rename = rotator.rename
+		rotator.seq = seq
+	}
+
+	if rotator.advisoryLock {
+		if rotator.dirLock, err = openDirLock(dir, name); err != nil {
+			return nil, err
+		}
+	}
+
+	if rotator.monotonic {
+		if err = writeOrderFile(dir, name, rotator.rename); err != nil {
+			return nil, err
+		}
+	}
+	if rotator.cpr.compress {
+		if err = removeStaleCompressTmp(dir, name); err != nil {
+			return nil, err
+		}
+	}
+
+	rotator.cleanup = rotator.newCleanUp()
+	rotator.checkDirEntries()
+	if err = rotator.mkdirAll(); err != nil {
+		return nil, err
+	}
+
+	f, err := rotator.openNewFile()
+	if err != nil {
+		return nil, err
 	}
+	rotator.f = f
 
 	if IsNil(rotator.stg) {
 		rotator.stg, err = NewMixStrategy(DefaultMaxSize, Hour)
@@ -194,151 +648,781 @@ func newRotator(dir, filename string, opts ...Option) (*Rotator, error) {
 			return nil, err
 		}
 	}
+	if err = rotator.applyRotateCron(); err != nil {
+		return nil, err
+	}
 
 	if rotator.cpr.compress && rotator.cpr.cs == nil {
 		return nil, errorx.ErrCompress
 	}
 
-	go rotator.asyncWork()
+	if rotator.recompress != nil {
+		err = rotator.addJob("recompress", DefaultRecompressCron, rotator.labeled(ProfileRecompress, rotator.recompressOld))
+		if err != nil {
+			return nil, err
+		}
+	}
+	if rotator.cpr.compress && (rotator.cpr.delay > 0 || rotator.cpr.window != nil) {
+		if err = rotator.addJob("compress-delay", DefaultCompressDelayCron, rotator.compressDelayed); err != nil {
+			return nil, err
+		}
+	}
+	if rotator.bundle != 0 {
+		if err = rotator.addJob("bundle", DefaultBundleCron, rotator.labeled(ProfileBundle, rotator.bundleDays)); err != nil {
+			return nil, err
+		}
+	}
+	if err = rotator.scheduleCleanup(); err != nil {
+		return nil, err
+	}
+	if rotator.sealQueueSize > 0 {
+		if err = rotator.startSealer(); err != nil {
+			return nil, err
+		}
+	}
+	if rotator.writeQueueSize > 0 {
+		rotator.startWriter()
+	}
+	if err = rotator.startStdio(); err != nil {
+		return nil, err
+	}
+	if rotator.uploader != nil {
+		rotator.startUploader()
+	}
+	if rotator.synchronous {
+		// 同步模式下不启动任何后台goroutine
+		if rotator.needReconcile() {
+			rotator.reconcile()
+		}
+		rotator.watchContext()
+		return rotator, nil
+	}
+	if err = rotator.scheduleJobs(); err != nil {
+		return nil, err
+	}
+	rotator.sched.start()
+	if rotator.needReconcile() {
+		// 启动之后立即封存上一次退出时遗留的文件，不阻塞初始化
+		rotator.sched.trigger(reconcileJobName)
+	}
+
+	if _, ok := rotator.stg.(*SizeStrategy); !ok {
+		// 只按照大小轮转时没有定时轮转的通知，不需要监听
+		go rotator.asyncWork()
+	}
+	if rotator.signalShutdown {
+		ch := make(chan os.Signal, 1)
+		signal.Notify(ch, shutdownSignals...)
+		go rotator.watchSignals(ch)
+	}
+	if rotator.lastGasp {
+		rotator.watchLastGasp()
+	}
+	rotator.watchContext()
 
 	return rotator, nil
 }
 
+// splitFilename 拆分基础文件名称和后缀名，文件名称中必须包含且只包含一个'.'
+func splitFilename(filename string) (name, ext string, err error) {
+	const fileNameSliLength = 2
+	sli := strings.Split(filename, ".")
+	if len(sli) != fileNameSliLength {
+		return "", "", errorx.ErrFilename
+	}
+
+	return sli[0], sli[1], nil
+}
+
+// normalizeDir 规范化文件存储目录：展开用户主目录(~)、转换为绝对路径并清理路径，
+// 防止进程初始化后切换工作目录导致相对路径失效
+func normalizeDir(dir string) (string, error) {
+	if dir == "~" || strings.HasPrefix(dir, "~/") || strings.HasPrefix(dir, "~"+string(filepath.Separator)) {
+		home, err := os.UserHomeDir()
+		if err != nil {
+			return "", err
+		}
+		dir = filepath.Join(home, dir[1:])
+	}
+
+	abs, err := filepath.Abs(dir)
+	if err != nil {
+		return "", err
+	}
+
+	return filepath.Clean(abs), nil
+}
+
 // Write 执行写入逻辑，判断大小是否已经达到最大大小，如果是则执行轮转逻辑
-// 轮转后根据压缩配置执行压缩逻辑
+// 轮转后根据压缩配置执行压缩逻辑。注册了转换函数时，写入文件的是转换之后的内容，
+// 全部写入成功时返回len(p)。
 func (r *Rotator) Write(p []byte) (int, error) {
+	if r.admission != nil && !r.admit(r.admission(p)) {
+		return len(p), nil
+	}
+	if r.writeQueue != nil {
+		return r.enqueueWrite(p)
+	}
+
+	return r.writeEntry(p)
+}
+
+// writeEntry 获取写锁并写入内容
+func (r *Rotator) writeEntry(p []byte) (int, error) {
+	return r.writeEntryBudget(p, true)
+}
+
+// writeEntryBudget 获取写锁并写入内容，reserve为true时转换期间按照输入的大小申请内存预算，
+// 异步写入的内容在入队时已经计入预算，不再重复申请
+func (r *Rotator) writeEntryBudget(p []byte, reserve bool) (int, error) {
 	if r.sig.Load() == 1 {
 		return 0, errorx.ErrRotateClosed
 	}
+	r.capture.record(len(p))
 
-	r.writeLock.Lock()
+	// 转换函数按照输入的大小分配输出缓冲区，转换期间计入内存预算，写入之前释放，
+	// 避免写入时内联封存申请压缩内存的时候等待自己占用的预算
+	var reserved int64
+	if reserve && len(r.transformers) > 0 && r.budget != nil {
+		reserved = int64(len(p))
+		r.budget.acquire(reserved)
+		defer func() {
+			r.budget.release(reserved)
+		}()
+	}
+
+	r.lockWrite()
 	defer r.writeLock.Unlock()
+	if r.sig.Load() == 1 {
+		return 0, errorx.ErrRotateClosed
+	}
 	if r.f == nil {
-		return 0, os.ErrClosed
+		return 0, errorx.ErrClosed
+	}
+	if r.wal {
+		return 0, errorx.ErrWALMode
+	}
+	hook(hookBeforeWrite, r.f.Name())
+
+	if len(r.transformers) == 0 {
+		return r.write(p)
+	}
+
+	data := r.transform(p)
+	r.budget.release(reserved)
+	reserved = 0
+	if len(data) == 0 {
+		return len(p), nil
+	}
+
+	if _, err := r.write(data); err != nil {
+		return 0, err
+	}
+
+	return len(p), nil
+}
+
+// write 执行真正的写入，必须持有写锁
+func (r *Rotator) write(p []byte) (int, error) {
+	_, n, err := r.writeAt(p)
+	return n, err
+}
+
+// writeAt 执行真正的写入，返回写入内容的起始位置，必须持有写锁
+func (r *Rotator) writeAt(p []byte) (LSN, int, error) {
+	if r.broken {
+		if err := r.recoverFile(); err != nil {
+			return LSN{}, 0, err
+		}
+	}
+
+	if r.midnightRotate {
+		if err := r.rotateAtMidnight(); err != nil {
+			return LSN{}, 0, err
+		}
+	}
+
+	if r.synchronous {
+		// 同步模式下没有后台的定时任务，在写入时判断定时轮转
+		if err := r.pollRotate(); err != nil {
+			return LSN{}, 0, err
+		}
 	}
 
+	lines := r.countLines(p)
 	if r.stg.ShouldRotate(uint64(len(p))) {
 		// 需要执行日志轮转
-		if err := r.rotate(); err != nil {
-			return 0, err
+		if err := r.rotate(RotateReasonSize); err != nil {
+			return LSN{}, 0, err
 		}
+	} else if r.shouldRotateLines(lines) {
+		// 行数达到限制，执行日志轮转
+		if err := r.rotate(RotateReasonLines); err != nil {
+			return LSN{}, 0, err
+		}
+		r.resetStrategy()
 	}
 
-	n, err := r.f.Write(p)
+	lsn := LSN{Segment: r.fileSeq, Offset: r.offset}
+	if err := r.faults.writeFault(); err != nil {
+		return lsn, 0, err
+	}
+	r.recordIndex()
+	n, err := r.output().Write(p)
+	r.lines += lines
+	r.offset += int64(n)
+	if n > 0 {
+		r.midLine = p[n-1] != '\n'
+	}
+	r.collectWrite(p[:n])
 	if err != nil {
-		return n, err
+		return lsn, n, err
+	}
+
+	if r.syncPolicy == SyncEveryWrite {
+		if err = r.syncFile(); err != nil {
+			return lsn, n, err
+		}
+	}
+
+	return lsn, n, nil
+}
+
+// rotate 执行轮转，必须持有写锁，分组轮转时同一组的所有文件一起轮转
+func (r *Rotator) rotate(reason RotateReason) error {
+	if r.group != nil {
+		return r.group.rotateLocked(reason)
 	}
 
-	return n, nil
+	return r.rotateFile(reason)
 }
 
-func (r *Rotator) rotate() (err error) {
-	_ = r.f.Close()
-	if r.cpr.compress {
-		r.l.Printf("rotate old file %s", r.f.Name())
-		if err = r.cps(r.f.Name()); err != nil {
-			fmt.Println("failed to cpr, cause: ", err.Error())
+// rotateFile 封存当前文件并打开新的文件，必须持有写锁
+func (r *Rotator) rotateFile(reason RotateReason) (err error) {
+	if r.broken {
+		return r.recoverFile()
+	}
+
+	var pause RotatePause
+	start := time.Now()
+	defer func() {
+		pause.Total = time.Since(start)
+		r.checkPause(pause)
+	}()
+
+	hook(hookBeforeRotate, r.f.Name())
+	if r.syncPolicy == SyncOnRotate {
+		// 数据没有持久化时不封存，继续写入当前文件，下一次轮转时重试
+		if err = r.syncFile(); err != nil {
+			return fmt.Errorf("sync file %s before rotate error: %w", r.f.Name(), err)
+		}
+	}
+	r.detachStdio()
+	if cerr := r.closeFile(); cerr != nil {
+		r.l.Printf("failed to close file %s before rotate, cause: %v", r.f.Name(), cerr)
+	}
+	r.closeIndex()
+	pause.Close = time.Since(start)
+	// 打开新的文件之前失败时，下一次写入或者轮转时重新打开新的文件
+	r.broken = true
+
+	if r.sealQueue != nil {
+		// 队列已满时阻塞等待，等待的时间计入压缩阶段
+		begin := time.Now()
+		r.enqueueSeal(r.f.Name())
+		pause.Compress = time.Since(begin)
+	} else {
+		err = r.seal(r.f.Name(), r.cpr.cs, &pause)
+		if err != nil {
+			r.l.Printf("failed to seal %s, cause: %v", r.f.Name(), err)
 			return err
 		}
 	}
 
-	f, err := os.OpenFile(r.newFile(), os.O_CREATE|os.O_RDWR, ReadWriteFile)
+	// 跨天轮转时需要先创建新一天的目录
+	begin := time.Now()
+	r.checkTimezone()
+	r.checkDirEntries()
+	err = r.mkdirAll()
+	pause.Mkdir = time.Since(begin)
+	if err != nil {
+		return err
+	}
+
+	begin = time.Now()
+	f, err := r.openNewFile()
+	pause.Open = time.Since(begin)
 	if err != nil {
 		return err
 	}
 
 	r.f = f
+	r.broken = false
+	r.lines = 0
+	r.offset = 0
+	r.nextIndex = 0
+	r.midLine = false
+	r.rotations[reason].Add(1)
+	r.attachStdio()
+	hook(hookAfterRotate, r.f.Name())
 
 	return nil
 }
 
-// cps 执行压缩操作
-func (r *Rotator) cps(oldPath string) error {
-	wf := compressFn(oldPath, r.cpr.compressType)
-	w, err := os.OpenFile(wf, os.O_RDWR|os.O_CREATE, ReadWriteFile)
-	if err != nil {
-		return err
+// checkPause 检查本次轮转阻塞写入的时间是否超过了SLO，超过则发送告警事件
+func (r *Rotator) checkPause(pause RotatePause) {
+	if r.pauseSLO <= 0 || pause.Total <= r.pauseSLO {
+		return
 	}
 
-	f, err := os.Open(oldPath)
-	if err != nil {
-		return err
+	r.emit(Event{
+		Type:    EventRotatePauseSLO,
+		Path:    r.f.Name(),
+		Message: fmt.Sprintf("rotate pause exceeds slo %s, %s", r.pauseSLO, pause),
+		Pause:   pause,
+	})
+}
+
+// countLines 统计写入内容的行数，没有开启行数轮转时不统计
+func (r *Rotator) countLines(p []byte) uint64 {
+	if r.maxLines == 0 {
+		return 0
+	}
+	if r.wal {
+		// WAL模式下每次写入一条记录
+		return 1
 	}
 
-	r.cpr.cs.Reset(w, f)
-	if err := r.cpr.cs.Compress(); err != nil {
+	return uint64(bytes.Count(p, []byte{'\n'}))
+}
+
+// shouldRotateLines 当前文件写入的行数加上本次写入的行数超过限制时需要轮转，
+// 当前文件为空时不轮转，防止单次写入的行数超过限制时反复轮转
+func (r *Rotator) shouldRotateLines(lines uint64) bool {
+	return r.maxLines > 0 && r.lines > 0 && r.lines+lines > r.maxLines
+}
+
+// Rotate 立即执行一次轮转，封存当前写入的文件，根据压缩配置执行压缩，并打开新的文件继续写入，
+// 可以在任意goroutine中调用，比如发布前或者收到管理命令时强制轮转
+func (r *Rotator) Rotate() error {
+	return r.rotateNow()
+}
+
+// rotateNow 立即执行一次轮转，轮转之后重置轮转策略的状态，同步模式下压缩没有压缩的轮转文件
+func (r *Rotator) rotateNow() error {
+	if err := r.rotateLocked(); err != nil {
 		return err
 	}
+	if r.synchronous && r.cpr.compress {
+		return r.sealPending()
+	}
 
 	return nil
 }
 
-func (r *Rotator) Close() {
+// rotateLocked 持有写锁执行一次轮转
+func (r *Rotator) rotateLocked() error {
 	r.writeLock.Lock()
 	defer r.writeLock.Unlock()
 
-	r.sig.Store(1)
-	if r.f == nil {
-		return
+	if r.sig.Load() == 1 {
+		return errorx.ErrRotateClosed
 	}
 
-	_ = r.f.Close()
+	if err := r.rotate(RotateReasonManual); err != nil {
+		return err
+	}
+	r.resetStrategy()
+
+	return nil
 }
 
-// newFile 新的文件名称，组合日期(年月日)和当天的文件计数器来生成唯一的文件名称
-func (r *Rotator) newFile() string {
-	t := time.Now().Format(Layout)
-	const template = "%s/%s/%s_%s_%04d.log"
-	newFile := fmt.Sprintf(template, r.dir, t, r.filename, t, r.counter.Load())
-	r.counter.Add(1)
-	return newFile
+// resetStrategy 轮转策略之外触发的轮转完成之后，重置轮转策略的状态
+func (r *Rotator) resetStrategy() {
+	if rs, ok := r.stg.(ResettableStrategy); ok {
+		rs.Reset()
+	}
 }
 
-// mkdirAll 创建文件目录，文件父目录是年月日时间，初始化时候会创建当天的目录，定时任务每天凌晨00:00会创建第二天的目录
-func (r *Rotator) mkdirAll() error {
-	t := time.Now().Format(Layout)
-	return os.MkdirAll(fmt.Sprintf("%s/%s", r.dir, t), os.ModePerm)
+// cps 使用压缩策略cs执行压缩操作
+func (r *Rotator) cps(cs CompressStrategy, oldPath string) ([]byte, error) {
+	if err := r.faults.compressFault(); err != nil {
+		return nil, err
+	}
+
+	// 先压缩到临时文件，成功之后再rename为正式文件，进程在压缩过程中退出时不会留下不完整的压缩文件
+	wf := compressFn(oldPath, r.cpr.compressType)
+	tmp := wf + TmpFileExt
+	w, err := os.OpenFile(tmp, os.O_CREATE|os.O_TRUNC|os.O_WRONLY|openNoFollow, ReadWriteFile)
+	if err != nil {
+		return nil, err
+	}
+
+	f, err := os.Open(oldPath)
+	if err != nil {
+		_ = w.Close()
+		_ = os.Remove(tmp)
+		return nil, err
+	}
+
+	mem := r.cpr.memory()
+	r.budget.acquire(mem)
+	defer r.budget.release(mem)
+
+	resources.acquire()
+	defer resources.release()
+
+	// 压缩器在Compress结束时关闭并刷新压缩流，以及关闭源文件，这里负责关闭压缩输出文件
+	// 开启校验和时在写入压缩文件的同时计算校验和
+	var out io.Writer = w
+	h := sha256.New()
+	if r.checksum {
+		out = io.MultiWriter(w, h)
+	}
+	cs.Reset(out, f)
+	r.setSealHeader(cs, f)
+	if err = cs.Compress(); err != nil {
+		_ = w.Close()
+		_ = os.Remove(tmp)
+		return nil, err
+	}
+
+	// rename之前fsync压缩文件，rename会fsync所在的目录，之后才能删除源文件，否则崩溃之后
+	// 可能只剩下rename完成但是内容没有落盘的压缩文件，源文件已经被删除
+	err = w.Sync()
+	if closeErr := w.Close(); err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		_ = os.Remove(tmp)
+		return nil, err
+	}
+
+	if err = r.rename(tmp, wf); err != nil {
+		_ = os.Remove(tmp)
+		return nil, err
+	}
+	if !r.checksum {
+		return nil, nil
+	}
+
+	return h.Sum(nil), nil
 }
 
-// asyncWork 异步任务，用于接收定时轮转的信号，接收到之后立即执行文件轮转
-func (r *Rotator) asyncWork() {
-	const (
-		ContextTimeout          = time.Second * 10
-		InitExponentialInterval = time.Millisecond * 5
-		TickerInterval          = time.Millisecond * 10
-	)
-
-	notify := r.stg.NotifyRotate()
-	ticker := time.NewTicker(TickerInterval)
-	defer ticker.Stop()
-
-	for range ticker.C {
-		select {
-		case _, ok := <-notify:
-			if !ok {
-				r.l.Println("notify channel closed")
-				return
-			}
+// removeStaleCompressTmp 删除进程在压缩过程中退出时残留的压缩临时文件
+func removeStaleCompressTmp(dir, name string) error {
+	re := segmentRegexp(name)
+	return filepath.WalkDir(dir, func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if d.IsDir() || !re.MatchString(d.Name()) || !strings.HasSuffix(d.Name(), TmpFileExt) {
+			return nil
+		}
+		if compressTypeOf(strings.TrimSuffix(d.Name(), TmpFileExt)) == CompressTypeUnknown {
+			return nil
+		}
+
+		if err = os.Remove(path); err != nil && !os.IsNotExist(err) {
+			return err
+		}
+		return nil
+	})
+}
 
-			r.writeLock.Lock()
-			info, err := r.f.Stat()
-			if err != nil {
-				r.writeLock.Unlock()
-				r.l.Println("failed to stat file, cause: ", err.Error())
-				continue
+// Close 关闭轮转器，停止轮转策略和后台任务，关闭当前写入的文件，可以重复调用，也可以与Write
+// 并发调用，只有第一次调用会执行关闭操作，之后的调用返回第一次关闭的结果
+func (r *Rotator) Close() error {
+	return r.close(r.sealOnClose)
+}
+
+// close 执行关闭操作，seal为true时封存当前写入的文件
+func (r *Rotator) close(seal bool) error {
+	r.closeOnce.Do(func() {
+		// 先写完异步写入队列中的内容，之后的写入返回关闭错误
+		r.drainWriter()
+		r.sig.Store(1)
+		close(r.done)
+
+		// 轮转策略需要在获取写锁之前关闭，防止定时轮转的通知阻塞在等待写锁的asyncWork上
+		r.stg.Close()
+		if r.cleanup != nil {
+			r.cleanup.Stop()
+		}
+
+		r.writeLock.Lock()
+		defer r.writeLock.Unlock()
+
+		if r.stopCtx != nil {
+			r.stopCtx()
+		}
+		var errs []error
+		errs = append(errs, r.stopStdio())
+		errs = append(errs, r.flushTransformers())
+		if r.f != nil {
+			switch {
+			case r.broken:
+				// 上一次轮转失败时当前文件已经关闭
+			case seal:
+				errs = append(errs, r.sealActive())
+			default:
+				errs = append(errs, r.closeFile())
+				r.closeIndex()
 			}
+			r.f = nil
+		}
+
+		r.drainSealer()
+		r.drainUploader()
+		r.tracker.abort(errorx.ErrRotateClosed)
+		errs = append(errs, r.flushSummary())
+		r.drained = r.sched.stop()
+		errs = append(errs, r.dirLock.Close())
+		errs = append(errs, r.capture.Close())
+		r.closeErr = errors.Join(errs...)
+	})
 
-			if float64(info.Size()) < RotateSizeThreshold*float64(r.maxSize) {
-				r.writeLock.Unlock()
-				continue
+	return r.closeErr
+}
+
+// closeAcquired 初始化失败时按照获取的相反顺序释放已经获取的资源，停止已经启动的后台任务，
+// 删除已经创建但是没有写入内容的文件
+func (r *Rotator) closeAcquired() {
+	r.sched.stop()
+	r.drainUploader()
+	_ = r.stopStdio()
+	r.drainWriter()
+	r.drainSealer()
+	if !IsNil(r.stg) {
+		r.stg.Close()
+	}
+	if r.f != nil {
+		_ = r.closeFile()
+		r.closeIndex()
+		if r.offset == 0 {
+			_ = os.Remove(r.f.Name())
+		}
+		r.f = nil
+	}
+	if r.cleanup != nil {
+		r.cleanup.Stop()
+	}
+	_ = r.dirLock.Close()
+	_ = r.capture.Close()
+}
+
+// newFile 新的文件名称，组合日期(年月日)和持久化的文件序列号来生成唯一的文件名称
+func (r *Rotator) newFile() (string, error) {
+	seq, err := r.nextSeq()
+	if err != nil {
+		return "", err
+	}
+
+	r.fileSeq = int64(seq)
+	dir := r.segmentDir()
+	const template = "%s_%s_%04d.log"
+	fn := fmt.Sprintf(template, r.filename, r.bucketDate, seq)
+	if r.cow != CompressTypeUnknown {
+		// 边写边压缩时文件名称带有压缩后缀
+		fn = compressFn(fn, r.cow)
+	}
+	return filepath.Join(dir, fn), nil
+}
+
+// openNewFile 以独占的方式创建新的轮转文件，不跟随符号链接，文件已经存在时(比如被其他
+// 进程预先创建)跳过该序列号重新分配
+func (r *Rotator) openNewFile() (*os.File, error) {
+	const maxRetry = 16
+	for i := 0; i < maxRetry; i++ {
+		fn, err := r.newFile()
+		if err != nil {
+			return nil, err
+		}
+
+		f, err := os.OpenFile(fn, os.O_CREATE|os.O_EXCL|os.O_RDWR|os.O_APPEND|openNoFollow, ReadWriteFile)
+		if err == nil {
+			if r.cow != CompressTypeUnknown {
+				if r.cw, err = r.newCowWriter(f); err != nil {
+					_ = f.Close()
+					return nil, err
+				}
+			}
+			r.collectSegment()
+			return f, nil
+		}
+		if !os.IsExist(err) {
+			if os.IsPermission(err) {
+				return nil, fmt.Errorf("%w: %w", errorx.ErrDirUnwritable, err)
 			}
+			return nil, err
+		}
+		if r.group != nil {
+			// 分组轮转时同一组的文件必须使用相同的序列号
+			return nil, errorx.ErrSegmentExists
+		}
+
+		r.l.Printf("segment %s already exists, skip", fn)
+	}
+
+	return nil, errorx.ErrSegmentExists
+}
+
+// mkdirAll 创建文件目录，文件父目录是年月日时间，初始化时候会创建当天的目录，跨天轮转时会创建新一天的目录，
+// 日期目录不能是符号链接
+func (r *Rotator) mkdirAll() error {
+	err := mkdirNoFollow(r.dir, r.segmentDir())
+	if os.IsPermission(err) {
+		return fmt.Errorf("%w: %w", errorx.ErrDirUnwritable, err)
+	}
+
+	return err
+}
+
+// mkdirNoFollow 创建root下的目录path，root是用户配置的存储目录，允许是符号链接，root之下的每一级
+// 目录都通过os.Mkdir逐级创建，创建之后(或者已经存在时)通过Lstat确认是真实的目录而不是符号链接，
+// 不会像MkdirAll那样先检查再创建，在检查和创建之间被替换为符号链接
+func mkdirNoFollow(root, path string) error {
+	if err := os.MkdirAll(root, os.ModePerm); err != nil {
+		return err
+	}
+
+	rel, err := filepath.Rel(root, path)
+	if err != nil {
+		return err
+	}
+	if rel == "." {
+		return nil
+	}
+	if rel == ".." || strings.HasPrefix(rel, ".."+string(filepath.Separator)) {
+		return fmt.Errorf("directory %s is outside of %s", path, root)
+	}
+
+	cur := root
+	for _, part := range strings.Split(rel, string(filepath.Separator)) {
+		cur = filepath.Join(cur, part)
+		if err = os.Mkdir(cur, os.ModePerm); err != nil && !os.IsExist(err) {
+			return err
+		}
+
+		info, err := os.Lstat(cur)
+		if err != nil {
+			return err
+		}
+		if info.Mode()&os.ModeSymlink != 0 || !info.IsDir() {
+			return fmt.Errorf("%w: %s", errorx.ErrSymlink, cur)
+		}
+	}
+
+	return nil
+}
+
+// addJob 添加后台定时任务，spec为带秒的cron表达式
+func (r *Rotator) addJob(name, spec string, fn func()) error {
+	return r.sched.cron(name, spec, r.jobJitter, fn)
+}
+
+// every 添加按照固定间隔执行的后台任务，轮转器关闭之后不再执行
+func (r *Rotator) every(name string, interval time.Duration, fn func()) error {
+	return r.sched.every(name, interval, 0, func() {
+		if r.sig.Load() == 0 {
+			fn()
+		}
+	})
+}
+
+// scheduleJobs 将轮转策略、清理以及各种周期性检查注册到调度器中
+func (r *Rotator) scheduleJobs() error {
+	if ts, ok := r.stg.(timedStrategy); ok {
+		if err := ts.attach(r.sched, mixJobName); err != nil {
+			return err
+		}
+	}
+	if r.cleanup != nil {
+		if err := r.cleanup.startOn(r.sched, r.jobJitter); err != nil {
+			return err
+		}
+	}
+	if r.rotateTrigger {
+		if err := r.every("trigger", r.triggerInterval(), r.checkTrigger); err != nil {
+			return err
+		}
+	}
+	if r.syncPolicy == SyncInterval {
+		if err := r.every("sync", r.syncInterval, r.syncJob); err != nil {
+			return err
+		}
+	} else if r.cow != CompressTypeUnknown && r.syncPolicy != SyncEveryWrite {
+		if err := r.every("flush", DefaultCompressFlushInterval, r.flushJob); err != nil {
+			return err
+		}
+	}
+	if r.onBackpressure != nil {
+		if err := r.every("backpressure", BackpressureCheckInterval, r.checkBackpressure); err != nil {
+			return err
+		}
+	}
+	if r.midnightRotate {
+		if err := r.sched.add(midnightJobName, midnightSchedule{loc: r.location()}, 0, r.midnightJob); err != nil {
+			return err
+		}
+	}
+	if r.needReconcile() {
+		if err := r.every(reconcileJobName, ReconcileInterval, r.labeled(ProfileReconcile, r.reconcile)); err != nil {
+			return err
+		}
+	}
 
-			err = r.rotate()
+	return nil
+}
+
+// asyncWork 异步任务，用于接收定时轮转的信号，接收到之后立即执行文件轮转
+func (r *Rotator) asyncWork() {
+	// 轮转策略关闭时关闭通知通道，asyncWork随之退出
+	for range r.stg.NotifyRotate() {
+		r.writeLock.Lock()
+		if r.f == nil {
 			r.writeLock.Unlock()
-			if err != nil {
-				r.l.Printf("asyncWork: rotate error: %v", err)
-			}
-		default:
+			return
+		}
+
+		err := r.scheduledRotate()
+		r.writeLock.Unlock()
+		if err != nil {
+			r.l.Printf("asyncWork: rotate error: %v", err)
 		}
 	}
+	r.l.Println("notify channel closed")
+}
+
+// scheduledRotate 执行定时轮转，当前文件的大小没有达到最大大小的RotateSizeThreshold时跳过，
+// 只按照时间轮转或者使用组合策略时当前文件为空才跳过，必须持有写锁
+func (r *Rotator) scheduledRotate() error {
+	info, err := r.f.Stat()
+	if err != nil {
+		return fmt.Errorf("failed to stat file: %w", err)
+	}
+
+	size := info.Size()
+	if r.cw != nil {
+		// 边写边压缩时按照压缩之前的大小计算
+		size = r.offset
+	}
+	switch s := r.stg.(type) {
+	case *TimeStrategy, *CompositeStrategy:
+		// 只按照时间轮转或者组合策略已经判断了轮转条件时不限制文件大小，没有写入数据时跳过
+		if size == 0 {
+			return nil
+		}
+	case *MixStrategy:
+		// 设置了定时轮转的时间表时按照时间表轮转所有写入了数据的文件
+		if size == 0 || !s.cron() && float64(size) < RotateSizeThreshold*float64(r.maxSize) {
+			return nil
+		}
+	default:
+		if float64(size) < RotateSizeThreshold*float64(r.maxSize) {
+			return nil
+		}
+	}
+
+	return r.rotate(r.scheduledReason())
 }
@@ -29,6 +29,10 @@ const (
 	RotateInterval      = time.Millisecond * 100
 )
 
+// mixJobName 混合策略和定时策略的定时轮转任务名称，组合策略的子策略迁移到轮转器的调度器上时
+// 加上子策略的序号
+const mixJobName = "rotate"
+
 type TimingType string
 
 const (
@@ -62,7 +66,31 @@ type RotateStrategy interface {
 	Close()
 }
 
-var _ RotateStrategy = (*MixStrategy)(nil)
+// timedStrategy 内置的定时轮转策略，定时任务迁移到轮转器的调度器上，同步模式下在写入时检查定时轮转
+type timedStrategy interface {
+	RotateStrategy
+	// attach 将定时轮转任务以名称name迁移到轮转器的调度器上
+	attach(sched *scheduler, name string) error
+	// poll 同步模式下判断是否需要执行定时轮转
+	poll(now time.Time) bool
+}
+
+// ResettableStrategy 支持重置内部状态的轮转策略，在外部触发的轮转(比如手动轮转)完成之后调用，
+// 重新开始统计文件大小和轮转时间
+type ResettableStrategy interface {
+	RotateStrategy
+	// Reset 重置轮转策略的状态
+	Reset()
+}
+
+var (
+	_ RotateStrategy     = (*MixStrategy)(nil)
+	_ ResettableStrategy = (*MixStrategy)(nil)
+	_ timedStrategy      = (*MixStrategy)(nil)
+	_ timedStrategy      = (*TimeStrategy)(nil)
+	_ RotateStrategy     = (*SizeStrategy)(nil)
+	_ ResettableStrategy = (*SizeStrategy)(nil)
+)
 
 // MixStrategy 混合策略包括两个触发因子：定时和当前文件大小。
 // 文件大小的优先级高于定时，当在一个定时的时间窗口周期内，数据写入时文件
@@ -81,16 +109,30 @@ type MixStrategy struct {
 	size uint64
 	// 加锁保护
 	lock sync.Mutex
-	// 定时任务
-	c *cron.Cron
+	// 定时任务调度器，创建轮转器时迁移到轮转器的调度器上
+	sched *scheduler
+	// 是否已经迁移到轮转器的调度器上
+	attached bool
+	// 迁移之后在轮转器的调度器上的任务名称
+	job string
+	// 第一次获取通知通道时启动调度器
+	startOnce sync.Once
+	// 定时轮转的时间表
+	schedule cron.Schedule
+	// 同步模式下下一次定时轮转的时间
+	next time.Time
 	// 定时事件类型
 	tp TimingType
+	// 自定义的定时轮转cron表达式，为空时根据定时事件类型确定
+	spec string
 	// 上次轮转的事件
 	lastTime int64
 	// 事件通知通道，只用于定时轮转的事件通知
 	events chan struct{}
 	// 日志
 	lg *log.Logger
+	// 保证只关闭一次
+	closeOnce sync.Once
 }
 
 func NewMixStrategy(maxSize uint64, tp TimingType) (*MixStrategy, error) {
@@ -98,12 +140,18 @@ func NewMixStrategy(maxSize uint64, tp TimingType) (*MixStrategy, error) {
 		return nil, errorx.ErrTimeType
 	}
 
+	return newMixStrategy(maxSize, tp, "")
+}
+
+// newMixStrategy 创建混合策略，spec不为空时按照cron表达式定时轮转
+func newMixStrategy(maxSize uint64, tp TimingType, spec string) (*MixStrategy, error) {
 	stg := &MixStrategy{
 		maxSize: maxSize,
 		lock:    sync.Mutex{},
 		events:  make(chan struct{}),
-		c:       cron.New(cron.WithSeconds()),
+		sched:   newScheduler(),
 		tp:      tp,
+		spec:    spec,
 		lg:      log.New(os.Stdout, "", log.LstdFlags),
 	}
 
@@ -114,7 +162,15 @@ func NewMixStrategy(maxSize uint64, tp TimingType) (*MixStrategy, error) {
 	return stg, nil
 }
 
+// NotifyRotate 获取定时轮转信号，第一次调用时启动定时任务
 func (s *MixStrategy) NotifyRotate() <-chan struct{} {
+	s.startOnce.Do(func() {
+		s.lock.Lock()
+		sched := s.sched
+		s.lock.Unlock()
+		sched.start()
+	})
+
 	return s.events
 }
 
@@ -133,58 +189,334 @@ func (s *MixStrategy) ShouldRotate(writeSize uint64) bool {
 	return true
 }
 
+// Reset 外部触发轮转之后重置已写入的大小和上次轮转的时间
+func (s *MixStrategy) Reset() {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	s.lastTime = time.Now().UnixMilli()
+	s.size = 0
+}
+
 // asyncWorker 开启定时任务执行轮转判断逻辑，定时任务表达式根据定时任务类型确定
 // _Second: 不支持秒级的定时任务，这个只用于单元测试
 // Hour: 每隔一小时执行一次，0 0 * * * *
 // Day: 每天凌晨0点执行一次，0 0 0 * * *
 // Week: 每周一凌晨0点执行一次，0 0 0 * * 1
 // Month: 每月1号凌晨0点执行一次，0 0 0 1 * *
+// 设置了自定义的cron表达式时按照cron表达式执行
 func (s *MixStrategy) asyncWorker() error {
-	var cronStr string
-	switch s.tp {
+	var err error
+	cronStr := s.spec
+	if cronStr == "" {
+		if cronStr, err = timingSpec(s.tp); err != nil {
+			return err
+		}
+	}
+
+	if s.schedule, err = parseCron(cronStr); err != nil {
+		return err
+	}
+	s.next = s.schedule.Next(time.Now())
+
+	// 定时任务在第一次获取通知通道时启动，同步模式下不获取通知通道，不启动goroutine
+	return s.sched.add(mixJobName, s.schedule, 0, s.tick)
+}
+
+// fire 定时轮转的判断逻辑，距离上次轮转的时间过短并且写入的数据过少时跳过本次定时轮转，
+// 通过WithRotateCron设置了定时轮转的时间时按照时间表轮转，不跳过
+func (s *MixStrategy) fire() bool {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	if s.spec == "" && time.Duration(time.Now().UnixMilli()-s.lastTime) < RotateInterval {
+		if float64(s.size) < float64(s.maxSize)*RotateSizeThreshold {
+			threshold := float64(s.maxSize) * RotateSizeThreshold
+			s.lg.Printf("rotate size too small, size: %d, threshold: %0.2f, skip!", s.size, threshold)
+			return false
+		}
+	}
+
+	s.lastTime = time.Now().UnixMilli()
+	s.size = 0
+	return true
+}
+
+// cron 是否通过WithRotateCron设置了定时轮转的时间
+func (s *MixStrategy) cron() bool {
+	return s.spec != ""
+}
+
+// poll 同步模式下在写入时判断是否到达定时轮转的时间，到达时执行与定时任务相同的判断逻辑
+func (s *MixStrategy) poll(now time.Time) bool {
+	s.lock.Lock()
+	due := !now.Before(s.next)
+	if due {
+		s.next = s.schedule.Next(now)
+	}
+	s.lock.Unlock()
+
+	return due && s.fire()
+}
+
+// tick 定时轮转的判断逻辑，满足条件时发送轮转通知
+func (s *MixStrategy) tick() {
+	if !s.fire() {
+		return
+	}
+
+	select {
+	case s.events <- struct{}{}:
+		s.lg.Println("rotate event send success!")
+	case <-time.After(time.Second):
+		s.lg.Println("rotate event send timeout!")
+	}
+}
+
+// attach 将定时轮转任务以名称name迁移到轮转器的调度器上，由轮转器统一调度和关闭，只能迁移一次
+func (s *MixStrategy) attach(sched *scheduler, name string) error {
+	s.lock.Lock()
+	old, attached := s.sched, s.attached
+	s.lock.Unlock()
+	if attached {
+		return nil
+	}
+
+	// 等待正在执行的定时任务结束，任务中需要获取s.lock，不能持有锁等待
+	<-old.stop()
+	if err := sched.add(name, s.schedule, 0, s.tick); err != nil {
+		return err
+	}
+
+	s.lock.Lock()
+	s.sched, s.attached, s.job = sched, true, name
+	s.lock.Unlock()
+	return nil
+}
+
+// timingSpec 定时轮转类型对应的cron表达式
+func timingSpec(tp TimingType) (string, error) {
+	switch tp {
 	case _Second:
-		cronStr = "*/1 * * * * *"
+		return "*/1 * * * * *", nil
 	case Hour:
-		cronStr = "0 0 * * * *"
+		return "0 0 * * * *", nil
 	case Day:
-		cronStr = "0 0 0 * * *"
+		return "0 0 0 * * *", nil
 	case Week:
-		cronStr = "0 0 0 * * 1"
+		return "0 0 0 * * 1", nil
 	case Month:
-		cronStr = "0 0 0 1 * *"
+		return "0 0 0 1 * *", nil
 	default:
-		return errorx.ErrTimeType
+		return "", errorx.ErrTimeType
 	}
+}
 
-	_, err := s.c.AddFunc(cronStr, func() {
+// Close 关闭轮转策略，等待正在执行的定时任务结束之后再关闭通知通道，可以重复调用
+func (s *MixStrategy) Close() {
+	s.closeOnce.Do(func() {
 		s.lock.Lock()
-		if time.Duration(time.Now().UnixMilli()-s.lastTime) < RotateInterval {
-			if float64(s.size) < float64(s.maxSize)*RotateSizeThreshold {
-				threshold := float64(s.maxSize) * RotateSizeThreshold
-				s.lg.Printf("rotate size too small, size: %d, threshold: %0.2f, skip!", s.size, threshold)
-				s.lock.Unlock()
-				return
-			}
+		sched, attached, job := s.sched, s.attached, s.job
+		s.lock.Unlock()
+		if attached {
+			sched.remove(job)
+		} else {
+			<-sched.stop()
 		}
+		close(s.events)
+	})
+}
 
-		s.lastTime = time.Now().UnixMilli()
-		s.size = 0
-		s.lock.Unlock()
+// SizeStrategy 只按照文件大小轮转的策略，不依赖定时任务，也不会发送定时轮转的通知，
+// 适用于不需要按时间轮转并且希望减少后台goroutine的场景
+type SizeStrategy struct {
+	// 单个文件允许的最大字节
+	maxSize uint64
+	// 当前已经写入的字节数
+	size uint64
+	// 加锁保护
+	lock sync.Mutex
+	// 通知通道，不会发送任何通知，关闭策略时关闭
+	events chan struct{}
+	// 保证只关闭一次
+	closeOnce sync.Once
+}
 
-		select {
-		case s.events <- struct{}{}:
-			s.lg.Println("rotate event send success!")
-		case <-time.After(time.Second):
-			s.lg.Println("rotate event send timeout!")
-		}
+func NewSizeStrategy(maxSize uint64) *SizeStrategy {
+	return &SizeStrategy{
+		maxSize: maxSize,
+		events:  make(chan struct{}),
+	}
+}
+
+// NotifyRotate 获取定时轮转信号，只按照大小轮转，通道在关闭策略之前不会收到任何通知
+func (s *SizeStrategy) NotifyRotate() <-chan struct{} {
+	return s.events
+}
+
+// ShouldRotate 写入之后的大小达到最大限制时需要执行轮转操作
+func (s *SizeStrategy) ShouldRotate(writeSize uint64) bool {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	if s.size+writeSize < s.maxSize {
+		s.size += writeSize
+		return false
+	}
+
+	s.size = 0
+	return true
+}
+
+// Reset 外部触发轮转之后重置已写入的大小
+func (s *SizeStrategy) Reset() {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	s.size = 0
+}
+
+// Close 关闭轮转策略，可以重复调用
+func (s *SizeStrategy) Close() {
+	s.closeOnce.Do(func() {
+		close(s.events)
 	})
+}
 
-	s.c.Start()
+var _ RotateStrategy = (*TimeStrategy)(nil)
 
-	return err
+// TimeStrategy 只按照时间表定时轮转的策略，不限制文件的大小，每到轮转时间只要当前文件写入了数据就
+// 执行轮转，适用于写入量小但是稳定、需要按小时/天等固定周期切分文件的场景
+type TimeStrategy struct {
+	// 加锁保护
+	lock sync.Mutex
+	// 定时任务调度器，创建轮转器时迁移到轮转器的调度器上
+	sched *scheduler
+	// 是否已经迁移到轮转器的调度器上
+	attached bool
+	// 迁移之后在轮转器的调度器上的任务名称
+	job string
+	// 第一次获取通知通道时启动调度器
+	startOnce sync.Once
+	// 定时轮转的时间表
+	schedule cron.Schedule
+	// 同步模式下下一次定时轮转的时间
+	next time.Time
+	// 定时事件类型
+	tp TimingType
+	// 定时轮转的cron表达式
+	spec string
+	// 事件通知通道
+	events chan struct{}
+	// 日志
+	lg *log.Logger
+	// 保证只关闭一次
+	closeOnce sync.Once
 }
 
-func (s *MixStrategy) Close() {
-	s.c.Stop()
-	close(s.events)
+func NewTimeStrategy(tp TimingType) (*TimeStrategy, error) {
+	spec, err := timingSpec(tp)
+	if err != nil {
+		return nil, err
+	}
+
+	return newTimeStrategy(tp, spec)
+}
+
+// newTimeStrategy 创建按照cron表达式spec定时轮转的策略
+func newTimeStrategy(tp TimingType, spec string) (*TimeStrategy, error) {
+	s := &TimeStrategy{
+		sched:  newScheduler(),
+		tp:     tp,
+		spec:   spec,
+		events: make(chan struct{}),
+		lg:     log.New(os.Stdout, "", log.LstdFlags),
+	}
+
+	var err error
+	if s.schedule, err = parseCron(spec); err != nil {
+		return nil, err
+	}
+	s.next = s.schedule.Next(time.Now())
+
+	// 定时任务在第一次获取通知通道时启动，同步模式下不获取通知通道，不启动goroutine
+	if err = s.sched.add(mixJobName, s.schedule, 0, s.tick); err != nil {
+		return nil, err
+	}
+
+	return s, nil
+}
+
+// NotifyRotate 获取定时轮转信号，第一次调用时启动定时任务
+func (s *TimeStrategy) NotifyRotate() <-chan struct{} {
+	s.startOnce.Do(func() {
+		s.lock.Lock()
+		sched := s.sched
+		s.lock.Unlock()
+		sched.start()
+	})
+
+	return s.events
+}
+
+// ShouldRotate 不按照大小轮转，总是返回false
+func (s *TimeStrategy) ShouldRotate(uint64) bool {
+	return false
+}
+
+// poll 同步模式下在写入时判断是否到达定时轮转的时间
+func (s *TimeStrategy) poll(now time.Time) bool {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	if now.Before(s.next) {
+		return false
+	}
+	s.next = s.schedule.Next(now)
+	return true
+}
+
+// tick 到达定时轮转的时间，发送轮转通知
+func (s *TimeStrategy) tick() {
+	select {
+	case s.events <- struct{}{}:
+		s.lg.Println("rotate event send success!")
+	case <-time.After(time.Second):
+		s.lg.Println("rotate event send timeout!")
+	}
+}
+
+// attach 将定时轮转任务以名称name迁移到轮转器的调度器上，由轮转器统一调度和关闭，只能迁移一次
+func (s *TimeStrategy) attach(sched *scheduler, name string) error {
+	s.lock.Lock()
+	old, attached := s.sched, s.attached
+	s.lock.Unlock()
+	if attached {
+		return nil
+	}
+
+	<-old.stop()
+	if err := sched.add(name, s.schedule, 0, s.tick); err != nil {
+		return err
+	}
+
+	s.lock.Lock()
+	s.sched, s.attached, s.job = sched, true, name
+	s.lock.Unlock()
+	return nil
+}
+
+// Close 关闭轮转策略，等待正在执行的定时任务结束之后再关闭通知通道，可以重复调用
+func (s *TimeStrategy) Close() {
+	s.closeOnce.Do(func() {
+		s.lock.Lock()
+		sched, attached, job := s.sched, s.attached, s.job
+		s.lock.Unlock()
+		if attached {
+			sched.remove(job)
+		} else {
+			<-sched.stop()
+		}
+		close(s.events)
+	})
 }
@@ -123,3 +123,130 @@ func TestNewMixStrategy_Concurrent(t *testing.T) {
 	}()
 	wg.Wait()
 }
+
+func TestSizeStrategy(t *testing.T) {
+	ss := NewSizeStrategy(100)
+	assert.False(t, ss.ShouldRotate(60))
+	assert.True(t, ss.ShouldRotate(40))
+	assert.False(t, ss.ShouldRotate(99))
+	ss.Reset()
+	assert.False(t, ss.ShouldRotate(99))
+
+	ch := ss.NotifyRotate()
+	select {
+	case <-ch:
+		t.Fatal("size strategy should not notify")
+	default:
+	}
+
+	ss.Close()
+	ss.Close()
+	_, ok := <-ch
+	assert.False(t, ok)
+}
+
+func TestRotator_WithSizeRotate(t *testing.T) {
+	_, err := newRotator(t.TempDir(), "testdata.log", WithSizeRotate(0))
+	assert.Error(t, err)
+
+	rotator, err := newRotator(t.TempDir(), "testdata.log", WithSizeRotate(64))
+	assert.NoError(t, err)
+	defer rotator.Close()
+
+	first := rotator.f.Name()
+	_, err = rotator.Write([]byte("size strategy test\n"))
+	assert.NoError(t, err)
+	assert.Equal(t, first, rotator.f.Name())
+
+	for i := 0; i < 4; i++ {
+		_, err = rotator.Write([]byte("size strategy test\n"))
+		assert.NoError(t, err)
+	}
+	assert.NotEqual(t, first, rotator.f.Name())
+}
+
+func TestRotator_WithTimeRotate(t *testing.T) {
+	_, err := NewTimeStrategy("minute")
+	assert.Error(t, err)
+
+	rotator, err := newRotator(t.TempDir(), "testdata.log", WithTimeRotate(_Second))
+	assert.NoError(t, err)
+	defer rotator.Close()
+	assert.False(t, rotator.stg.ShouldRotate(DefaultMaxSize*2))
+
+	// 没有写入数据时跳过定时轮转
+	time.Sleep(1100 * time.Millisecond)
+	assert.Zero(t, rotator.Stats().TotalRotations)
+
+	_, err = rotator.Write([]byte("time strategy test\n"))
+	assert.NoError(t, err)
+	assert.Eventually(t, func() bool {
+		stats := rotator.Stats()
+		return stats.Rotations[RotateReasonScheduled]+stats.Rotations[RotateReasonRollover] > 0
+	}, 3*time.Second, 10*time.Millisecond)
+}
+
+func TestRotator_WithTimeRotateSynchronous(t *testing.T) {
+	rotator, err := newRotator(t.TempDir(), "testdata.log", WithTimeRotate(_Second), WithSynchronousMode())
+	assert.NoError(t, err)
+	defer rotator.Close()
+
+	_, err = rotator.Write([]byte("time strategy test\n"))
+	assert.NoError(t, err)
+	active := rotator.f.Name()
+	time.Sleep(1100 * time.Millisecond)
+	_, err = rotator.Write([]byte("time strategy test\n"))
+	assert.NoError(t, err)
+	assert.NotEqual(t, active, rotator.f.Name())
+}
+
+func TestRotator_WithRotateCron(t *testing.T) {
+	_, err := newRotator(t.TempDir(), "testdata.log", WithRotateCron("every 15 minutes"))
+	assert.Error(t, err)
+	_, err = newRotator(t.TempDir(), "testdata.log", WithSizeRotate(1024), WithRotateCron("0 */15 * * * *"))
+	assert.Error(t, err)
+
+	rotator, err := newRotator(t.TempDir(), "testdata.log", WithRotate(1024, Day), WithRotateCron("0 */15 * * * *"))
+	assert.NoError(t, err)
+	ms, ok := rotator.stg.(*MixStrategy)
+	assert.True(t, ok)
+	assert.Equal(t, uint64(1024), ms.maxSize)
+	now := time.Date(2025, 1, 1, 10, 5, 0, 0, time.Local)
+	assert.Equal(t, time.Date(2025, 1, 1, 10, 15, 0, 0, time.Local), ms.schedule.Next(now))
+	assert.NoError(t, rotator.Close())
+
+	// 与按时间轮转组合使用，每秒轮转一次
+	rotator, err = newRotator(t.TempDir(), "testdata.log",
+		WithTimeRotate(Day), WithRotateCron("*/1 * * * * *"), WithSynchronousMode())
+	assert.NoError(t, err)
+	defer rotator.Close()
+
+	_, err = rotator.Write([]byte("rotate cron test\n"))
+	assert.NoError(t, err)
+	active := rotator.f.Name()
+	time.Sleep(1100 * time.Millisecond)
+	_, err = rotator.Write([]byte("rotate cron test\n"))
+	assert.NoError(t, err)
+	assert.NotEqual(t, active, rotator.f.Name())
+
+	// 与默认的混合策略组合使用，文件远小于最大大小时也按照时间表轮转
+	mixed, err := newRotator(t.TempDir(), "testdata.log", WithRotateCron("*/1 * * * * *"))
+	assert.NoError(t, err)
+	defer mixed.Close()
+
+	_, err = mixed.Write([]byte("rotate cron test\n"))
+	assert.NoError(t, err)
+	active = mixed.f.Name()
+	assert.Eventually(t, func() bool {
+		mixed.writeLock.RLock()
+		defer mixed.writeLock.RUnlock()
+		return mixed.f.Name() != active
+	}, 3*time.Second, 50*time.Millisecond)
+
+	// 没有写入数据时不轮转
+	mixed.writeLock.Lock()
+	active = mixed.f.Name()
+	assert.NoError(t, mixed.scheduledRotate())
+	assert.Equal(t, active, mixed.f.Name())
+	mixed.writeLock.Unlock()
+}
@@ -15,69 +15,133 @@
 package vortexrotate
 
 import (
+	"bytes"
+	"compress/gzip"
 	"context"
+	"encoding/json"
+	"errors"
 	"fmt"
+	"io"
 	"math/rand"
+	"os"
+	"path/filepath"
+	"strings"
 	"sync"
 	"testing"
 	"time"
 
+	"github.com/TimeWtr/vortexrotate/errorx"
 	"github.com/stretchr/testify/assert"
 	"golang.org/x/sync/semaphore"
 )
 
-// initForTest 为测试程序创建多实例初始化，生产环境使用单例初始化
-func initForTest(compress bool) error {
-	var err error
+// initForTest 为测试程序创建轮转器实例
+func initForTest(compress bool) (*Rotator, error) {
 	if compress {
-		r, err = newRotator("./tests",
+		return NewRotator("./tests",
 			fmt.Sprintf("testdata_%d.log", rand.Intn(1000)),
 			WithCompress(CompressTypeGzip, GzipBestCompression),
 			WithRotate(1024*1024*10, _Second),
 			WithMaxCount(1024))
-	} else {
-		r, err = newRotator("./tests",
-			fmt.Sprintf("testdata_%d.log", rand.Intn(1000)),
-			WithCompress(CompressTypeGzip, GzipBestSpeed),
-			WithRotate(1024*1024*10, _Second))
 	}
 
-	return err
+	return NewRotator("./tests",
+		fmt.Sprintf("testdata_%d.log", rand.Intn(1000)),
+		WithCompress(CompressTypeGzip, GzipBestSpeed),
+		WithRotate(1024*1024*10, _Second))
 }
 
 func TestNewFile(t *testing.T) {
+	r, err := NewRotator("./tests", "testdata.log")
+	assert.Nil(t, err)
+	defer r.Close()
+
+	dir, err := filepath.Abs("./tests")
+	assert.Nil(t, err)
+
 	tf := time.Now().Format(Layout)
-	testCases := []struct {
-		name    string
-		wantRes string
-	}{
-		{
-			name:    "0002 count",
-			wantRes: fmt.Sprintf("./tests/%s/testdata_%s_0002.log", tf, tf),
-		},
-		{
-			name:    "0003 count",
-			wantRes: fmt.Sprintf("./tests/%s/testdata_%s_0003.log", tf, tf),
-		},
-		{
-			name:    "0004 count",
-			wantRes: fmt.Sprintf("./tests/%s/testdata_%s_0004.log", tf, tf),
-		},
+	start := r.seq.Load()
+	for i := uint32(0); i < 3; i++ {
+		t.Run(fmt.Sprintf("%04d count", start+i), func(t *testing.T) {
+			f, err := r.newFile()
+			assert.Nil(t, err)
+			wantRes := filepath.Join(dir, tf, fmt.Sprintf("testdata_%s_%04d.log", tf, start+i))
+			assert.Equal(t, wantRes, f)
+		})
 	}
+}
 
-	r, err := NewRotator("./tests", "testdata.log")
+func TestNewRotator_MultiInstance(t *testing.T) {
+	dir := t.TempDir()
+	access, err := NewRotator(dir, "access.log", WithRotate(64, _Second))
+	assert.Nil(t, err)
+	audit, err := NewRotator(dir, "audit.log", WithRotate(1024*1024, _Second))
 	assert.Nil(t, err)
+	assert.NotSame(t, access, audit)
 
-	for _, tc := range testCases {
-		t.Run(tc.name, func(t *testing.T) {
-			f := r.newFile()
-			assert.Equal(t, tc.wantRes, f)
-		})
+	for i := 0; i < 10; i++ {
+		_, err = access.Write([]byte(fmt.Sprintf("access %02d\n", i)))
+		assert.Nil(t, err)
+		_, err = audit.Write([]byte(fmt.Sprintf("audit %02d\n", i)))
+		assert.Nil(t, err)
+	}
+	assert.Nil(t, access.Close())
+	assert.Nil(t, audit.Close())
+
+	// 两个实例使用各自的策略，访问日志发生了轮转，审计日志没有
+	ro, err := OpenReadOnly(dir, "access.log")
+	assert.Nil(t, err)
+	segments, err := ro.List()
+	assert.Nil(t, err)
+	assert.Greater(t, len(segments), 1)
+
+	ro, err = OpenReadOnly(dir, "audit.log")
+	assert.Nil(t, err)
+	segments, err = ro.List()
+	assert.Nil(t, err)
+	assert.Len(t, segments, 1)
+}
+
+func TestNewSingletonRotator(t *testing.T) {
+	first, err := NewSingletonRotator("./tests", "testdata_singleton.log")
+	assert.Nil(t, err)
+	defer first.Close()
+
+	second, err := NewSingletonRotator("./tests", "testdata_other.log")
+	assert.Nil(t, err)
+	assert.Same(t, first, second)
+}
+
+func TestSequence_Persist(t *testing.T) {
+	dir := t.TempDir()
+	seq, err := newSequence(dir, "testdata")
+	assert.Nil(t, err)
+	assert.Equal(t, uint32(1), seq.Load())
+
+	for i := uint32(1); i <= 3; i++ {
+		n, err := seq.Next()
+		assert.Nil(t, err)
+		assert.Equal(t, i, n)
 	}
+
+	// 模拟进程重启，序列号从序列号文件中恢复
+	seq, err = newSequence(dir, "testdata")
+	assert.Nil(t, err)
+	assert.Equal(t, uint32(4), seq.Load())
+
+	// 序列号文件丢失，通过扫描目录修复
+	tf := time.Now().Format(Layout)
+	assert.Nil(t, os.MkdirAll(filepath.Join(dir, tf), os.ModePerm))
+	fn := filepath.Join(dir, tf, fmt.Sprintf("testdata_%s_0007.log", tf))
+	assert.Nil(t, os.WriteFile(fn, []byte("test"), ReadWriteFile))
+	assert.Nil(t, os.Remove(filepath.Join(dir, "testdata"+SeqFileExt)))
+	seq, err = newSequence(dir, "testdata")
+	assert.Nil(t, err)
+	assert.Equal(t, uint32(8), seq.Load())
 }
 
 func TestNewRotator_Compress(t *testing.T) {
-	err := initForTest(true)
+	r, err := initForTest(true)
 	assert.Nil(t, err)
 	defer r.Close()
 
@@ -90,7 +154,7 @@ func TestNewRotator_Compress(t *testing.T) {
 }
 
 func TestNewRotator_No_Compress(t *testing.T) {
-	err := initForTest(false)
+	r, err := initForTest(false)
 	assert.Nil(t, err)
 	defer r.Close()
 
@@ -103,7 +167,7 @@ func TestNewRotator_No_Compress(t *testing.T) {
 }
 
 func TestNewRotator_Concurrent(t *testing.T) {
-	err := initForTest(true)
+	r, err := initForTest(true)
 	assert.Nil(t, err)
 	defer r.Close()
 
@@ -130,3 +194,846 @@ func TestNewRotator_Concurrent(t *testing.T) {
 
 	wg.Wait()
 }
+
+func TestRotator_PauseSLO(t *testing.T) {
+	var events []Event
+	rotator, err := newRotator("./tests",
+		fmt.Sprintf("testdata_slo_%d.log", rand.Intn(1000)),
+		WithRotate(1024, _Second),
+		WithRotatePauseSLO(time.Nanosecond),
+		WithEventHandler(func(e Event) {
+			events = append(events, e)
+		}))
+	assert.Nil(t, err)
+	defer rotator.Close()
+
+	for i := 0; i < 100; i++ {
+		_, err = rotator.Write([]byte(fmt.Sprintf("pause slo test line %d\n", i)))
+		assert.Nil(t, err)
+	}
+
+	rotator.writeLock.Lock()
+	defer rotator.writeLock.Unlock()
+	assert.NotEmpty(t, events)
+	for _, e := range events {
+		assert.Equal(t, EventRotatePauseSLO, e.Type)
+		assert.True(t, e.Pause.Total > 0)
+	}
+}
+
+func TestRotator_Symlink(t *testing.T) {
+	dir := t.TempDir()
+	target := t.TempDir()
+	tf := time.Now().Format(Layout)
+	assert.Nil(t, os.Symlink(target, filepath.Join(dir, tf)))
+
+	_, err := newRotator(dir, "testdata.log")
+	assert.ErrorIs(t, err, errorx.ErrSymlink)
+}
+
+func TestRotator_WorldWritable(t *testing.T) {
+	dir := t.TempDir()
+	assert.Nil(t, os.Chmod(dir, 0o777))
+
+	_, err := newRotator(dir, "testdata.log")
+	assert.ErrorIs(t, err, errorx.ErrDirWorldWritable)
+
+	rotator, err := newRotator(dir, "testdata.log", WithAllowWorldWritable())
+	assert.Nil(t, err)
+	rotator.Close()
+
+	// 设置了粘滞位的共享目录(比如/tmp)允许使用
+	sticky := t.TempDir()
+	assert.Nil(t, os.Chmod(sticky, 0o777|os.ModeSticky))
+	rotator, err = newRotator(sticky, "testdata.log")
+	assert.Nil(t, err)
+	rotator.Close()
+}
+
+func TestMkdirNoFollow(t *testing.T) {
+	root := t.TempDir()
+	assert.Nil(t, mkdirNoFollow(root, filepath.Join(root, "20250101", "001")))
+	info, err := os.Lstat(filepath.Join(root, "20250101", "001"))
+	assert.Nil(t, err)
+	assert.True(t, info.IsDir())
+	assert.Nil(t, mkdirNoFollow(root, root))
+
+	// 中间一级目录是符号链接时不跟随
+	assert.Nil(t, os.Symlink(t.TempDir(), filepath.Join(root, "20250102")))
+	assert.ErrorIs(t, mkdirNoFollow(root, filepath.Join(root, "20250102", "001")), errorx.ErrSymlink)
+	assert.Error(t, mkdirNoFollow(root, filepath.Dir(root)))
+}
+
+func TestNormalizeDir(t *testing.T) {
+	home, err := os.UserHomeDir()
+	assert.Nil(t, err)
+	wd, err := os.Getwd()
+	assert.Nil(t, err)
+
+	testCases := []struct {
+		name    string
+		dir     string
+		wantRes string
+	}{
+		{
+			name:    "home",
+			dir:     "~",
+			wantRes: home,
+		},
+		{
+			name:    "home sub dir",
+			dir:     "~/logs/../app",
+			wantRes: filepath.Join(home, "app"),
+		},
+		{
+			name:    "relative",
+			dir:     "./tests/",
+			wantRes: filepath.Join(wd, "tests"),
+		},
+		{
+			name:    "absolute",
+			dir:     "/var//log/app/",
+			wantRes: "/var/log/app",
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			dir, err := normalizeDir(tc.dir)
+			assert.Nil(t, err)
+			assert.Equal(t, tc.wantRes, dir)
+		})
+	}
+}
+
+func TestRotator_MaxDirEntries(t *testing.T) {
+	dir := t.TempDir()
+	var events []Event
+	rotator, err := newRotator(dir, "testdata.log",
+		WithRotate(1024, _Second),
+		WithMaxDirEntries(3, DirOverflowSplit),
+		WithEventHandler(func(e Event) {
+			events = append(events, e)
+		}))
+	assert.Nil(t, err)
+	defer rotator.Close()
+
+	for i := 0; i < 200; i++ {
+		_, err = rotator.Write([]byte(fmt.Sprintf("max dir entries test line %d\n", i)))
+		assert.Nil(t, err)
+	}
+
+	rotator.writeLock.Lock()
+	defer rotator.writeLock.Unlock()
+	assert.NotEmpty(t, events)
+	assert.Equal(t, EventDirEntriesExceeded, events[0].Type)
+
+	tf := time.Now().Format(Layout)
+	entries, err := os.ReadDir(filepath.Join(dir, tf, "001"))
+	assert.Nil(t, err)
+	assert.LessOrEqual(t, len(entries), 3)
+}
+
+func TestRotator_MaxDirEntriesCleanup(t *testing.T) {
+	dir := t.TempDir()
+	rotator, err := newRotator(dir, "testdata.log",
+		WithRotate(1024, _Second),
+		WithMaxCount(2),
+		WithMaxDirEntries(3, DirOverflowCleanup))
+	assert.Nil(t, err)
+
+	for i := 0; i < 200; i++ {
+		_, err = rotator.Write([]byte(fmt.Sprintf("max dir entries cleanup test line %d\n", i)))
+		assert.Nil(t, err)
+	}
+
+	// 目录文件数量超限时触发调度器中的清理任务，而不是每次轮转启动一个清理goroutine
+	var runs uint64
+	for _, job := range rotator.Jobs() {
+		if job.Name == cleanJobName {
+			runs = job.Runs + job.Skipped
+		}
+	}
+	assert.Greater(t, runs, uint64(1))
+	assert.Nil(t, rotator.Close())
+
+	// 关闭之后不再触发清理
+	rotator.triggerCleanup()
+	for _, job := range rotator.Jobs() {
+		if job.Name == cleanJobName {
+			assert.Equal(t, runs, job.Runs+job.Skipped)
+		}
+	}
+}
+
+func TestRotator_DoneMarker(t *testing.T) {
+	testCases := []struct {
+		name string
+		mode DoneMarkerMode
+		want func(path string) string
+	}{
+		{
+			name: "marker file",
+			mode: DoneMarkerFile,
+			want: func(path string) string {
+				return path + DoneFileExt
+			},
+		},
+		{
+			name: "done dir",
+			mode: DoneMarkerDir,
+			want: func(path string) string {
+				return filepath.Join(filepath.Dir(path), DoneDirName, filepath.Base(path))
+			},
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			rotator, err := newRotator(t.TempDir(), "testdata.log",
+				WithCompress(CompressTypeGzip),
+				WithDoneMarker(tc.mode))
+			assert.Nil(t, err)
+			defer rotator.Close()
+
+			_, err = rotator.Write([]byte("done marker test\n"))
+			assert.Nil(t, err)
+
+			rotator.writeLock.Lock()
+			path := rotator.f.Name()
+			err = rotator.rotate(RotateReasonManual)
+			rotator.writeLock.Unlock()
+			assert.Nil(t, err)
+			assert.FileExists(t, tc.want(compressFn(path, CompressTypeGzip)))
+		})
+	}
+}
+
+func TestRotator_DoneMarkerKeepSource(t *testing.T) {
+	rotator, err := newRotator(t.TempDir(), "testdata.log",
+		WithCompress(CompressTypeGzip),
+		WithRemoveSource(false),
+		WithDoneMarker(DoneMarkerDir))
+	assert.Nil(t, err)
+	defer rotator.Close()
+
+	_, err = rotator.Write([]byte("done marker keep source test\n"))
+	assert.Nil(t, err)
+
+	rotator.writeLock.Lock()
+	path := rotator.f.Name()
+	err = rotator.rotate(RotateReasonManual)
+	rotator.writeLock.Unlock()
+	assert.Nil(t, err)
+
+	// 保留的源文件与压缩文件一起移动到完成目录
+	doneDir := filepath.Join(filepath.Dir(path), DoneDirName)
+	assert.NoFileExists(t, path)
+	assert.FileExists(t, filepath.Join(doneDir, filepath.Base(path)))
+	assert.FileExists(t, filepath.Join(doneDir, compressFn(filepath.Base(path), CompressTypeGzip)))
+}
+
+func TestRotator_AwaitSealed(t *testing.T) {
+	rotator, err := newRotator(t.TempDir(), "testdata.log", WithCompress(CompressTypeGzip))
+	assert.Nil(t, err)
+	defer rotator.Close()
+
+	_, err = rotator.Write([]byte("await sealed test\n"))
+	assert.Nil(t, err)
+
+	rotator.writeLock.RLock()
+	path := rotator.f.Name()
+	rotator.writeLock.RUnlock()
+
+	ctx, cancel := context.WithTimeout(context.Background(), time.Millisecond*10)
+	err = rotator.AwaitSealed(ctx, path)
+	cancel()
+	assert.ErrorIs(t, err, context.DeadlineExceeded)
+
+	errCh := make(chan error, 1)
+	go func() {
+		errCh <- rotator.AwaitSealed(context.Background(), path)
+	}()
+	time.Sleep(time.Millisecond * 10)
+
+	rotator.writeLock.Lock()
+	err = rotator.rotate(RotateReasonManual)
+	rotator.writeLock.Unlock()
+	assert.Nil(t, err)
+	assert.Nil(t, <-errCh)
+	assert.FileExists(t, compressFn(path, CompressTypeGzip))
+
+	// 已经封存完成的文件立即返回
+	assert.Nil(t, rotator.AwaitSealed(context.Background(), path))
+	err = rotator.AwaitSealed(context.Background(), path+".missing")
+	assert.ErrorIs(t, err, errorx.ErrSegmentNotFound)
+}
+
+func TestRotator_InitFailureCleanup(t *testing.T) {
+	dir := t.TempDir()
+	// 按照大小轮转的策略不支持cron表达式，在打开文件之后初始化失败
+	_, err := newRotator(dir, "testdata.log", WithAdvisoryLock(),
+		WithRotateStrategy(NewSizeStrategy(DefaultMaxSize)), WithRotateCron("0 */15 * * * *"))
+	assert.Error(t, err)
+
+	// 已经创建的空文件被删除，不会在下一次启动时作为遗留文件封存
+	matches, err := filepath.Glob(filepath.Join(dir, "*", "testdata_*.log"))
+	assert.Nil(t, err)
+	assert.Empty(t, matches)
+
+	rotator, err := newRotator(dir, "testdata.log", WithAdvisoryLock())
+	assert.Nil(t, err)
+	assert.Nil(t, rotator.Close())
+}
+
+func TestRotator_AwaitSealedPipeline(t *testing.T) {
+	// 开启上传时等到上传完成
+	up := blockingUploader{release: make(chan struct{})}
+	rotator, err := newRotator(t.TempDir(), "testdata.log",
+		WithCompress(CompressTypeGzip), WithDoneMarker(DoneMarkerFile), withTestUploader(up))
+	assert.Nil(t, err)
+	defer rotator.Close()
+
+	_, err = rotator.Write([]byte("await sealed upload test\n"))
+	assert.Nil(t, err)
+	path := rotator.f.Name()
+	assert.Nil(t, rotator.Rotate())
+	ctx, cancel := context.WithTimeout(context.Background(), time.Millisecond*50)
+	assert.ErrorIs(t, rotator.AwaitSealed(ctx, path), context.DeadlineExceeded)
+	cancel()
+	close(up.release)
+	assert.Nil(t, rotator.AwaitSealed(context.Background(), path))
+	assert.FileExists(t, compressFn(path, CompressTypeGzip)+DoneFileExt)
+
+	// 延迟压缩时等到压缩完成
+	rotator2, err := newRotator(t.TempDir(), "testdata.log",
+		WithCompress(CompressTypeGzip), WithCompressDelay(time.Hour))
+	assert.Nil(t, err)
+	defer rotator2.Close()
+
+	_, err = rotator2.Write([]byte("await sealed delay test\n"))
+	assert.Nil(t, err)
+	path = rotator2.f.Name()
+	assert.Nil(t, rotator2.Rotate())
+	ctx, cancel = context.WithTimeout(context.Background(), time.Millisecond*50)
+	assert.ErrorIs(t, rotator2.AwaitSealed(ctx, path), context.DeadlineExceeded)
+	cancel()
+	assert.NoFileExists(t, compressFn(path, CompressTypeGzip))
+	assert.False(t, rotator2.tracker.pending(path))
+
+	assert.Nil(t, rotator2.sealPending())
+	assert.Nil(t, rotator2.AwaitSealed(context.Background(), path))
+	assert.FileExists(t, compressFn(path, CompressTypeGzip))
+}
+
+func TestRotator_AwaitSealedEvicted(t *testing.T) {
+	rotator, err := newRotator(t.TempDir(), "testdata.log",
+		WithCompress(CompressTypeZstd), WithDoneMarker(DoneMarkerDir))
+	assert.Nil(t, err)
+	defer rotator.Close()
+
+	_, err = rotator.Write([]byte("await sealed evicted test\n"))
+	assert.Nil(t, err)
+
+	rotator.writeLock.Lock()
+	path := rotator.f.Name()
+	err = rotator.rotate(RotateReasonManual)
+	rotator.writeLock.Unlock()
+	assert.Nil(t, err)
+
+	// 封存记录被淘汰之后扫描目录，文件已经被压缩并移动到完成目录
+	rotator.tracker.lock.Lock()
+	delete(rotator.tracker.states, path)
+	rotator.tracker.lock.Unlock()
+	assert.Nil(t, rotator.AwaitSealed(context.Background(), path))
+}
+
+func TestRotator_RotateTrigger(t *testing.T) {
+	dir := t.TempDir()
+	rotator, err := newRotator(dir, "testdata.log", WithRotateTrigger())
+	assert.Nil(t, err)
+	defer rotator.Close()
+
+	rotator.writeLock.RLock()
+	path := rotator.f.Name()
+	rotator.writeLock.RUnlock()
+
+	trigger := filepath.Join(dir, TriggerFileName)
+	assert.Nil(t, os.WriteFile(trigger, nil, ReadWriteFile))
+	assert.Eventually(t, func() bool {
+		rotator.writeLock.RLock()
+		defer rotator.writeLock.RUnlock()
+		return rotator.f.Name() != path
+	}, TriggerCheckInterval*3, time.Millisecond*50)
+	assert.NoFileExists(t, trigger)
+}
+
+func TestRotator_Rotate(t *testing.T) {
+	rotator, err := newRotator(t.TempDir(), "testdata.log", WithCompress(CompressTypeSnappy))
+	assert.Nil(t, err)
+
+	_, err = rotator.Write([]byte("manual rotate test\n"))
+	assert.Nil(t, err)
+
+	rotator.writeLock.RLock()
+	path := rotator.f.Name()
+	rotator.writeLock.RUnlock()
+
+	assert.Nil(t, rotator.Rotate())
+	assert.FileExists(t, compressFn(path, CompressTypeSnappy))
+	assert.Equal(t, uint64(0), rotator.stg.(*MixStrategy).size)
+
+	rotator.Close()
+	assert.ErrorIs(t, rotator.Rotate(), errorx.ErrRotateClosed)
+}
+
+func TestRotator_CompressXz(t *testing.T) {
+	rotator, err := newRotator(t.TempDir(), "testdata.log", WithCompress(CompressTypeXz))
+	assert.Nil(t, err)
+	defer rotator.Close()
+	assert.Equal(t, XzDefaultCompression, rotator.cpr.cs.(*Xz).l)
+
+	_, err = rotator.Write([]byte("xz rotate test\n"))
+	assert.Nil(t, err)
+	path := rotator.f.Name()
+	assert.Nil(t, rotator.Rotate())
+	assert.FileExists(t, compressFn(path, CompressTypeXz))
+
+	_, err = newRotator(t.TempDir(), "testdata.log", WithCompress(CompressTypeXz, 10))
+	assert.Error(t, err)
+}
+
+func TestRotator_CompressS2(t *testing.T) {
+	dir := t.TempDir()
+	rotator, err := newRotator(dir, "testdata.log", WithCompressOptions(CompressTypeS2,
+		CompressOptions{S2: S2Options{Level: S2BetterCompression, Concurrency: 2}}))
+	assert.Nil(t, err)
+	defer rotator.Close()
+	assert.Equal(t, 2, rotator.cpr.cs.(*S2).concurrency)
+
+	content := strings.Repeat("s2 rotate test\n", 1024)
+	_, err = rotator.Write([]byte(content))
+	assert.Nil(t, err)
+	path := rotator.f.Name()
+	assert.Nil(t, rotator.Rotate())
+	assert.FileExists(t, compressFn(path, CompressTypeS2))
+
+	ro, err := OpenReadOnly(dir, "testdata.log")
+	assert.Nil(t, err)
+	segments, err := ro.List()
+	assert.Nil(t, err)
+	assert.Equal(t, CompressTypeS2, segments[0].CompressType)
+	rc, err := ro.Open(segments[0])
+	assert.Nil(t, err)
+	bs, err := io.ReadAll(rc)
+	assert.Nil(t, err)
+	assert.Nil(t, rc.Close())
+	assert.Equal(t, content, string(bs))
+
+	_, err = newRotator(t.TempDir(), "testdata.log", WithCompress(CompressTypeS2, 3))
+	assert.Error(t, err)
+}
+
+func TestRotator_ParallelGzip(t *testing.T) {
+	rotator, err := newRotator(t.TempDir(), "testdata.log",
+		WithParallelGzip(2), WithCompress(CompressTypeGzip, GzipBestSpeed))
+	assert.Nil(t, err)
+	defer rotator.Close()
+	assert.IsType(t, &Pgzip{}, rotator.cpr.cs)
+	assert.Equal(t, int64(bufferSize+gzipMemoryEstimate+2*2*PgzipBlockSize), rotator.cpr.memory())
+
+	_, err = rotator.Write([]byte("pgzip rotate test\n"))
+	assert.Nil(t, err)
+	path := rotator.f.Name()
+	assert.Nil(t, rotator.Rotate())
+	assert.FileExists(t, compressFn(path, CompressTypeGzip))
+
+	// 选项的顺序无关
+	rotator2, err := newRotator(t.TempDir(), "testdata.log",
+		WithCompress(CompressTypeGzip), WithParallelGzip(0))
+	assert.Nil(t, err)
+	defer rotator2.Close()
+	assert.IsType(t, &Pgzip{}, rotator2.cpr.cs)
+}
+
+func TestRotator_CompressOptions(t *testing.T) {
+	testCases := []struct {
+		name  string
+		tp    int
+		opts  CompressOptions
+		check func(t *testing.T, cs CompressStrategy)
+	}{
+		{
+			name: "zstd",
+			tp:   CompressTypeZstd,
+			opts: CompressOptions{Zstd: ZstdOptions{Level: 9, WindowLog: 20}},
+			check: func(t *testing.T, cs CompressStrategy) {
+				assert.Equal(t, 9, cs.(*Zstd).l)
+				assert.Equal(t, 20, cs.(*Zstd).windowLog)
+			},
+		},
+		{
+			name: "gzip",
+			tp:   CompressTypeGzip,
+			opts: CompressOptions{Gzip: GzipOptions{Level: GzipBestSpeed, Workers: 2}},
+			check: func(t *testing.T, cs CompressStrategy) {
+				assert.IsType(t, &Pgzip{}, cs)
+			},
+		},
+		{
+			name: "xz",
+			tp:   CompressTypeXz,
+			opts: CompressOptions{Xz: XzOptions{DictCap: 1 << 20}},
+			check: func(t *testing.T, cs CompressStrategy) {
+				assert.Equal(t, XzDefaultCompression, cs.(*Xz).l)
+				assert.Equal(t, 1<<20, cs.(*Xz).dictCap)
+			},
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			dir := t.TempDir()
+			rotator, err := newRotator(dir, "testdata.log", WithCompressOptions(tc.tp, tc.opts))
+			assert.Nil(t, err)
+			defer rotator.Close()
+			tc.check(t, rotator.cpr.cs)
+
+			_, err = rotator.Write([]byte("compress options test\n"))
+			assert.Nil(t, err)
+			assert.Nil(t, rotator.Rotate())
+			ro, err := OpenReadOnly(dir, "testdata.log")
+			assert.Nil(t, err)
+			results, err := ro.Verify(context.Background())
+			assert.Nil(t, err)
+			assert.Len(t, results, 1)
+			assert.Nil(t, results[0].Err)
+		})
+	}
+
+	// WithCompress设置的zstd压缩等级生效
+	rotator, err := newRotator(t.TempDir(), "testdata.log", WithCompress(CompressTypeZstd, 19))
+	assert.Nil(t, err)
+	assert.Equal(t, 19, rotator.cpr.cs.(*Zstd).l)
+	assert.Nil(t, rotator.Close())
+
+	_, err = newRotator(t.TempDir(), "testdata.log", WithCompress(CompressTypeZstd, ZstdMaxLevel+1))
+	assert.Error(t, err)
+	_, err = newRotator(t.TempDir(), "testdata.log",
+		WithCompressOptions(CompressTypeZstd, CompressOptions{Zstd: ZstdOptions{WindowLog: ZstdMaxWindowLog + 1}}))
+	assert.Error(t, err)
+}
+
+func TestRotator_CompressMinSize(t *testing.T) {
+	rotator, err := newRotator(t.TempDir(), "testdata.log",
+		WithCompress(CompressTypeGzip), WithCompressMinSize(1024))
+	assert.Nil(t, err)
+	defer rotator.Close()
+
+	// 小文件不压缩，直接封存原始文件
+	_, err = rotator.Write([]byte("small\n"))
+	assert.Nil(t, err)
+	small := rotator.f.Name()
+	assert.Nil(t, rotator.Rotate())
+	assert.FileExists(t, small)
+	assert.NoFileExists(t, compressFn(small, CompressTypeGzip))
+
+	_, err = rotator.Write(bytes.Repeat([]byte("large line\n"), 100))
+	assert.Nil(t, err)
+	large := rotator.f.Name()
+	assert.Nil(t, rotator.Rotate())
+	assert.NoFileExists(t, large)
+	assert.FileExists(t, compressFn(large, CompressTypeGzip))
+
+	_, err = newRotator(t.TempDir(), "testdata.log", WithCompressMinSize(-1))
+	assert.Error(t, err)
+}
+
+func TestRotator_RemoveSource(t *testing.T) {
+	for _, keep := range []bool{false, true} {
+		opts := []Option{WithCompress(CompressTypeGzip)}
+		if keep {
+			opts = append(opts, WithRemoveSource(false))
+		}
+		rotator, err := newRotator(t.TempDir(), "testdata.log", opts...)
+		assert.Nil(t, err)
+
+		_, err = rotator.Write([]byte("remove source test\n"))
+		assert.Nil(t, err)
+		path := rotator.f.Name()
+		assert.Nil(t, rotator.Rotate())
+		assert.FileExists(t, compressFn(path, CompressTypeGzip))
+		if keep {
+			assert.FileExists(t, path)
+		} else {
+			assert.NoFileExists(t, path)
+		}
+		assert.Nil(t, rotator.Close())
+	}
+}
+
+// partialStrategy 写入部分数据之后失败的压缩策略
+type partialStrategy struct {
+	w io.Writer
+	f *os.File
+}
+
+func (p *partialStrategy) Compress() error {
+	_ = p.f.Close()
+	_, _ = p.w.Write([]byte("partial"))
+	return errors.New("compress interrupted")
+}
+
+func (p *partialStrategy) Reset(w io.Writer, f *os.File) {
+	p.w, p.f = w, f
+}
+
+func TestRotator_CompressTmp(t *testing.T) {
+	dir := t.TempDir()
+	date := time.Now().Format(Layout)
+	// 上一次进程在压缩过程中退出残留的临时文件
+	stale := filepath.Join(dir, date, fmt.Sprintf("testdata_%s_0001.log.gz%s", date, TmpFileExt))
+	assert.Nil(t, os.MkdirAll(filepath.Dir(stale), os.ModePerm))
+	assert.Nil(t, os.WriteFile(stale, []byte("truncated"), ReadWriteFile))
+
+	rotator, err := newRotator(dir, "testdata.log", WithCompress(CompressTypeGzip))
+	assert.Nil(t, err)
+	defer rotator.Close()
+	assert.NoFileExists(t, stale)
+
+	_, err = rotator.Write([]byte("compress tmp test\n"))
+	assert.Nil(t, err)
+	path := rotator.f.Name()
+	assert.Error(t, rotator.seal(path, &partialStrategy{}, &RotatePause{}))
+	assert.NoFileExists(t, compressFn(path, CompressTypeGzip))
+	assert.NoFileExists(t, compressFn(path, CompressTypeGzip)+TmpFileExt)
+	assert.FileExists(t, path)
+
+	assert.Nil(t, rotator.Rotate())
+	assert.FileExists(t, compressFn(path, CompressTypeGzip))
+	assert.NoFileExists(t, compressFn(path, CompressTypeGzip)+TmpFileExt)
+}
+
+// corruptStrategy 输出格式正确但是内容与源文件不一致的压缩策略
+type corruptStrategy struct {
+	w io.Writer
+	f *os.File
+}
+
+func (c *corruptStrategy) Compress() error {
+	_ = c.f.Close()
+	gw := gzip.NewWriter(c.w)
+	_, _ = gw.Write([]byte("corrupt"))
+	return gw.Close()
+}
+
+func (c *corruptStrategy) Reset(w io.Writer, f *os.File) {
+	c.w, c.f = w, f
+}
+
+func TestRotator_VerifyBeforeRemove(t *testing.T) {
+	var events []Event
+	rotator, err := newRotator(t.TempDir(), "testdata.log", WithCompress(CompressTypeGzip),
+		WithEventHandler(func(e Event) {
+			events = append(events, e)
+		}))
+	assert.Nil(t, err)
+	defer rotator.Close()
+
+	_, err = rotator.Write([]byte("verify test\n"))
+	assert.Nil(t, err)
+	path := rotator.f.Name()
+	err = rotator.seal(path, &corruptStrategy{}, &RotatePause{})
+	assert.ErrorIs(t, err, errorx.ErrArchiveMismatch)
+	// 校验失败时隔离压缩文件，保留源文件
+	assert.FileExists(t, path)
+	assert.NoFileExists(t, compressFn(path, CompressTypeGzip))
+	assert.Len(t, events, 2)
+	assert.Equal(t, EventQuarantine, events[0].Type)
+	assert.Equal(t, filepath.Join(rotator.dir, QuarantineDirName, filepath.Base(compressFn(path, CompressTypeGzip))),
+		events[0].Path)
+	assert.FileExists(t, events[0].Path)
+	assert.Equal(t, EventVerifyFailed, events[1].Type)
+	assert.ErrorIs(t, events[1].Err, errorx.ErrArchiveMismatch)
+
+	assert.Nil(t, rotator.Rotate())
+	assert.NoFileExists(t, path)
+	assert.FileExists(t, compressFn(path, CompressTypeGzip))
+}
+
+func TestRotator_DailySummary(t *testing.T) {
+	dir := t.TempDir()
+	rotator, err := newRotator(dir, "testdata.log",
+		WithCompress(CompressTypeGzip),
+		WithDailySummary(func(p []byte) string {
+			if bytes.Contains(p, []byte("ERROR")) {
+				return "error"
+			}
+			return ""
+		}))
+	assert.Nil(t, err)
+	defer rotator.Close()
+
+	_, err = rotator.Write([]byte("INFO line 1\nINFO line 2\n"))
+	assert.Nil(t, err)
+	_, err = rotator.Write([]byte("ERROR line 3\n"))
+	assert.Nil(t, err)
+	assert.Nil(t, rotator.Rotate())
+
+	// 模拟跨天
+	rotator.writeLock.Lock()
+	date := rotator.summary.cur.Date
+	rotator.rollover(time.Now().AddDate(0, 0, 1))
+	rotator.writeLock.Unlock()
+
+	bs, err := os.ReadFile(filepath.Join(dir, date+SummaryFileExt))
+	assert.Nil(t, err)
+	var summary DailySummary
+	assert.Nil(t, json.Unmarshal(bs, &summary))
+	assert.Equal(t, date, summary.Date)
+	assert.Equal(t, int64(37), summary.Bytes)
+	assert.Equal(t, int64(3), summary.Lines)
+	assert.Equal(t, int64(2), summary.Segments)
+	assert.Equal(t, int64(37), summary.RawBytes)
+	assert.Equal(t, int64(1), summary.Classes["error"])
+}
+
+func TestRotator_DailySummaryClose(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, time.Now().Format(Layout)+SummaryFileExt)
+	for i := 1; i <= 2; i++ {
+		rotator, err := newRotator(dir, "testdata.log", WithDailySummary(nil))
+		assert.Nil(t, err)
+		_, err = rotator.Write([]byte("summary close test\n"))
+		assert.Nil(t, err)
+		assert.Nil(t, rotator.Close())
+
+		// 关闭时生成当天的汇总文件，同一天重启之后继续累加
+		bs, err := os.ReadFile(path)
+		assert.Nil(t, err)
+		var summary DailySummary
+		assert.Nil(t, json.Unmarshal(bs, &summary))
+		assert.Equal(t, int64(19*i), summary.Bytes)
+		assert.Equal(t, int64(i), summary.Lines)
+	}
+}
+
+func TestRotator_MaxLines(t *testing.T) {
+	dir := t.TempDir()
+	rotator, err := newRotator(dir, "testdata.log", WithMaxLines(10))
+	assert.Nil(t, err)
+
+	for i := 0; i < 25; i++ {
+		_, err = rotator.Write([]byte(fmt.Sprintf("max lines test %d\n", i)))
+		assert.Nil(t, err)
+	}
+	rotator.Close()
+
+	ro, err := OpenReadOnly(dir, "testdata.log")
+	assert.Nil(t, err)
+	segments, err := ro.List()
+	assert.Nil(t, err)
+	assert.Len(t, segments, 3)
+
+	for i, want := range []int{10, 10, 5} {
+		rc, err := ro.Open(segments[i])
+		assert.Nil(t, err)
+		content, err := io.ReadAll(rc)
+		assert.Nil(t, err)
+		assert.Nil(t, rc.Close())
+		assert.Equal(t, want, bytes.Count(content, []byte{'\n'}))
+	}
+}
+
+func TestRotator_Close(t *testing.T) {
+	rotator, err := newRotator(t.TempDir(), "testdata.log", WithRotate(1024, _Second))
+	assert.Nil(t, err)
+
+	var wg sync.WaitGroup
+	for i := 0; i < 10; i++ {
+		wg.Add(1)
+		go func(idx int) {
+			defer wg.Done()
+			for j := 0; j < 100; j++ {
+				_, localErr := rotator.Write([]byte(fmt.Sprintf("close test %d-%d\n", idx, j)))
+				if localErr != nil {
+					assert.ErrorIs(t, localErr, errorx.ErrRotateClosed)
+					return
+				}
+			}
+		}(i)
+	}
+
+	for i := 0; i < 3; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			assert.Nil(t, rotator.Close())
+		}()
+	}
+	wg.Wait()
+
+	assert.Nil(t, rotator.Close())
+	_, err = rotator.Write([]byte("after close\n"))
+	assert.ErrorIs(t, err, errorx.ErrRotateClosed)
+}
+
+func TestRotator_SyncPolicy(t *testing.T) {
+	testCases := []struct {
+		name   string
+		policy SyncPolicy
+	}{
+		{name: "never", policy: SyncNever},
+		{name: "every write", policy: SyncEveryWrite},
+		{name: "interval", policy: SyncInterval},
+		{name: "on rotate", policy: SyncOnRotate},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			rotator, err := newRotator(t.TempDir(), "testdata.log",
+				WithSyncPolicy(tc.policy, time.Millisecond*10))
+			assert.Nil(t, err)
+
+			_, err = rotator.Write([]byte("sync policy test\n"))
+			assert.Nil(t, err)
+			assert.Nil(t, rotator.Sync())
+			assert.Nil(t, rotator.Rotate())
+			time.Sleep(time.Millisecond * 20)
+
+			assert.Nil(t, rotator.Close())
+			assert.ErrorIs(t, rotator.Sync(), errorx.ErrRotateClosed)
+		})
+	}
+
+	_, err := newRotator(t.TempDir(), "testdata.log", WithSyncPolicy(SyncPolicy(100)))
+	assert.NotNil(t, err)
+}
+
+func TestRotator_SyncOnRotateError(t *testing.T) {
+	rotator, err := newRotator(t.TempDir(), "testdata.log",
+		WithSyncPolicy(SyncOnRotate), WithCompress(CompressTypeGzip))
+	assert.Nil(t, err)
+	defer rotator.Close()
+
+	_, err = rotator.Write([]byte("sync on rotate error test\n"))
+	assert.Nil(t, err)
+
+	// 替换为已经关闭的文件模拟fsync失败
+	rotator.writeLock.Lock()
+	f := rotator.f
+	broken, err := os.Open(f.Name())
+	assert.Nil(t, err)
+	assert.Nil(t, broken.Close())
+	rotator.f = broken
+	err = rotator.rotate(RotateReasonManual)
+	rotator.f = f
+	rotator.writeLock.Unlock()
+
+	// fsync失败时返回错误，不封存当前文件
+	assert.ErrorIs(t, err, os.ErrClosed)
+	assert.FileExists(t, f.Name())
+	assert.NoFileExists(t, compressFn(f.Name(), CompressTypeGzip))
+	_, err = rotator.Write([]byte("sync on rotate error test\n"))
+	assert.Nil(t, err)
+}
@@ -0,0 +1,374 @@
+// Copyright 2025 TimeWtr
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package vortexrotate
+
+import (
+	"fmt"
+	"math/rand/v2"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/robfig/cron/v3"
+)
+
+// cronParser 解析带秒的cron表达式，与定时任务使用的格式一致
+var cronParser = cron.NewParser(cron.Second | cron.Minute | cron.Hour | cron.Dom | cron.Month | cron.Dow)
+
+// JobStats 后台定时任务的执行统计
+type JobStats struct {
+	// 任务名称
+	Name string
+	// 执行的次数
+	Runs uint64
+	// 上一次执行还没有结束而跳过的次数
+	Skipped uint64
+	// 是否正在执行
+	Running bool
+	// 最近一次开始执行的时间
+	LastRun time.Time
+	// 最近一次执行的耗时
+	LastDuration time.Duration
+	// 下一次执行的时间
+	NextRun time.Time
+}
+
+// intervalSchedule 按照固定间隔执行的时间表
+type intervalSchedule time.Duration
+
+func (s intervalSchedule) Next(t time.Time) time.Time {
+	return t.Add(time.Duration(s))
+}
+
+// schedJob 调度器中的一个命名任务
+type schedJob struct {
+	name   string
+	sched  cron.Schedule
+	jitter time.Duration
+	fn     func()
+	// 以下字段由调度器的锁保护
+	next         time.Time
+	running      bool
+	runs         uint64
+	skipped      uint64
+	lastRun      time.Time
+	lastDuration time.Duration
+	// 等待正在执行的任务结束
+	wg sync.WaitGroup
+}
+
+// scheduler 内部的定时任务调度器，定时轮转、清理、fsync、延迟压缩等周期性任务都作为命名任务注册
+// 到同一个调度器中，由一个goroutine按照下一次执行的时间统一调度，每次执行在独立的goroutine中
+// 运行，上一次执行还没有结束时跳过本次执行，不会堆积。任务可以设置随机抖动，避免同一时刻启动的
+// 多个轮转器同时执行清理等IO密集的任务。关闭时停止调度并等待正在执行的任务结束。
+type scheduler struct {
+	lock sync.Mutex
+	jobs map[string]*schedJob
+	// 任务变化时唤醒调度goroutine重新计算等待时间
+	wake chan struct{}
+	// 关闭信号
+	stopC chan struct{}
+	// 调度goroutine和所有任务都已经退出
+	done chan struct{}
+	// 正在执行的任务
+	running sync.WaitGroup
+	// 正在执行的任务数量，由锁保护
+	active   int
+	started  bool
+	stopped  bool
+	stopOnce sync.Once
+}
+
+func newScheduler() *scheduler {
+	return &scheduler{
+		jobs:  make(map[string]*schedJob),
+		wake:  make(chan struct{}, 1),
+		stopC: make(chan struct{}),
+		done:  make(chan struct{}),
+	}
+}
+
+// every 注册按照固定间隔执行的任务
+func (s *scheduler) every(name string, interval, jitter time.Duration, fn func()) error {
+	if interval <= 0 {
+		return fmt.Errorf("job %s interval %s must be positive", name, interval)
+	}
+
+	return s.add(name, intervalSchedule(interval), jitter, fn)
+}
+
+// parseCron 解析带秒的cron表达式，表达式永远不会触发(比如"0 0 0 30 2 *"，2月30日)时返回错误
+func parseCron(spec string) (cron.Schedule, error) {
+	sched, err := cronParser.Parse(spec)
+	if err != nil {
+		return nil, err
+	}
+	if sched.Next(time.Now()).IsZero() {
+		return nil, fmt.Errorf("cron %q never fires", spec)
+	}
+
+	return sched, nil
+}
+
+// cron 注册按照cron表达式(带秒)执行的任务
+func (s *scheduler) cron(name, spec string, jitter time.Duration, fn func()) error {
+	sched, err := parseCron(spec)
+	if err != nil {
+		return err
+	}
+
+	return s.add(name, sched, jitter, fn)
+}
+
+// add 注册任务，同名的任务已经存在或者任务永远不会执行时返回错误
+func (s *scheduler) add(name string, sched cron.Schedule, jitter time.Duration, fn func()) error {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	if _, ok := s.jobs[name]; ok {
+		return fmt.Errorf("job %s already exists", name)
+	}
+
+	job := &schedJob{
+		name:   name,
+		sched:  sched,
+		jitter: jitter,
+		fn:     fn,
+	}
+	job.next = job.nextAfter(time.Now())
+	if job.next.IsZero() {
+		return fmt.Errorf("job %s never fires", name)
+	}
+	s.jobs[name] = job
+	s.notify()
+
+	return nil
+}
+
+// reschedule 修改任务的执行间隔，从当前时间开始重新计算下一次执行的时间
+func (s *scheduler) reschedule(name string, interval time.Duration) {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	job, ok := s.jobs[name]
+	if !ok || interval <= 0 {
+		return
+	}
+
+	job.sched = intervalSchedule(interval)
+	job.next = job.nextAfter(time.Now())
+	s.notify()
+}
+
+// trigger 不等待调度立即执行一次任务，不影响下一次调度的时间，任务正在执行时跳过
+func (s *scheduler) trigger(name string) {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	job, ok := s.jobs[name]
+	switch {
+	case !ok || s.stopped:
+	case job.running:
+		job.skipped++
+	default:
+		s.run(job, time.Now())
+	}
+}
+
+// remove 删除任务，等待正在执行的任务结束之后返回
+func (s *scheduler) remove(name string) {
+	s.lock.Lock()
+	job, ok := s.jobs[name]
+	delete(s.jobs, name)
+	s.lock.Unlock()
+
+	if ok {
+		job.wg.Wait()
+	}
+}
+
+// start 启动调度goroutine，可以重复调用
+func (s *scheduler) start() {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	if s.started || s.stopped {
+		return
+	}
+
+	s.started = true
+	go s.loop()
+}
+
+// stop 停止调度，返回的通道在正在执行的任务全部结束之后关闭，可以重复调用
+func (s *scheduler) stop() <-chan struct{} {
+	s.stopOnce.Do(func() {
+		s.lock.Lock()
+		started := s.started
+		s.stopped = true
+		s.lock.Unlock()
+
+		close(s.stopC)
+		if started {
+			return
+		}
+
+		// 没有启动调度时仍然可能有立即执行的任务，没有任务时不启动goroutine
+		s.lock.Lock()
+		idle := s.active == 0
+		s.lock.Unlock()
+		if idle {
+			close(s.done)
+			return
+		}
+		go func() {
+			s.running.Wait()
+			close(s.done)
+		}()
+	})
+
+	return s.done
+}
+
+// stats 所有任务的执行统计，按照任务名称排序
+func (s *scheduler) stats() []JobStats {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	res := make([]JobStats, 0, len(s.jobs))
+	for _, job := range s.jobs {
+		res = append(res, JobStats{
+			Name:         job.name,
+			Runs:         job.runs,
+			Skipped:      job.skipped,
+			Running:      job.running,
+			LastRun:      job.lastRun,
+			LastDuration: job.lastDuration,
+			NextRun:      job.next,
+		})
+	}
+	sort.Slice(res, func(i, j int) bool {
+		return res[i].Name < res[j].Name
+	})
+
+	return res
+}
+
+// notify 唤醒调度goroutine，必须持有锁
+func (s *scheduler) notify() {
+	select {
+	case s.wake <- struct{}{}:
+	default:
+	}
+}
+
+// loop 等待到最早的任务执行时间，执行所有到期的任务
+func (s *scheduler) loop() {
+	defer func() {
+		s.running.Wait()
+		close(s.done)
+	}()
+
+	timer := time.NewTimer(time.Hour)
+	defer timer.Stop()
+	for {
+		timer.Reset(s.runDue(time.Now()))
+		select {
+		case <-s.stopC:
+			return
+		case <-s.wake:
+		case <-timer.C:
+		}
+	}
+}
+
+// runDue 执行所有到期的任务，返回距离下一个任务执行的时间
+func (s *scheduler) runDue(now time.Time) time.Duration {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	wait := time.Hour
+	for _, job := range s.jobs {
+		if !job.next.After(now) {
+			job.next = job.nextAfter(now)
+			if job.running {
+				job.skipped++
+			} else {
+				s.run(job, now)
+			}
+			if job.next.IsZero() {
+				// 之后不会再执行，移除任务，避免一直被当作到期任务
+				delete(s.jobs, job.name)
+				continue
+			}
+		}
+		wait = min(wait, job.next.Sub(now))
+	}
+
+	return max(wait, 0)
+}
+
+// run 在独立的goroutine中执行任务，必须持有锁
+func (s *scheduler) run(job *schedJob, now time.Time) {
+	job.running = true
+	s.active++
+	job.runs++
+	job.lastRun = now
+	job.wg.Add(1)
+	s.running.Add(1)
+	go func() {
+		defer s.running.Done()
+		defer job.wg.Done()
+
+		begin := time.Now()
+		job.fn()
+
+		s.lock.Lock()
+		job.running = false
+		s.active--
+		job.lastDuration = time.Since(begin)
+		s.lock.Unlock()
+	}()
+}
+
+// nextAfter 时间t之后的下一次执行时间，加上随机抖动，之后不会再执行时返回零值
+func (j *schedJob) nextAfter(t time.Time) time.Time {
+	next := j.sched.Next(t)
+	if next.IsZero() {
+		return next
+	}
+	if j.jitter > 0 {
+		next = next.Add(rand.N(j.jitter))
+	}
+
+	return next
+}
+
+// WithJobJitter 为清理、二次压缩、延迟压缩等后台定时任务的每次执行增加[0, jitter)的随机延迟，
+// 避免同一时刻启动的大量轮转器(比如同一台机器上的多个进程)在同一时间集中执行IO密集的任务
+func WithJobJitter(jitter time.Duration) Option {
+	return func(r *Rotator) error {
+		if jitter < 0 {
+			return fmt.Errorf("job jitter %s must not be negative", jitter)
+		}
+
+		r.jobJitter = jitter
+		return nil
+	}
+}
+
+// Jobs 返回所有后台定时任务的执行统计，按照任务名称排序
+func (r *Rotator) Jobs() []JobStats {
+	return r.sched.stats()
+}
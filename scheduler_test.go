@@ -0,0 +1,198 @@
+// Copyright 2025 TimeWtr
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package vortexrotate
+
+import (
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestScheduler_Every(t *testing.T) {
+	s := newScheduler()
+	var runs atomic.Int64
+	assert.NoError(t, s.every("tick", time.Millisecond*10, 0, func() {
+		runs.Add(1)
+	}))
+	assert.Error(t, s.every("tick", time.Millisecond*10, 0, func() {}))
+	assert.Error(t, s.every("zero", 0, 0, func() {}))
+	assert.Error(t, s.cron("bad", "not a cron", 0, func() {}))
+
+	s.start()
+	assert.Eventually(t, func() bool {
+		return runs.Load() >= 3
+	}, time.Second, time.Millisecond*10)
+	<-s.stop()
+
+	stopped := runs.Load()
+	time.Sleep(time.Millisecond * 50)
+	assert.Equal(t, stopped, runs.Load())
+}
+
+// onceSchedule 只在at执行一次的时间表
+type onceSchedule struct {
+	at time.Time
+}
+
+func (s onceSchedule) Next(t time.Time) time.Time {
+	if t.Before(s.at) {
+		return s.at
+	}
+	return time.Time{}
+}
+
+func TestScheduler_NeverFires(t *testing.T) {
+	s := newScheduler()
+	// 2月30日永远不会触发
+	assert.Error(t, s.cron("never", "0 0 0 30 2 *", 0, func() {}))
+	assert.Error(t, s.add("past", onceSchedule{at: time.Now().Add(-time.Hour)}, time.Second, func() {}))
+	_, err := NewRotator(t.TempDir(), "testdata.log", WithRotateCron("0 0 0 30 2 *"))
+	assert.Error(t, err)
+
+	// 执行之后不会再触发的任务从调度器中移除
+	var runs atomic.Int64
+	assert.NoError(t, s.add("once", onceSchedule{at: time.Now().Add(time.Millisecond * 10)}, 0, func() {
+		runs.Add(1)
+	}))
+	s.start()
+	defer s.stop()
+	assert.Eventually(t, func() bool {
+		return len(s.stats()) == 0
+	}, time.Second, time.Millisecond*5)
+	time.Sleep(time.Millisecond * 20)
+	assert.Equal(t, int64(1), runs.Load())
+}
+
+func TestScheduler_SkipRunning(t *testing.T) {
+	s := newScheduler()
+	release := make(chan struct{})
+	var runs atomic.Int64
+	assert.NoError(t, s.every("slow", time.Millisecond*5, 0, func() {
+		runs.Add(1)
+		<-release
+	}))
+	s.start()
+
+	assert.Eventually(t, func() bool {
+		stats := s.stats()
+		return len(stats) == 1 && stats[0].Running && stats[0].Skipped > 0
+	}, time.Second, time.Millisecond*5)
+	assert.Equal(t, int64(1), runs.Load())
+
+	// 停止时等待正在执行的任务结束
+	done := s.stop()
+	select {
+	case <-done:
+		t.Fatal("stop should wait for running job")
+	case <-time.After(time.Millisecond * 20):
+	}
+	close(release)
+	<-done
+
+	stats := s.stats()
+	assert.False(t, stats[0].Running)
+	assert.Equal(t, uint64(1), stats[0].Runs)
+	assert.False(t, stats[0].LastRun.IsZero())
+}
+
+func TestScheduler_TriggerRemove(t *testing.T) {
+	s := newScheduler()
+	var runs atomic.Int64
+	assert.NoError(t, s.every("hourly", time.Hour, 0, func() {
+		time.Sleep(time.Millisecond * 20)
+		runs.Add(1)
+	}))
+
+	// 没有启动调度时也可以立即执行
+	s.trigger("hourly")
+	s.remove("hourly")
+	assert.Equal(t, int64(1), runs.Load())
+	assert.Empty(t, s.stats())
+	<-s.stop()
+}
+
+func TestScheduler_TriggerSerialized(t *testing.T) {
+	s := newScheduler()
+	release := make(chan struct{})
+	var runs atomic.Int64
+	assert.NoError(t, s.every("cleanup", time.Hour, 0, func() {
+		runs.Add(1)
+		<-release
+	}))
+
+	// 任务正在执行时立即触发只记录跳过，同一个任务不会并发执行
+	s.trigger("cleanup")
+	s.trigger("cleanup")
+	s.trigger("cleanup")
+	stats := s.stats()
+	assert.Equal(t, uint64(1), stats[0].Runs)
+	assert.Equal(t, uint64(2), stats[0].Skipped)
+
+	done := s.stop()
+	close(release)
+	<-done
+
+	// 停止之后不再执行
+	s.trigger("cleanup")
+	assert.Equal(t, int64(1), runs.Load())
+}
+
+func TestScheduler_Reschedule(t *testing.T) {
+	s := newScheduler()
+	var runs atomic.Int64
+	assert.NoError(t, s.every("job", time.Hour, 0, func() {
+		runs.Add(1)
+	}))
+	s.start()
+	defer s.stop()
+
+	s.reschedule("job", time.Millisecond*10)
+	assert.Eventually(t, func() bool {
+		return runs.Load() >= 2
+	}, time.Second, time.Millisecond*10)
+}
+
+func TestScheduler_Jitter(t *testing.T) {
+	job := &schedJob{sched: intervalSchedule(time.Minute), jitter: time.Second}
+	now := time.Now()
+	for i := 0; i < 100; i++ {
+		next := job.nextAfter(now)
+		assert.False(t, next.Before(now.Add(time.Minute)))
+		assert.True(t, next.Before(now.Add(time.Minute+time.Second)))
+	}
+}
+
+func TestRotator_Jobs(t *testing.T) {
+	_, err := NewRotator(t.TempDir(), "testdata.log", WithJobJitter(-time.Second))
+	assert.Error(t, err)
+
+	rotator, err := NewRotator(t.TempDir(), "testdata.log",
+		WithRotate(1024, Hour),
+		WithMaxCount(10),
+		WithRotateTrigger(),
+		WithJobJitter(time.Second))
+	assert.NoError(t, err)
+
+	var names []string
+	for _, job := range rotator.Jobs() {
+		names = append(names, job.Name)
+		assert.False(t, job.NextRun.IsZero())
+	}
+	assert.Equal(t, []string{"cleanup", "rotate", "trigger"}, names)
+	assert.NoError(t, rotator.Close())
+	<-rotator.drained
+}
@@ -0,0 +1,199 @@
+// Copyright 2025 TimeWtr
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package vortexrotate
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+
+	"github.com/TimeWtr/vortexrotate/errorx"
+)
+
+// maxSealedHistory 保留的已完成封存的文件数量，超过之后淘汰最早完成的记录
+const maxSealedHistory = 1024
+
+// sealState 单个文件的封存状态
+type sealState struct {
+	// 封存完成之后关闭
+	done chan struct{}
+	// 封存的结果
+	err error
+	// 是否已经完成
+	finished bool
+	// 是否延迟压缩，延迟压缩的文件由后台任务或者Rotate封存
+	deferred bool
+}
+
+// sealTracker 跟踪轮转文件的封存流程，用于等待指定文件封存完成
+type sealTracker struct {
+	lock sync.Mutex
+	// 文件路径 -> 封存状态
+	states map[string]*sealState
+	// 已经完成封存的文件，按照完成的顺序排列
+	history []string
+}
+
+func newSealTracker() *sealTracker {
+	return &sealTracker{
+		states: make(map[string]*sealState),
+	}
+}
+
+// watch 获取文件的封存状态，不存在则创建
+func (t *sealTracker) watch(path string) *sealState {
+	t.lock.Lock()
+	defer t.lock.Unlock()
+
+	return t.watchLocked(path)
+}
+
+func (t *sealTracker) watchLocked(path string) *sealState {
+	st, ok := t.states[path]
+	if !ok {
+		st = &sealState{done: make(chan struct{})}
+		t.states[path] = st
+	}
+
+	return st
+}
+
+// lookup 获取已经存在的文件封存状态
+func (t *sealTracker) lookup(path string) (*sealState, bool) {
+	t.lock.Lock()
+	defer t.lock.Unlock()
+
+	st, ok := t.states[path]
+	return st, ok
+}
+
+// pending 文件是否正在等待封存或者正在封存，延迟压缩的文件交给扫描任务处理，不算作封存中
+func (t *sealTracker) pending(path string) bool {
+	t.lock.Lock()
+	defer t.lock.Unlock()
+
+	st, ok := t.states[path]
+	return ok && !st.finished && !st.deferred
+}
+
+// deferSeal 文件延迟压缩，等待扫描任务封存之后再完成
+func (t *sealTracker) deferSeal(path string) {
+	t.lock.Lock()
+	defer t.lock.Unlock()
+
+	t.watchLocked(path).deferred = true
+}
+
+// finish 文件封存完成，通知所有等待的调用方
+func (t *sealTracker) finish(path string, err error) {
+	t.lock.Lock()
+	defer t.lock.Unlock()
+
+	st := t.watchLocked(path)
+	if st.finished {
+		return
+	}
+	st.err = err
+	st.finished = true
+	close(st.done)
+
+	t.history = append(t.history, path)
+	if len(t.history) > maxSealedHistory {
+		delete(t.states, t.history[0])
+		t.history = t.history[1:]
+	}
+}
+
+// abort 结束所有未完成的等待，用于关闭轮转器
+func (t *sealTracker) abort(err error) {
+	t.lock.Lock()
+	defer t.lock.Unlock()
+
+	for path, st := range t.states {
+		if st.finished {
+			continue
+		}
+		st.err = err
+		st.finished = true
+		close(st.done)
+		t.history = append(t.history, path)
+	}
+}
+
+// sealedOnDisk 扫描轮转文件所在的目录和完成目录，判断是否存在该文件封存之后的产物(原始文件、
+// 压缩文件、加密文件等)，不依赖封存时的压缩、加密和完成标记配置
+func sealedOnDisk(path string) bool {
+	base := filepath.Base(path)
+	for _, dir := range []string{filepath.Dir(path), filepath.Join(filepath.Dir(path), DoneDirName)} {
+		entries, err := os.ReadDir(dir)
+		if err != nil {
+			continue
+		}
+		for _, e := range entries {
+			fn := e.Name()
+			if e.IsDir() || !strings.HasPrefix(fn, base) || isArtifactSidecar(fn) {
+				continue
+			}
+			if fn == base || strings.HasPrefix(fn[len(base):], ".") {
+				return true
+			}
+		}
+	}
+
+	return false
+}
+
+// AwaitSealed 阻塞等待指定的轮转文件完成整个封存流程(压缩、校验、上传、完成标记等)，延迟压缩的
+// 文件等到压缩完成，开启上传时等到上传成功或者全部重试失败，path为轮转文件
+// 的原始路径，可以是当前正在写入的文件，此时会一直等到该文件被轮转并完成封存。适用于应用
+// 在删除本地状态之前必须确认日志已经安全归档的场景。
+// 返回值：
+//
+//	error - 封存流程的执行结果，ctx超时或者取消时返回ctx.Err()，文件不存在时返回errorx.ErrSegmentNotFound
+func (r *Rotator) AwaitSealed(ctx context.Context, path string) error {
+	path, err := filepath.Abs(path)
+	if err != nil {
+		return err
+	}
+
+	st, ok := r.tracker.lookup(path)
+	if !ok {
+		r.writeLock.RLock()
+		active := r.f != nil && r.f.Name() == path && r.sig.Load() == 0
+		if active {
+			// 持有读锁期间不会发生轮转，注册之后轮转完成时一定能收到通知
+			st = r.tracker.watch(path)
+		}
+		r.writeLock.RUnlock()
+
+		if !active {
+			// 不是当前写入的文件，也没有进行中的封存流程(或者记录已经被淘汰)，说明已经封存完成
+			// 或者不存在，扫描目录确认
+			if sealedOnDisk(path) {
+				return nil
+			}
+			return errorx.ErrSegmentNotFound
+		}
+	}
+
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	case <-st.done:
+		return st.err
+	}
+}
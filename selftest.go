@@ -0,0 +1,239 @@
+// Copyright 2025 TimeWtr
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package vortexrotate
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"os"
+	"time"
+
+	"github.com/TimeWtr/vortexrotate/errorx"
+)
+
+const (
+	// SelfTestWrite 写入测试数据
+	SelfTestWrite = "write"
+	// SelfTestRotate 强制轮转
+	SelfTestRotate = "rotate"
+	// SelfTestSeal 等待压缩、完成标记等封存流程结束
+	SelfTestSeal = "seal"
+	// SelfTestVerify 读取封存之后的文件，校验内容与写入的数据一致
+	SelfTestVerify = "verify"
+	// SelfTestUpload 上传到测试路径
+	SelfTestUpload = "upload"
+	// SelfTestClean 关闭轮转器并删除自检目录
+	SelfTestClean = "clean"
+)
+
+const (
+	// selfTestFilename 自检使用的文件名称，与业务的文件名称不同，不会被业务轮转器的清理任务处理
+	selfTestFilename = "vortex-selftest.log"
+	// selfTestLines 自检写入的数据行数
+	selfTestLines = 1024
+)
+
+// SelfTestStep 自检中单个步骤的执行结果
+type SelfTestStep struct {
+	// 步骤名称
+	Name string
+	// 执行耗时
+	Duration time.Duration
+	// 是否跳过了该步骤
+	Skipped bool
+	// 步骤的说明，比如跳过的原因
+	Message string
+	// 执行失败的原因，成功时为nil
+	Err error
+}
+
+// SelfTestResult 自检结果
+type SelfTestResult struct {
+	// 自检使用的临时目录，自检结束时删除
+	Dir string
+	// 按照执行顺序记录的所有步骤
+	Steps []SelfTestStep
+}
+
+// Err 返回第一个失败步骤的错误，所有步骤都成功时返回nil
+func (s *SelfTestResult) Err() error {
+	for _, step := range s.Steps {
+		if step.Err != nil {
+			return fmt.Errorf("selftest %s: %w", step.Name, step.Err)
+		}
+	}
+
+	return nil
+}
+
+// run 执行一个步骤，前面的步骤失败或者ctx结束时不再执行
+func (s *SelfTestResult) run(ctx context.Context, name string, fn func() error) {
+	if s.Err() != nil {
+		return
+	}
+
+	step := SelfTestStep{Name: name}
+	begin := time.Now()
+	if step.Err = ctx.Err(); step.Err == nil {
+		step.Err = fn()
+	}
+	step.Duration = time.Since(begin)
+	s.Steps = append(s.Steps, step)
+}
+
+// SelfTest 在dir下的临时目录中使用opts创建轮转器，依次执行写入测试数据、强制轮转、等待封存、
+// 校验封存之后的文件和清理，用于新部署环境的一次性预检，确认目录权限、压缩算法和文件系统
+// 等配置可以正常工作。无论执行结果如何，临时目录都会在返回之前删除。
+// 返回值：
+//
+//	*SelfTestResult - 每个步骤的执行结果
+//	error - 第一个失败步骤的错误
+func SelfTest(ctx context.Context, dir string, opts ...Option) (*SelfTestResult, error) {
+	dir, err := normalizeDir(dir)
+	if err != nil {
+		return nil, err
+	}
+
+	scratch, err := os.MkdirTemp(dir, ".vortex-selftest-")
+	if err != nil {
+		return nil, err
+	}
+
+	res := &SelfTestResult{Dir: scratch}
+	var (
+		rotator *Rotator
+		path    string
+		data    bytes.Buffer
+	)
+	res.run(ctx, SelfTestWrite, func() error {
+		if rotator, err = newRotator(scratch, selfTestFilename, opts...); err != nil {
+			return err
+		}
+
+		for i := 0; i < selfTestLines; i++ {
+			_, _ = fmt.Fprintf(&data, "vortexrotate selftest line %04d\n", i)
+		}
+		path = rotator.f.Name()
+		_, err = rotator.Write(data.Bytes())
+		return err
+	})
+	res.run(ctx, SelfTestRotate, func() error {
+		return rotator.Rotate()
+	})
+	res.run(ctx, SelfTestSeal, func() error {
+		return rotator.AwaitSealed(ctx, path)
+	})
+	res.run(ctx, SelfTestVerify, func() error {
+		return verifySelfTest(scratch, rotator.cpr, data.Bytes())
+	})
+	if rotator != nil && rotator.uploader != nil {
+		res.run(ctx, SelfTestUpload, func() error {
+			// 等待后台上传完成
+			if err := rotator.awaitUploads(ctx); err != nil {
+				return err
+			}
+			stats := rotator.Stats()
+			if stats.UploadFailures > 0 {
+				return errorx.ErrUploadFailed
+			}
+			if stats.Uploads == 0 {
+				return fmt.Errorf("%w: no file uploaded", errorx.ErrUploadFailed)
+			}
+			return nil
+		})
+	} else {
+		res.Steps = append(res.Steps, SelfTestStep{
+			Name:    SelfTestUpload,
+			Skipped: true,
+			Message: "no uploader configured",
+		})
+	}
+
+	// 清理步骤总是执行
+	begin := time.Now()
+	clean := SelfTestStep{Name: SelfTestClean}
+	if rotator != nil {
+		clean.Err = rotator.Close()
+	}
+	if err = os.RemoveAll(scratch); clean.Err == nil {
+		clean.Err = err
+	}
+	clean.Duration = time.Since(begin)
+	res.Steps = append(res.Steps, clean)
+
+	return res, res.Err()
+}
+
+// verifySelfTest 读取自检目录中的第一个轮转文件，校验压缩类型和内容
+func verifySelfTest(dir string, cpr Compress, want []byte) error {
+	ro, err := OpenReadOnly(dir, selfTestFilename)
+	if err != nil {
+		return err
+	}
+
+	segments, err := ro.List()
+	if err != nil {
+		return err
+	}
+	if len(segments) == 0 {
+		return fmt.Errorf("no segment found in %s", dir)
+	}
+
+	seg := segments[0]
+	if cpr.compress && cpr.delay == 0 && cpr.window == nil && int64(len(want)) >= cpr.minSize && seg.CompressType != cpr.compressType {
+		return fmt.Errorf("segment %s is not compressed with type %d", seg.Path, cpr.compressType)
+	}
+
+	rc, err := ro.Open(seg)
+	if err != nil {
+		return err
+	}
+	defer func() {
+		_ = rc.Close()
+	}()
+
+	got, err := io.ReadAll(rc)
+	if err != nil {
+		return err
+	}
+	if !bytes.Equal(got, want) {
+		return fmt.Errorf("segment %s content mismatch, want %d bytes, got %d bytes", seg.Path, len(want), len(got))
+	}
+
+	return nil
+}
+
+// SelfTest 使用与当前轮转器相同的压缩、完成标记等配置，在存储目录下的临时目录中执行自检，
+// 不会影响当前轮转器写入的文件，详见SelfTest
+func (r *Rotator) SelfTest(ctx context.Context) (*SelfTestResult, error) {
+	opts := []Option{
+		WithZstdBackend(r.zstdBackend),
+		WithDoneMarker(r.doneMarker),
+		WithRemoveSource(!r.cpr.keepSource),
+		WithCompressMinSize(r.cpr.minSize),
+	}
+	if r.cpr.compress {
+		// 压缩等级之外的参数通过WithCompressOptions设置，压缩等级以WithCompress为准
+		opts = append(opts, WithCompressOptions(r.cpr.compressType, CompressOptions{
+			Gzip: GzipOptions{Workers: r.cpr.workers},
+			Zstd: ZstdOptions{WindowLog: r.cpr.windowLog},
+			Xz:   XzOptions{DictCap: r.cpr.dictCap},
+		}), WithCompress(r.cpr.compressType, r.cpr.level))
+	}
+
+	return SelfTest(ctx, r.dir, opts...)
+}
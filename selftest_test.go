@@ -0,0 +1,73 @@
+// Copyright 2025 TimeWtr
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package vortexrotate
+
+import (
+	"context"
+	"os"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestSelfTest(t *testing.T) {
+	dir := t.TempDir()
+	res, err := SelfTest(context.Background(), dir, WithCompress(CompressTypeGzip), WithDoneMarker(DoneMarkerDir))
+	assert.NoError(t, err)
+	assert.NoError(t, res.Err())
+
+	var names []string
+	for _, step := range res.Steps {
+		names = append(names, step.Name)
+		assert.NoError(t, step.Err)
+	}
+	assert.Equal(t, []string{SelfTestWrite, SelfTestRotate, SelfTestSeal, SelfTestVerify,
+		SelfTestUpload, SelfTestClean}, names)
+	assert.True(t, res.Steps[4].Skipped)
+	// 临时目录已经删除
+	assert.NoDirExists(t, res.Dir)
+	entries, err := os.ReadDir(dir)
+	assert.NoError(t, err)
+	assert.Empty(t, entries)
+
+	// ctx结束时后续步骤不再执行，清理步骤仍然执行
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	res, err = SelfTest(ctx, dir)
+	assert.ErrorIs(t, err, context.Canceled)
+	assert.Equal(t, SelfTestClean, res.Steps[len(res.Steps)-1].Name)
+	assert.NoDirExists(t, res.Dir)
+}
+
+func TestRotator_SelfTest(t *testing.T) {
+	dir := t.TempDir()
+	rotator, err := newRotator(dir, "testdata.log", WithCompress(CompressTypeZstd))
+	assert.NoError(t, err)
+	defer rotator.Close()
+
+	_, err = rotator.Write([]byte("business data\n"))
+	assert.NoError(t, err)
+	res, err := rotator.SelfTest(context.Background())
+	assert.NoError(t, err)
+	assert.Len(t, res.Steps, 6)
+
+	// 自检不影响当前轮转器写入的文件
+	ro, err := OpenReadOnly(dir, "testdata.log")
+	assert.NoError(t, err)
+	segments, err := ro.List()
+	assert.NoError(t, err)
+	assert.Len(t, segments, 1)
+	assert.Equal(t, rotator.f.Name(), segments[0].Path)
+}
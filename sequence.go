@@ -0,0 +1,179 @@
+// Copyright 2025 TimeWtr
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package vortexrotate
+
+import (
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+// SeqFileExt 序列号文件的后缀名
+const SeqFileExt = ".seq"
+
+// segmentRegexp 匹配轮转文件名称中的日期和序号，文件名称格式为：filename_日期_序号.log
+func segmentRegexp(filename string) *regexp.Regexp {
+	escapedPrefix := regexp.QuoteMeta(filename)
+	return regexp.MustCompile(fmt.Sprintf(`^%s_(\d{8})_(\d{4,})\.log`, escapedPrefix))
+}
+
+// sequence 文件序列号生成器，下一个可用的序列号持久化在dir/filename.seq文件中，
+// 每次分配序列号之前先通过写临时文件+rename的方式原子更新序列号文件，保证进程崩溃
+// 重启后不会分配重复的序列号，也不需要每次启动都全量扫描目录。序列号文件丢失或损坏
+// 时通过扫描目录中已有的文件进行修复。
+type sequence struct {
+	// 文件存储目录
+	dir string
+	// 基础的文件名称
+	filename string
+	// 序列号文件路径
+	path string
+	// 下一个可用的序列号
+	next uint32
+	// 重命名序列号临时文件的函数，nil时使用renameLocal
+	rename renamer
+	// 加锁保护
+	lock sync.Mutex
+}
+
+func newSequence(dir, filename string) (*sequence, error) {
+	s := &sequence{
+		dir:      dir,
+		filename: filename,
+		path:     filepath.Join(dir, filename+SeqFileExt),
+	}
+
+	next, err := s.load()
+	if err != nil {
+		// 序列号文件不存在或者已损坏，扫描目录进行修复
+		next, err = s.rescan()
+		if err != nil {
+			return nil, err
+		}
+	}
+	s.next = next
+
+	return s, nil
+}
+
+// Next 分配一个序列号，分配前先持久化下一个序列号
+func (s *sequence) Next() (uint32, error) {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	n := s.next
+	if err := s.store(n + 1); err != nil {
+		return 0, err
+	}
+	s.next = n + 1
+
+	return n, nil
+}
+
+// Load 获取下一个将要分配的序列号
+func (s *sequence) Load() uint32 {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	return s.next
+}
+
+// load 从序列号文件中读取下一个可用的序列号
+func (s *sequence) load() (uint32, error) {
+	bs, err := os.ReadFile(s.path)
+	if err != nil {
+		return 0, err
+	}
+
+	n, err := strconv.ParseUint(strings.TrimSpace(string(bs)), 10, 32)
+	if err != nil {
+		return 0, fmt.Errorf("parse seq file %s error: %w", s.path, err)
+	}
+	if n == 0 {
+		return 0, fmt.Errorf("invalid seq in file %s", s.path)
+	}
+
+	return uint32(n), nil
+}
+
+// store 通过写临时文件+rename的方式原子更新序列号文件
+func (s *sequence) store(n uint32) error {
+	tmp := s.path + TmpFileExt
+	f, err := os.OpenFile(tmp, os.O_CREATE|os.O_WRONLY|os.O_TRUNC|openNoFollow, ReadWriteFile)
+	if err != nil {
+		return err
+	}
+
+	if _, err = f.WriteString(strconv.FormatUint(uint64(n), 10) + "\n"); err != nil {
+		_ = f.Close()
+		return err
+	}
+
+	if err = f.Sync(); err != nil {
+		_ = f.Close()
+		return err
+	}
+
+	if err = f.Close(); err != nil {
+		return err
+	}
+
+	rename := s.rename
+	if rename == nil {
+		rename = renameLocal
+	}
+
+	return rename(tmp, s.path)
+}
+
+// rescan 扫描目录中已有的轮转文件，返回最大的序列号+1
+func (s *sequence) rescan() (uint32, error) {
+	re := segmentRegexp(s.filename)
+	var maxSeq uint64
+	err := filepath.WalkDir(s.dir, func(_ string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if d.IsDir() {
+			return nil
+		}
+
+		const matchesLen = 3
+		matches := re.FindStringSubmatch(d.Name())
+		if len(matches) < matchesLen {
+			return nil
+		}
+
+		seq, err := strconv.ParseUint(matches[2], 10, 32)
+		if err != nil {
+			return nil
+		}
+		if seq > maxSeq {
+			maxSeq = seq
+		}
+
+		return nil
+	})
+	if err != nil {
+		return 0, err
+	}
+
+	return uint32(maxSeq) + 1, nil
+}
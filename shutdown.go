@@ -0,0 +1,185 @@
+// Copyright 2025 TimeWtr
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package vortexrotate
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"os/signal"
+	"syscall"
+	"time"
+)
+
+// DefaultShutdownTimeout 收到退出信号之后优雅关闭的默认超时时间
+const DefaultShutdownTimeout = time.Second * 10
+
+// shutdownSignals 触发优雅关闭的信号
+var shutdownSignals = []os.Signal{syscall.SIGTERM, os.Interrupt}
+
+// raise 优雅关闭完成之后重新向进程发送信号，测试中替换为空实现
+var raise = func(sig os.Signal) error {
+	p, err := os.FindProcess(os.Getpid())
+	if err != nil {
+		return err
+	}
+
+	return p.Signal(sig)
+}
+
+// WithSignalShutdown 安装SIGTERM/SIGINT信号处理函数，收到信号之后在timeout内执行优雅关闭：
+// 刷新暂存的内容并fsync当前文件，finalRotate为true时强制封存当前写入的文件(压缩、完成标记等)，
+// 等待后台任务执行完成，保证批处理任务退出时归档文件是完整一致的。timeout<=0时使用
+// DefaultShutdownTimeout。关闭完成之后停止监听并重新向进程发送该信号，进程按照默认行为退出，
+// 应用自己处理退出信号时不要使用该选项，直接调用Shutdown即可。
+func WithSignalShutdown(timeout time.Duration, finalRotate bool) Option {
+	return func(r *Rotator) error {
+		if timeout <= 0 {
+			timeout = DefaultShutdownTimeout
+		}
+
+		r.signalShutdown = true
+		r.shutdownTimeout = timeout
+		r.finalRotate = finalRotate
+		return nil
+	}
+}
+
+// WithContext 绑定父级上下文，ctx取消时关闭轮转器：停止轮转策略和所有后台任务，按照Close的
+// 流程关闭(WithSealOnClose时封存)当前写入的文件，之后的写入返回errorx.ErrRotateClosed，
+// 和其他组件一样由上层统一管理生命周期。ctx取消之前不会启动额外的goroutine，Close时解除绑定。
+func WithContext(ctx context.Context) Option {
+	return func(r *Rotator) error {
+		if ctx == nil {
+			return errors.New("context must not be nil")
+		}
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+
+		r.ctx = ctx
+		return nil
+	}
+}
+
+// WithSealOnClose 关闭(Close/Shutdown)时封存当前写入的文件，和轮转时一样执行压缩、完成标记等
+// 流程，没有写入任何内容的文件直接删除。批处理任务每次运行结束之后目录中只留下已经封存的文件，
+// 下游不需要区分写入中的文件。
+func WithSealOnClose() Option {
+	return func(r *Rotator) error {
+		r.sealOnClose = true
+		return nil
+	}
+}
+
+// Shutdown 优雅关闭轮转器，关闭当前文件之后等待进行中的后台任务(二次压缩、清理、上传等)执行完成，
+// ctx超时或者取消时取消正在执行的上传并返回ctx.Err()，关闭流程在后台继续执行，没有上传完成的
+// 文件在下一次启动时上传。
+func (r *Rotator) Shutdown(ctx context.Context) error {
+	return r.shutdown(ctx, r.sealOnClose)
+}
+
+// shutdown 优雅关闭轮转器，seal为true时封存当前写入的文件
+func (r *Rotator) shutdown(ctx context.Context, seal bool) error {
+	done := make(chan error, 1)
+	go func() {
+		err := r.close(seal)
+		if r.drained != nil {
+			<-r.drained
+		}
+		done <- err
+	}()
+
+	select {
+	case <-ctx.Done():
+		r.cancelUploads()
+		return ctx.Err()
+	case err := <-done:
+		return err
+	}
+}
+
+// sealActive 封存当前写入的文件，必须持有写锁，没有写入任何内容的文件直接删除
+func (r *Rotator) sealActive() error {
+	path := r.f.Name()
+	hook(hookBeforeRotate, path)
+	syncErr := r.syncFile()
+	if syncErr != nil {
+		r.l.Printf("failed to sync file %s before seal, cause: %v", path, syncErr)
+	}
+	if err := r.closeFile(); err != nil {
+		r.l.Printf("failed to close file %s before seal, cause: %v", path, err)
+	}
+	r.closeIndex()
+	if syncErr != nil && r.syncPolicy == SyncOnRotate {
+		// 数据没有持久化时不封存，保留原始文件，下一次启动时由遗留文件的封存流程处理
+		syncErr = fmt.Errorf("sync file %s before seal error: %w", path, syncErr)
+		r.tracker.finish(path, syncErr)
+		return syncErr
+	}
+
+	switch {
+	case r.offset == 0:
+		err := os.Remove(path)
+		r.tracker.finish(path, err)
+		return err
+	case r.sealQueue != nil:
+		r.enqueueSeal(path)
+		return nil
+	default:
+		var pause RotatePause
+		return r.seal(path, r.cpr.cs, &pause)
+	}
+}
+
+// watchContext 父级上下文取消时关闭轮转器
+func (r *Rotator) watchContext() {
+	if r.ctx == nil {
+		return
+	}
+
+	r.writeLock.Lock()
+	defer r.writeLock.Unlock()
+	r.stopCtx = context.AfterFunc(r.ctx, func() {
+		r.l.Printf("context done: %v, shutdown", context.Cause(r.ctx))
+		if err := r.close(r.sealOnClose); err != nil {
+			r.l.Printf("failed to close on context done, cause: %v", err)
+		}
+	})
+}
+
+// watchSignals 监听退出信号，收到信号之后执行优雅关闭
+func (r *Rotator) watchSignals(ch chan os.Signal) {
+	defer signal.Stop(ch)
+
+	select {
+	case <-r.done:
+		return
+	case sig := <-ch:
+		signal.Stop(ch)
+		r.l.Printf("receive signal %s, shutdown", sig)
+
+		ctx, cancel := context.WithTimeout(context.Background(), r.shutdownTimeout)
+		defer cancel()
+		if err := r.shutdown(ctx, r.finalRotate || r.sealOnClose); err != nil {
+			r.l.Printf("failed to shutdown, cause: %v", err)
+		}
+
+		if err := raise(sig); err != nil {
+			r.l.Printf("failed to raise signal %s, cause: %v", sig, err)
+		}
+	}
+}
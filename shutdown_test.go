@@ -0,0 +1,162 @@
+// Copyright 2025 TimeWtr
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package vortexrotate
+
+import (
+	"context"
+	"errors"
+	"os"
+	"runtime"
+	"syscall"
+	"testing"
+	"time"
+
+	"github.com/TimeWtr/vortexrotate/errorx"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestRotator_Shutdown(t *testing.T) {
+	rotator, err := NewRotator(t.TempDir(), "testdata.log",
+		WithCompress(CompressTypeGzip, GzipBestSpeed))
+	assert.NoError(t, err)
+
+	_, err = rotator.Write([]byte("hello\n"))
+	assert.NoError(t, err)
+	path := rotator.f.Name()
+
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second*5)
+	defer cancel()
+	assert.NoError(t, rotator.Shutdown(ctx))
+
+	_, err = rotator.Write([]byte("hello\n"))
+	assert.ErrorIs(t, err, errorx.ErrRotateClosed)
+	// 没有开启封存时当前文件不会被压缩
+	_, err = os.Stat(compressFn(path, CompressTypeGzip))
+	assert.True(t, os.IsNotExist(err))
+}
+
+func TestRotator_SignalShutdown(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("signal is not supported on windows")
+	}
+
+	raised := make(chan os.Signal, 1)
+	old := raise
+	raise = func(sig os.Signal) error {
+		raised <- sig
+		return nil
+	}
+	defer func() {
+		raise = old
+	}()
+
+	rotator, err := NewRotator(t.TempDir(), "testdata.log",
+		WithCompress(CompressTypeGzip, GzipBestSpeed),
+		WithSignalShutdown(time.Second*5, true))
+	assert.NoError(t, err)
+	defer rotator.Close()
+
+	_, err = rotator.Write([]byte("hello\n"))
+	assert.NoError(t, err)
+	rotator.writeLock.RLock()
+	path := rotator.f.Name()
+	rotator.writeLock.RUnlock()
+
+	p, err := os.FindProcess(os.Getpid())
+	assert.NoError(t, err)
+	assert.NoError(t, p.Signal(syscall.SIGTERM))
+	select {
+	case sig := <-raised:
+		assert.Equal(t, syscall.SIGTERM, sig)
+	case <-time.After(time.Second * 5):
+		t.Fatal("shutdown timeout")
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+	defer cancel()
+	assert.NoError(t, rotator.AwaitSealed(ctx, path))
+	_, err = os.Stat(compressFn(path, CompressTypeGzip))
+	assert.NoError(t, err)
+	_, err = rotator.Write([]byte("hello\n"))
+	assert.ErrorIs(t, err, errorx.ErrRotateClosed)
+}
+
+func TestRotator_SealOnClose(t *testing.T) {
+	dir := t.TempDir()
+	rotator, err := NewRotator(dir, "testdata.log",
+		WithCompress(CompressTypeGzip, GzipBestSpeed),
+		WithDoneMarker(DoneMarkerFile),
+		WithSealOnClose())
+	assert.NoError(t, err)
+
+	_, err = rotator.Write([]byte("hello\n"))
+	assert.NoError(t, err)
+	path := rotator.f.Name()
+	assert.NoError(t, rotator.Close())
+
+	_, err = os.Stat(compressFn(path, CompressTypeGzip) + DoneFileExt)
+	assert.NoError(t, err)
+
+	// 没有写入内容的文件直接删除
+	rotator, err = NewRotator(dir, "testdata.log", WithSealOnClose())
+	assert.NoError(t, err)
+	path = rotator.f.Name()
+	assert.NoError(t, rotator.Close())
+	_, err = os.Stat(path)
+	assert.True(t, os.IsNotExist(err))
+}
+
+func TestRotator_WithContext(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	rotator, err := NewRotator(t.TempDir(), "testdata.log",
+		WithCompress(CompressTypeGzip, GzipBestSpeed),
+		WithSealOnClose(),
+		WithContext(ctx))
+	assert.NoError(t, err)
+	defer rotator.Close()
+
+	_, err = rotator.Write([]byte("hello\n"))
+	assert.NoError(t, err)
+	rotator.writeLock.RLock()
+	path := rotator.f.Name()
+	rotator.writeLock.RUnlock()
+
+	cancel()
+	assert.Eventually(t, func() bool {
+		_, err := rotator.Write([]byte("hello\n"))
+		return errors.Is(err, errorx.ErrRotateClosed)
+	}, time.Second*5, time.Millisecond*10)
+	// 等待关闭流程和后台任务执行完成
+	assert.NoError(t, rotator.Shutdown(context.Background()))
+	// 取消时按照Close的流程封存当前文件
+	_, err = os.Stat(compressFn(path, CompressTypeGzip))
+	assert.NoError(t, err)
+}
+
+func TestRotator_WithContextClose(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	rotator, err := NewRotator(t.TempDir(), "testdata.log", WithContext(ctx))
+	assert.NoError(t, err)
+	assert.NoError(t, rotator.Close())
+	// Close之后解除绑定，取消上下文不再触发关闭
+	assert.False(t, rotator.stopCtx())
+
+	_, err = NewRotator(t.TempDir(), "testdata.log", WithContext(nil)) //nolint:staticcheck
+	assert.Error(t, err)
+	cancel()
+	_, err = NewRotator(t.TempDir(), "testdata.log", WithContext(ctx))
+	assert.ErrorIs(t, err, context.Canceled)
+}
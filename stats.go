@@ -0,0 +1,146 @@
+// Copyright 2025 TimeWtr
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package vortexrotate
+
+import "time"
+
+// RotateReason 触发轮转的原因
+type RotateReason int
+
+const (
+	// RotateReasonSize 文件大小达到限制
+	RotateReasonSize RotateReason = iota + 1
+	// RotateReasonScheduled 定时轮转
+	RotateReasonScheduled
+	// RotateReasonManual 手动轮转，包括调用Rotate和触发文件
+	RotateReasonManual
+	// RotateReasonRollover 跨天之后的定时轮转
+	RotateReasonRollover
+	// RotateReasonErrorRecovery 上一次轮转失败之后重新打开新的文件
+	RotateReasonErrorRecovery
+	// RotateReasonLines 文件行数达到限制
+	RotateReasonLines
+
+	rotateReasonCount
+)
+
+func (r RotateReason) String() string {
+	switch r {
+	case RotateReasonSize:
+		return "size"
+	case RotateReasonScheduled:
+		return "scheduled"
+	case RotateReasonManual:
+		return "manual"
+	case RotateReasonRollover:
+		return "rollover"
+	case RotateReasonErrorRecovery:
+		return "error_recovery"
+	case RotateReasonLines:
+		return "lines"
+	default:
+		return "unknown"
+	}
+}
+
+// Stats 轮转器的运行统计
+type Stats struct {
+	// 各个原因触发的轮转次数
+	Rotations map[RotateReason]uint64
+	// 轮转的总次数
+	TotalRotations uint64
+	// 当前阻塞等待写入的写入方数量
+	BlockedWriters int64
+	// 当前的背压等级，取值范围为[0, 1]
+	BackpressureLevel float64
+	// 准入控制丢弃的写入数量
+	DroppedWrites uint64
+	// 异步压缩队列中等待的文件数量
+	CompressQueueDepth int
+	// 异步压缩队列中最早的文件(包括正在封存的文件)已经等待的时间，队列为空时为0
+	CompressQueueAge time.Duration
+	// 异步写入队列中等待写入的内容数量
+	WriteQueueDepth int
+	// 异步写入队列中最早的内容已经等待的时间，队列为空时为0
+	WriteQueueAge time.Duration
+	// 上传队列中最早进入队列的文件已经等待的时间(包括重试的等待)，没有等待上传的文件时为0
+	UploadQueueAge time.Duration
+	// 后台写入失败的次数
+	AsyncWriteErrors uint64
+	// 上传成功的文件数量
+	Uploads uint64
+	// 上传失败的文件数量
+	UploadFailures uint64
+}
+
+// Stats 获取轮转器的运行统计，轮转次数按照触发原因分别计数，用于排查异常的轮转风暴，
+// 阻塞的写入方数量和背压等级用于观测日志子系统是否饱和，各个队列中最早的任务已经等待的时间
+// 用于发现流量较低时后台任务停滞的情况
+func (r *Rotator) Stats() Stats {
+	stats := Stats{Rotations: make(map[RotateReason]uint64, rotateReasonCount-1)}
+	for reason := RotateReasonSize; reason < rotateReasonCount; reason++ {
+		n := r.rotations[reason].Load()
+		stats.Rotations[reason] = n
+		stats.TotalRotations += n
+	}
+	stats.BlockedWriters = r.blockedWriters.Load()
+	stats.BackpressureLevel = r.backpressureLevel()
+	stats.DroppedWrites = r.droppedWrites.Load()
+	stats.CompressQueueDepth = r.compressQueueDepth()
+	stats.CompressQueueAge = r.sealAges.oldest()
+	stats.WriteQueueDepth = r.writeQueueDepth()
+	stats.WriteQueueAge = r.writeQueueAge()
+	stats.UploadQueueAge = r.uploadAges.oldest()
+	stats.AsyncWriteErrors = r.asyncWriteErrors.Load()
+	stats.Uploads = r.uploads.Load()
+	stats.UploadFailures = r.uploadFailures.Load()
+
+	return stats
+}
+
+// scheduledReason 定时轮转的原因，当前文件的日期不是今天时为跨天轮转
+func (r *Rotator) scheduledReason() RotateReason {
+	if r.bucketDate != r.currentDate() {
+		return RotateReasonRollover
+	}
+
+	return RotateReasonScheduled
+}
+
+// recoverFile 上一次轮转失败之后当前文件已经关闭，重新打开新的文件继续写入，必须持有写锁
+func (r *Rotator) recoverFile() error {
+	r.checkDirEntries()
+	if err := r.mkdirAll(); err != nil {
+		return err
+	}
+
+	f, err := r.openNewFile()
+	if err != nil {
+		return err
+	}
+
+	r.f = f
+	r.broken = false
+	r.lines = 0
+	r.offset = 0
+	r.nextIndex = 0
+	r.midLine = false
+	r.resetStrategy()
+	r.rotations[RotateReasonErrorRecovery].Add(1)
+	r.attachStdio()
+	hook(hookAfterRotate, r.f.Name())
+
+	return nil
+}
@@ -0,0 +1,66 @@
+// Copyright 2025 TimeWtr
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package vortexrotate
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestRotator_Stats(t *testing.T) {
+	rotator, err := NewRotator(t.TempDir(), "testdata.log", WithMaxLines(1))
+	assert.NoError(t, err)
+	defer rotator.Close()
+
+	for i := 0; i < 3; i++ {
+		_, err = rotator.Write([]byte("hello\n"))
+		assert.NoError(t, err)
+	}
+	assert.NoError(t, rotator.Rotate())
+
+	stats := rotator.Stats()
+	assert.Equal(t, uint64(2), stats.Rotations[RotateReasonLines])
+	assert.Equal(t, uint64(0), stats.Rotations[RotateReasonSize])
+	assert.Equal(t, uint64(1), stats.Rotations[RotateReasonManual])
+	assert.Equal(t, uint64(0), stats.Rotations[RotateReasonScheduled])
+	assert.Equal(t, uint64(3), stats.TotalRotations)
+}
+
+func TestRotator_StatsErrorRecovery(t *testing.T) {
+	rotator, err := NewRotator(t.TempDir(), "testdata.log", WithDoneMarker(DoneMarkerDir))
+	assert.NoError(t, err)
+	defer rotator.Close()
+
+	_, err = rotator.Write([]byte("hello\n"))
+	assert.NoError(t, err)
+
+	// 完成目录的位置被普通文件占用，封存失败
+	done := filepath.Join(filepath.Dir(rotator.f.Name()), DoneDirName)
+	assert.NoError(t, os.WriteFile(done, nil, ReadWriteFile))
+	assert.Error(t, rotator.Rotate())
+	assert.NoError(t, os.Remove(done))
+
+	_, err = rotator.Write([]byte("hello\n"))
+	assert.NoError(t, err)
+
+	stats := rotator.Stats()
+	assert.Equal(t, uint64(1), stats.Rotations[RotateReasonErrorRecovery])
+	assert.Equal(t, uint64(0), stats.Rotations[RotateReasonManual])
+	assert.Equal(t, "error_recovery", RotateReasonErrorRecovery.String())
+	assert.Equal(t, "lines", RotateReasonLines.String())
+}
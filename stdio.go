@@ -0,0 +1,161 @@
+// Copyright 2025 TimeWtr
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package vortexrotate
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"sync/atomic"
+)
+
+// StdioStream 需要重定向到轮转文件的标准输出流
+type StdioStream int
+
+const (
+	// CaptureStdout 重定向标准输出(fd 1)
+	CaptureStdout StdioStream = 1 << iota
+	// CaptureStderr 重定向标准错误(fd 2)
+	CaptureStderr
+)
+
+// stdioCaptured 进程的标准输出流只能重定向到一个轮转器
+var stdioCaptured atomic.Bool
+
+// WithStdioCapture 将进程的标准输出和标准错误的文件描述符dup到当前写入的文件，每次轮转之后重新
+// dup到新的文件，直接写入fd 1/2的内容(panic堆栈、运行时的致命错误、第三方库的输出等)也会进入
+// 轮转文件。轮转封存旧文件期间以及关闭之后恢复为原始的标准输出流，一个进程中只能有一个轮转器
+// 开启该选项。直接写入文件描述符的内容不经过轮转器，不计入按照大小轮转的统计，因此不能与边写边
+// 压缩、WAL模式同时使用。只支持Linux和BSD系列的系统。
+func WithStdioCapture(streams StdioStream) Option {
+	return func(r *Rotator) error {
+		if streams&(CaptureStdout|CaptureStderr) == 0 || streams&^(CaptureStdout|CaptureStderr) != 0 {
+			return fmt.Errorf("invalid stdio streams %d", streams)
+		}
+
+		r.stdioStreams = streams
+		return nil
+	}
+}
+
+// stdioCapture 标准输出流的重定向状态
+type stdioCapture struct {
+	// 被重定向的文件描述符
+	fds []int
+	// 重定向之前的文件描述符的副本，恢复时dup回原来的文件描述符
+	saved []int
+}
+
+// startStdio 保存原始的标准输出流并重定向到当前写入的文件
+func (r *Rotator) startStdio() error {
+	if r.stdioStreams == 0 {
+		return nil
+	}
+	if r.cow != CompressTypeUnknown || r.wal {
+		return errors.New("stdio capture can not be used with compress on write or wal mode")
+	}
+	if !stdioCaptured.CompareAndSwap(false, true) {
+		return errors.New("stdio is already captured by another rotator")
+	}
+
+	c := &stdioCapture{}
+	for _, fd := range []int{1, 2} {
+		if r.stdioStreams&StdioStream(fd) == 0 {
+			continue
+		}
+		saved, err := dupFd(fd)
+		if err != nil {
+			_ = c.close()
+			return fmt.Errorf("dup fd %d error: %w", fd, err)
+		}
+		c.fds = append(c.fds, fd)
+		c.saved = append(c.saved, saved)
+	}
+
+	if err := c.attach(r.f); err != nil {
+		_ = c.close()
+		return err
+	}
+	r.stdio = c
+
+	return nil
+}
+
+// attach 将标准输出流重定向到文件f
+func (c *stdioCapture) attach(f *os.File) error {
+	fd := int(f.Fd())
+	for _, target := range c.fds {
+		if err := dup2Fd(fd, target); err != nil {
+			return fmt.Errorf("redirect fd %d to %s error: %w", target, f.Name(), err)
+		}
+	}
+
+	return nil
+}
+
+// detach 恢复为原始的标准输出流
+func (c *stdioCapture) detach() error {
+	var errs []error
+	for i, target := range c.fds {
+		errs = append(errs, dup2Fd(c.saved[i], target))
+	}
+
+	return errors.Join(errs...)
+}
+
+// close 恢复为原始的标准输出流并释放保存的副本
+func (c *stdioCapture) close() error {
+	errs := []error{c.detach()}
+	for _, fd := range c.saved {
+		errs = append(errs, closeFd(fd))
+	}
+	stdioCaptured.Store(false)
+
+	return errors.Join(errs...)
+}
+
+// attachStdio 轮转打开新的文件之后重新重定向标准输出流，必须持有写锁
+func (r *Rotator) attachStdio() {
+	if r.stdio == nil {
+		return
+	}
+
+	if err := r.stdio.attach(r.f); err != nil {
+		r.l.Printf("failed to capture stdio, cause: %v", err)
+	}
+}
+
+// detachStdio 轮转关闭旧文件之前恢复标准输出流，封存期间写入的内容输出到原始的标准输出流，
+// 不会修改正在压缩的文件，必须持有写锁
+func (r *Rotator) detachStdio() {
+	if r.stdio == nil {
+		return
+	}
+
+	if err := r.stdio.detach(); err != nil {
+		r.l.Printf("failed to release stdio, cause: %v", err)
+	}
+}
+
+// stopStdio 关闭时恢复标准输出流，必须持有写锁
+func (r *Rotator) stopStdio() error {
+	if r.stdio == nil {
+		return nil
+	}
+
+	err := r.stdio.close()
+	r.stdio = nil
+	return err
+}
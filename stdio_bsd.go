@@ -0,0 +1,43 @@
+// Copyright 2025 TimeWtr
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+//go:build darwin || dragonfly || freebsd || netbsd || openbsd
+
+package vortexrotate
+
+import "syscall"
+
+// dupFd 复制文件描述符，副本设置close-on-exec，持有ForkLock防止设置之前被并发的fork继承
+func dupFd(fd int) (int, error) {
+	syscall.ForkLock.RLock()
+	defer syscall.ForkLock.RUnlock()
+
+	nfd, err := syscall.Dup(fd)
+	if err != nil {
+		return -1, err
+	}
+	syscall.CloseOnExec(nfd)
+
+	return nfd, nil
+}
+
+// dup2Fd 将newfd指向oldfd打开的文件
+func dup2Fd(oldfd, newfd int) error {
+	return syscall.Dup2(oldfd, newfd)
+}
+
+// closeFd 关闭文件描述符
+func closeFd(fd int) error {
+	return syscall.Close(fd)
+}
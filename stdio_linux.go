@@ -0,0 +1,39 @@
+// Copyright 2025 TimeWtr
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+//go:build linux
+
+package vortexrotate
+
+import "syscall"
+
+// dupFd 复制文件描述符，副本原子地设置close-on-exec，不会泄漏到子进程中
+func dupFd(fd int) (int, error) {
+	nfd, _, errno := syscall.Syscall(syscall.SYS_FCNTL, uintptr(fd), syscall.F_DUPFD_CLOEXEC, 0)
+	if errno != 0 {
+		return -1, errno
+	}
+
+	return int(nfd), nil
+}
+
+// dup2Fd 将newfd指向oldfd打开的文件，部分架构没有dup2系统调用，使用dup3
+func dup2Fd(oldfd, newfd int) error {
+	return syscall.Dup3(oldfd, newfd, 0)
+}
+
+// closeFd 关闭文件描述符
+func closeFd(fd int) error {
+	return syscall.Close(fd)
+}
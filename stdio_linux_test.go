@@ -0,0 +1,37 @@
+// Copyright 2025 TimeWtr
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+//go:build linux
+
+package vortexrotate
+
+import (
+	"syscall"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestDupFd_CloseOnExec(t *testing.T) {
+	fd, err := dupFd(1)
+	assert.NoError(t, err)
+	defer func() {
+		_ = closeFd(fd)
+	}()
+
+	// 保存的标准输出流副本不会被子进程继承
+	flags, _, errno := syscall.Syscall(syscall.SYS_FCNTL, uintptr(fd), syscall.F_GETFD, 0)
+	assert.Zero(t, errno)
+	assert.NotZero(t, flags&syscall.FD_CLOEXEC)
+}
@@ -0,0 +1,34 @@
+// Copyright 2025 TimeWtr
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+//go:build !linux && !darwin && !dragonfly && !freebsd && !netbsd && !openbsd
+
+package vortexrotate
+
+import "errors"
+
+// errStdioUnsupported 当前系统不支持重定向标准输出流
+var errStdioUnsupported = errors.New("stdio capture is not supported on this platform")
+
+func dupFd(int) (int, error) {
+	return 0, errStdioUnsupported
+}
+
+func dup2Fd(int, int) error {
+	return errStdioUnsupported
+}
+
+func closeFd(int) error {
+	return errStdioUnsupported
+}
@@ -0,0 +1,67 @@
+// Copyright 2025 TimeWtr
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package vortexrotate
+
+import (
+	"os"
+	"runtime"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestRotator_StdioCapture(t *testing.T) {
+	if runtime.GOOS != "linux" && runtime.GOOS != "darwin" {
+		t.Skip("stdio capture is not supported")
+	}
+
+	rotator, err := NewRotator(t.TempDir(), "testdata.log", WithStdioCapture(CaptureStdout))
+	assert.NoError(t, err)
+	defer rotator.Close()
+
+	_, err = NewRotator(t.TempDir(), "testdata.log", WithStdioCapture(CaptureStdout))
+	assert.Error(t, err)
+
+	// 直接写入fd 1的内容进入当前文件
+	_, err = os.Stdout.WriteString("from stdout 1\n")
+	assert.NoError(t, err)
+	_, err = rotator.Write([]byte("from rotator\n"))
+	assert.NoError(t, err)
+	first := rotator.f.Name()
+
+	assert.NoError(t, rotator.Rotate())
+	_, err = os.Stdout.WriteString("from stdout 2\n")
+	assert.NoError(t, err)
+	second := rotator.f.Name()
+	assert.NoError(t, rotator.Close())
+
+	bs, err := os.ReadFile(first)
+	assert.NoError(t, err)
+	assert.Equal(t, "from stdout 1\nfrom rotator\n", string(bs))
+	bs, err = os.ReadFile(second)
+	assert.NoError(t, err)
+	assert.Equal(t, "from stdout 2\n", string(bs))
+
+	// 关闭之后恢复原始的标准输出，其他轮转器可以重新开启
+	rotator, err = NewRotator(t.TempDir(), "testdata.log", WithStdioCapture(CaptureStderr))
+	assert.NoError(t, err)
+	assert.NoError(t, rotator.Close())
+
+	_, err = NewRotator(t.TempDir(), "testdata.log", WithStdioCapture(0))
+	assert.Error(t, err)
+	_, err = NewRotator(t.TempDir(), "testdata.log",
+		WithCompressOnWrite(CompressTypeGzip), WithStdioCapture(CaptureStderr))
+	assert.Error(t, err)
+}
@@ -0,0 +1,220 @@
+// Copyright 2025 TimeWtr
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package vortexrotate
+
+import (
+	"fmt"
+	"sync"
+	"time"
+)
+
+var (
+	_ timedStrategy      = (*CompositeStrategy)(nil)
+	_ ResettableStrategy = (*CompositeStrategy)(nil)
+)
+
+// composeMode 组合策略的组合方式
+type composeMode int
+
+const (
+	// composeOr 任意一个子策略触发即轮转
+	composeOr composeMode = iota + 1
+	// composeAnd 所有子策略都触发之后才轮转
+	composeAnd
+)
+
+// CompositeStrategy 由多个轮转策略组合而成的策略，通过Or和And创建，可以嵌套组合大小、时间以及
+// 自定义的轮转策略。子策略在写入时(ShouldRotate返回true)或者定时(NotifyRotate收到通知)触发之后
+// 记为已触发，满足组合条件时执行轮转，轮转之后清除所有子策略的触发状态并重置可以重置的子策略，
+// 因此And表示在上一次轮转之后所有子策略都至少触发过一次，比如And(按大小, 按小时)表示每个整点
+// 检查一次，只有写入量达到最大大小才轮转(先达到大小时等到整点再轮转)。定时的子策略与内置的定时
+// 策略一样迁移到轮转器的调度器上，同步模式下在写入时检查，这些都依赖内部的调度器，因此组合策略与
+// 内置的轮转策略一样放在根包中，而不是单独的strategy包
+type CompositeStrategy struct {
+	mode     composeMode
+	children []RotateStrategy
+	// 加锁保护
+	lock sync.Mutex
+	// 子策略在上一次轮转之后是否已经触发
+	armed []bool
+	// 组合之后的定时轮转通知
+	events chan struct{}
+	// 关闭时通知转发的goroutine不再转发
+	stop chan struct{}
+	// 等待转发的goroutine退出
+	wg sync.WaitGroup
+	// 第一次获取通知通道时开始转发子策略的通知
+	startOnce sync.Once
+	// 保证只关闭一次
+	closeOnce sync.Once
+}
+
+// Or 组合多个轮转策略，任意一个子策略触发时执行轮转，nil的策略被忽略
+func Or(strategies ...RotateStrategy) *CompositeStrategy {
+	return newCompositeStrategy(composeOr, strategies)
+}
+
+// And 组合多个轮转策略，上一次轮转之后所有子策略都触发过时执行轮转，nil的策略被忽略，
+// 没有子策略时不会轮转
+func And(strategies ...RotateStrategy) *CompositeStrategy {
+	return newCompositeStrategy(composeAnd, strategies)
+}
+
+func newCompositeStrategy(mode composeMode, strategies []RotateStrategy) *CompositeStrategy {
+	children := make([]RotateStrategy, 0, len(strategies))
+	for _, stg := range strategies {
+		if !IsNil(stg) {
+			children = append(children, stg)
+		}
+	}
+
+	return &CompositeStrategy{
+		mode:     mode,
+		children: children,
+		armed:    make([]bool, len(children)),
+		events:   make(chan struct{}),
+		stop:     make(chan struct{}),
+	}
+}
+
+// ShouldRotate 将写入的大小传递给所有的子策略，满足组合条件时返回true
+func (s *CompositeStrategy) ShouldRotate(writeSize uint64) bool {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	// 所有的子策略都需要统计写入的大小，不能短路
+	for i, stg := range s.children {
+		if stg.ShouldRotate(writeSize) {
+			s.armed[i] = true
+		}
+	}
+
+	return s.fire()
+}
+
+// fire 判断是否满足组合条件，满足时清除触发状态并重置子策略，必须持有锁
+func (s *CompositeStrategy) fire() bool {
+	if len(s.children) == 0 {
+		return false
+	}
+
+	ok := s.mode == composeAnd
+	for _, armed := range s.armed {
+		if s.mode == composeOr && armed {
+			ok = true
+			break
+		}
+		if s.mode == composeAnd && !armed {
+			ok = false
+			break
+		}
+	}
+	if ok {
+		s.resetLocked()
+	}
+
+	return ok
+}
+
+// resetLocked 清除触发状态并重置子策略，必须持有锁
+func (s *CompositeStrategy) resetLocked() {
+	for i, stg := range s.children {
+		s.armed[i] = false
+		if rs, ok := stg.(ResettableStrategy); ok {
+			rs.Reset()
+		}
+	}
+}
+
+// Reset 外部触发轮转之后清除所有子策略的触发状态并重置子策略
+func (s *CompositeStrategy) Reset() {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	s.resetLocked()
+}
+
+// attach 将定时的子策略迁移到轮转器的调度器上，子策略的任务名称为name加上子策略的序号，嵌套的
+// 组合策略依次加上各层的序号
+func (s *CompositeStrategy) attach(sched *scheduler, name string) error {
+	for i, stg := range s.children {
+		if ts, ok := stg.(timedStrategy); ok {
+			if err := ts.attach(sched, fmt.Sprintf("%s-%d", name, i)); err != nil {
+				return err
+			}
+		}
+	}
+
+	return nil
+}
+
+// poll 同步模式下检查所有定时的子策略，到达定时轮转时间的子策略记为触发，满足组合条件时返回true
+func (s *CompositeStrategy) poll(now time.Time) bool {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	// 每个定时的子策略都需要推进下一次定时轮转的时间，不能短路
+	for i, stg := range s.children {
+		if ts, ok := stg.(timedStrategy); ok && ts.poll(now) {
+			s.armed[i] = true
+		}
+	}
+
+	return s.fire()
+}
+
+// NotifyRotate 获取定时轮转信号，第一次调用时开始转发子策略的定时轮转通知
+func (s *CompositeStrategy) NotifyRotate() <-chan struct{} {
+	s.startOnce.Do(func() {
+		for i, stg := range s.children {
+			s.wg.Add(1)
+			go s.forward(i, stg.NotifyRotate())
+		}
+	})
+
+	return s.events
+}
+
+// forward 子策略的定时轮转通知记为触发，满足组合条件时发送组合之后的通知，子策略关闭通知通道之后退出
+func (s *CompositeStrategy) forward(i int, ch <-chan struct{}) {
+	defer s.wg.Done()
+
+	for range ch {
+		s.lock.Lock()
+		s.armed[i] = true
+		ok := s.fire()
+		s.lock.Unlock()
+		if !ok {
+			continue
+		}
+
+		select {
+		case s.events <- struct{}{}:
+		case <-s.stop:
+		}
+	}
+}
+
+// Close 关闭所有的子策略，等待转发的goroutine退出之后关闭通知通道，可以重复调用
+func (s *CompositeStrategy) Close() {
+	s.closeOnce.Do(func() {
+		close(s.stop)
+		for _, stg := range s.children {
+			stg.Close()
+		}
+		s.wg.Wait()
+		close(s.events)
+	})
+}
@@ -0,0 +1,142 @@
+// Copyright 2025 TimeWtr
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package vortexrotate
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// manualStrategy 测试使用的轮转策略，由测试代码手动发送定时轮转通知
+type manualStrategy struct {
+	events chan struct{}
+}
+
+func newManualStrategy() *manualStrategy {
+	return &manualStrategy{events: make(chan struct{})}
+}
+
+func (s *manualStrategy) ShouldRotate(uint64) bool { return false }
+
+func (s *manualStrategy) NotifyRotate() <-chan struct{} { return s.events }
+
+func (s *manualStrategy) Close() { close(s.events) }
+
+// notified 在超时之前是否收到了轮转通知
+func notified(ch <-chan struct{}) bool {
+	select {
+	case <-ch:
+		return true
+	case <-time.After(100 * time.Millisecond):
+		return false
+	}
+}
+
+func TestCompositeStrategy_Or(t *testing.T) {
+	manual := newManualStrategy()
+	stg := Or(NewSizeStrategy(10), NewSizeStrategy(100), manual, nil)
+	defer stg.Close()
+	assert.Len(t, stg.children, 3)
+
+	assert.False(t, stg.ShouldRotate(5))
+	assert.True(t, stg.ShouldRotate(5))
+	// 轮转之后所有的子策略都重置
+	assert.False(t, stg.ShouldRotate(9))
+
+	ch := stg.NotifyRotate()
+	manual.events <- struct{}{}
+	assert.True(t, notified(ch))
+}
+
+func TestCompositeStrategy_And(t *testing.T) {
+	manual := newManualStrategy()
+	stg := And(NewSizeStrategy(10), manual)
+	ch := stg.NotifyRotate()
+
+	// 只有定时触发，大小没有达到限制时不轮转
+	manual.events <- struct{}{}
+	assert.False(t, notified(ch))
+	assert.False(t, stg.ShouldRotate(5))
+	// 大小达到限制之后写入时立即轮转
+	assert.True(t, stg.ShouldRotate(5))
+
+	// 先达到大小限制，等到定时通知再轮转
+	assert.False(t, stg.ShouldRotate(10))
+	manual.events <- struct{}{}
+	assert.True(t, notified(ch))
+
+	assert.False(t, stg.ShouldRotate(10))
+	stg.Reset()
+	manual.events <- struct{}{}
+	assert.False(t, notified(ch))
+
+	stg.Close()
+	stg.Close()
+	_, ok := <-ch
+	assert.False(t, ok)
+
+	empty := And()
+	assert.False(t, empty.ShouldRotate(1))
+	empty.Close()
+}
+
+func TestCompositeStrategy_Timed(t *testing.T) {
+	ts1, err := NewTimeStrategy(_Second)
+	assert.NoError(t, err)
+	ts2, err := NewTimeStrategy(Hour)
+	assert.NoError(t, err)
+	stg := And(ts1, Or(NewSizeStrategy(10), ts2))
+	defer stg.Close()
+
+	// 多个定时的子策略迁移到同一个调度器上，任务名称不冲突
+	sched := newScheduler()
+	defer sched.stop()
+	assert.NoError(t, stg.attach(sched, mixJobName))
+	assert.Equal(t, mixJobName+"-0", ts1.job)
+	assert.Equal(t, mixJobName+"-1-1", ts2.job)
+
+	// 同步模式下检查定时的子策略
+	now := time.Now()
+	assert.False(t, stg.ShouldRotate(5))
+	assert.False(t, stg.poll(now))
+	// 大小的子策略已经触发，等待定时的子策略
+	assert.False(t, stg.ShouldRotate(5))
+	assert.True(t, stg.poll(now.Add(2*time.Second)))
+	assert.False(t, stg.poll(now.Add(2*time.Second)))
+
+	assert.True(t, pollable(stg))
+	assert.False(t, pollable(Or(ts1, newManualStrategy())))
+}
+
+func TestRotator_WithRotateStrategy(t *testing.T) {
+	_, err := newRotator(t.TempDir(), "testdata.log", WithRotateStrategy(nil))
+	assert.Error(t, err)
+
+	ts, err := NewTimeStrategy(_Second)
+	assert.NoError(t, err)
+	rotator, err := newRotator(t.TempDir(), "testdata.log",
+		WithRotateStrategy(Or(NewSizeStrategy(1024), ts)))
+	assert.NoError(t, err)
+	defer rotator.Close()
+
+	_, err = rotator.Write([]byte("composite strategy test\n"))
+	assert.NoError(t, err)
+	// 文件大小远小于最大大小，子策略的定时轮转仍然生效
+	assert.Eventually(t, func() bool {
+		return rotator.Stats().TotalRotations > 0
+	}, 3*time.Second, 50*time.Millisecond)
+}
@@ -0,0 +1,207 @@
+// Copyright 2025 TimeWtr
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package vortexrotate
+
+import (
+	"bytes"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+)
+
+// SummaryFileExt 每日汇总文件的后缀名，文件名称格式为：YYYYMMDD.summary.json
+const SummaryFileExt = ".summary.json"
+
+// Classifier 日志分类函数，返回写入内容的错误类别，返回空字符串表示不计数
+type Classifier func(p []byte) string
+
+// DailySummary 每日汇总信息
+type DailySummary struct {
+	// 日期，格式为YYYYMMDD
+	Date string `json:"date"`
+	// 写入的总字节数
+	Bytes int64 `json:"bytes"`
+	// 写入的总行数
+	Lines int64 `json:"lines"`
+	// 当天创建的轮转文件数量
+	Segments int64 `json:"segments"`
+	// 压缩前的总字节数
+	RawBytes int64 `json:"raw_bytes"`
+	// 压缩后的总字节数
+	CompressedBytes int64 `json:"compressed_bytes"`
+	// 压缩比，压缩前的大小/压缩后的大小，没有压缩时为0
+	CompressionRatio float64 `json:"compression_ratio"`
+	// 错误类别 -> 计数，只有设置了分类函数时才有
+	Classes map[string]int64 `json:"classes,omitempty"`
+}
+
+// WithDailySummary 开启每日汇总，跨天之后在存储目录中生成前一天的YYYYMMDD.summary.json汇总文件，
+// 包括写入的字节数、行数、文件数量、压缩比，设置了分类函数classifier时还包括各个错误类别的计数，
+// 不需要日志处理管道就可以生成轻量的报表。关闭时生成当天已经统计的部分，同一天重启之后继续累加。
+// classifier可以为nil。
+func WithDailySummary(classifier Classifier) Option {
+	return func(r *Rotator) error {
+		r.summary = &summaryCollector{classifier: classifier}
+		return nil
+	}
+}
+
+// summaryCollector 每日汇总信息的统计，异步压缩时压缩结果在后台goroutine中统计，通过lock保护
+type summaryCollector struct {
+	lock       sync.Mutex
+	classifier Classifier
+	// 当天的汇总信息
+	cur DailySummary
+}
+
+// reset 开始统计新一天的汇总信息
+func (c *summaryCollector) reset(date string) {
+	c.cur = DailySummary{Date: date}
+	if c.classifier != nil {
+		c.cur.Classes = make(map[string]int64)
+	}
+}
+
+// rollover 检查是否跨天，跨天时生成前一天的汇总文件，并开始统计新一天的汇总信息，必须持有
+// 汇总信息的锁
+func (r *Rotator) rollover(now time.Time) {
+	if r.summary == nil {
+		return
+	}
+
+	date := now.Format(Layout)
+	if r.summary.cur.Date == date {
+		return
+	}
+
+	if r.summary.cur.Date != "" {
+		if err := r.writeSummary(r.summary.cur); err != nil {
+			r.l.Printf("failed to write daily summary %s, cause: %v", r.summary.cur.Date, err)
+		}
+	}
+	r.summary.reset(date)
+	r.loadSummary()
+}
+
+// loadSummary 当天的汇总文件已经存在(进程在当天重启)时，在已有的汇总信息上继续统计，
+// 必须持有汇总信息的锁
+func (r *Rotator) loadSummary() {
+	bs, err := os.ReadFile(filepath.Join(r.dir, r.summary.cur.Date+SummaryFileExt))
+	if err != nil {
+		return
+	}
+
+	var s DailySummary
+	if err = json.Unmarshal(bs, &s); err != nil || s.Date != r.summary.cur.Date {
+		r.l.Printf("ignore invalid daily summary %s, cause: %v", r.summary.cur.Date, err)
+		return
+	}
+	if r.summary.classifier != nil && s.Classes == nil {
+		s.Classes = make(map[string]int64)
+	}
+	r.summary.cur = s
+}
+
+// flushSummary 关闭时生成当天已经统计的汇总文件，同一天重启之后在汇总文件的基础上继续统计
+func (r *Rotator) flushSummary() error {
+	if r.summary == nil {
+		return nil
+	}
+
+	r.summary.lock.Lock()
+	defer r.summary.lock.Unlock()
+
+	if r.summary.cur.Date == "" {
+		return nil
+	}
+
+	return r.writeSummary(r.summary.cur)
+}
+
+// collectWrite 统计写入的数据
+func (r *Rotator) collectWrite(p []byte) {
+	if r.summary == nil {
+		return
+	}
+
+	r.summary.lock.Lock()
+	defer r.summary.lock.Unlock()
+
+	r.rollover(time.Now())
+	r.summary.cur.Bytes += int64(len(p))
+	r.summary.cur.Lines += int64(bytes.Count(p, []byte{'\n'}))
+	if r.summary.classifier != nil {
+		if class := r.summary.classifier(p); class != "" {
+			r.summary.cur.Classes[class]++
+		}
+	}
+}
+
+// collectSegment 统计新创建的轮转文件
+func (r *Rotator) collectSegment() {
+	if r.summary == nil {
+		return
+	}
+
+	r.summary.lock.Lock()
+	defer r.summary.lock.Unlock()
+
+	r.rollover(time.Now())
+	r.summary.cur.Segments++
+}
+
+// collectCompress 统计压缩前后的文件大小
+func (r *Rotator) collectCompress(raw, compressed string) {
+	if r.summary == nil {
+		return
+	}
+
+	rawInfo, err := os.Stat(raw)
+	if err != nil {
+		return
+	}
+	info, err := os.Stat(compressed)
+	if err != nil {
+		return
+	}
+
+	r.summary.lock.Lock()
+	defer r.summary.lock.Unlock()
+
+	r.rollover(time.Now())
+	r.summary.cur.RawBytes += rawInfo.Size()
+	r.summary.cur.CompressedBytes += info.Size()
+}
+
+// writeSummary 通过写临时文件+rename的方式生成汇总文件
+func (r *Rotator) writeSummary(s DailySummary) error {
+	bs, err := encodeSummary(s)
+	if err != nil {
+		return err
+	}
+
+	return writeFileAtomic(filepath.Join(r.dir, s.Date+SummaryFileExt), bs, r.rename)
+}
+
+// encodeSummary 计算压缩比之后序列化汇总信息
+func encodeSummary(s DailySummary) ([]byte, error) {
+	if s.CompressedBytes > 0 {
+		s.CompressionRatio = float64(s.RawBytes) / float64(s.CompressedBytes)
+	}
+
+	return json.MarshalIndent(s, "", "  ")
+}
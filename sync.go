@@ -0,0 +1,92 @@
+// Copyright 2025 TimeWtr
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package vortexrotate
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/TimeWtr/vortexrotate/errorx"
+)
+
+// DefaultSyncInterval 按照时间间隔执行fsync的默认间隔
+const DefaultSyncInterval = time.Second
+
+// SyncPolicy 执行fsync的策略
+type SyncPolicy int
+
+const (
+	// SyncNever 从不主动执行fsync，由操作系统决定何时刷盘，吞吐量最高
+	SyncNever SyncPolicy = iota
+	// SyncEveryWrite 每次写入之后执行fsync，持久性最好，吞吐量最低
+	SyncEveryWrite
+	// SyncInterval 按照固定的时间间隔执行fsync
+	SyncInterval
+	// SyncOnRotate 轮转关闭旧文件之前执行fsync，fsync失败时不封存旧文件并返回错误
+	SyncOnRotate
+)
+
+func (p SyncPolicy) String() string {
+	switch p {
+	case SyncNever:
+		return "never"
+	case SyncEveryWrite:
+		return "every-write"
+	case SyncInterval:
+		return "interval"
+	case SyncOnRotate:
+		return "on-rotate"
+	default:
+		return "unknown"
+	}
+}
+
+// WithSyncPolicy 设置执行fsync的策略，用于审计日志等对持久性要求高的场景，在吞吐量和持久性之间
+// 明确取舍，policy为SyncInterval时可以通过interval设置时间间隔，默认为1秒。默认策略为SyncNever。
+func WithSyncPolicy(policy SyncPolicy, interval ...time.Duration) Option {
+	return func(r *Rotator) error {
+		if policy < SyncNever || policy > SyncOnRotate {
+			return fmt.Errorf("sync policy %d not support", policy)
+		}
+
+		r.syncPolicy = policy
+		r.syncPolicySet = true
+		r.syncInterval = DefaultSyncInterval
+		if len(interval) > 0 && interval[0] > 0 {
+			r.syncInterval = interval[0]
+		}
+		return nil
+	}
+}
+
+// Sync 将当前写入文件的数据刷新到磁盘，开启了异步写入时先等待调用之前入队的内容全部写入
+func (r *Rotator) Sync() error {
+	r.awaitWrites()
+	r.writeLock.Lock()
+	defer r.writeLock.Unlock()
+
+	if r.sig.Load() == 1 || r.f == nil {
+		return errorx.ErrRotateClosed
+	}
+
+	return r.syncFile()
+}
+
+// syncJob 按照固定的时间间隔执行fsync
+func (r *Rotator) syncJob() {
+	if err := r.Sync(); err != nil && r.sig.Load() == 0 {
+		r.l.Printf("sync file error: %v", err)
+	}
+}
@@ -0,0 +1,126 @@
+// Copyright 2025 TimeWtr
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package vortexrotate
+
+import (
+	"errors"
+	"fmt"
+	"slices"
+	"time"
+)
+
+// WithSynchronousMode 开启同步模式，轮转器不启动任何后台goroutine：轮转只在Write中判断(包括定时
+// 轮转，在到达轮转时间之后的第一次写入时执行)，写入时轮转的文件以原始文件保留，压缩只在调用Rotate
+// 时在当前goroutine中执行，同时压缩之前写入时轮转的文件，清理只在调用CleanNow时执行，关闭时没有
+// 压缩的文件在下一次启动时压缩。适用于短生命周期的命令行工具以及禁止后台goroutine的运行环境。
+// 同步模式只支持大小、时间和混合轮转策略以及由这些策略组合而成的组合策略，不支持依赖后台任务的功能：异步压缩、延迟压缩、压缩时间窗口、
+// 二次压缩、每日打包、按照时间间隔fsync、触发文件、背压回调、信号关闭和信号终止前刷新；边写边压缩的缓冲
+// 只在轮转、Sync和关闭时刷新。
+func WithSynchronousMode() Option {
+	return func(r *Rotator) error {
+		r.synchronous = true
+		return nil
+	}
+}
+
+// checkSynchronous 检查同步模式与其他配置的兼容性
+func (r *Rotator) checkSynchronous() error {
+	if !r.synchronous {
+		return nil
+	}
+
+	switch {
+	case !IsNil(r.stg) && !pollable(r.stg):
+		return fmt.Errorf("synchronous mode does not support rotate strategy %T", r.stg)
+	case r.sealQueueSize > 0:
+		return errors.New("synchronous mode does not support async compress")
+	case r.writeQueueSize > 0:
+		return errors.New("synchronous mode does not support async write")
+	case r.cpr.delay > 0 || r.cpr.window != nil:
+		return errors.New("synchronous mode does not support compress delay or compress window")
+	case r.recompress != nil:
+		return errors.New("synchronous mode does not support recompress")
+	case r.bundle != 0:
+		return errors.New("synchronous mode does not support daily bundle")
+	case r.syncPolicy == SyncInterval:
+		return errors.New("synchronous mode does not support interval sync policy")
+	case r.rotateTrigger:
+		return errors.New("synchronous mode does not support rotate trigger")
+	case r.onBackpressure != nil:
+		return errors.New("synchronous mode does not support backpressure callback")
+	case r.signalShutdown:
+		return errors.New("synchronous mode does not support signal shutdown")
+	case r.lastGasp:
+		return errors.New("synchronous mode does not support last gasp flush")
+	default:
+		return nil
+	}
+}
+
+// pollable 判断轮转策略是否可以在同步模式下使用，定时轮转必须可以在写入时检查，不能依赖NotifyRotate，
+// 获取通知通道会启动轮转策略的后台goroutine
+func pollable(stg RotateStrategy) bool {
+	switch s := stg.(type) {
+	case *CompositeStrategy:
+		// 组合策略的所有子策略都需要可以在写入时检查
+		return !slices.ContainsFunc(s.children, func(child RotateStrategy) bool {
+			return !pollable(child)
+		})
+	case timedStrategy, *SizeStrategy:
+		return true
+	default:
+		return false
+	}
+}
+
+// pollRotate 同步模式下在写入时检查定时轮转，根据定时轮转的时间表判断，只按照大小轮转时没有定时轮转，
+// 必须持有写锁
+func (r *Rotator) pollRotate() error {
+	ts, ok := r.stg.(timedStrategy)
+	if !ok || !ts.poll(time.Now()) {
+		return nil
+	}
+
+	return r.scheduledRotate()
+}
+
+// sealPending 同步模式下调用Rotate之后在当前goroutine中压缩所有没有压缩的轮转文件，包括写入时轮转
+// 的文件和本次轮转的文件，不能持有写锁
+func (r *Rotator) sealPending() error {
+	r.archiveLock.Lock()
+	defer r.archiveLock.Unlock()
+
+	files, err := r.leftoverFiles(time.Now())
+	if err != nil || len(files) == 0 {
+		return err
+	}
+
+	cs, err := r.newCompressStrategy()
+	if err != nil {
+		return err
+	}
+
+	var errs []error
+	for _, path := range files {
+		if !r.reserveSpace(path) {
+			// 磁盘空间不足，等待清理之后下一次调用Rotate时再压缩
+			break
+		}
+		var pause RotatePause
+		errs = append(errs, r.sealFile(path, cs, &pause))
+	}
+
+	return errors.Join(errs...)
+}
@@ -0,0 +1,101 @@
+// Copyright 2025 TimeWtr
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package vortexrotate
+
+import (
+	"runtime"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/TimeWtr/vortexrotate/errorx"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestRotator_SynchronousMode(t *testing.T) {
+	_, err := NewRotator(t.TempDir(), "testdata.log", WithSynchronousMode(), WithAsyncCompress(8))
+	assert.Error(t, err)
+
+	before := runtime.NumGoroutine()
+	rotator, err := newRotator(t.TempDir(), "testdata.log",
+		WithSynchronousMode(),
+		WithRotate(100, _Second),
+		WithCompress(CompressTypeGzip),
+		WithMaxCount(2))
+	assert.NoError(t, err)
+	assert.LessOrEqual(t, runtime.NumGoroutine(), before)
+
+	// 按照大小轮转，写入时轮转的文件不压缩，调用Rotate时在当前goroutine中压缩
+	line := strings.Repeat("x", 59) + "\n"
+	first := rotator.f.Name()
+	for i := 0; i < 5; i++ {
+		_, err = rotator.Write([]byte(line))
+		assert.NoError(t, err)
+	}
+	assert.FileExists(t, first)
+	assert.NoFileExists(t, compressFn(first, CompressTypeGzip))
+	active := rotator.f.Name()
+	assert.NoError(t, rotator.Rotate())
+	assert.FileExists(t, compressFn(first, CompressTypeGzip))
+	assert.NoFileExists(t, first)
+	assert.FileExists(t, compressFn(active, CompressTypeGzip))
+	assert.LessOrEqual(t, runtime.NumGoroutine(), before)
+
+	// 清理只在CleanNow时执行
+	files, err := rotator.cleanup.listFileInfo()
+	assert.NoError(t, err)
+	assert.Greater(t, len(files), 2)
+	assert.NoError(t, rotator.CleanNow())
+	files, err = rotator.cleanup.listFileInfo()
+	assert.NoError(t, err)
+	assert.Len(t, files, 2)
+
+	// 定时轮转在到达轮转时间之后的第一次写入时执行
+	assert.NoError(t, rotator.Rotate())
+	_, err = rotator.Write([]byte(strings.Repeat("y", 89) + "\n"))
+	assert.NoError(t, err)
+	active = rotator.f.Name()
+	time.Sleep(time.Millisecond * 1100)
+	_, err = rotator.Write([]byte("z\n"))
+	assert.NoError(t, err)
+	assert.NotEqual(t, active, rotator.f.Name())
+	assert.FileExists(t, active)
+
+	assert.NoError(t, rotator.Close())
+	assert.LessOrEqual(t, runtime.NumGoroutine(), before)
+	assert.ErrorIs(t, rotator.CleanNow(), errorx.ErrRotateClosed)
+
+	// 不能在写入时检查的轮转策略
+	_, err = NewRotator(t.TempDir(), "testdata.log", WithSynchronousMode(),
+		WithRotateStrategy(Or(NewSizeStrategy(100), newManualStrategy())))
+	assert.Error(t, err)
+}
+
+func TestRotator_SynchronousComposite(t *testing.T) {
+	ts, err := NewTimeStrategy(_Second)
+	assert.NoError(t, err)
+	rotator, err := NewRotator(t.TempDir(), "testdata.log", WithSynchronousMode(),
+		WithRotateStrategy(Or(NewSizeStrategy(1<<20), ts)))
+	assert.NoError(t, err)
+	defer rotator.Close()
+
+	// 定时的子策略在写入时检查
+	_, err = rotator.Write([]byte("composite\n"))
+	assert.NoError(t, err)
+	time.Sleep(1100 * time.Millisecond)
+	_, err = rotator.Write([]byte("composite\n"))
+	assert.NoError(t, err)
+	assert.Equal(t, uint64(1), rotator.Stats().TotalRotations)
+}
@@ -0,0 +1,154 @@
+// Copyright 2025 TimeWtr
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package vortexrotate
+
+import (
+	"bufio"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// TimeIndexExt 时间索引文件的后缀名
+const TimeIndexExt = ".tidx"
+
+// WithTimeIndex 开启稀疏时间索引，写入过程中每隔every字节记录一次"偏移 时间戳"的索引项到轮转文件
+// 旁边的<文件名>.tidx文件中，索引项只记录在行首，偏移是原始内容(压缩之前)中的偏移。读取方可以通过
+// ReadOnly.CursorAt按照时间定位到大文件的中间位置，不需要从头开始扫描。
+func WithTimeIndex(every int64) Option {
+	return func(r *Rotator) error {
+		if every <= 0 {
+			return fmt.Errorf("time index interval %d must be positive", every)
+		}
+
+		r.indexEvery = every
+		return nil
+	}
+}
+
+// recordIndex 写入之前检查是否需要记录索引项，必须持有写锁
+func (r *Rotator) recordIndex() {
+	if r.indexEvery <= 0 || r.offset < r.nextIndex || r.midLine {
+		return
+	}
+
+	if r.index == nil {
+		f, err := os.OpenFile(trimCompressExt(r.f.Name())+TimeIndexExt,
+			os.O_CREATE|os.O_WRONLY|os.O_APPEND|openNoFollow, ReadWriteFile)
+		if err != nil {
+			r.l.Printf("failed to open time index file, cause: %v", err)
+			// 跳过当前区间，避免每次写入都尝试打开
+			r.nextIndex = r.offset + r.indexEvery
+			return
+		}
+		r.index = f
+	}
+
+	entry := strconv.FormatInt(r.offset, 10) + " " + strconv.FormatInt(time.Now().UnixNano(), 10) + "\n"
+	if _, err := r.index.WriteString(entry); err != nil {
+		r.l.Printf("failed to write time index, cause: %v", err)
+	}
+	r.nextIndex = r.offset + r.indexEvery
+}
+
+// closeIndex 关闭当前文件的时间索引文件
+func (r *Rotator) closeIndex() {
+	if r.index == nil {
+		return
+	}
+
+	_ = r.index.Close()
+	r.index = nil
+}
+
+// indexPath 轮转文件对应的时间索引文件路径，文件被移动到完成目录时索引文件仍然在原来的目录
+func (ro *ReadOnly) indexPath(seg SegmentInfo) string {
+	base := trimCompressExt(filepath.Base(seg.Path)) + TimeIndexExt
+	dir := filepath.Dir(seg.Path)
+	path := filepath.Join(dir, base)
+	if _, err := os.Stat(path); err != nil && filepath.Base(dir) == DoneDirName {
+		return filepath.Join(filepath.Dir(dir), base)
+	}
+
+	return path
+}
+
+// indexOffset 根据时间索引返回文件中时间戳不晚于t的最后一个索引项的偏移，没有索引时返回0
+func (ro *ReadOnly) indexOffset(seg SegmentInfo, t time.Time) (int64, error) {
+	f, err := os.Open(ro.indexPath(seg))
+	if err != nil {
+		if os.IsNotExist(err) {
+			return 0, nil
+		}
+		return 0, err
+	}
+	defer func() {
+		_ = f.Close()
+	}()
+
+	var offset int64
+	sc := bufio.NewScanner(f)
+	for sc.Scan() {
+		fields := strings.Fields(sc.Text())
+		const fieldsLen = 2
+		if len(fields) != fieldsLen {
+			// 崩溃时写入了一半的索引项
+			continue
+		}
+
+		off, err1 := strconv.ParseInt(fields[0], 10, 64)
+		ts, err2 := strconv.ParseInt(fields[1], 10, 64)
+		if err1 != nil || err2 != nil {
+			continue
+		}
+		if time.Unix(0, ts).After(t) {
+			break
+		}
+		offset = off
+	}
+
+	return offset, sc.Err()
+}
+
+// CursorAt 返回时间t对应的读取位置，定位到最后一次写入时间不早于t的第一个轮转文件，并通过时间索引
+// 定位到文件中不晚于t的最近的一行，返回的位置之后可能还有少量早于t的记录，需要调用方过滤。所有
+// 文件都早于t时定位到最后一个文件的最后一个索引项。
+func (ro *ReadOnly) CursorAt(t time.Time) (Cursor, error) {
+	segments, err := ro.List()
+	if err != nil {
+		return Cursor{}, err
+	}
+	if len(segments) == 0 {
+		return Cursor{}, nil
+	}
+
+	seg := segments[len(segments)-1]
+	for _, s := range segments {
+		if !s.ModTime.Before(t) {
+			seg = s
+			break
+		}
+	}
+
+	offset, err := ro.indexOffset(seg, t)
+	if err != nil {
+		return Cursor{}, err
+	}
+
+	return Cursor{Segment: seg.Sequence, Offset: offset}, nil
+}
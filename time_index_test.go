@@ -0,0 +1,84 @@
+// Copyright 2025 TimeWtr
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package vortexrotate
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestRotator_TimeIndex(t *testing.T) {
+	dir := t.TempDir()
+	rotator, err := newRotator(dir, "testdata.log", WithTimeIndex(256), WithCompress(CompressTypeGzip))
+	assert.NoError(t, err)
+	defer rotator.Close()
+
+	line := func(i int) string {
+		return fmt.Sprintf("line %04d %s\n", i, strings.Repeat("x", 50))
+	}
+	var mid time.Time
+	for i := 0; i < 100; i++ {
+		if i == 60 {
+			time.Sleep(10 * time.Millisecond)
+			mid = time.Now()
+			time.Sleep(10 * time.Millisecond)
+		}
+		_, err = rotator.Write([]byte(line(i)))
+		assert.NoError(t, err)
+	}
+	// 轮转之后索引仍然有效
+	assert.NoError(t, rotator.Rotate())
+	_, err = rotator.Write([]byte(line(100)))
+	assert.NoError(t, err)
+
+	ro, err := OpenReadOnly(dir, "testdata.log")
+	assert.NoError(t, err)
+	segments, err := ro.List()
+	assert.NoError(t, err)
+	_, err = os.Stat(ro.indexPath(segments[0]))
+	assert.NoError(t, err)
+	// 压缩之后删除了原始文件，压缩文件使用原始文件的索引
+	assert.Equal(t, compressFn(trimCompressExt(segments[0].Path), CompressTypeGzip), segments[0].Path)
+	raw := segments[0]
+	raw.Path = trimCompressExt(raw.Path)
+	assert.Equal(t, ro.indexPath(raw), ro.indexPath(segments[0]))
+
+	cur, err := ro.CursorAt(mid)
+	assert.NoError(t, err)
+	assert.Equal(t, segments[0].Sequence, cur.Segment)
+	assert.Greater(t, cur.Offset, int64(0))
+
+	var records []string
+	_, err = ro.ReadRecords(context.Background(), cur, func(rec Record) bool {
+		records = append(records, string(rec.Data)+"\n")
+		return true
+	})
+	assert.NoError(t, err)
+	// 从索引位置开始读取，跳过了大部分早于mid的记录，并且不会漏掉晚于mid的记录
+	assert.Less(t, len(records), 60)
+	assert.Contains(t, records, line(60))
+	assert.Equal(t, line(100), records[len(records)-1])
+
+	// 晚于所有写入的时间定位到最后一个文件
+	cur, err = ro.CursorAt(time.Now().Add(time.Hour))
+	assert.NoError(t, err)
+	assert.Equal(t, segments[1].Sequence, cur.Segment)
+}
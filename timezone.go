@@ -0,0 +1,160 @@
+// Copyright 2025 TimeWtr
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package vortexrotate
+
+import (
+	"fmt"
+	"os"
+	"strings"
+	"time"
+)
+
+// localtimePath 系统时区配置文件的路径
+const localtimePath = "/etc/localtime"
+
+// hostLocation 读取主机当前的时区配置，测试中可以替换
+var hostLocation = loadHostLocation
+
+// loadHostLocation 按照与标准库相同的规则重新读取主机时区：优先使用TZ环境变量，
+// 未设置时读取/etc/localtime，标准库的time.Local只在进程启动时加载一次，不会感知运行期间的变化
+func loadHostLocation() (*time.Location, error) {
+	if tz, ok := os.LookupEnv("TZ"); ok {
+		tz = strings.TrimPrefix(tz, ":")
+		switch {
+		case tz == "" || tz == "UTC":
+			return time.UTC, nil
+		case strings.HasPrefix(tz, "/"):
+			data, err := os.ReadFile(tz)
+			if err != nil {
+				return nil, err
+			}
+			return time.LoadLocationFromTZData(tz, data)
+		default:
+			return time.LoadLocation(tz)
+		}
+	}
+
+	data, err := os.ReadFile(localtimePath)
+	if err != nil {
+		return nil, err
+	}
+
+	return time.LoadLocationFromTZData("Local", data)
+}
+
+// WithTimezoneTracking 开启时区变化检测，每次轮转时重新读取主机的时区配置(TZ环境变量或者
+// /etc/localtime)，发现时区变化时发送EventTimezoneChange事件。为了避免同一天的目录中混杂
+// 两个时区的文件，新的时区不会立即生效，当前的日期目录一直使用到新时区的日期发生变化为止，
+// 之后的目录和文件名称都按照新的时区命名。只能检测主机配置的变化，程序中直接修改time.Local
+// 的场景不需要开启
+func WithTimezoneTracking() Option {
+	return func(r *Rotator) error {
+		r.tzTracking = true
+		return nil
+	}
+}
+
+// now 按照轮转器当前使用的时区返回当前时间
+func (r *Rotator) now() time.Time {
+	if r.loc == nil {
+		return time.Now()
+	}
+
+	return time.Now().In(r.loc)
+}
+
+// location 轮转器当前使用的时区
+func (r *Rotator) location() *time.Location {
+	if r.loc == nil {
+		return time.Local
+	}
+
+	return r.loc
+}
+
+// sameZone 判断两个时区在指定时刻的名称和偏移量是否一致
+func sameZone(t time.Time, a, b *time.Location) bool {
+	an, ao := t.In(a).Zone()
+	bn, bo := t.In(b).Zone()
+	return an == bn && ao == bo
+}
+
+// checkTimezone 检测主机时区是否发生了变化，变化时记录待切换的时区，必须持有写锁
+func (r *Rotator) checkTimezone() {
+	if !r.tzTracking {
+		return
+	}
+
+	loc, err := hostLocation()
+	if err != nil {
+		r.l.Printf("failed to load host timezone, cause: %v", err)
+		return
+	}
+
+	now := time.Now()
+	cur := r.location()
+	if sameZone(now, cur, loc) {
+		// 时区变化之后又恢复了原来的配置
+		r.pendingLoc = nil
+		return
+	}
+	if r.pendingLoc != nil && sameZone(now, r.pendingLoc, loc) {
+		return
+	}
+
+	r.pendingLoc = loc
+	from, _ := now.In(cur).Zone()
+	to, _ := now.In(loc).Zone()
+	r.emit(Event{
+		Type: EventTimezoneChange,
+		Path: r.dir,
+		Message: fmt.Sprintf("host timezone changed from %s to %s, adopt after the date changes in %s",
+			from, to, to),
+	})
+}
+
+// currentDate 当前的日期，检测到时区变化之后按照待切换的时区计算
+func (r *Rotator) currentDate() string {
+	if r.pendingLoc != nil {
+		return time.Now().In(r.pendingLoc).Format(Layout)
+	}
+
+	return r.now().Format(Layout)
+}
+
+// adoptTimezone 待切换时区的日期晚于当前目录的日期时切换到新的时区，返回当前使用的日期，
+// 切换之前继续使用当前目录的日期，保证同一天的目录中不会混杂两个时区的文件。时区向西变化时
+// 新时区的日期可能早于当前目录的日期，此时同样等待新时区的日期超过当前目录的日期，防止日期回退
+func (r *Rotator) adoptTimezone() string {
+	t := r.currentDate()
+	if r.pendingLoc == nil {
+		return t
+	}
+	if r.bucketDate != "" && t <= r.bucketDate {
+		return r.bucketDate
+	}
+
+	from, _ := r.now().Zone()
+	r.loc = r.pendingLoc
+	r.pendingLoc = nil
+	to, _ := r.now().Zone()
+	r.emit(Event{
+		Type:    EventTimezoneChange,
+		Path:    r.dir,
+		Message: fmt.Sprintf("adopt timezone %s, previous timezone %s", to, from),
+	})
+
+	return t
+}
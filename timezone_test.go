@@ -0,0 +1,115 @@
+// Copyright 2025 TimeWtr
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package vortexrotate
+
+import (
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// setHostLocation 替换主机时区的读取函数，测试结束后恢复
+func setHostLocation(t *testing.T, loc *time.Location) {
+	old := hostLocation
+	hostLocation = func() (*time.Location, error) { return loc, nil }
+	t.Cleanup(func() { hostLocation = old })
+}
+
+func TestRotator_TimezoneChange(t *testing.T) {
+	now := time.Now()
+	today := now.Format(Layout)
+
+	var events []Event
+	dir := t.TempDir()
+	setHostLocation(t, time.Local)
+	rotator, err := newRotator(dir, "testdata.log", WithTimezoneTracking(),
+		WithEventHandler(func(e Event) {
+			if e.Type == EventTimezoneChange {
+				events = append(events, e)
+			}
+		}))
+	assert.NoError(t, err)
+	defer rotator.Close()
+
+	// 时区没有变化
+	assert.NoError(t, rotator.Rotate())
+	assert.Empty(t, events)
+	assert.Equal(t, today, filepath.Base(filepath.Dir(rotator.f.Name())))
+
+	// 新时区的日期与当前目录相同，继续使用当前目录，等待日期变化之后再切换
+	_, offset := now.Zone()
+	same := time.FixedZone("TZS", offset)
+	setHostLocation(t, same)
+	assert.NoError(t, rotator.Rotate())
+	assert.Len(t, events, 1)
+	assert.Equal(t, same, rotator.pendingLoc)
+	assert.Equal(t, today, filepath.Base(filepath.Dir(rotator.f.Name())))
+
+	// 重复检测到同一个时区不再发送事件
+	assert.NoError(t, rotator.Rotate())
+	assert.Len(t, events, 1)
+
+	// 新时区的日期晚于当前目录的日期，切换到新的时区，当前时区已经是UTC+14时将当前目录视为前一天
+	other := time.FixedZone("TZA", 14*3600)
+	if time.Now().In(other).Format(Layout) == today {
+		rotator.bucketDate = now.AddDate(0, 0, -1).Format(Layout)
+	}
+	setHostLocation(t, other)
+	assert.NoError(t, rotator.Rotate())
+	assert.Len(t, events, 3)
+	assert.True(t, strings.HasPrefix(events[2].Message, "adopt timezone"))
+	assert.Nil(t, rotator.pendingLoc)
+	assert.Equal(t, other, rotator.loc)
+	date := time.Now().In(other).Format(Layout)
+	assert.Equal(t, date, filepath.Base(filepath.Dir(rotator.f.Name())))
+	assert.Contains(t, filepath.Base(rotator.f.Name()), date)
+	assert.Equal(t, RotateReasonScheduled, rotator.scheduledReason())
+}
+
+func TestRotator_TimezoneEarlierDate(t *testing.T) {
+	setHostLocation(t, time.Local)
+	rotator, err := newRotator(t.TempDir(), "testdata.log", WithTimezoneTracking())
+	assert.NoError(t, err)
+	defer rotator.Close()
+
+	// 新时区的日期早于当前目录的日期，继续使用当前目录，日期不会回退
+	west := time.FixedZone("TZW", -12*3600)
+	rotator.writeLock.Lock()
+	rotator.pendingLoc = west
+	rotator.bucketDate = "99991231"
+	assert.Equal(t, "99991231", rotator.adoptTimezone())
+	assert.Equal(t, west, rotator.pendingLoc)
+
+	// 新时区的日期超过当前目录的日期之后切换
+	rotator.bucketDate = "20000101"
+	assert.Equal(t, time.Now().In(west).Format(Layout), rotator.adoptTimezone())
+	assert.Nil(t, rotator.pendingLoc)
+	assert.Equal(t, west, rotator.loc)
+	rotator.writeLock.Unlock()
+}
+
+func TestRotator_TimezoneTrackingDisabled(t *testing.T) {
+	setHostLocation(t, time.FixedZone("TZA", 14*3600))
+	rotator, err := newRotator(t.TempDir(), "testdata.log")
+	assert.NoError(t, err)
+	defer rotator.Close()
+
+	assert.NoError(t, rotator.Rotate())
+	assert.Nil(t, rotator.loc)
+	assert.Nil(t, rotator.pendingLoc)
+}
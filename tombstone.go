@@ -0,0 +1,261 @@
+// Copyright 2025 TimeWtr
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package vortexrotate
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+// TombstoneFileExt 墓碑清单文件的后缀名，清单位于存储目录下，名称为filename.tombstones
+const TombstoneFileExt = ".tombstones"
+
+// DeleteReason 文件被清理的原因
+type DeleteReason string
+
+const (
+	// DeleteMaxCount 文件数量超过了最大数量
+	DeleteMaxCount DeleteReason = "max_count"
+	// DeletePeriod 文件日期早于保存周期
+	DeletePeriod DeleteReason = "period"
+	// DeleteMaxAge 文件的保存时长超过了最大保存时长
+	DeleteMaxAge DeleteReason = "max_age"
+	// DeleteTier 分层保存策略中不需要保留的文件
+	DeleteTier DeleteReason = "tier"
+	// DeleteMaxTotalSize 文件总大小超过了限制
+	DeleteMaxTotalSize DeleteReason = "max_total_size"
+	// DeleteEmergency 磁盘可用空间不足时紧急清理
+	DeleteEmergency DeleteReason = "emergency"
+)
+
+// Tombstone 被保存策略删除的轮转文件的记录，用于审计时证明文件曾经存在以及被删除的原因
+type Tombstone struct {
+	// 轮转文件名称，不包括压缩等后缀
+	Name string `json:"name"`
+	// 删除的所有关联文件，相对于存储目录的路径
+	Files []string `json:"files"`
+	// 所有关联文件的总大小
+	Size int64 `json:"size"`
+	// 归档文件(没有压缩时为原始文件)的SHA-256校验和，十六进制编码，无法计算时为空
+	Checksum string `json:"checksum,omitempty"`
+	// 删除的时间
+	Deleted time.Time `json:"deleted"`
+	// 删除的原因
+	Reason DeleteReason `json:"reason"`
+}
+
+// WithTombstones 保存策略删除文件时在存储目录下的墓碑清单(filename.tombstones)中记录文件名称、大小、
+// 校验和、删除时间以及删除原因，retention为墓碑的保存时间，从删除时开始计算，0表示永久保存，过期的
+// 墓碑由清理任务删除。墓碑在删除文件之前追加到清单中并fsync，无法写入时不删除文件(磁盘空间不足时的
+// 紧急清理除外)。需要同时配置保存策略。
+func WithTombstones(retention time.Duration) Option {
+	return func(r *Rotator) error {
+		if retention < 0 {
+			return errors.New("tombstone retention must not be negative")
+		}
+
+		r.tombstones = true
+		r.tombstoneRetention = retention
+		return nil
+	}
+}
+
+// Tombstones 读取存储目录下墓碑清单中的所有记录，按照删除时间升序排列，filename为基础文件名称，
+// 格式与NewRotator一致，比如：app.log，清单不存在时返回nil
+func Tombstones(dir, filename string) ([]Tombstone, error) {
+	name, _, err := splitFilename(filename)
+	if err != nil {
+		return nil, err
+	}
+
+	dir, err = normalizeDir(dir)
+	if err != nil {
+		return nil, err
+	}
+
+	ts, err := readTombstones(filepath.Join(dir, name+TombstoneFileExt))
+	if os.IsNotExist(err) {
+		return nil, nil
+	}
+
+	return ts, err
+}
+
+// readTombstones 读取墓碑清单，每一行为一条JSON格式的记录
+func readTombstones(path string) ([]Tombstone, error) {
+	bs, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	var ts []Tombstone
+	sc := bufio.NewScanner(bytes.NewReader(bs))
+	sc.Buffer(nil, len(bs)+1)
+	for sc.Scan() {
+		line := bytes.TrimSpace(sc.Bytes())
+		if len(line) == 0 {
+			continue
+		}
+
+		var t Tombstone
+		if err = json.Unmarshal(line, &t); err != nil {
+			return nil, fmt.Errorf("parse tombstone file %s error: %w", path, err)
+		}
+		ts = append(ts, t)
+	}
+
+	return ts, sc.Err()
+}
+
+// writeTombstones 通过写临时文件+rename的方式原子更新墓碑清单
+func writeTombstones(path string, ts []Tombstone, rename renamer) error {
+	var buf bytes.Buffer
+	enc := json.NewEncoder(&buf)
+	for _, t := range ts {
+		if err := enc.Encode(t); err != nil {
+			return err
+		}
+	}
+
+	tmp := path + TmpFileExt
+	f, err := os.OpenFile(tmp, os.O_CREATE|os.O_WRONLY|os.O_TRUNC|openNoFollow, ReadWriteFile)
+	if err != nil {
+		return err
+	}
+	if _, err = f.Write(buf.Bytes()); err != nil {
+		_ = f.Close()
+		return err
+	}
+	if err = f.Sync(); err != nil {
+		_ = f.Close()
+		return err
+	}
+	if err = f.Close(); err != nil {
+		return err
+	}
+
+	return rename(tmp, path)
+}
+
+// tombstone 在删除文件之前生成墓碑记录，优先使用校验和文件中记录的校验和，没有时计算归档文件的校验和
+func (c *CleanUp) tombstone(fi FileInfo, reason DeleteReason) Tombstone {
+	t := Tombstone{
+		Name:    fi.Name,
+		Size:    fi.Size,
+		Deleted: time.Now(),
+		Reason:  reason,
+	}
+
+	var artifact string
+	for _, path := range fi.Files {
+		rel, err := filepath.Rel(c.dir, path)
+		if err != nil {
+			rel = path
+		}
+		t.Files = append(t.Files, rel)
+
+		switch {
+		case strings.HasSuffix(path, ChecksumFileExt):
+			if sum, err := readChecksum(strings.TrimSuffix(path, ChecksumFileExt)); err == nil {
+				t.Checksum = hex.EncodeToString(sum)
+			}
+		case strings.HasSuffix(path, DoneFileExt):
+		case len(filepath.Base(path)) > len(filepath.Base(artifact)):
+			// 压缩、加密之后的文件名称更长
+			artifact = path
+		}
+	}
+
+	if t.Checksum == "" && artifact != "" {
+		if sum, err := fileChecksum(artifact); err == nil {
+			t.Checksum = hex.EncodeToString(sum)
+		} else {
+			c.reportError(fmt.Errorf("checksum of file %s error: %w", artifact, err))
+		}
+	}
+
+	return t
+}
+
+// tombstonePath 墓碑清单的路径
+func (c *CleanUp) tombstonePath() string {
+	return filepath.Join(c.dir, c.filename+TombstoneFileExt)
+}
+
+// appendTombstones 在删除文件之前将墓碑追加到清单的末尾并fsync，进程在删除的过程中崩溃时不会丢失
+// 删除记录，必须持有目录锁
+func (c *CleanUp) appendTombstones(ts ...Tombstone) error {
+	var buf bytes.Buffer
+	enc := json.NewEncoder(&buf)
+	for _, t := range ts {
+		if err := enc.Encode(t); err != nil {
+			return err
+		}
+	}
+
+	path := c.tombstonePath()
+	if err := appendDurable(path, buf.Bytes()); err != nil {
+		return fmt.Errorf("append tombstone file %s error: %w", path, err)
+	}
+
+	return nil
+}
+
+// expireTombstones 删除清单中过期的墓碑，只在存在过期的墓碑时重写清单
+func (c *CleanUp) expireTombstones() {
+	if !c.tombstones || c.tombstoneRetention == 0 {
+		return
+	}
+
+	if err := c.dirLock.Lock(); err != nil {
+		c.reportError(fmt.Errorf("lock dir %s error: %w", c.dir, err))
+		return
+	}
+	defer func() {
+		_ = c.dirLock.Unlock()
+	}()
+
+	path := c.tombstonePath()
+	old, err := readTombstones(path)
+	if err != nil {
+		if !os.IsNotExist(err) {
+			c.reportError(fmt.Errorf("read tombstone file %s error: %w", path, err))
+		}
+		return
+	}
+
+	deadline := time.Now().Add(-c.tombstoneRetention)
+	kept := make([]Tombstone, 0, len(old))
+	for _, t := range old {
+		if !t.Deleted.Before(deadline) {
+			kept = append(kept, t)
+		}
+	}
+	if len(kept) == len(old) {
+		return
+	}
+
+	if err = writeTombstones(path, kept, c.rename); err != nil {
+		c.reportError(fmt.Errorf("write tombstone file %s error: %w", path, err))
+	}
+}
@@ -0,0 +1,107 @@
+// Copyright 2025 TimeWtr
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package vortexrotate
+
+import (
+	"fmt"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestCleanUp_ExpireReasons(t *testing.T) {
+	now := time.Date(2025, 3, 10, 12, 0, 0, 0, time.Local)
+	files := make([]FileInfo, 0, 10)
+	for i := 1; i <= 10; i++ {
+		date := time.Date(2025, 3, i, 0, 0, 0, 0, time.UTC)
+		files = append(files, FileInfo{
+			Name:     fmt.Sprintf("app_%s_%04d.log", date.Format(Layout), i),
+			Date:     date,
+			Sequence: int64(i),
+			Size:     100,
+			ModTime:  date.Add(23 * time.Hour),
+		})
+	}
+
+	c := NewFileCountCleanUp(t.TempDir(), "app", 8, 0)
+	c.maxAge = 72 * time.Hour
+	c.maxTotalSize = 250
+	got, reasons := c.expire(files, now)
+	assert.Equal(t, files[:8], got)
+	assert.Equal(t, []DeleteReason{
+		DeleteMaxCount, DeleteMaxCount,
+		DeleteMaxAge, DeleteMaxAge, DeleteMaxAge, DeleteMaxAge,
+		DeleteMaxTotalSize, DeleteMaxTotalSize,
+	}, reasons)
+}
+
+func TestRotator_Tombstones(t *testing.T) {
+	dir := t.TempDir()
+	rotator, err := newRotator(dir, "testdata.log",
+		WithRotate(64, _Second),
+		WithMaxCount(2),
+		WithChecksum(),
+		WithTombstones(0),
+	)
+	assert.NoError(t, err)
+
+	for i := 0; i < 10; i++ {
+		_, err = rotator.Write([]byte(fmt.Sprintf("line %02d, rotate by size\n", i)))
+		assert.NoError(t, err)
+	}
+	assert.NoError(t, rotator.CleanNow())
+	assert.NoError(t, rotator.Close())
+
+	ts, err := Tombstones(dir, "testdata.log")
+	assert.NoError(t, err)
+	assert.NotEmpty(t, ts)
+	for _, tomb := range ts {
+		assert.Equal(t, DeleteMaxCount, tomb.Reason)
+		assert.Len(t, tomb.Checksum, 64)
+		assert.Positive(t, tomb.Size)
+		assert.False(t, tomb.Deleted.IsZero())
+		for _, fn := range tomb.Files {
+			assert.NoFileExists(t, filepath.Join(dir, fn))
+		}
+	}
+}
+
+func TestCleanUp_ExpireTombstones(t *testing.T) {
+	dir := t.TempDir()
+	c := NewFileCountCleanUp(dir, "app", 0, 0)
+	c.tombstones = true
+	c.tombstoneRetention = time.Hour
+
+	path := filepath.Join(dir, "app"+TombstoneFileExt)
+	fresh := Tombstone{Name: "app_20250302_0002.log", Deleted: time.Now().Truncate(time.Second), Reason: DeleteMaxAge}
+	assert.NoError(t, writeTombstones(path, []Tombstone{
+		{Name: "app_20250301_0001.log", Deleted: time.Now().Add(-2 * time.Hour), Reason: DeleteMaxCount},
+		fresh,
+	}, renameLocal))
+
+	c.expireTombstones()
+	ts, err := Tombstones(dir, "app.log")
+	assert.NoError(t, err)
+	assert.Len(t, ts, 1)
+	assert.Equal(t, fresh.Name, ts[0].Name)
+	assert.True(t, fresh.Deleted.Equal(ts[0].Deleted))
+
+	// 不存在的清单
+	ts, err = Tombstones(t.TempDir(), "app.log")
+	assert.NoError(t, err)
+	assert.Nil(t, ts)
+}
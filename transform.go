@@ -0,0 +1,380 @@
+// Copyright 2025 TimeWtr
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package vortexrotate
+
+import (
+	"bytes"
+	"fmt"
+	"regexp"
+	"strconv"
+	"time"
+)
+
+// Transformer 写入内容的转换函数，在写入文件之前对内容进行处理，比如添加主机名/时间戳前缀、
+// 去除ANSI颜色代码等，返回空切片表示丢弃本次写入的内容。转换函数在写锁内按照注册的顺序
+// 依次调用，可以安全的保存跨写入的状态，但不能修改或者持有传入的切片。
+type Transformer func(p []byte) []byte
+
+// WithTransformers 注册写入内容的转换函数链，调用方不需要再额外包装一层Writer，多次调用时
+// 按照调用顺序追加。
+func WithTransformers(fns ...Transformer) Option {
+	return func(r *Rotator) error {
+		for _, fn := range fns {
+			if fn != nil {
+				r.transformers = append(r.transformers, fn)
+			}
+		}
+		return nil
+	}
+}
+
+// transformFlusher 暂存了内容的转换函数，关闭时需要将暂存的内容写入文件
+type transformFlusher struct {
+	// 在转换函数链中的位置
+	index int
+	// 返回暂存的内容
+	flush func() []byte
+}
+
+// addFlushTransformer 注册暂存了内容的内置转换函数
+func (r *Rotator) addFlushTransformer(fn Transformer, flush func() []byte) {
+	r.flushers = append(r.flushers, transformFlusher{index: len(r.transformers), flush: flush})
+	r.transformers = append(r.transformers, fn)
+}
+
+// flushTransformers 按照转换函数链的顺序将暂存的内容经过后续的转换函数写入文件，必须持有写锁
+func (r *Rotator) flushTransformers() error {
+	if r.f == nil {
+		return nil
+	}
+
+	for _, fl := range r.flushers {
+		data := r.transformFrom(fl.index+1, fl.flush())
+		if len(data) == 0 {
+			continue
+		}
+
+		if _, err := r.write(data); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// transform 依次执行所有的转换函数
+func (r *Rotator) transform(p []byte) []byte {
+	return r.transformFrom(0, p)
+}
+
+// transformFrom 从第start个转换函数开始依次执行
+func (r *Rotator) transformFrom(start int, p []byte) []byte {
+	for _, fn := range r.transformers[start:] {
+		if len(p) == 0 {
+			return p
+		}
+		p = fn(p)
+	}
+
+	return p
+}
+
+// ansiRegexp 匹配ANSI转义序列，包括CSI序列(颜色、光标控制等)、OSC序列(终端标题、超链接等)
+// 以及其他两个字节的转义序列
+var ansiRegexp = regexp.MustCompile(
+	`\x1b\[[0-?]*[ -/]*[@-~]|\x1b\][^\x07\x1b]*(?:\x07|\x1b\\)|\x1b[@-Z\\-_]`)
+
+// StripANSI 内置的转换函数，去除写入内容中的ANSI转义序列(比如颜色代码)，避免转义序列
+// 污染轮转文件以及影响下游的解析，使用方式：WithTransformers(StripANSI)
+func StripANSI(p []byte) []byte {
+	if bytes.IndexByte(p, 0x1b) < 0 {
+		return p
+	}
+
+	return ansiRegexp.ReplaceAll(p, nil)
+}
+
+// TimestampPrefix 创建在每一行开头添加RFC3339Nano格式时间戳的转换函数，适用于上游日志
+// 没有包含时间戳的场景，一次写入包含多行时每一行都会添加时间戳，一行内容被拆分成多次写入时
+// 只在行首添加一次。返回的转换函数是有状态的，每个轮转器需要单独创建，使用方式：
+// WithTransformers(TimestampPrefix())
+func TimestampPrefix() Transformer {
+	return timestampPrefix(time.Now)
+}
+
+func timestampPrefix(now func() time.Time) Transformer {
+	// 下一次写入是否从新的一行开始
+	lineStart := true
+	return func(p []byte) []byte {
+		ts := now().AppendFormat(nil, time.RFC3339Nano)
+		ts = append(ts, ' ')
+
+		out := make([]byte, 0, len(p)+len(ts)*(bytes.Count(p, []byte{'\n'})+1))
+		for len(p) > 0 {
+			if lineStart {
+				out = append(out, ts...)
+			}
+
+			idx := bytes.IndexByte(p, '\n')
+			if idx < 0 {
+				out = append(out, p...)
+				lineStart = false
+				break
+			}
+
+			out = append(out, p[:idx+1]...)
+			p = p[idx+1:]
+			lineStart = true
+		}
+
+		return out
+	}
+}
+
+// WithDedupLines 开启连续重复行的折叠，连续写入的相同的行只保留第一行，后续重复的行替换为
+// "last message repeated N times"，可以大幅减少异常情况下刷屏日志占用的空间，同时保留准确的
+// 重复次数。折叠以完整的行为单位，没有换行符结尾的内容会暂存到下一次写入或者关闭时再写入。
+// 折叠作为转换函数链中的一环，与WithTransformers的调用顺序决定了执行顺序。
+func WithDedupLines() Option {
+	return func(r *Rotator) error {
+		if r.dedup != nil {
+			return nil
+		}
+
+		r.dedup = &lineDedup{}
+		r.addFlushTransformer(r.dedup.transform, r.dedup.flush)
+		return nil
+	}
+}
+
+// lineDedup 连续重复行的折叠状态
+type lineDedup struct {
+	// 上一次写入的行，包括换行符
+	last []byte
+	// 上一行连续重复的次数
+	repeats int
+	// 暂存的没有换行符结尾的内容
+	partial []byte
+}
+
+func (d *lineDedup) transform(p []byte) []byte {
+	out := make([]byte, 0, len(p))
+	for len(p) > 0 {
+		idx := bytes.IndexByte(p, '\n')
+		if idx < 0 {
+			d.partial = append(d.partial, p...)
+			break
+		}
+
+		line := p[:idx+1]
+		if len(d.partial) > 0 {
+			line = append(d.partial, line...)
+			d.partial = nil
+		}
+		p = p[idx+1:]
+
+		if d.last != nil && bytes.Equal(line, d.last) {
+			d.repeats++
+			continue
+		}
+
+		out = d.appendRepeated(out)
+		out = append(out, line...)
+		d.last = append(d.last[:0], line...)
+	}
+
+	return out
+}
+
+// flush 返回暂存的重复次数和没有换行符结尾的内容
+func (d *lineDedup) flush() []byte {
+	out := d.appendRepeated(nil)
+	out = append(out, d.partial...)
+	d.partial = nil
+	d.last = nil
+
+	return out
+}
+
+func (d *lineDedup) appendRepeated(out []byte) []byte {
+	if d.repeats == 0 {
+		return out
+	}
+
+	out = append(out, "last message repeated "...)
+	out = strconv.AppendInt(out, int64(d.repeats), 10)
+	out = append(out, " times\n"...)
+	d.repeats = 0
+	return out
+}
+
+// WithMaxLineLength 设置单行内容的最大长度(不包括换行符)，超过长度的部分被截断，并在行尾添加
+// "[truncated N bytes]"标记，防止意外写入的二进制内容等超长的行影响下游的解析以及轮转大小的
+// 计算。一行被拆分成多次写入时累计计算长度，截断标记在换行符写入或者关闭时添加。
+func WithMaxLineLength(maxLen int) Option {
+	return func(r *Rotator) error {
+		if maxLen <= 0 {
+			return fmt.Errorf("max line length %d must be positive", maxLen)
+		}
+
+		lt := &lineTruncator{maxLen: maxLen}
+		r.addFlushTransformer(lt.transform, lt.flush)
+		return nil
+	}
+}
+
+// lineTruncator 超长行的截断状态
+type lineTruncator struct {
+	// 单行的最大长度
+	maxLen int
+	// 当前行已经写入的长度
+	cur int
+	// 当前行被截断的字节数
+	truncated int
+}
+
+func (t *lineTruncator) transform(p []byte) []byte {
+	if t.truncated == 0 && t.cur+len(p) <= t.maxLen && bytes.IndexByte(p, '\n') < 0 {
+		t.cur += len(p)
+		return p
+	}
+
+	out := make([]byte, 0, min(len(p), t.maxLen+64))
+	for len(p) > 0 {
+		idx := bytes.IndexByte(p, '\n')
+		content := p
+		if idx >= 0 {
+			content = p[:idx]
+		}
+
+		keep := min(len(content), t.maxLen-t.cur)
+		out = append(out, content[:keep]...)
+		t.cur += keep
+		t.truncated += len(content) - keep
+		if idx < 0 {
+			break
+		}
+
+		out = t.appendMarker(out)
+		out = append(out, '\n')
+		t.cur = 0
+		p = p[idx+1:]
+	}
+
+	return out
+}
+
+// flush 关闭时为没有换行符结尾的被截断的行添加截断标记
+func (t *lineTruncator) flush() []byte {
+	out := t.appendMarker(nil)
+	t.cur = 0
+	return out
+}
+
+func (t *lineTruncator) appendMarker(out []byte) []byte {
+	if t.truncated == 0 {
+		return out
+	}
+
+	out = append(out, " [truncated "...)
+	out = strconv.AppendInt(out, int64(t.truncated), 10)
+	out = append(out, " bytes]"...)
+	t.truncated = 0
+	return out
+}
+
+// LineEnding 写入文件的换行符
+type LineEnding int
+
+const (
+	// LineEndingLF 使用\n换行
+	LineEndingLF LineEnding = iota + 1
+	// LineEndingCRLF 使用\r\n换行，适用于Windows下的工具读取
+	LineEndingCRLF
+)
+
+func (le LineEnding) String() string {
+	switch le {
+	case LineEndingLF:
+		return "lf"
+	case LineEndingCRLF:
+		return "crlf"
+	default:
+		return "unknown"
+	}
+}
+
+// WithLineEnding 统一写入文件的换行符，无论写入的内容使用\n还是\r\n换行，都转换为指定的换行符，
+// 单独的\r保持不变。\r\n被拆分到两次写入时同样可以正确识别。
+func WithLineEnding(le LineEnding) Option {
+	return func(r *Rotator) error {
+		if le != LineEndingLF && le != LineEndingCRLF {
+			return fmt.Errorf("line ending %d not support", le)
+		}
+
+		n := &lineEndingNormalizer{crlf: le == LineEndingCRLF}
+		r.addFlushTransformer(n.transform, n.flush)
+		return nil
+	}
+}
+
+// lineEndingNormalizer 换行符的转换状态
+type lineEndingNormalizer struct {
+	// 是否转换为\r\n
+	crlf bool
+	// 上一次写入是否以\r结尾
+	pendingCR bool
+}
+
+func (n *lineEndingNormalizer) transform(p []byte) []byte {
+	if !n.pendingCR && bytes.IndexByte(p, '\r') < 0 && (!n.crlf || bytes.IndexByte(p, '\n') < 0) {
+		return p
+	}
+
+	out := make([]byte, 0, len(p)+bytes.Count(p, []byte{'\n'}))
+	for _, b := range p {
+		switch b {
+		case '\r':
+			if n.pendingCR {
+				out = append(out, '\r')
+			}
+			n.pendingCR = true
+		case '\n':
+			n.pendingCR = false
+			if n.crlf {
+				out = append(out, '\r')
+			}
+			out = append(out, '\n')
+		default:
+			if n.pendingCR {
+				out = append(out, '\r')
+				n.pendingCR = false
+			}
+			out = append(out, b)
+		}
+	}
+
+	return out
+}
+
+// flush 关闭时写入暂存的\r
+func (n *lineEndingNormalizer) flush() []byte {
+	if !n.pendingCR {
+		return nil
+	}
+
+	n.pendingCR = false
+	return []byte{'\r'}
+}
@@ -0,0 +1,213 @@
+// Copyright 2025 TimeWtr
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package vortexrotate
+
+import (
+	"bytes"
+	"io"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// readSegments 按照顺序读取目录中所有轮转文件的内容
+func readSegments(t *testing.T, dir string) string {
+	ro, err := OpenReadOnly(dir, "testdata.log")
+	assert.NoError(t, err)
+	segments, err := ro.List()
+	assert.NoError(t, err)
+
+	var buf bytes.Buffer
+	for _, seg := range segments {
+		rc, err := ro.Open(seg)
+		assert.NoError(t, err)
+		_, err = io.Copy(&buf, rc)
+		assert.NoError(t, err)
+		assert.NoError(t, rc.Close())
+	}
+
+	return buf.String()
+}
+
+func TestRotator_Transformers(t *testing.T) {
+	dir := t.TempDir()
+	rotator, err := newRotator(dir, "testdata.log",
+		WithTransformers(
+			func(p []byte) []byte {
+				if bytes.HasPrefix(p, []byte("DROP")) {
+					return nil
+				}
+				return p
+			},
+			func(p []byte) []byte {
+				return append([]byte("host-1 "), p...)
+			},
+		))
+	assert.NoError(t, err)
+
+	for _, line := range []string{"line 1\n", "DROP line 2\n", "line 3\n"} {
+		n, err := rotator.Write([]byte(line))
+		assert.NoError(t, err)
+		assert.Equal(t, len(line), n)
+	}
+	assert.NoError(t, rotator.Close())
+
+	assert.Equal(t, "host-1 line 1\nhost-1 line 3\n", readSegments(t, dir))
+}
+
+func TestStripANSI(t *testing.T) {
+	testCases := []struct {
+		name  string
+		input string
+		want  string
+	}{
+		{name: "plain", input: "hello world\n", want: "hello world\n"},
+		{name: "color", input: "\x1b[31mERROR\x1b[0m something failed\n", want: "ERROR something failed\n"},
+		{name: "bold color", input: "\x1b[1;32mINFO\x1b[m ok\n", want: "INFO ok\n"},
+		{name: "cursor", input: "\x1b[2K\x1b[1Gprogress 50%\n", want: "progress 50%\n"},
+		{name: "osc title", input: "\x1b]0;title\x07text\n", want: "text\n"},
+		{name: "osc st", input: "\x1b]8;;http://a\x1b\\link\x1b]8;;\x1b\\\n", want: "link\n"},
+		{name: "two bytes", input: "\x1bMline\n", want: "line\n"},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			input := []byte(tc.input)
+			assert.Equal(t, tc.want, string(StripANSI(input)))
+			assert.Equal(t, tc.input, string(input))
+		})
+	}
+}
+
+func TestTimestampPrefix(t *testing.T) {
+	now := time.Date(2025, 3, 1, 8, 30, 0, 123456789, time.UTC)
+	prefix := now.Format(time.RFC3339Nano) + " "
+	fn := timestampPrefix(func() time.Time { return now })
+
+	// 单行写入
+	assert.Equal(t, prefix+"line 1\n", string(fn([]byte("line 1\n"))))
+	// 多行写入，每一行都添加时间戳
+	assert.Equal(t, prefix+"line 2\n"+prefix+"line 3\n", string(fn([]byte("line 2\nline 3\n"))))
+	// 一行被拆分成多次写入，只在行首添加时间戳
+	assert.Equal(t, prefix+"line 4 ", string(fn([]byte("line 4 "))))
+	assert.Equal(t, "part 2\n"+prefix+"line 5", string(fn([]byte("part 2\nline 5"))))
+	assert.Equal(t, "\n", string(fn([]byte("\n"))))
+	// 空行
+	assert.Equal(t, prefix+"\n", string(fn([]byte("\n"))))
+}
+
+func TestRotator_DedupLines(t *testing.T) {
+	dir := t.TempDir()
+	rotator, err := newRotator(dir, "testdata.log",
+		WithDedupLines(),
+		WithTransformers(bytes.ToUpper))
+	assert.NoError(t, err)
+
+	for _, data := range []string{"a\n", "a\n", "a\nb\n", "b\n", "b", "\nc\n", "d"} {
+		n, err := rotator.Write([]byte(data))
+		assert.NoError(t, err)
+		assert.Equal(t, len(data), n)
+	}
+	assert.NoError(t, rotator.Close())
+
+	want := "A\n" +
+		"LAST MESSAGE REPEATED 2 TIMES\n" +
+		"B\n" +
+		"LAST MESSAGE REPEATED 2 TIMES\n" +
+		"C\n" +
+		"D"
+	assert.Equal(t, want, readSegments(t, dir))
+}
+
+func TestLineTruncator(t *testing.T) {
+	lt := &lineTruncator{maxLen: 5}
+	assert.Equal(t, "abc\n", string(lt.transform([]byte("abc\n"))))
+	assert.Equal(t, "abcde [truncated 3 bytes]\nxy\n", string(lt.transform([]byte("abcdefgh\nxy\n"))))
+	// 一行被拆分成多次写入
+	assert.Equal(t, "abc", string(lt.transform([]byte("abc"))))
+	assert.Equal(t, "de", string(lt.transform([]byte("defg"))))
+	assert.Equal(t, "", string(lt.transform([]byte("hij"))))
+	assert.Equal(t, " [truncated 5 bytes]\n", string(lt.transform([]byte("\n"))))
+	// 关闭时没有换行符结尾的被截断的行
+	assert.Equal(t, "12345", string(lt.transform([]byte("1234567"))))
+	assert.Equal(t, " [truncated 2 bytes]", string(lt.flush()))
+	assert.Equal(t, "", string(lt.flush()))
+}
+
+func TestRotator_MaxLineLength(t *testing.T) {
+	dir := t.TempDir()
+	_, err := newRotator(dir, "testdata.log", WithMaxLineLength(0))
+	assert.Error(t, err)
+
+	rotator, err := newRotator(dir, "testdata.log", WithMaxLineLength(8), WithDedupLines())
+	assert.NoError(t, err)
+	for _, data := range []string{"short\n", strings.Repeat("x", 100) + "\n", "12345678901"} {
+		n, err := rotator.Write([]byte(data))
+		assert.NoError(t, err)
+		assert.Equal(t, len(data), n)
+	}
+	assert.NoError(t, rotator.Close())
+
+	want := "short\n" +
+		"xxxxxxxx [truncated 92 bytes]\n" +
+		"12345678 [truncated 3 bytes]"
+	assert.Equal(t, want, readSegments(t, dir))
+}
+
+func TestLineEndingNormalizer(t *testing.T) {
+	testCases := []struct {
+		name   string
+		crlf   bool
+		inputs []string
+		want   string
+	}{
+		{name: "lf to crlf", crlf: true, inputs: []string{"a\nb\n"}, want: "a\r\nb\r\n"},
+		{name: "crlf to crlf", crlf: true, inputs: []string{"a\r\nb\n"}, want: "a\r\nb\r\n"},
+		{name: "crlf to lf", inputs: []string{"a\r\nb\r\n"}, want: "a\nb\n"},
+		{name: "lf to lf", inputs: []string{"a\nb\n"}, want: "a\nb\n"},
+		{name: "split crlf to lf", inputs: []string{"a\r", "\nb\r", "\n"}, want: "a\nb\n"},
+		{name: "split crlf to crlf", crlf: true, inputs: []string{"a\r", "\nb"}, want: "a\r\nb"},
+		{name: "lone cr", inputs: []string{"a\rb\r\r\n", "c\r"}, want: "a\rb\r\nc\r"},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			n := &lineEndingNormalizer{crlf: tc.crlf}
+			var out []byte
+			for _, in := range tc.inputs {
+				out = append(out, n.transform([]byte(in))...)
+			}
+			out = append(out, n.flush()...)
+			assert.Equal(t, tc.want, string(out))
+		})
+	}
+}
+
+func TestRotator_LineEnding(t *testing.T) {
+	dir := t.TempDir()
+	_, err := newRotator(dir, "testdata.log", WithLineEnding(LineEnding(0)))
+	assert.Error(t, err)
+
+	rotator, err := newRotator(dir, "testdata.log", WithLineEnding(LineEndingCRLF))
+	assert.NoError(t, err)
+	for _, data := range []string{"line 1\n", "line 2\r", "\nline 3\n"} {
+		_, err = rotator.Write([]byte(data))
+		assert.NoError(t, err)
+	}
+	assert.NoError(t, rotator.Close())
+	assert.Equal(t, "line 1\r\nline 2\r\nline 3\r\n", readSegments(t, dir))
+}
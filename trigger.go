@@ -0,0 +1,56 @@
+// Copyright 2025 TimeWtr
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package vortexrotate
+
+import (
+	"os"
+	"path/filepath"
+	"time"
+)
+
+const (
+	// TriggerFileName 触发立即轮转的文件名称
+	TriggerFileName = ".rotate-now"
+	// TriggerCheckInterval 检查触发文件的时间间隔，网络文件系统上使用NetworkFSCheckInterval
+	TriggerCheckInterval = time.Second
+)
+
+// WithRotateTrigger 开启触发文件轮转，当存储目录中出现名为.rotate-now的文件时立即执行轮转并删除
+// 触发文件，运维人员在无法发送信号或者访问HTTP接口的环境中(比如通过kubectl cp)可以借此强制轮转。
+func WithRotateTrigger() Option {
+	return func(r *Rotator) error {
+		r.rotateTrigger = true
+		return nil
+	}
+}
+
+// checkTrigger 定时检查触发文件，文件存在时删除触发文件并立即执行轮转
+func (r *Rotator) checkTrigger() {
+	path := filepath.Join(r.dir, TriggerFileName)
+	if _, err := os.Lstat(path); err != nil {
+		return
+	}
+
+	// 先删除触发文件，防止轮转失败时重复触发
+	if err := os.Remove(path); err != nil {
+		r.l.Printf("failed to remove trigger file %s, cause: %v", path, err)
+		return
+	}
+
+	r.l.Printf("trigger file %s found, rotate now", path)
+	if err := r.rotateNow(); err != nil {
+		r.l.Printf("trigger rotate error: %v", err)
+	}
+}
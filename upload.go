@@ -0,0 +1,334 @@
+// Copyright 2025 TimeWtr
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package vortexrotate
+
+import (
+	"context"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/TimeWtr/vortexrotate/errorx"
+)
+
+const (
+	// UploadPendingExt 等待上传的标记文件的后缀名，内容为压缩之前的源文件名称
+	UploadPendingExt = ".upload"
+	// DefaultUploadQueueSize 后台上传队列的长度，队列已满时文件等待reconcile上传
+	DefaultUploadQueueSize = 64
+	// UploadAttempts 每一轮上传的最大尝试次数
+	UploadAttempts = 3
+	// DefaultUploadBackoff 上传失败之后第一次重试的等待时间，之后每次翻倍
+	DefaultUploadBackoff = time.Second
+)
+
+// uploadJob 等待上传的文件
+type uploadJob struct {
+	// 封存完成的文件
+	path string
+	// 压缩之前的源文件，移动到完成目录时保留的源文件一起移动
+	source string
+	// 文件的校验和，nil时写入归档清单时重新计算
+	sum []byte
+}
+
+// startUploader 创建上传使用的上下文，非同步模式下启动后台上传的goroutine
+func (r *Rotator) startUploader() {
+	parent := r.ctx
+	if parent == nil {
+		parent = context.Background()
+	}
+	r.uploadCtx, r.stopUpload = context.WithCancel(parent)
+	if r.uploadBackoff == 0 {
+		r.uploadBackoff = DefaultUploadBackoff
+	}
+	if r.synchronous {
+		return
+	}
+
+	r.uploadQueue = make(chan uploadJob, DefaultUploadQueueSize)
+	r.uploadWG.Add(1)
+	go r.uploadLoop()
+}
+
+// queueUpload 写入.upload标记之后上传封存完成的文件，必须持有目录锁。同步模式下直接上传，
+// 否则放入后台上传队列
+func (r *Rotator) queueUpload(job uploadJob) error {
+	marker := job.path + UploadPendingExt
+	if err := writeFileAtomic(marker, []byte(filepath.Base(job.source)), r.rename); err != nil {
+		return err
+	}
+	if r.synchronous {
+		return r.uploadNow(job)
+	}
+
+	if !r.enqueueUpload(job) {
+		r.l.Printf("upload queue is full or closed, %s will be uploaded by reconcile", job.path)
+	}
+	return nil
+}
+
+// enqueueUpload 将文件放入后台上传队列，队列已满或者已经关闭时返回false，已经在队列中的文件
+// 不重复放入
+func (r *Rotator) enqueueUpload(job uploadJob) bool {
+	r.uploadLock.Lock()
+	defer r.uploadLock.Unlock()
+
+	if r.uploadClosed {
+		return false
+	}
+	if r.uploadAges.contains(job.path) {
+		return true
+	}
+
+	// 先记录进入队列的时间，保证后台goroutine处理完成时可以删除
+	r.uploadAges.add(job.path)
+	select {
+	case r.uploadQueue <- job:
+		return true
+	default:
+		r.uploadAges.done(job.path)
+		return false
+	}
+}
+
+// uploadLoop 依次上传队列中的文件，队列关闭之后处理完剩余的文件再退出
+func (r *Rotator) uploadLoop() {
+	defer r.uploadWG.Done()
+
+	for job := range r.uploadQueue {
+		// 轮转器关闭之后剩余的文件保留.upload标记，下一次启动时上传
+		if r.uploadCtx.Err() == nil && r.uploadRetry(job) == nil {
+			r.finishUploadLocked(job)
+		}
+		r.uploadAges.done(job.path)
+	}
+}
+
+// uploadRetry 上传文件，失败时等待之后重试，轮转器关闭之后不再重试。全部失败时发送事件，
+// 保留.upload标记等待reconcile重新上传，因为轮转器关闭而中断时不发送事件
+func (r *Rotator) uploadRetry(job uploadJob) error {
+	backoff := r.uploadBackoff
+	var err error
+	for i := 0; i < UploadAttempts; i++ {
+		if err = r.upload(job.path); err == nil {
+			return nil
+		}
+		if i == UploadAttempts-1 || r.sig.Load() == 1 {
+			break
+		}
+
+		timer := time.NewTimer(backoff)
+		select {
+		case <-timer.C:
+		case <-r.done:
+			timer.Stop()
+		case <-r.uploadCtx.Done():
+			timer.Stop()
+		}
+		if r.sig.Load() == 1 || r.uploadCtx.Err() != nil {
+			break
+		}
+		backoff *= 2
+	}
+
+	if r.uploadCtx.Err() == nil {
+		r.uploadFailed(job, err)
+	}
+	return err
+}
+
+// uploadNow 上传一次文件，成功之后写入完成标记，失败时发送事件，必须持有目录锁
+func (r *Rotator) uploadNow(job uploadJob) error {
+	if err := r.upload(job.path); err != nil {
+		r.uploadFailed(job, err)
+		return nil
+	}
+
+	return r.finishUpload(job)
+}
+
+// upload 执行一次上传，开启pprof标签时为上传任务添加标签
+func (r *Rotator) upload(path string) error {
+	var err error
+	r.profile(ProfileUpload, func() {
+		err = r.uploadFile(path)
+	})
+
+	return err
+}
+
+// uploadFile 执行一次上传，使用随轮转器关闭而取消的上下文
+func (r *Rotator) uploadFile(path string) error {
+	ctx, cancel := context.WithTimeout(r.uploadCtx, DefaultUploadTimeout)
+	defer cancel()
+
+	err := r.faults.uploadFault()
+	if err == nil {
+		err = r.uploader.Upload(ctx, path)
+	}
+	if err != nil {
+		return fmt.Errorf("%w: %s: %w", errorx.ErrUploadFailed, path, err)
+	}
+
+	r.uploads.Add(1)
+	return nil
+}
+
+// uploadFailed 记录上传失败并发送事件，以上传错误结束源文件的封存状态
+func (r *Rotator) uploadFailed(job uploadJob, err error) {
+	r.uploadFailures.Add(1)
+	r.tracker.finish(job.source, err)
+	r.emit(Event{
+		Type:    EventUploadFailed,
+		Path:    job.path,
+		Message: err.Error(),
+		Err:     err,
+	})
+}
+
+// finishUploadLocked 获取目录锁之后完成上传之后的封存流程
+func (r *Rotator) finishUploadLocked(job uploadJob) {
+	if err := r.dirLock.Lock(); err != nil {
+		r.l.Printf("failed to lock dir for %s, cause: %v", job.path, err)
+		return
+	}
+	defer func() {
+		_ = r.dirLock.Unlock()
+	}()
+
+	if err := r.finishUpload(job); err != nil {
+		r.l.Printf("failed to finish upload of %s, cause: %v", job.path, err)
+	}
+}
+
+// finishUpload 上传成功之后写入完成标记和归档清单，最后删除.upload标记并结束源文件的封存状态，
+// 必须持有目录锁
+func (r *Rotator) finishUpload(job uploadJob) (err error) {
+	defer func() {
+		r.tracker.finish(job.source, err)
+	}()
+
+	if err = r.markDone(job.path, job.source); err != nil {
+		return err
+	}
+	if err = r.recordManifest(r.donePath(job.path), job.sum); err != nil {
+		return err
+	}
+
+	if err = os.Remove(job.path + UploadPendingExt); err != nil && !os.IsNotExist(err) {
+		return err
+	}
+	return nil
+}
+
+// retryUploads 重新上传存储目录中带有.upload标记的文件，文件已经不存在(比如已经被清理)时
+// 删除标记
+func (r *Rotator) retryUploads() {
+	re := segmentRegexp(r.filename)
+	var jobs []uploadJob
+	err := filepath.WalkDir(r.dir, func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if isQuarantineDir(r.dir, path, d) {
+			return filepath.SkipDir
+		}
+		if d.IsDir() || !strings.HasSuffix(d.Name(), UploadPendingExt) || !re.MatchString(d.Name()) {
+			return nil
+		}
+
+		artifact := strings.TrimSuffix(path, UploadPendingExt)
+		if _, err := os.Lstat(artifact); os.IsNotExist(err) {
+			_ = os.Remove(path)
+			return nil
+		}
+		source, err := os.ReadFile(path)
+		if err != nil || len(source) == 0 {
+			source = []byte(filepath.Base(artifact))
+		}
+		jobs = append(jobs, uploadJob{
+			path:   artifact,
+			source: filepath.Join(filepath.Dir(artifact), filepath.Base(string(source))),
+		})
+		return nil
+	})
+	if err != nil {
+		r.l.Printf("retry uploads: walk dir %s error: %v", r.dir, err)
+	}
+
+	for _, job := range jobs {
+		if r.sig.Load() == 1 {
+			return
+		}
+		if !r.synchronous {
+			r.enqueueUpload(job)
+			continue
+		}
+
+		if err = r.dirLock.Lock(); err != nil {
+			r.l.Printf("retry uploads: lock dir error: %v", err)
+			return
+		}
+		if err = r.uploadNow(job); err != nil {
+			r.l.Printf("retry uploads: finish upload of %s error: %v", job.path, err)
+		}
+		_ = r.dirLock.Unlock()
+	}
+}
+
+// awaitUploads 等待后台上传队列中的文件全部处理完成
+func (r *Rotator) awaitUploads(ctx context.Context) error {
+	ticker := time.NewTicker(10 * time.Millisecond)
+	defer ticker.Stop()
+
+	for r.uploadAges.size() > 0 {
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-ticker.C:
+		}
+	}
+
+	return nil
+}
+
+// drainUploader 关闭上传队列，等待后台goroutine将队列中剩余的文件(包括关闭时封存的文件)各上传
+// 一次之后取消上传的上下文。Shutdown的ctx到期时通过cancelUploads提前取消，中断的文件保留.upload
+// 标记，下一次启动时上传
+func (r *Rotator) drainUploader() {
+	if r.stopUpload == nil {
+		return
+	}
+
+	if r.uploadQueue != nil {
+		r.uploadLock.Lock()
+		r.uploadClosed = true
+		close(r.uploadQueue)
+		r.uploadLock.Unlock()
+		r.uploadWG.Wait()
+	}
+	r.stopUpload()
+}
+
+// cancelUploads 取消上传的上下文，中断正在执行和等待的上传
+func (r *Rotator) cancelUploads() {
+	if r.stopUpload != nil {
+		r.stopUpload()
+	}
+}
@@ -0,0 +1,143 @@
+// Copyright 2025 TimeWtr
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package vortexrotate
+
+import (
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// flakyUploader 前failures次上传失败的测试上传器
+type flakyUploader struct {
+	failures int32
+	calls    atomic.Int32
+}
+
+func (u *flakyUploader) Upload(ctx context.Context, _ string) error {
+	if u.calls.Add(1) <= u.failures {
+		return errors.New("temporary failure")
+	}
+
+	return ctx.Err()
+}
+
+// withTestUploader 使用测试上传器，缩短重试的等待时间
+func withTestUploader(u Uploader) Option {
+	return func(r *Rotator) error {
+		r.uploader = u
+		r.uploadBackoff = time.Millisecond
+		return nil
+	}
+}
+
+func TestRotator_UploadRetry(t *testing.T) {
+	dir := t.TempDir()
+	up := &flakyUploader{failures: UploadAttempts - 1}
+	rotator, err := newRotator(dir, "testdata.log",
+		WithCompress(CompressTypeGzip), WithDoneMarker(DoneMarkerFile), withTestUploader(up))
+	assert.NoError(t, err)
+	defer rotator.Close()
+
+	_, err = rotator.Write([]byte("upload retry test\n"))
+	assert.NoError(t, err)
+	path := compressFn(rotator.f.Name(), CompressTypeGzip)
+	assert.NoError(t, rotator.Rotate())
+	assert.NoError(t, rotator.awaitUploads(context.Background()))
+
+	// 重试之后上传成功，写入完成标记并删除.upload标记
+	assert.Equal(t, int32(UploadAttempts), up.calls.Load())
+	assert.Equal(t, uint64(1), rotator.Stats().Uploads)
+	assert.Equal(t, uint64(0), rotator.Stats().UploadFailures)
+	assert.FileExists(t, path+DoneFileExt)
+	assert.NoFileExists(t, path+UploadPendingExt)
+}
+
+func TestRotator_UploadPending(t *testing.T) {
+	dir := t.TempDir()
+	up := &flakyUploader{failures: UploadAttempts}
+	rotator, err := newRotator(dir, "testdata.log", WithDoneMarker(DoneMarkerFile), withTestUploader(up))
+	assert.NoError(t, err)
+	defer rotator.Close()
+
+	_, err = rotator.Write([]byte("upload pending test\n"))
+	assert.NoError(t, err)
+	path := rotator.f.Name()
+	assert.NoError(t, rotator.Rotate())
+	assert.NoError(t, rotator.awaitUploads(context.Background()))
+
+	// 全部重试失败时不写入完成标记，保留.upload标记
+	assert.Equal(t, uint64(1), rotator.Stats().UploadFailures)
+	assert.NoFileExists(t, path+DoneFileExt)
+	bs, err := os.ReadFile(path + UploadPendingExt)
+	assert.NoError(t, err)
+	assert.Equal(t, filepath.Base(path), string(bs))
+
+	// 已经被删除的文件只删除标记
+	orphan := filepath.Join(filepath.Dir(path), "testdata_20000101_0001.log"+UploadPendingExt)
+	assert.NoError(t, os.WriteFile(orphan, nil, ReadWriteFile))
+
+	rotator.reconcile()
+	assert.NoError(t, rotator.awaitUploads(context.Background()))
+	assert.FileExists(t, path+DoneFileExt)
+	assert.NoFileExists(t, path+UploadPendingExt)
+	assert.NoFileExists(t, orphan)
+	assert.Equal(t, uint64(1), rotator.Stats().Uploads)
+}
+
+func TestRotator_UploadContext(t *testing.T) {
+	up := blockingUploader{release: make(chan struct{})}
+	rotator, err := newRotator(t.TempDir(), "testdata.log", withTestUploader(up))
+	assert.NoError(t, err)
+
+	_, err = rotator.Write([]byte("upload context test\n"))
+	assert.NoError(t, err)
+	path := rotator.f.Name()
+	assert.NoError(t, rotator.Rotate())
+
+	// 上传阻塞时Shutdown的ctx到期之后取消上传，文件保留.upload标记，不发送失败事件
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+	assert.ErrorIs(t, rotator.Shutdown(ctx), context.DeadlineExceeded)
+	// 等待后台的关闭流程完成
+	assert.NoError(t, rotator.Close())
+	assert.Error(t, rotator.uploadCtx.Err())
+	assert.FileExists(t, path+UploadPendingExt)
+	assert.Equal(t, uint64(0), rotator.Stats().UploadFailures)
+}
+
+func TestRotator_UploadSealOnClose(t *testing.T) {
+	up := &flakyUploader{}
+	rotator, err := newRotator(t.TempDir(), "testdata.log",
+		WithSealOnClose(), WithDoneMarker(DoneMarkerFile), withTestUploader(up))
+	assert.NoError(t, err)
+
+	_, err = rotator.Write([]byte("seal on close test\n"))
+	assert.NoError(t, err)
+	path := rotator.f.Name()
+
+	// 关闭时封存的文件在取消上传的上下文之前上传
+	assert.NoError(t, rotator.Close())
+	assert.Equal(t, int32(1), up.calls.Load())
+	assert.Equal(t, uint64(1), rotator.Stats().Uploads)
+	assert.FileExists(t, path+DoneFileExt)
+	assert.NoFileExists(t, path+UploadPendingExt)
+}
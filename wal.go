@@ -0,0 +1,152 @@
+// Copyright 2025 TimeWtr
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package vortexrotate
+
+import (
+	"bufio"
+	"context"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"hash/crc32"
+	"io"
+
+	"github.com/TimeWtr/vortexrotate/errorx"
+)
+
+// MaxRecordSize WAL模式下单条记录的最大长度
+const MaxRecordSize = 64 * 1024 * 1024
+
+// walHeaderCRCSize 记录头中CRC校验码的长度
+const walHeaderCRCSize = 4
+
+var crcTable = crc32.MakeTable(crc32.Castagnoli)
+
+// LSN 日志序列号，即记录在轮转文件流中的起始位置，可以直接作为Cursor从该位置开始读取
+type LSN = Cursor
+
+// WithWAL 开启WAL模式，轮转器作为轻量级的预写日志使用，只能通过Append追加记录，不能再调用Write，
+// 记录以"长度(uvarint)+CRC32C校验码+内容"的格式写入，可以包含任意的二进制内容。文件轮转、压缩和
+// 清理复用已有的策略，WithMaxLines在WAL模式下限制的是单个文件中的记录数量。WAL模式不支持写入
+// 内容的转换函数。
+func WithWAL() Option {
+	return func(r *Rotator) error {
+		r.wal = true
+		return nil
+	}
+}
+
+// checkWAL 检查WAL模式与其他配置是否冲突
+func (r *Rotator) checkWAL() error {
+	if r.wal && len(r.transformers) > 0 {
+		return fmt.Errorf("%w: transformers", errorx.ErrWALConflict)
+	}
+
+	return nil
+}
+
+// Append 追加一条记录，记录写入并且fsync到磁盘之后才返回记录的LSN，返回成功的记录在进程或者
+// 系统崩溃之后不会丢失
+func (r *Rotator) Append(record []byte) (LSN, error) {
+	if len(record) > MaxRecordSize {
+		return LSN{}, fmt.Errorf("%w: size %d exceeds limit %d", errorx.ErrRecordTooLarge, len(record), MaxRecordSize)
+	}
+	if r.sig.Load() == 1 {
+		return LSN{}, errorx.ErrRotateClosed
+	}
+
+	r.lockWrite()
+	defer r.writeLock.Unlock()
+	if r.sig.Load() == 1 {
+		return LSN{}, errorx.ErrRotateClosed
+	}
+	if r.f == nil {
+		return LSN{}, errorx.ErrClosed
+	}
+	if !r.wal {
+		return LSN{}, errorx.ErrNotWALMode
+	}
+
+	lsn, _, err := r.writeAt(encodeRecord(record))
+	if err != nil {
+		return lsn, err
+	}
+
+	if r.syncPolicy != SyncEveryWrite {
+		if err = r.f.Sync(); err != nil {
+			return lsn, err
+		}
+	}
+
+	return lsn, nil
+}
+
+// encodeRecord 编码一条记录：长度(uvarint)+CRC32C校验码(小端)+内容
+func encodeRecord(record []byte) []byte {
+	buf := make([]byte, 0, binary.MaxVarintLen64+walHeaderCRCSize+len(record))
+	buf = binary.AppendUvarint(buf, uint64(len(record)))
+	buf = binary.LittleEndian.AppendUint32(buf, crc32.Checksum(record, crcTable))
+	return append(buf, record...)
+}
+
+// decodeRecord 解码一条WAL记录，已经轮转的文件末尾不完整的记录(崩溃时写入了一半)视为文件结束
+func decodeRecord(br *bufio.Reader, sealed bool) ([]byte, int64, error) {
+	incomplete := func() ([]byte, int64, error) {
+		if sealed {
+			return nil, 0, io.EOF
+		}
+		return nil, 0, errIncomplete
+	}
+
+	header, err := br.Peek(binary.MaxVarintLen64 + walHeaderCRCSize)
+	if err != nil && !errors.Is(err, io.EOF) {
+		return nil, 0, err
+	}
+	if len(header) == 0 {
+		return nil, 0, io.EOF
+	}
+
+	length, k := binary.Uvarint(header)
+	if k < 0 || length > MaxRecordSize {
+		return nil, 0, errorx.ErrCorruptRecord
+	}
+	if k == 0 || len(header) < k+walHeaderCRCSize {
+		return incomplete()
+	}
+	checksum := binary.LittleEndian.Uint32(header[k:])
+	headerSize := k + walHeaderCRCSize
+
+	data := make([]byte, length)
+	if _, err = br.Discard(headerSize); err != nil {
+		return nil, 0, err
+	}
+	if _, err = io.ReadFull(br, data); err != nil {
+		if errors.Is(err, io.EOF) || errors.Is(err, io.ErrUnexpectedEOF) {
+			return incomplete()
+		}
+		return nil, 0, err
+	}
+	if crc32.Checksum(data, crcTable) != checksum {
+		return nil, 0, errorx.ErrCorruptRecord
+	}
+
+	return data, int64(headerSize) + int64(length), nil
+}
+
+// ReadWAL 从from开始按照文件顺序逐条读取WAL记录，每一条记录调用一次fn，fn返回false时停止读取，
+// 返回下一条未读取记录的LSN。记录的校验码不一致时返回errorx.ErrCorruptRecord。
+func (ro *ReadOnly) ReadWAL(ctx context.Context, from LSN, fn func(Record) bool) (LSN, error) {
+	return ro.readRecords(ctx, from, decodeRecord, fn)
+}
@@ -0,0 +1,112 @@
+// Copyright 2025 TimeWtr
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package vortexrotate
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"testing"
+
+	"github.com/TimeWtr/vortexrotate/errorx"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestRotator_WAL(t *testing.T) {
+	dir := t.TempDir()
+	rotator, err := newRotator(dir, "testdata.log",
+		WithWAL(),
+		WithMaxLines(3),
+		WithCompress(CompressTypeSnappy))
+	assert.NoError(t, err)
+	defer rotator.Close()
+
+	_, err = rotator.Write([]byte("line\n"))
+	assert.ErrorIs(t, err, errorx.ErrWALMode)
+
+	var lsns []LSN
+	for i := 0; i < 10; i++ {
+		// 记录中可以包含换行符等任意内容
+		lsn, err := rotator.Append([]byte(fmt.Sprintf("record\n%d\x00", i)))
+		assert.NoError(t, err)
+		lsns = append(lsns, lsn)
+	}
+	for i := 1; i < len(lsns); i++ {
+		if lsns[i].Segment == lsns[i-1].Segment {
+			assert.Greater(t, lsns[i].Offset, lsns[i-1].Offset)
+		} else {
+			assert.Greater(t, lsns[i].Segment, lsns[i-1].Segment)
+			assert.Equal(t, int64(0), lsns[i].Offset)
+		}
+	}
+
+	ro, err := OpenReadOnly(dir, "testdata.log")
+	assert.NoError(t, err)
+	var records []Record
+	next, err := ro.ReadWAL(context.Background(), LSN{}, func(rec Record) bool {
+		records = append(records, rec)
+		return true
+	})
+	assert.NoError(t, err)
+	assert.Len(t, records, 10)
+	for i, rec := range records {
+		assert.Equal(t, lsns[i], rec.Cursor)
+		assert.Equal(t, fmt.Sprintf("record\n%d\x00", i), string(rec.Data))
+	}
+
+	// 从指定的LSN开始读取
+	records = records[:0]
+	_, err = ro.ReadWAL(context.Background(), lsns[7], func(rec Record) bool {
+		records = append(records, rec)
+		return true
+	})
+	assert.NoError(t, err)
+	assert.Len(t, records, 3)
+
+	// 写入了一半的记录不会返回
+	rotator.writeLock.Lock()
+	_, err = rotator.f.Write(encodeRecord([]byte("partial"))[:5])
+	rotator.writeLock.Unlock()
+	assert.NoError(t, err)
+	got, err := ro.ReadWAL(context.Background(), next, func(Record) bool { return true })
+	assert.NoError(t, err)
+	assert.Equal(t, next, got)
+}
+
+func TestRotator_WALMode(t *testing.T) {
+	dir := t.TempDir()
+	_, err := newRotator(dir, "testdata.log", WithWAL(), WithTransformers(StripANSI))
+	assert.ErrorIs(t, err, errorx.ErrWALConflict)
+	assert.True(t, errorx.IsWALMisuse(err))
+
+	rotator, err := newRotator(dir, "testdata.log")
+	assert.NoError(t, err)
+	_, err = rotator.Append([]byte("record"))
+	assert.ErrorIs(t, err, errorx.ErrNotWALMode)
+	assert.NoError(t, rotator.Close())
+}
+
+func TestDecodeRecord_Corrupt(t *testing.T) {
+	dir := t.TempDir()
+	data := encodeRecord([]byte("record"))
+	data[len(data)-1] ^= 0xff
+	seg := dir + "/testdata_20250101_0001.log"
+	assert.NoError(t, os.WriteFile(seg, data, ReadWriteFile))
+
+	ro, err := OpenReadOnly(dir, "testdata.log")
+	assert.NoError(t, err)
+	_, err = ro.ReadWAL(context.Background(), LSN{}, func(Record) bool { return true })
+	assert.ErrorIs(t, err, errorx.ErrCorruptRecord)
+}
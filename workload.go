@@ -0,0 +1,214 @@
+// Copyright 2025 TimeWtr
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package vortexrotate
+
+import (
+	"bufio"
+	"context"
+	"fmt"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// WorkloadRecord 写入负载中的一次写入
+type WorkloadRecord struct {
+	// 相对于开始记录的时间偏移
+	Offset time.Duration
+	// 写入的字节数
+	Size int
+}
+
+// workloadRecorder 记录写入负载，每一行记录一次写入："<时间偏移(纳秒)> <字节数>"
+type workloadRecorder struct {
+	lock  sync.Mutex
+	f     *os.File
+	w     *bufio.Writer
+	start time.Time
+}
+
+// WithWorkloadCapture 记录每一次写入的时间和大小到path文件中，不记录写入的内容，记录的负载
+// 可以通过ReadWorkload读取，使用ReplayWorkload在不同的配置下回放，用于衡量写入和轮转路径的
+// 性能变化
+func WithWorkloadCapture(path string) Option {
+	return func(r *Rotator) error {
+		f, err := os.OpenFile(path, os.O_CREATE|os.O_TRUNC|os.O_WRONLY|openNoFollow, ReadWriteFile)
+		if err != nil {
+			return err
+		}
+
+		r.capture = &workloadRecorder{
+			f:     f,
+			w:     bufio.NewWriter(f),
+			start: time.Now(),
+		}
+		return nil
+	}
+}
+
+// record 记录一次写入
+func (w *workloadRecorder) record(size int) {
+	if w == nil {
+		return
+	}
+
+	w.lock.Lock()
+	defer w.lock.Unlock()
+
+	if w.f == nil {
+		return
+	}
+	_, _ = fmt.Fprintf(w.w, "%d %d\n", time.Since(w.start).Nanoseconds(), size)
+}
+
+// Close 刷新缓冲区并关闭记录文件
+func (w *workloadRecorder) Close() error {
+	if w == nil {
+		return nil
+	}
+
+	w.lock.Lock()
+	defer w.lock.Unlock()
+
+	if w.f == nil {
+		return nil
+	}
+	err := w.w.Flush()
+	if err1 := w.f.Close(); err == nil {
+		err = err1
+	}
+	w.f = nil
+
+	return err
+}
+
+// ReadWorkload 读取WithWorkloadCapture记录的写入负载
+func ReadWorkload(path string) ([]WorkloadRecord, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer func() {
+		_ = f.Close()
+	}()
+
+	var records []WorkloadRecord
+	sc := bufio.NewScanner(f)
+	for line := 1; sc.Scan(); line++ {
+		fields := strings.Fields(sc.Text())
+		const fieldsLen = 2
+		if len(fields) != fieldsLen {
+			return nil, fmt.Errorf("invalid workload record at line %d", line)
+		}
+
+		offset, err := strconv.ParseInt(fields[0], 10, 64)
+		if err != nil {
+			return nil, fmt.Errorf("parse offset at line %d error: %w", line, err)
+		}
+		size, err := strconv.Atoi(fields[1])
+		if err != nil || size < 0 {
+			return nil, fmt.Errorf("invalid size at line %d", line)
+		}
+		records = append(records, WorkloadRecord{Offset: time.Duration(offset), Size: size})
+	}
+
+	return records, sc.Err()
+}
+
+// ReplayResult 回放写入负载的结果
+type ReplayResult struct {
+	// 写入次数
+	Writes int
+	// 写入的总字节数
+	Bytes int64
+	// 回放的总耗时
+	Duration time.Duration
+	// 吞吐量(字节/秒)
+	Throughput float64
+	// 写入延迟的分位数
+	P50, P99, Max time.Duration
+	// 回放过程中的轮转统计
+	Stats Stats
+}
+
+// ReplayWorkload 在dir中使用opts创建轮转器，按照records中的时间和大小回放写入负载，写入的内容
+// 是固定的数据，相同的负载和配置每次写入完全相同的数据。speed为回放速度的倍数，比如2表示以两倍
+// 的速度回放，speed<=0时不等待，以最快的速度依次写入。
+func ReplayWorkload(ctx context.Context, records []WorkloadRecord, dir, filename string,
+	speed float64, opts ...Option) (*ReplayResult, error) {
+	rotator, err := newRotator(dir, filename, opts...)
+	if err != nil {
+		return nil, err
+	}
+
+	var buf []byte
+	latencies := make([]time.Duration, 0, len(records))
+	res := &ReplayResult{}
+	start := time.Now()
+	for _, rec := range records {
+		if err = ctx.Err(); err != nil {
+			break
+		}
+		if speed > 0 {
+			if wait := time.Until(start.Add(time.Duration(float64(rec.Offset) / speed))); wait > 0 {
+				time.Sleep(wait)
+			}
+		}
+
+		if cap(buf) < rec.Size {
+			buf = workloadPayload(rec.Size)
+		}
+		begin := time.Now()
+		if _, err = rotator.Write(buf[len(buf)-rec.Size:]); err != nil {
+			break
+		}
+		latencies = append(latencies, time.Since(begin))
+		res.Writes++
+		res.Bytes += int64(rec.Size)
+	}
+	res.Duration = time.Since(start)
+	res.Stats = rotator.Stats()
+	if err1 := rotator.Close(); err == nil {
+		err = err1
+	}
+
+	if res.Duration > 0 {
+		res.Throughput = float64(res.Bytes) / res.Duration.Seconds()
+	}
+	if len(latencies) > 0 {
+		sort.Slice(latencies, func(i, j int) bool { return latencies[i] < latencies[j] })
+		res.P50 = latencies[len(latencies)/2]
+		res.P99 = latencies[len(latencies)*99/100]
+		res.Max = latencies[len(latencies)-1]
+	}
+
+	return res, err
+}
+
+// workloadPayload 生成回放使用的固定数据，以换行符结尾，任意长度的后缀仍然以换行符结尾
+func workloadPayload(size int) []byte {
+	buf := make([]byte, size)
+	for i := range buf {
+		buf[i] = 'a' + byte(i%26)
+	}
+	if size > 0 {
+		buf[size-1] = '\n'
+	}
+
+	return buf
+}
@@ -0,0 +1,75 @@
+// Copyright 2025 TimeWtr
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package vortexrotate
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestWorkload_CaptureReplay(t *testing.T) {
+	trace := filepath.Join(t.TempDir(), "workload.trace")
+	rotator, err := newRotator(t.TempDir(), "testdata.log", WithWorkloadCapture(trace))
+	assert.NoError(t, err)
+	sizes := []int{10, 200, 0, 35}
+	for _, size := range sizes {
+		_, err = rotator.Write(make([]byte, size))
+		assert.NoError(t, err)
+		time.Sleep(time.Millisecond)
+	}
+	assert.NoError(t, rotator.Close())
+
+	records, err := ReadWorkload(trace)
+	assert.NoError(t, err)
+	assert.Len(t, records, len(sizes))
+	for i, rec := range records {
+		assert.Equal(t, sizes[i], rec.Size)
+		if i > 0 {
+			assert.Greater(t, rec.Offset, records[i-1].Offset)
+		}
+	}
+
+	dir := t.TempDir()
+	res, err := ReplayWorkload(context.Background(), records, dir, "replay.log", 0,
+		WithRotate(128, Hour))
+	assert.NoError(t, err)
+	assert.Equal(t, len(sizes), res.Writes)
+	assert.Equal(t, int64(245), res.Bytes)
+	assert.Greater(t, res.Stats.TotalRotations, uint64(0))
+	assert.LessOrEqual(t, res.P50, res.Max)
+
+	// 回放的内容是确定的
+	ro, err := OpenReadOnly(dir, "replay.log")
+	assert.NoError(t, err)
+	segments, err := ro.List()
+	assert.NoError(t, err)
+	var total int64
+	for _, seg := range segments {
+		total += seg.Size
+	}
+	assert.Equal(t, res.Bytes, total)
+}
+
+func TestReadWorkload_Invalid(t *testing.T) {
+	trace := filepath.Join(t.TempDir(), "workload.trace")
+	assert.NoError(t, os.WriteFile(trace, []byte("100 20\nbad\n"), ReadWriteFile))
+	_, err := ReadWorkload(trace)
+	assert.ErrorContains(t, err, "line 2")
+}
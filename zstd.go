@@ -0,0 +1,195 @@
+// Copyright 2025 TimeWtr
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package vortexrotate
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"sync"
+
+	"github.com/klauspost/compress/zstd"
+)
+
+const (
+	// ZstdMinLevel zstd最小的压缩等级
+	ZstdMinLevel = 1
+	// ZstdDefaultLevel zstd默认的压缩等级
+	ZstdDefaultLevel = 3
+	// ZstdMaxLevel zstd最大的压缩等级
+	ZstdMaxLevel = 22
+	// ZstdMinWindowLog zstd最小的窗口大小(以2为底的对数)
+	ZstdMinWindowLog = 10
+	// ZstdMaxWindowLog zstd最大的窗口大小(以2为底的对数)，更大的窗口需要解压方显式放开限制
+	ZstdMaxWindowLog = 27
+)
+
+// ZstdBackend zstd压缩的实现
+type ZstdBackend int
+
+const (
+	// ZstdBackendDefault 默认实现，通过RegisterZstdCodec注册了cgo实现(导入zstdcgo模块)时使用
+	// 注册的实现，否则使用纯Go实现
+	ZstdBackendDefault ZstdBackend = iota
+	// ZstdBackendCgo 通过RegisterZstdCodec注册的cgo实现，比如zstdcgo模块中基于gozstd的实现，压缩速度最快
+	ZstdBackendCgo
+	// ZstdBackendPureGo 纯Go实现(klauspost/compress/zstd)，不依赖cgo，可以交叉编译
+	ZstdBackendPureGo
+)
+
+// WithZstdBackend 设置zstd压缩使用的实现，与WithCompress的顺序无关，没有注册cgo实现时
+// 不能选择ZstdBackendCgo。解压时始终使用默认实现，两种实现生成的文件格式完全兼容。
+func WithZstdBackend(backend ZstdBackend) Option {
+	return func(r *Rotator) error {
+		switch backend {
+		case ZstdBackendDefault, ZstdBackendPureGo:
+		case ZstdBackendCgo:
+			if !HasFeature(FeatureZstdCgo) {
+				return fmt.Errorf("feature %s is not available in this build", FeatureZstdCgo)
+			}
+		default:
+			return errors.New("unknown zstd backend")
+		}
+
+		r.zstdBackend = backend
+		if z, ok := r.cpr.cs.(*Zstd); ok {
+			z.backend = backend
+		}
+		return nil
+	}
+}
+
+// ZstdWriter 可以复用的zstd压缩写入器，Close时写入帧的结尾，但不会关闭底层的输出
+type ZstdWriter interface {
+	io.WriteCloser
+	// Flush 刷新缓冲的数据
+	Flush() error
+	// Reset 重置输出，开始新的压缩帧
+	Reset(w io.Writer)
+}
+
+// ZstdParams zstd压缩的参数，同时作为压缩写入器缓存的键，必须可以比较
+type ZstdParams struct {
+	// Level 压缩等级
+	Level int
+	// WindowLog 窗口大小以2为底的对数，0表示使用压缩等级对应的默认值
+	WindowLog int
+	// Dict 压缩使用的字典ID，0表示不使用字典，字典内容通过ZstdDictionary获取
+	Dict uint32
+}
+
+// ZstdCodec zstd压缩的实现，依赖cgo的实现放在单独的模块中(github.com/TimeWtr/vortexrotate/zstdcgo)，
+// 通过RegisterZstdCodec注册，核心的轮转库只依赖纯Go实现
+type ZstdCodec interface {
+	// NewWriter 创建指定压缩参数的压缩写入器
+	NewWriter(w io.Writer, p ZstdParams) (ZstdWriter, error)
+	// NewReader 创建解压读取器，加载了字典时不会调用，使用纯Go实现按照帧中的字典ID选择字典
+	NewReader(r io.Reader) (io.ReadCloser, error)
+}
+
+var (
+	zstdCodecLock sync.RWMutex
+	// cgoZstd 通过RegisterZstdCodec注册的实现，没有注册时为nil
+	cgoZstd ZstdCodec
+)
+
+// RegisterZstdCodec 注册基于cgo的zstd实现，通常在实现模块的init中调用，只能注册一次。注册之后
+// ZstdBackendDefault使用该实现，可以选择ZstdBackendCgo，并且注册FeatureZstdCgo功能。
+func RegisterZstdCodec(c ZstdCodec) error {
+	if IsNil(c) {
+		return errors.New("zstd codec must not be nil")
+	}
+
+	zstdCodecLock.Lock()
+	defer zstdCodecLock.Unlock()
+
+	if cgoZstd != nil {
+		return errors.New("zstd codec already registered")
+	}
+	cgoZstd = c
+	registerFeature(FeatureZstdCgo)
+	return nil
+}
+
+// ZstdDictionary 获取通过WithZstdDictionary加载的字典，用于ZstdCodec创建带字典的压缩写入器
+func ZstdDictionary(id uint32) ([]byte, bool) {
+	dict := resources.zstdDict(id)
+	return dict, dict != nil
+}
+
+// resolveZstdBackend 解析实际使用的zstd实现，没有注册cgo实现时使用纯Go实现，实现注册之后
+// 不能替换，解析的结果可以作为压缩上下文池的键
+func resolveZstdBackend(backend ZstdBackend) ZstdBackend {
+	zstdCodecLock.RLock()
+	defer zstdCodecLock.RUnlock()
+
+	if backend == ZstdBackendPureGo || cgoZstd == nil {
+		return ZstdBackendPureGo
+	}
+
+	return ZstdBackendCgo
+}
+
+// zstdCodecOf 获取zstd压缩的实现
+func zstdCodecOf(backend ZstdBackend) ZstdCodec {
+	if resolveZstdBackend(backend) == ZstdBackendPureGo {
+		return pureZstd{}
+	}
+
+	zstdCodecLock.RLock()
+	defer zstdCodecLock.RUnlock()
+	return cgoZstd
+}
+
+// newZstdReader 创建zstd解压读取器，加载了字典时使用纯Go实现，注册的实现只能指定一个字典，
+// 纯Go实现可以按照帧中记录的字典ID选择字典
+func newZstdReader(backend ZstdBackend, r io.Reader) (io.ReadCloser, error) {
+	if len(resources.allZstdDicts()) > 0 {
+		return pureZstd{}.NewReader(r)
+	}
+
+	return zstdCodecOf(backend).NewReader(r)
+}
+
+// pureZstd 纯Go的zstd实现
+type pureZstd struct{}
+
+func (pureZstd) NewWriter(w io.Writer, p ZstdParams) (ZstdWriter, error) {
+	opts := []zstd.EOption{
+		zstd.WithEncoderLevel(zstd.EncoderLevelFromZstd(p.Level)),
+		zstd.WithEncoderConcurrency(1),
+	}
+	if p.WindowLog > 0 {
+		opts = append(opts, zstd.WithWindowSize(1<<p.WindowLog))
+	}
+	if p.Dict != 0 {
+		opts = append(opts, zstd.WithEncoderDict(resources.zstdDict(p.Dict)))
+	}
+
+	return zstd.NewWriter(w, opts...)
+}
+
+func (pureZstd) NewReader(r io.Reader) (io.ReadCloser, error) {
+	opts := []zstd.DOption{zstd.WithDecoderConcurrency(1)}
+	if dicts := resources.allZstdDicts(); len(dicts) > 0 {
+		opts = append(opts, zstd.WithDecoderDicts(dicts...))
+	}
+	d, err := zstd.NewReader(r, opts...)
+	if err != nil {
+		return nil, err
+	}
+
+	return d.IOReadCloser(), nil
+}
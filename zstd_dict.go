@@ -0,0 +1,90 @@
+// Copyright 2025 TimeWtr
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package vortexrotate
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+
+	"github.com/klauspost/compress/zstd"
+)
+
+// WithZstdDictionary 使用zstd字典压缩轮转文件，dict为zstd --train等工具生成的字典，适用于单个文件
+// 较小、格式高度相似的日志。字典按照字典ID在进程内所有轮转器之间共享，相同ID的字典只加载一次，
+// 不同内容的字典不能使用相同的ID。压缩帧中记录了字典ID，解压(校验、读取、导出等)时自动使用进程内
+// 已经加载的字典，与WithCompress的顺序无关，边写边压缩不使用字典。
+func WithZstdDictionary(dict []byte) Option {
+	return func(r *Rotator) error {
+		id, err := resources.addZstdDict(dict)
+		if err != nil {
+			return err
+		}
+
+		r.zstdDict = id
+		if z, ok := r.cpr.cs.(*Zstd); ok {
+			z.dict = id
+		}
+		return nil
+	}
+}
+
+// addZstdDict 加载zstd字典，返回字典ID，相同ID的字典已经加载时直接复用
+func (m *resourceManager) addZstdDict(dict []byte) (uint32, error) {
+	d, err := zstd.InspectDictionary(dict)
+	if err != nil {
+		return 0, fmt.Errorf("invalid zstd dictionary: %w", err)
+	}
+	id := d.ID()
+	if id == 0 {
+		// 压缩帧中不记录ID为0的字典，解压时无法选择字典
+		return 0, errors.New("zstd dictionary id must not be zero")
+	}
+
+	m.dictLock.Lock()
+	defer m.dictLock.Unlock()
+
+	if old, ok := m.zstdDicts[id]; ok {
+		if !bytes.Equal(old, dict) {
+			return 0, fmt.Errorf("zstd dictionary %d already loaded with different content", id)
+		}
+		return id, nil
+	}
+	if m.zstdDicts == nil {
+		m.zstdDicts = make(map[uint32][]byte)
+	}
+	m.zstdDicts[id] = bytes.Clone(dict)
+	return id, nil
+}
+
+// zstdDict 获取已经加载的zstd字典
+func (m *resourceManager) zstdDict(id uint32) []byte {
+	m.dictLock.RLock()
+	defer m.dictLock.RUnlock()
+
+	return m.zstdDicts[id]
+}
+
+// allZstdDicts 获取所有已经加载的zstd字典，用于解压
+func (m *resourceManager) allZstdDicts() [][]byte {
+	m.dictLock.RLock()
+	defer m.dictLock.RUnlock()
+
+	res := make([][]byte, 0, len(m.zstdDicts))
+	for _, d := range m.zstdDicts {
+		res = append(res, d)
+	}
+	return res
+}
@@ -0,0 +1,188 @@
+// Copyright 2025 TimeWtr
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package vortexrotate
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/klauspost/compress/zstd"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestZstdBackend_RoundTrip(t *testing.T) {
+	content := bytes.Repeat([]byte("zstd backend test content\n"), 1024)
+	params := []ZstdParams{
+		{Level: ZstdDefaultLevel},
+		{Level: 9, WindowLog: ZstdMaxWindowLog},
+	}
+	for _, backend := range []ZstdBackend{ZstdBackendDefault, ZstdBackendPureGo} {
+		for _, p := range params {
+			resolved := resolveZstdBackend(backend)
+			var buf bytes.Buffer
+			w, err := resources.getZstdWriter(resolved, &buf, p)
+			assert.NoError(t, err)
+			_, err = w.Write(content)
+			assert.NoError(t, err)
+			assert.NoError(t, w.Close())
+			resources.putZstdWriter(resolved, w, p)
+
+			// 两种实现生成的文件格式兼容
+			for _, other := range []ZstdBackend{ZstdBackendDefault, ZstdBackendPureGo} {
+				r, err := newZstdReader(other, bytes.NewReader(buf.Bytes()))
+				assert.NoError(t, err)
+				bs, err := io.ReadAll(r)
+				assert.NoError(t, err)
+				assert.NoError(t, r.Close())
+				assert.Equal(t, content, bs)
+			}
+		}
+	}
+}
+
+// testZstdCodec 测试用的注册实现，委托给纯Go实现，包含不可比较的字段
+type testZstdCodec struct {
+	pureZstd
+	levels []int
+}
+
+func TestRegisterZstdCodec(t *testing.T) {
+	assert.Error(t, RegisterZstdCodec(nil))
+
+	zstdCodecLock.RLock()
+	registered := cgoZstd != nil
+	zstdCodecLock.RUnlock()
+	if !registered {
+		assert.NoError(t, RegisterZstdCodec(testZstdCodec{}))
+		assert.Equal(t, ZstdCodec(testZstdCodec{}), zstdCodecOf(ZstdBackendDefault))
+	}
+	assert.True(t, HasFeature(FeatureZstdCgo))
+	assert.Equal(t, ZstdCodec(pureZstd{}), zstdCodecOf(ZstdBackendPureGo))
+	// 只能注册一次
+	assert.Error(t, RegisterZstdCodec(testZstdCodec{}))
+
+	rotator, err := newRotator(t.TempDir(), "testdata.log",
+		WithZstdBackend(ZstdBackendCgo), WithCompress(CompressTypeZstd))
+	assert.NoError(t, err)
+	defer rotator.Close()
+	assert.Equal(t, ZstdBackendCgo, rotator.cpr.cs.(*Zstd).backend)
+	// 注册的实现不可比较时也可以复用压缩上下文
+	_, err = rotator.Write([]byte("registered zstd codec test\n"))
+	assert.NoError(t, err)
+	path := rotator.f.Name()
+	assert.NoError(t, rotator.Rotate())
+	assert.FileExists(t, compressFn(path, CompressTypeZstd))
+
+	_, ok := ZstdDictionary(0)
+	assert.False(t, ok)
+	dict := buildTestDict(t, 4321)
+	rotator2, err := newRotator(t.TempDir(), "testdata.log", WithZstdDictionary(dict))
+	assert.NoError(t, err)
+	defer rotator2.Close()
+	bs, ok := ZstdDictionary(4321)
+	assert.True(t, ok)
+	assert.Equal(t, dict, bs)
+}
+
+func TestRotator_ZstdBackend(t *testing.T) {
+	rotator, err := newRotator(t.TempDir(), "testdata.log",
+		WithZstdBackend(ZstdBackendPureGo), WithCompress(CompressTypeZstd))
+	assert.NoError(t, err)
+	defer rotator.Close()
+	assert.Equal(t, ZstdBackendPureGo, rotator.cpr.cs.(*Zstd).backend)
+
+	_, err = rotator.Write([]byte("zstd backend test\n"))
+	assert.NoError(t, err)
+	path := rotator.f.Name()
+	assert.NoError(t, rotator.Rotate())
+
+	f, err := os.Open(compressFn(path, CompressTypeZstd))
+	assert.NoError(t, err)
+	defer f.Close()
+	dr, err := newDecompressReader(CompressTypeZstd, f)
+	assert.NoError(t, err)
+	defer dr.Close()
+	bs, err := io.ReadAll(dr)
+	assert.NoError(t, err)
+	assert.Equal(t, "zstd backend test\n", string(bs))
+
+	// 选项的顺序无关
+	rotator2, err := newRotator(filepath.Join(t.TempDir(), "other"), "testdata.log",
+		WithCompress(CompressTypeZstd), WithZstdBackend(ZstdBackendPureGo))
+	assert.NoError(t, err)
+	defer rotator2.Close()
+	assert.Equal(t, ZstdBackendPureGo, rotator2.cpr.cs.(*Zstd).backend)
+
+	_, err = newRotator(t.TempDir(), "testdata.log", WithZstdBackend(ZstdBackend(100)))
+	assert.Error(t, err)
+}
+
+// buildTestDict 根据样本生成测试用的zstd字典
+func buildTestDict(t *testing.T, id uint32) []byte {
+	samples := make([][]byte, 0, 64)
+	for i := 0; i < 64; i++ {
+		samples = append(samples, []byte(fmt.Sprintf(
+			`{"level":"info","ts":"2025-01-01T00:00:%02d","msg":"request served","status":200,"id":%d}`, i%60, i)))
+	}
+	dict, err := zstd.BuildDict(zstd.BuildDictOptions{
+		ID:       id,
+		Contents: samples,
+		History:  bytes.Repeat([]byte(`{"level":"info","msg":"request served","status":200}`), 16),
+		Offsets:  [3]int{1, 4, 8},
+	})
+	assert.NoError(t, err)
+	return dict
+}
+
+func TestRotator_ZstdDictionary(t *testing.T) {
+	dict := buildTestDict(t, 0x7e57)
+	for _, backend := range []ZstdBackend{ZstdBackendDefault, ZstdBackendPureGo} {
+		rotator, err := newRotator(t.TempDir(), "testdata.log",
+			WithZstdDictionary(dict), WithZstdBackend(backend), WithCompress(CompressTypeZstd))
+		assert.NoError(t, err)
+		assert.Equal(t, uint32(0x7e57), rotator.cpr.cs.(*Zstd).dict)
+
+		content := `{"level":"info","ts":"2025-01-02T00:00:00","msg":"request served","status":200,"id":1}` + "\n"
+		_, err = rotator.Write([]byte(content))
+		assert.NoError(t, err)
+		path := rotator.f.Name()
+		// 压缩之后解压校验通过才会删除源文件
+		assert.NoError(t, rotator.Rotate())
+		assert.NoFileExists(t, path)
+
+		f, err := os.Open(compressFn(path, CompressTypeZstd))
+		assert.NoError(t, err)
+		dr, err := newDecompressReader(CompressTypeZstd, f)
+		assert.NoError(t, err)
+		bs, err := io.ReadAll(dr)
+		assert.NoError(t, err)
+		assert.Equal(t, content, string(bs))
+		assert.NoError(t, dr.Close())
+		assert.NoError(t, f.Close())
+		assert.NoError(t, rotator.Close())
+	}
+
+	// 相同ID的字典内容必须一致
+	other := bytes.Clone(dict)
+	other[len(other)-1] ^= 0xff
+	_, err := newRotator(t.TempDir(), "testdata.log", WithZstdDictionary(other))
+	assert.Error(t, err)
+	_, err = newRotator(t.TempDir(), "testdata.log", WithZstdDictionary([]byte("not a dictionary")))
+	assert.Error(t, err)
+}
@@ -0,0 +1,22 @@
+// Copyright 2025 TimeWtr
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package zstdcgo 基于cgo(gozstd)的zstd压缩实现，单独作为一个模块，核心的轮转库不依赖cgo。
+// 匿名导入之后在init中通过vortexrotate.RegisterZstdCodec注册，ZstdBackendDefault使用该实现，
+// 并且可以通过WithZstdBackend选择ZstdBackendCgo：
+//
+//	import _ "github.com/TimeWtr/vortexrotate/zstdcgo"
+//
+// 关闭cgo(CGO_ENABLED=0)时不注册任何实现，继续使用纯Go实现。
+package zstdcgo
@@ -0,0 +1,20 @@
+module github.com/TimeWtr/vortexrotate/zstdcgo
+
+go 1.23.4
+
+require (
+	github.com/TimeWtr/vortexrotate v0.0.0-20261016153402-5659fb2c17ba
+	github.com/klauspost/compress v1.18.0
+	github.com/stretchr/testify v1.10.0
+	github.com/valyala/gozstd v1.21.2
+)
+
+require (
+	github.com/davecgh/go-spew v1.1.1 // indirect
+	github.com/golang/snappy v1.0.0 // indirect
+	github.com/klauspost/pgzip v1.2.6 // indirect
+	github.com/pmezard/go-difflib v1.0.0 // indirect
+	github.com/robfig/cron/v3 v3.0.1 // indirect
+	github.com/ulikunitz/xz v0.5.15 // indirect
+	gopkg.in/yaml.v3 v3.0.1 // indirect
+)
@@ -0,0 +1,94 @@
+// Copyright 2025 TimeWtr
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+//go:build cgo
+
+package zstdcgo
+
+import (
+	"errors"
+	"io"
+	"sync"
+
+	"github.com/TimeWtr/vortexrotate"
+	"github.com/valyala/gozstd"
+)
+
+func init() {
+	if err := vortexrotate.RegisterZstdCodec(codec{}); err != nil {
+		panic(err)
+	}
+}
+
+// codec 基于cgo的gozstd实现
+type codec struct{}
+
+// cdicts 字典ID和压缩等级 -> gozstd压缩字典，字典在进程内共享，不释放
+var cdicts sync.Map
+
+// cdictKey gozstd压缩字典的键
+type cdictKey struct {
+	id    uint32
+	level int
+}
+
+func (codec) NewWriter(w io.Writer, p vortexrotate.ZstdParams) (vortexrotate.ZstdWriter, error) {
+	params := &gozstd.WriterParams{CompressionLevel: p.Level, WindowLog: p.WindowLog}
+	if p.Dict != 0 {
+		key := cdictKey{id: p.Dict, level: p.Level}
+		v, ok := cdicts.Load(key)
+		if !ok {
+			dict, ok := vortexrotate.ZstdDictionary(p.Dict)
+			if !ok {
+				return nil, errors.New("zstd dictionary not loaded")
+			}
+			cd, err := gozstd.NewCDictLevel(dict, p.Level)
+			if err != nil {
+				return nil, err
+			}
+			v, _ = cdicts.LoadOrStore(key, cd)
+		}
+		params.Dict, _ = v.(*gozstd.CDict)
+	}
+
+	return &writer{Writer: gozstd.NewWriterParams(w, params), params: params}, nil
+}
+
+func (codec) NewReader(r io.Reader) (io.ReadCloser, error) {
+	return &reader{r: gozstd.NewReader(r)}, nil
+}
+
+// writer 重置输出时保持压缩参数不变
+type writer struct {
+	*gozstd.Writer
+	params *gozstd.WriterParams
+}
+
+func (w *writer) Reset(out io.Writer) {
+	w.Writer.ResetWriterParams(out, w.params)
+}
+
+// reader Close时释放zstd解压上下文
+type reader struct {
+	r *gozstd.Reader
+}
+
+func (z *reader) Read(p []byte) (int, error) {
+	return z.r.Read(p)
+}
+
+func (z *reader) Close() error {
+	z.r.Release()
+	return nil
+}
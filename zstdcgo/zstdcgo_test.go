@@ -0,0 +1,83 @@
+// Copyright 2025 TimeWtr
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+//go:build cgo
+
+package zstdcgo
+
+import (
+	"bytes"
+	"io"
+	"testing"
+
+	"github.com/TimeWtr/vortexrotate"
+	"github.com/klauspost/compress/zstd"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestCodec_Register(t *testing.T) {
+	assert.True(t, vortexrotate.HasFeature(vortexrotate.FeatureZstdCgo))
+	assert.Error(t, vortexrotate.RegisterZstdCodec(codec{}))
+
+	rotator, err := vortexrotate.NewRotator(t.TempDir(), "testdata.log",
+		vortexrotate.WithZstdBackend(vortexrotate.ZstdBackendCgo), vortexrotate.WithCompress(vortexrotate.CompressTypeZstd))
+	assert.NoError(t, err)
+	_, err = rotator.Write([]byte("zstd cgo test\n"))
+	assert.NoError(t, err)
+	assert.NoError(t, rotator.Rotate())
+	assert.NoError(t, rotator.Close())
+}
+
+func TestCodec_RoundTrip(t *testing.T) {
+	content := bytes.Repeat([]byte("zstd cgo test content\n"), 1024)
+	for _, p := range []vortexrotate.ZstdParams{
+		{Level: vortexrotate.ZstdDefaultLevel},
+		{Level: 9, WindowLog: vortexrotate.ZstdMaxWindowLog},
+	} {
+		var buf bytes.Buffer
+		w, err := codec{}.NewWriter(&buf, p)
+		assert.NoError(t, err)
+		_, err = w.Write(content)
+		assert.NoError(t, err)
+		assert.NoError(t, w.Close())
+
+		r, err := codec{}.NewReader(bytes.NewReader(buf.Bytes()))
+		assert.NoError(t, err)
+		bs, err := io.ReadAll(r)
+		assert.NoError(t, err)
+		assert.NoError(t, r.Close())
+		assert.Equal(t, content, bs)
+
+		// 与纯Go实现的文件格式兼容
+		d, err := zstd.NewReader(bytes.NewReader(buf.Bytes()))
+		assert.NoError(t, err)
+		bs, err = io.ReadAll(d)
+		assert.NoError(t, err)
+		d.Close()
+		assert.Equal(t, content, bs)
+
+		// 重置输出之后继续使用相同的参数
+		buf.Reset()
+		w.Reset(&buf)
+		_, err = w.Write(content)
+		assert.NoError(t, err)
+		assert.NoError(t, w.Close())
+		assert.NotZero(t, buf.Len())
+	}
+}
+
+func TestCodec_DictNotLoaded(t *testing.T) {
+	_, err := codec{}.NewWriter(io.Discard, vortexrotate.ZstdParams{Level: 3, Dict: 12345})
+	assert.Error(t, err)
+}